@@ -21,6 +21,7 @@ import (
 
 	"github.com/ironhost/agent/internal/docker"
 	agentgrpc "github.com/ironhost/agent/internal/grpc"
+	"github.com/ironhost/agent/internal/quota"
 	"github.com/ironhost/agent/internal/sysinfo"
 )
 
@@ -33,6 +34,7 @@ var (
 	nodeID    = flag.String("node-id", "", "Unique node identifier")
 	insecure  = flag.Bool("insecure", false, "Run without TLS (for development)")
 	authToken = flag.String("token", "", "Authentication token (required in insecure mode)")
+	diskQuota = flag.String("disk-quota", "auto", "Disk limit enforcement: auto, xfs, loopback or none")
 )
 
 func main() {
@@ -67,13 +69,23 @@ func main() {
 	}
 	defer dockerMgr.Close()
 
+	// Initialize disk quota enforcement for server data directories
+	quotaMgr, err := quota.New(*dataDir, quota.Mode(*diskQuota))
+	if err != nil {
+		log.Fatalf("Failed to initialize disk quotas: %v", err)
+	}
+	log.Printf("Disk quota mode: %s", quotaMgr.Mode())
+
 	// Setup gRPC server options
 	var opts []grpc.ServerOption
 
 	if *insecure {
 		// Insecure mode - use token authentication via interceptor
 		if *authToken != "" {
-			opts = append(opts, grpc.UnaryInterceptor(tokenAuthInterceptor(*authToken)))
+			opts = append(opts,
+				grpc.UnaryInterceptor(tokenAuthInterceptor(*authToken)),
+				grpc.StreamInterceptor(tokenAuthStreamInterceptor(*authToken)),
+			)
 			log.Println("Token authentication enabled")
 		} else {
 			log.Println("WARNING: No token set. Agent is open to connections!")
@@ -93,7 +105,7 @@ func main() {
 	grpcServer := grpc.NewServer(opts...)
 
 	// Register agent service
-	agentService := agentgrpc.NewAgentService(*nodeID, dockerMgr, *dataDir)
+	agentService := agentgrpc.NewAgentService(*nodeID, dockerMgr, quotaMgr, *dataDir)
 	agentgrpc.RegisterAgentServiceServer(grpcServer, agentService)
 
 	// Start listening
@@ -106,6 +118,15 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Rebuild the agent's view of its servers before accepting requests
+	if err := agentService.Reconcile(ctx); err != nil {
+		log.Printf("WARNING: Initial reconciliation failed: %v", err)
+	}
+
+	go quotaMgr.Run(ctx)
+	go agentService.RunReconciler(ctx)
+	go agentService.RunEventWatcher(ctx)
+
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -132,23 +153,41 @@ func main() {
 // tokenAuthInterceptor creates a gRPC interceptor that validates tokens
 func tokenAuthInterceptor(validToken string) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Error(codes.Unauthenticated, "missing metadata")
+		if err := checkToken(ctx, validToken); err != nil {
+			return nil, err
 		}
+		return handler(ctx, req)
+	}
+}
 
-		authHeaders := md.Get("authorization")
-		if len(authHeaders) == 0 {
-			return nil, status.Error(codes.Unauthenticated, "missing authorization header")
+// tokenAuthStreamInterceptor validates tokens on streaming RPCs (console, stats)
+func tokenAuthStreamInterceptor(validToken string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkToken(ss.Context(), validToken); err != nil {
+			return err
 		}
+		return handler(srv, ss)
+	}
+}
 
-		token := strings.TrimPrefix(authHeaders[0], "Bearer ")
-		if token != validToken {
-			return nil, status.Error(codes.Unauthenticated, "invalid token")
-		}
+// checkToken validates the bearer token in the incoming request metadata
+func checkToken(ctx context.Context, validToken string) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing metadata")
+	}
 
-		return handler(ctx, req)
+	authHeaders := md.Get("authorization")
+	if len(authHeaders) == 0 {
+		return status.Error(codes.Unauthenticated, "missing authorization header")
+	}
+
+	token := strings.TrimPrefix(authHeaders[0], "Bearer ")
+	if token != validToken {
+		return status.Error(codes.Unauthenticated, "invalid token")
 	}
+
+	return nil
 }
 
 // loadTLSConfig creates a TLS config for mTLS (mutual TLS)
@@ -0,0 +1,394 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ironhost/agent/internal/sandbox"
+)
+
+// Format is a supported archive format
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatZip
+	FormatTarGz
+)
+
+// Errors returned when an archive breaks one of the extraction rules
+var (
+	ErrUnsafePath    = errors.New("archive entry escapes the destination directory")
+	ErrTooLarge      = errors.New("archive exceeds the size limit")
+	ErrTooManyFiles  = errors.New("archive exceeds the file count limit")
+	ErrUnknownFormat = errors.New("unknown archive format")
+)
+
+// Limits bounds the work done by Create and Extract
+type Limits struct {
+	MaxBytes int64 // Total uncompressed bytes (0 = unlimited)
+	MaxFiles int   // Number of files and directories (0 = unlimited)
+}
+
+// Stats describes what an operation wrote
+type Stats struct {
+	Files int
+	Bytes int64
+}
+
+// DetectFormat infers the archive format from a file name
+func DetectFormat(name string) Format {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return FormatZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return FormatTarGz
+	default:
+		return FormatUnknown
+	}
+}
+
+// budget tracks usage against Limits
+type budget struct {
+	limits Limits
+	stats  Stats
+}
+
+func (b *budget) addFile() error {
+	b.stats.Files++
+	if b.limits.MaxFiles > 0 && b.stats.Files > b.limits.MaxFiles {
+		return ErrTooManyFiles
+	}
+	return nil
+}
+
+// copy copies src to dst, failing as soon as the byte budget runs out
+func (b *budget) copy(dst io.Writer, src io.Reader) error {
+	if b.limits.MaxBytes > 0 {
+		remaining := b.limits.MaxBytes - b.stats.Bytes
+		n, err := io.Copy(dst, io.LimitReader(src, remaining+1))
+		b.stats.Bytes += n
+		if err != nil {
+			return err
+		}
+		if n > remaining {
+			return ErrTooLarge
+		}
+		return nil
+	}
+
+	n, err := io.Copy(dst, src)
+	b.stats.Bytes += n
+	return err
+}
+
+// Create writes an archive of the given paths, relative to root, to w.
+// Directories are added recursively. Symlinks are skipped rather than
+// followed, and the path skip (e.g. the archive being written) is left out.
+func Create(w io.Writer, format Format, root *sandbox.Root, paths []string, skip string, limits Limits) (Stats, error) {
+	b := &budget{limits: limits}
+
+	var add func(rel string, info fs.FileInfo, r io.Reader) error
+	var closeFn func() error
+
+	switch format {
+	case FormatZip:
+		zw := zip.NewWriter(w)
+		closeFn = zw.Close
+		add = func(rel string, info fs.FileInfo, r io.Reader) error {
+			header, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			header.Name = rel
+			if info.IsDir() {
+				header.Name += "/"
+			} else {
+				header.Method = zip.Deflate
+			}
+			fw, err := zw.CreateHeader(header)
+			if err != nil || r == nil {
+				return err
+			}
+			return b.copy(fw, r)
+		}
+	case FormatTarGz:
+		gw := gzip.NewWriter(w)
+		tw := tar.NewWriter(gw)
+		closeFn = func() error {
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			return gw.Close()
+		}
+		add = func(rel string, info fs.FileInfo, r io.Reader) error {
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = rel
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			if err := tw.WriteHeader(header); err != nil || r == nil {
+				return err
+			}
+			return b.copy(tw, r)
+		}
+	default:
+		return Stats{}, ErrUnknownFormat
+	}
+
+	skip = sandbox.Clean(skip)
+	for _, p := range paths {
+		err := fs.WalkDir(root.FS(), sandbox.Clean(p), func(rel string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if rel == skip || d.Type()&fs.ModeSymlink != 0 || rel == "." {
+				return nil
+			}
+			if !d.IsDir() && !d.Type().IsRegular() {
+				return nil // Sockets, devices, pipes
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			if err := b.addFile(); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return add(rel, info, nil)
+			}
+
+			f, err := root.Open(rel)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return add(rel, info, f)
+		})
+		if err != nil {
+			return b.stats, err
+		}
+	}
+
+	return b.stats, closeFn()
+}
+
+// Extract unpacks the archive at archivePath into dest, both relative to
+// root. Every entry is checked to stay inside dest, symlinks and other
+// special entries are skipped, and the limits are enforced on the actual
+// decompressed bytes rather than on the sizes the archive claims. On failure
+// the files and directories it created are removed again; existing files it
+// overwrote are not restored.
+func Extract(root *sandbox.Root, archivePath string, format Format, dest string, limits Limits) (Stats, error) {
+	x := &extractor{root: root, dest: sandbox.Clean(dest), budget: budget{limits: limits}}
+
+	file, err := root.Open(archivePath)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer file.Close()
+
+	switch format {
+	case FormatZip:
+		err = x.zip(file)
+	case FormatTarGz:
+		err = x.tarGz(file)
+	default:
+		err = ErrUnknownFormat
+	}
+
+	if err != nil {
+		x.rollback()
+		return x.stats, err
+	}
+	return x.stats, nil
+}
+
+type extractor struct {
+	root *sandbox.Root
+	dest string
+	budget
+	created []string // Paths created so far, in creation order
+}
+
+// target resolves an entry name inside dest, rejecting zip-slip attempts
+func (x *extractor) target(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+		}
+	}
+
+	return path.Join(x.dest, name), nil
+}
+
+func (x *extractor) mkdirAll(dir string) error {
+	// Record each directory we create so rollback can remove it
+	var missing []string
+	for d := dir; d != x.dest && d != "."; d = path.Dir(d) {
+		if _, err := x.root.Lstat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+	}
+	if err := x.root.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		x.created = append(x.created, missing[i])
+	}
+	return nil
+}
+
+func (x *extractor) writeFile(target string, mode fs.FileMode, r io.Reader) error {
+	if err := x.mkdirAll(path.Dir(target)); err != nil {
+		return err
+	}
+
+	// Never write through a symlink the server may have planted, even one
+	// that stays inside the root
+	info, err := x.root.Lstat(target)
+	if err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("%w: %s is a symlink", ErrUnsafePath, target)
+	}
+	existed := err == nil
+
+	perm := fs.FileMode(0644)
+	if mode&0111 != 0 {
+		perm = 0755
+	}
+
+	f, err := x.root.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if !existed {
+		x.created = append(x.created, target)
+	}
+
+	if err := x.copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rollback removes everything created by a failed extraction, newest first
+func (x *extractor) rollback() {
+	for i := len(x.created) - 1; i >= 0; i-- {
+		x.root.Remove(x.created[i])
+	}
+}
+
+func (x *extractor) zip(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return err
+	}
+
+	// Reject obviously oversized archives up front using the central directory;
+	// the copy below still enforces the limit on the real data.
+	if x.limits.MaxFiles > 0 && len(zr.File) > x.limits.MaxFiles {
+		return ErrTooManyFiles
+	}
+	if x.limits.MaxBytes > 0 {
+		var declared uint64
+		for _, f := range zr.File {
+			declared += f.UncompressedSize64
+		}
+		if declared > uint64(x.limits.MaxBytes) {
+			return ErrTooLarge
+		}
+	}
+
+	for _, f := range zr.File {
+		target, err := x.target(f.Name)
+		if err != nil {
+			return err
+		}
+		if err := x.addFile(); err != nil {
+			return err
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := x.mkdirAll(target); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = x.writeFile(target, mode, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (x *extractor) tarGz(file *os.File) error {
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := x.target(header.Name)
+		if err != nil {
+			return err
+		}
+		if err := x.addFile(); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := x.mkdirAll(target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := x.writeFile(target, fs.FileMode(header.Mode), tr); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultBufferLines is the number of console lines kept per server
+const DefaultBufferLines = 1000
+
+// maxLineBytes caps a single line; longer output is split into several lines
+const maxLineBytes = 64 * 1024
+
+// subscriberBuffer is how many lines a subscriber may fall behind before it is dropped
+const subscriberBuffer = 256
+
+// Stream identifies which container output stream a line came from
+type Stream int
+
+const (
+	StreamStdout Stream = iota + 1
+	StreamStderr
+)
+
+// Line is a single line of console output. Offsets increase by one for every
+// line a server produces, so a client can resume after the last offset it saw.
+// They start over at 1 whenever a server's buffer does (the agent restarted,
+// or the server was removed); Epoch then changes, so clients can tell.
+type Line struct {
+	Epoch     string
+	Offset    int64
+	Text      string
+	Stream    Stream
+	Timestamp time.Time
+}
+
+// LogSource follows a container's output, writing stdout and stderr to the
+// given writers until the context is cancelled or the container stops. It
+// replays the last tail lines first, or all lines produced at or after since
+// when since is non-zero (tail is then 0).
+type LogSource func(ctx context.Context, containerID string, since time.Time, tail int, stdout, stderr io.Writer) error
+
+// Hub owns one log follower per server and fans its lines out to subscribers
+type Hub struct {
+	source      LogSource
+	bufferLines int
+
+	servers map[string]*serverConsole
+	mu      sync.Mutex
+}
+
+// NewHub creates a console hub reading container output from source
+func NewHub(source LogSource, bufferLines int) *Hub {
+	if bufferLines <= 0 {
+		bufferLines = DefaultBufferLines
+	}
+	return &Hub{
+		source:      source,
+		bufferLines: bufferLines,
+		servers:     make(map[string]*serverConsole),
+	}
+}
+
+// Subscription receives live lines for one server. Lines is closed when the
+// follower stops (e.g. the container exited) or the subscriber fell too far
+// behind; the client should resubscribe from its last offset.
+type Subscription struct {
+	Lines <-chan Line
+
+	ch      chan Line
+	console *serverConsole
+}
+
+// Close unregisters the subscription
+func (sub *Subscription) Close() {
+	sub.console.unsubscribe(sub)
+}
+
+// Subscribe returns the buffered lines after afterOffset together with a
+// subscription for everything that follows. The backlog and the live stream
+// are taken under the same lock, so there are no gaps or duplicates between
+// them. An offset from another epoch is meaningless, so the whole buffer is
+// returned then. If the server has no running follower, one is started for
+// containerID.
+func (h *Hub) Subscribe(serverID, containerID, epoch string, afterOffset int64) ([]Line, *Subscription) {
+	sc := h.get(serverID)
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if !sc.following || sc.containerID != containerID {
+		h.startLocked(sc, containerID)
+	}
+
+	backlog := sc.ring.after(epoch, afterOffset)
+	ch := make(chan Line, subscriberBuffer)
+	sub := &Subscription{Lines: ch, ch: ch, console: sc}
+	sc.subscribers[sub] = struct{}{}
+
+	return backlog, sub
+}
+
+// Publish appends a line that did not come from the container's own output,
+// such as agent status messages, and delivers it to current subscribers
+func (h *Hub) Publish(serverID string, stream Stream, text string) {
+	sc := h.get(serverID)
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.appendLocked(stream, text, time.Now())
+}
+
+// Watch is Subscribe without a log follower: it delivers buffered and
+// published lines only. It is used while a server has no container whose
+// output could be followed, e.g. during its install script.
+func (h *Hub) Watch(serverID, epoch string, afterOffset int64) ([]Line, *Subscription) {
+	sc := h.get(serverID)
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	backlog := sc.ring.after(epoch, afterOffset)
+	ch := make(chan Line, subscriberBuffer)
+	sub := &Subscription{Lines: ch, ch: ch, console: sc}
+	sc.subscribers[sub] = struct{}{}
+
+	return backlog, sub
+}
+
+// Interrupt closes a server's subscriptions without discarding its buffered
+// lines, so clients resubscribe from their last offset. Used when the output
+// moves to another container.
+func (h *Hub) Interrupt(serverID string) {
+	sc := h.get(serverID)
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.closeSubscribersLocked()
+}
+
+// Writer returns a writer that publishes every line written to it. Close
+// publishes a trailing line that has no newline.
+func (h *Hub) Writer(serverID string, stream Stream) io.WriteCloser {
+	return &publishWriter{hub: h, serverID: serverID, stream: stream}
+}
+
+// Remove stops the follower for a server and discards its buffered lines
+func (h *Hub) Remove(serverID string) {
+	h.mu.Lock()
+	sc, exists := h.servers[serverID]
+	delete(h.servers, serverID)
+	h.mu.Unlock()
+
+	if !exists {
+		return
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.cancel != nil {
+		sc.cancel()
+	}
+	sc.closeSubscribersLocked()
+}
+
+func (h *Hub) get(serverID string) *serverConsole {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sc, exists := h.servers[serverID]
+	if !exists {
+		sc = &serverConsole{
+			serverID:    serverID,
+			ring:        newRing(h.bufferLines),
+			subscribers: make(map[*Subscription]struct{}),
+		}
+		h.servers[serverID] = sc
+	}
+	return sc
+}
+
+// startLocked launches the log follower for a server. sc.mu must be held.
+func (h *Hub) startLocked(sc *serverConsole, containerID string) {
+	if sc.cancel != nil {
+		sc.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sc.cancel = cancel
+	sc.containerID = containerID
+	sc.following = true
+	sc.generation++
+	generation := sc.generation
+
+	// Resume just after the newest container line we already have, so a
+	// restarted follower does not replay lines that are already buffered
+	since := sc.lastLogTimestamp
+	tail := h.bufferLines
+	if !since.IsZero() {
+		tail = 0 // everything since the last line
+	}
+
+	go func() {
+		stdout := &lineWriter{console: sc, stream: StreamStdout, generation: generation}
+		stderr := &lineWriter{console: sc, stream: StreamStderr, generation: generation}
+
+		err := h.source(ctx, containerID, since, tail, stdout, stderr)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("console: log follower for %s stopped: %v", sc.serverID, err)
+		}
+
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+		if sc.generation != generation {
+			return // A newer follower has taken over
+		}
+		stdout.flushLocked()
+		stderr.flushLocked()
+		sc.following = false
+		sc.cancel = nil
+		cancel()
+		sc.closeSubscribersLocked()
+	}()
+}
+
+// serverConsole is the shared console state for one server
+type serverConsole struct {
+	serverID    string
+	containerID string
+	ring        *ring
+	// lastLogTimestamp is the Docker timestamp of the newest container line
+	lastLogTimestamp time.Time
+
+	following  bool
+	generation int
+	cancel     context.CancelFunc
+
+	subscribers map[*Subscription]struct{}
+	mu          sync.Mutex
+}
+
+// appendLocked stores a line and fans it out. sc.mu must be held.
+func (sc *serverConsole) appendLocked(stream Stream, text string, ts time.Time) {
+	line := sc.ring.push(stream, text, ts)
+
+	for sub := range sc.subscribers {
+		select {
+		case sub.ch <- line:
+		default:
+			// Slow consumer: drop it rather than block every other viewer.
+			// It can resubscribe from its last offset without losing lines.
+			delete(sc.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+func (sc *serverConsole) unsubscribe(sub *Subscription) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if _, exists := sc.subscribers[sub]; exists {
+		delete(sc.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+func (sc *serverConsole) closeSubscribersLocked() {
+	for sub := range sc.subscribers {
+		delete(sc.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// lineWriter splits one output stream into whole lines. Docker prefixes every
+// line with an RFC3339Nano timestamp, which is parsed off and used both for
+// the line timestamp and to skip lines replayed after a follower restart.
+type lineWriter struct {
+	console    *serverConsole
+	stream     Stream
+	generation int
+	partial    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	sc := w.console
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.generation != w.generation {
+		return 0, fmt.Errorf("console follower replaced")
+	}
+
+	w.partial = append(w.partial, p...)
+	for {
+		idx := bytes.IndexByte(w.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		w.emitLocked(w.partial[:idx])
+		w.partial = w.partial[idx+1:]
+	}
+
+	if len(w.partial) >= maxLineBytes {
+		w.emitLocked(w.partial)
+		w.partial = nil
+	}
+
+	// Don't let the backing array grow without bound
+	w.partial = append([]byte(nil), w.partial...)
+	return len(p), nil
+}
+
+// flushLocked emits any trailing output that never got a newline
+func (w *lineWriter) flushLocked() {
+	if len(w.partial) > 0 {
+		w.emitLocked(w.partial)
+		w.partial = nil
+	}
+}
+
+func (w *lineWriter) emitLocked(raw []byte) {
+	raw = bytes.TrimSuffix(raw, []byte("\r")) // TTY output uses CRLF
+
+	ts := time.Now()
+	if sp := bytes.IndexByte(raw, ' '); sp > 0 {
+		if parsed, err := time.Parse(time.RFC3339Nano, string(raw[:sp])); err == nil {
+			if !parsed.After(w.console.lastLogTimestamp) {
+				return // Already buffered before the follower restarted
+			}
+			w.console.lastLogTimestamp = parsed
+			ts = parsed
+			raw = raw[sp+1:]
+		}
+	}
+
+	w.console.appendLocked(w.stream, string(raw), ts)
+}
+
+// publishWriter splits output that does not come from a followed container
+// into lines and publishes them
+type publishWriter struct {
+	hub      *Hub
+	serverID string
+	stream   Stream
+	partial  []byte
+}
+
+func (w *publishWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		idx := bytes.IndexByte(w.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		w.publish(w.partial[:idx])
+		w.partial = w.partial[idx+1:]
+	}
+
+	if len(w.partial) >= maxLineBytes {
+		w.publish(w.partial)
+		w.partial = nil
+	}
+
+	w.partial = append([]byte(nil), w.partial...)
+	return len(p), nil
+}
+
+func (w *publishWriter) Close() error {
+	if len(w.partial) > 0 {
+		w.publish(w.partial)
+		w.partial = nil
+	}
+	return nil
+}
+
+func (w *publishWriter) publish(raw []byte) {
+	w.hub.Publish(w.serverID, w.stream, string(bytes.TrimSuffix(raw, []byte("\r"))))
+}
+
+// ring is a fixed-size circular buffer of the most recent lines
+type ring struct {
+	epoch      string // Random, so offsets of an earlier buffer never match
+	lines      []Line
+	start      int   // index of the oldest line
+	count      int   // number of buffered lines
+	nextOffset int64 // offset assigned to the next line
+}
+
+func newRing(size int) *ring {
+	return &ring{epoch: newEpoch(), lines: make([]Line, size), nextOffset: 1}
+}
+
+// newEpoch returns a random epoch, or one derived from the clock if the
+// system has no randomness to give
+func newEpoch() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+func (r *ring) push(stream Stream, text string, ts time.Time) Line {
+	line := Line{Epoch: r.epoch, Offset: r.nextOffset, Text: text, Stream: stream, Timestamp: ts}
+	r.nextOffset++
+
+	if r.count < len(r.lines) {
+		r.lines[(r.start+r.count)%len(r.lines)] = line
+		r.count++
+	} else {
+		r.lines[r.start] = line
+		r.start = (r.start + 1) % len(r.lines)
+	}
+	return line
+}
+
+// after returns buffered lines with an offset greater than offset, oldest
+// first; all of them if offset belongs to another epoch
+func (r *ring) after(epoch string, offset int64) []Line {
+	if epoch != r.epoch {
+		offset = 0
+	}
+	out := make([]Line, 0, r.count)
+	for i := 0; i < r.count; i++ {
+		line := r.lines[(r.start+i)%len(r.lines)]
+		if line.Offset > offset {
+			out = append(out, line)
+		}
+	}
+	return out
+}
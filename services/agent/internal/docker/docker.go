@@ -1,32 +1,103 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
+
+	"github.com/ironhost/agent/internal/minecraft"
 )
 
 // DefaultMinecraftImage is the default image for Minecraft servers
 const DefaultMinecraftImage = "itzg/minecraft-server"
 
+// DiskLimitLabel records a server's disk limit (in MB) on its container
+const DiskLimitLabel = "ironhost.limits.disk_mb"
+
+// ReadinessLabel records a server's readiness probes (JSON) on its container
+const ReadinessLabel = "ironhost.readiness"
+
+// StopCommandLabel records the console command that stops a server gracefully
+const StopCommandLabel = "ironhost.stop_command"
+
+// DataMountLabel records where a server's data directory is mounted in its container
+const DataMountLabel = "ironhost.data_mount"
+
+// DefaultDataMount is where the data directory is mounted unless the template says otherwise
+const DefaultDataMount = "/data"
+
+// Environment variables itzg/minecraft-server configures RCON from
+const (
+	RCONPasswordEnv = "RCON_PASSWORD"
+	RCONPortEnv     = "RCON_PORT"
+)
+
 // ServerConfig holds the configuration for creating a game server container
 type ServerConfig struct {
 	ServerID    string            // Unique server identifier
 	Name        string            // Human-readable name
 	Image       string            // Docker image (defaults to itzg/minecraft-server)
 	MemoryMB    int64             // Memory limit in MB
+	DiskMB      int64             // Disk limit in MB (0 = unlimited)
 	CPUPercent  int               // CPU limit as percentage (100 = 1 core)
+	IOWeight    uint16            // Block IO weight, 10-1000 (0 = Docker default)
 	Environment map[string]string // Environment variables (includes TYPE for server type)
-	Port        int               // Primary game port
+	Ports       []PortBinding     // Published ports, one per allocation
 	DataPath    string            // Host path for persistent data
+	Readiness   string            // Encoded readiness probes (empty = image defaults)
+
+	StartupCommand string // Shell command replacing the image's command (empty = image default)
+	StopCommand    string // Console command that stops the server (empty = SIGTERM)
+	DataMount      string // Container path of the data directory (empty = DefaultDataMount)
+	RCON           bool   // Commands go over RCON: generate a password unless Environment has one
+}
+
+// Resources are the limits that can be changed on a live container
+type Resources struct {
+	MemoryMB   int64  // Memory limit in MB
+	CPUPercent int    // CPU limit as percentage (100 = 1 core)
+	IOWeight   uint16 // Block IO weight, 10-1000 (0 = leave unchanged)
+}
+
+// container converts the limits to Docker's cgroup settings
+func (r Resources) container() container.Resources {
+	return container.Resources{
+		Memory:      r.MemoryMB * 1024 * 1024,   // Convert MB to bytes
+		MemorySwap:  r.MemoryMB * 1024 * 1024,   // Same as memory (no swap)
+		CPUPeriod:   100000,                     // 100ms period
+		CPUQuota:    int64(r.CPUPercent) * 1000, // CPU quota based on percentage
+		BlkioWeight: r.IOWeight,
+	}
+}
+
+// newRCONPassword generates a random RCON password
+func newRCONPassword() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate RCON password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// memoryEnv is the MEMORY variable itzg/minecraft-server sizes the JVM heap from
+func memoryEnv(memoryMB int64) string {
+	return fmt.Sprintf("MEMORY=%dM", memoryMB)
 }
 
 // Manager handles Docker operations for game server containers
@@ -86,42 +157,60 @@ func (m *Manager) CreateContainer(ctx context.Context, cfg ServerConfig) (string
 	}
 
 	// Build environment variables slice
-	env := make([]string, 0, len(cfg.Environment)+2)
+	env := make([]string, 0, len(cfg.Environment)+4)
 
-	// Always accept EULA for Minecraft servers
-	env = append(env, "EULA=TRUE")
+	if minecraft.IsServerImage(cfg.Image) {
+		// Always accept EULA for Minecraft servers
+		env = append(env, "EULA=TRUE")
+
+		// Answer Query requests so the agent can list online players
+		env = append(env, "ENABLE_QUERY=TRUE")
+	}
+
+	// Every RCON server gets its own password unless one was given
+	if _, ok := cfg.Environment[RCONPasswordEnv]; cfg.RCON && !ok {
+		password, err := newRCONPassword()
+		if err != nil {
+			return "", err
+		}
+		env = append(env, RCONPasswordEnv+"="+password)
+	}
 
 	// Set memory for JVM (itzg/minecraft-server uses MEMORY env var)
-	env = append(env, fmt.Sprintf("MEMORY=%dM", cfg.MemoryMB))
+	env = append(env, memoryEnv(cfg.MemoryMB))
 
-	// Add user-provided environment variables
+	// Add the template's variables
 	// This includes TYPE for server type (e.g., TYPE=LEAF, TYPE=PAPER, TYPE=VANILLA)
 	for key, value := range cfg.Environment {
 		env = append(env, fmt.Sprintf("%s=%s", key, value))
 	}
 
-	// Port mapping: expose the game port
-	exposedPort := nat.Port(fmt.Sprintf("%d/tcp", cfg.Port))
-	portBindings := nat.PortMap{
-		"25565/tcp": []nat.PortBinding{
-			{
-				HostIP:   "0.0.0.0",
-				HostPort: fmt.Sprintf("%d", cfg.Port),
-			},
-		},
+	// Images that read their startup command from the environment get it there too
+	if cfg.StartupCommand != "" {
+		env = append(env, "STARTUP="+cfg.StartupCommand)
+	}
+
+	// Port mapping: publish each allocation's container port on its host port
+	exposedPorts, portBindings, primaryPort, err := publish(cfg.Ports)
+	if err != nil {
+		return "", err
+	}
+
+	dataMount := cfg.DataMount
+	if dataMount == "" {
+		dataMount = DefaultDataMount
 	}
 
 	// Container configuration
 	containerConfig := &container.Config{
-		Image: cfg.Image,
-		Env:   env,
-		ExposedPorts: nat.PortSet{
-			exposedPort: struct{}{},
-		},
+		Image:        cfg.Image,
+		Env:          env,
+		ExposedPorts: exposedPorts,
 		Labels: map[string]string{
 			"ironhost.server.id":   cfg.ServerID,
 			"ironhost.server.name": cfg.Name,
 			"ironhost.managed":     "true",
+			DiskLimitLabel:         strconv.FormatInt(cfg.DiskMB, 10),
 		},
 		Tty:          true,
 		AttachStdin:  true,
@@ -130,20 +219,35 @@ func (m *Manager) CreateContainer(ctx context.Context, cfg ServerConfig) (string
 		OpenStdin:    true,
 	}
 
+	if cfg.Readiness != "" {
+		containerConfig.Labels[ReadinessLabel] = cfg.Readiness
+	}
+	if cfg.StopCommand != "" {
+		containerConfig.Labels[StopCommandLabel] = cfg.StopCommand
+	}
+	if dataMount != DefaultDataMount {
+		containerConfig.Labels[DataMountLabel] = dataMount
+	}
+	if primaryPort != "" {
+		containerConfig.Labels[PrimaryPortLabel] = primaryPort
+	}
+	if cfg.StartupCommand != "" {
+		containerConfig.Cmd = []string{"/bin/sh", "-c", cfg.StartupCommand}
+	}
+
 	// Host configuration with resource limits
 	hostConfig := &container.HostConfig{
 		PortBindings: portBindings,
-		Resources: container.Resources{
-			Memory:     cfg.MemoryMB * 1024 * 1024,   // Convert MB to bytes
-			MemorySwap: cfg.MemoryMB * 1024 * 1024,   // Same as memory (no swap)
-			CPUPeriod:  100000,                       // 100ms period
-			CPUQuota:   int64(cfg.CPUPercent) * 1000, // CPU quota based on percentage
-		},
+		Resources: Resources{
+			MemoryMB:   cfg.MemoryMB,
+			CPUPercent: cfg.CPUPercent,
+			IOWeight:   cfg.IOWeight,
+		}.container(),
 		Mounts: []mount.Mount{
 			{
 				Type:   mount.TypeBind,
 				Source: cfg.DataPath,
-				Target: "/data",
+				Target: dataMount,
 			},
 		},
 		RestartPolicy: container.RestartPolicy{
@@ -194,32 +298,111 @@ func (m *Manager) StopContainer(ctx context.Context, containerID string, timeout
 	return nil
 }
 
-// RemoveContainer removes a container (must be stopped first)
-func (m *Manager) RemoveContainer(ctx context.Context, containerID string, force bool) error {
-	err := m.client.ContainerRemove(ctx, containerID, container.RemoveOptions{
-		Force:         force,
-		RemoveVolumes: false, // Preserve data volumes
+// WaitStopped blocks until a container is no longer running or ctx is done
+func (m *Manager) WaitStopped(ctx context.Context, containerID string) error {
+	statusCh, errCh := m.client.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+	select {
+	case <-statusCh:
+		return nil
+	case err := <-errCh:
+		return fmt.Errorf("failed to wait for container %s: %w", containerID, err)
+	}
+}
+
+// UpdateResources changes a running container's memory, CPU and IO limits in place
+func (m *Manager) UpdateResources(ctx context.Context, containerID string, res Resources) error {
+	_, err := m.client.ContainerUpdate(ctx, containerID, container.UpdateConfig{
+		Resources: res.container(),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to remove container %s: %w", containerID, err)
+		return fmt.Errorf("failed to update container %s: %w", containerID, err)
 	}
 	return nil
 }
 
-// GetContainerStats returns resource usage stats for a container
-func (m *Manager) GetContainerStats(ctx context.Context, containerID string) (*types.StatsJSON, error) {
-	resp, err := m.client.ContainerStats(ctx, containerID, false)
+// SyncLimits brings a stopped container's configuration in line with its
+// current limits. The MEMORY env the JVM reads at startup is derived from the
+// memory limit (which UpdateResources may have changed) and the disk limit
+// label is set to diskMB. Neither can be changed on an existing container, so
+// if either differs the container is recreated with the same settings. It
+// returns the ID of the container to use from now on.
+func (m *Manager) SyncLimits(ctx context.Context, containerID string, diskMB int64) (string, error) {
+	info, err := m.client.ContainerInspect(ctx, containerID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stats for container %s: %w", containerID, err)
+		return "", fmt.Errorf("failed to inspect container %s: %w", containerID, err)
 	}
-	defer resp.Body.Close()
 
-	var stats types.StatsJSON
-	if err := decodeStats(resp.Body, &stats); err != nil {
-		return nil, err
+	cfg := info.Config
+	changed := false
+
+	wantMemory := memoryEnv(info.HostConfig.Memory / 1024 / 1024)
+	found := false
+	for i, e := range cfg.Env {
+		if strings.HasPrefix(e, "MEMORY=") {
+			if e != wantMemory {
+				cfg.Env[i] = wantMemory
+				changed = true
+			}
+			found = true
+			break
+		}
+	}
+	if !found && info.HostConfig.Memory > 0 {
+		cfg.Env = append(cfg.Env, wantMemory)
+		changed = true
+	}
+
+	wantDisk := strconv.FormatInt(diskMB, 10)
+	if cfg.Labels[DiskLimitLabel] != wantDisk {
+		if cfg.Labels == nil {
+			cfg.Labels = make(map[string]string)
+		}
+		cfg.Labels[DiskLimitLabel] = wantDisk
+		changed = true
+	}
+
+	// A running container keeps its settings until it is next stopped
+	if !changed || (info.State != nil && info.State.Running) {
+		return containerID, nil
+	}
+
+	return m.recreate(ctx, info, cfg)
+}
+
+// recreate replaces a stopped container with one created from cfg and its
+// (possibly modified) host config, under the same name. It returns the new
+// container's ID.
+func (m *Manager) recreate(ctx context.Context, info types.ContainerJSON, cfg *container.Config) (string, error) {
+	containerID := info.ID
+
+	// Create the replacement first so a failure leaves the old container intact
+	name := strings.TrimPrefix(info.Name, "/")
+	resp, err := m.client.ContainerCreate(ctx, cfg, info.HostConfig, &network.NetworkingConfig{}, nil, name+"-next")
+	if err != nil {
+		return "", fmt.Errorf("failed to recreate container: %w", err)
+	}
+	if err := m.client.ContainerRemove(ctx, containerID, container.RemoveOptions{}); err != nil {
+		m.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		return "", fmt.Errorf("failed to remove container %s: %w", containerID, err)
+	}
+	if err := m.client.ContainerRename(ctx, resp.ID, name); err != nil {
+		// Still usable: servers are found by label, not by container name
+		fmt.Printf("⚠️  Failed to rename container %s to %s: %v\n", resp.ID, name, err)
 	}
 
-	return &stats, nil
+	return resp.ID, nil
+}
+
+// RemoveContainer removes a container (must be stopped first)
+func (m *Manager) RemoveContainer(ctx context.Context, containerID string, force bool) error {
+	err := m.client.ContainerRemove(ctx, containerID, container.RemoveOptions{
+		Force:         force,
+		RemoveVolumes: false, // Preserve data volumes
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove container %s: %w", containerID, err)
+	}
+	return nil
 }
 
 // GetLogs returns the last N lines of container logs (non-streaming)
@@ -228,6 +411,11 @@ func (m *Manager) GetLogs(ctx context.Context, containerID string, tail int) (st
 		tail = 100
 	}
 
+	tty, err := m.IsTTY(ctx, containerID)
+	if err != nil {
+		return "", err
+	}
+
 	options := container.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
@@ -242,23 +430,39 @@ func (m *Manager) GetLogs(ctx context.Context, containerID string, tail int) (st
 	}
 	defer reader.Close()
 
-	data, err := io.ReadAll(reader)
-	if err != nil {
+	// Interleave both streams into one buffer to keep their relative order
+	var buf bytes.Buffer
+	if _, err := copyOutput(tty, &buf, &buf, reader); err != nil {
 		return "", fmt.Errorf("failed to read logs: %w", err)
 	}
 
-	return string(data), nil
+	return buf.String(), nil
 }
 
-// StreamLogs streams container logs to the provided writer
-func (m *Manager) StreamLogs(ctx context.Context, containerID string, stdout, stderr io.Writer) error {
+// StreamLogs streams container logs to the provided writers. Containers
+// created with a TTY have a single raw stream, which is written to stdout;
+// otherwise Docker's multiplexed stream is split into stdout and stderr.
+// Output starts with the last tail lines, or with everything logged since
+// the given time when since is non-zero. Every line is prefixed with its
+// RFC3339Nano timestamp.
+func (m *Manager) StreamLogs(ctx context.Context, containerID string, since time.Time, tail int, stdout, stderr io.Writer) error {
+	tty, err := m.IsTTY(ctx, containerID)
+	if err != nil {
+		return err
+	}
+
 	options := container.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
 		Follow:     true,
-		Tail:       "100", // Start with last 100 lines
+		Tail:       "all",
 		Timestamps: true,
 	}
+	if !since.IsZero() {
+		options.Since = since.Format(time.RFC3339Nano)
+	} else if tail > 0 {
+		options.Tail = strconv.Itoa(tail)
+	}
 
 	reader, err := m.client.ContainerLogs(ctx, containerID, options)
 	if err != nil {
@@ -266,40 +470,175 @@ func (m *Manager) StreamLogs(ctx context.Context, containerID string, stdout, st
 	}
 	defer reader.Close()
 
-	// Docker multiplexes stdout/stderr in the stream
-	_, err = stdcopy(stdout, stderr, reader)
+	_, err = copyOutput(tty, stdout, stderr, reader)
 	return err
 }
 
-// SendCommand sends a command to the container via rcon-cli and returns the output
-func (m *Manager) SendCommand(ctx context.Context, containerID string, command string) (string, error) {
-	execConfig := types.ExecConfig{
-		Cmd:          []string{"rcon-cli", command},
-		AttachStdout: true,
-		AttachStderr: true,
+// WriteStdin writes a line to a container's stdin, for servers that read
+// console commands there. Nothing is returned: whatever the server prints in
+// response appears in its console output.
+func (m *Manager) WriteStdin(ctx context.Context, containerID string, line string) error {
+	stdin, err := m.AttachStdin(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	defer stdin.Close()
+
+	if _, err := io.WriteString(stdin, line+"\n"); err != nil {
+		return fmt.Errorf("failed to write to container stdin: %w", err)
+	}
+	return nil
+}
+
+// AttachStdin attaches to a container's stdin. The container keeps its stdin
+// open when the returned writer is closed.
+func (m *Manager) AttachStdin(ctx context.Context, containerID string) (io.WriteCloser, error) {
+	resp, err := m.client.ContainerAttach(ctx, containerID, container.AttachOptions{
+		Stream: true,
+		Stdin:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to container stdin: %w", err)
+	}
+	return &stdinConn{resp: resp}, nil
+}
+
+// stdinConn is the write side of a hijacked attach connection
+type stdinConn struct {
+	resp types.HijackedResponse
+}
+
+func (c *stdinConn) Write(p []byte) (int, error) { return c.resp.Conn.Write(p) }
+
+func (c *stdinConn) Close() error {
+	c.resp.Close()
+	return nil
+}
+
+// attachOutput attaches to a container's stdout and stderr. Attaching before
+// the container starts captures its output from the first byte.
+func (m *Manager) attachOutput(ctx context.Context, containerID string) (types.HijackedResponse, error) {
+	resp, err := m.client.ContainerAttach(ctx, containerID, container.AttachOptions{
+		Stream: true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return types.HijackedResponse{}, fmt.Errorf("failed to attach to container output: %w", err)
 	}
+	return resp, nil
+}
 
-	execID, err := m.client.ContainerExecCreate(ctx, containerID, execConfig)
+// IsTTY reports whether a container was created with a pseudo-terminal.
+// TTY containers emit a single raw output stream instead of Docker's
+// multiplexed stdout/stderr framing.
+func (m *Manager) IsTTY(ctx context.Context, containerID string) (bool, error) {
+	info, err := m.client.ContainerInspect(ctx, containerID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create exec: %w", err)
+		return false, fmt.Errorf("failed to inspect container %s: %w", containerID, err)
 	}
+	return info.Config != nil && info.Config.Tty, nil
+}
 
-	// Attach to capture output
-	resp, err := m.client.ContainerExecAttach(ctx, execID.ID, types.ExecStartCheck{})
+// GetContainerState returns a container's Docker state (running, exited, ...)
+// and its health check status, which is empty without a health check
+func (m *Manager) GetContainerState(ctx context.Context, containerID string) (state, health string, err error) {
+	info, err := m.client.ContainerInspect(ctx, containerID)
 	if err != nil {
-		return "", fmt.Errorf("failed to attach to exec: %w", err)
+		return "", "", fmt.Errorf("failed to inspect container %s: %w", containerID, err)
 	}
-	defer resp.Close()
+	if info.State == nil {
+		return "", "", nil
+	}
+	if info.State.Health != nil {
+		health = info.State.Health.Status
+	}
+	return info.State.Status, health, nil
+}
+
+// RuntimeInfo describes a started container, for probing its readiness and
+// talking to the game server in it
+type RuntimeInfo struct {
+	Image       string
+	StartedAt   time.Time
+	IPAddress   string      // Address on the container's network, empty if it has none
+	Ports       map[int]int // Container port to host port, for published TCP ports
+	PrimaryPort int         // The game's container port if it is TCP, else 0
+	Readiness   string      // Encoded readiness probes from ReadinessLabel
+	Running     bool        // Whether the container is running now
+	StopCommand string      // From StopCommandLabel, empty for SIGTERM
+	Env         map[string]string
+}
 
-	// Read all output
-	output, err := io.ReadAll(resp.Reader)
+// GetRuntimeInfo inspects a container for what readiness probes need
+func (m *Manager) GetRuntimeInfo(ctx context.Context, containerID string) (*RuntimeInfo, error) {
+	info, err := m.client.ContainerInspect(ctx, containerID)
 	if err != nil {
-		return "", fmt.Errorf("failed to read exec output: %w", err)
+		return nil, fmt.Errorf("failed to inspect container %s: %w", containerID, err)
+	}
+
+	rt := &RuntimeInfo{
+		Image: info.Config.Image,
+		Ports: make(map[int]int),
+	}
+	rt.Readiness = info.Config.Labels[ReadinessLabel]
+	rt.StopCommand = info.Config.Labels[StopCommandLabel]
+	rt.Env = make(map[string]string, len(info.Config.Env))
+	for _, e := range info.Config.Env {
+		if key, value, ok := strings.Cut(e, "="); ok {
+			rt.Env[key] = value
+		}
+	}
+	if info.State != nil {
+		rt.Running = info.State.Running
+		rt.StartedAt, _ = time.Parse(time.RFC3339Nano, info.State.StartedAt)
+	}
+
+	if ns := info.NetworkSettings; ns != nil {
+		rt.IPAddress = ns.IPAddress
+		for _, ep := range ns.Networks {
+			if rt.IPAddress == "" && ep != nil {
+				rt.IPAddress = ep.IPAddress
+			}
+		}
+	}
+
+	for port, bindings := range info.HostConfig.PortBindings {
+		if port.Proto() != "tcp" || len(bindings) == 0 {
+			continue
+		}
+		hostPort, err := strconv.Atoi(bindings[0].HostPort)
+		if err != nil {
+			continue
+		}
+		rt.Ports[port.Int()] = hostPort
+		if rt.PrimaryPort == 0 || port.Int() < rt.PrimaryPort {
+			rt.PrimaryPort = port.Int()
+		}
+	}
+
+	// Containers created before PrimaryPortLabel use their lowest TCP port
+	if primary, ok := info.Config.Labels[PrimaryPortLabel]; ok {
+		rt.PrimaryPort = 0
+		if port := nat.Port(primary); port.Proto() == "tcp" {
+			rt.PrimaryPort = port.Int()
+		}
 	}
 
-	// Clean up Docker stream header bytes (8-byte header per frame)
-	cleaned := string(output)
-	return cleaned, nil
+	return rt, nil
+}
+
+// Addr returns the address to dial a container port on. The container is
+// dialled directly where possible: Docker's userland proxy accepts connections
+// on the published port before the server listens.
+func (rt *RuntimeInfo) Addr(port int) string {
+	if rt.IPAddress != "" {
+		return net.JoinHostPort(rt.IPAddress, strconv.Itoa(port))
+	}
+	if hostPort, ok := rt.Ports[port]; ok {
+		port = hostPort
+	}
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
 }
 
 // GetContainerByServerID finds a container by IronHost server ID label
@@ -320,9 +659,65 @@ func (m *Manager) GetContainerByServerID(ctx context.Context, serverID string) (
 	return nil, fmt.Errorf("container not found for server: %s", serverID)
 }
 
+// ManagedContainer is a container created by IronHost, as found on the host
+type ManagedContainer struct {
+	ID        string
+	ServerID  string // Empty if the server ID label is missing
+	Name      string
+	State     string // Docker state: created, running, exited, ...
+	Health    string // starting, healthy or unhealthy; empty without a health check
+	Created   time.Time
+	DataPath  string // Host path mounted at /data, if any
+	DiskLimit int64  // Bytes, from the disk limit label (0 = unlimited)
+}
+
+// ListManagedContainers returns every container labelled ironhost.managed=true,
+// running or not
+func (m *Manager) ListManagedContainers(ctx context.Context) ([]ManagedContainer, error) {
+	containers, err := m.client.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "ironhost.managed=true")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	managed := make([]ManagedContainer, 0, len(containers))
+	for _, c := range containers {
+		mc := ManagedContainer{
+			ID:       c.ID,
+			ServerID: c.Labels["ironhost.server.id"],
+			State:    c.State,
+			Created:  time.Unix(c.Created, 0),
+		}
+		switch {
+		case strings.Contains(c.Status, "(health: starting)"):
+			mc.Health = "starting"
+		case strings.Contains(c.Status, "(healthy)"):
+			mc.Health = "healthy"
+		case strings.Contains(c.Status, "(unhealthy)"):
+			mc.Health = "unhealthy"
+		}
+		if limit, err := parseDiskLimit(c.Labels); err == nil {
+			mc.DiskLimit = limit
+		}
+		if len(c.Names) > 0 {
+			mc.Name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		for _, mnt := range c.Mounts {
+			if mnt.Destination == dataMountTarget(c.Labels) {
+				mc.DataPath = mnt.Source
+				break
+			}
+		}
+		managed = append(managed, mc)
+	}
+	return managed, nil
+}
+
 // GetContainerDataPath inspects the container for a server and returns
-// the host-side path mounted at /data. This is the directory that contains
-// the server's actual files.
+// the host-side path of its data mount (/data unless its template moved it).
+// This is the directory that contains the server's actual files.
 func (m *Manager) GetContainerDataPath(ctx context.Context, serverID string) (string, error) {
 	ctr, err := m.GetContainerByServerID(ctx, serverID)
 	if err != nil {
@@ -335,22 +730,54 @@ func (m *Manager) GetContainerDataPath(ctx context.Context, serverID string) (st
 		return "", fmt.Errorf("failed to inspect container: %w", err)
 	}
 
+	target := dataMountTarget(info.Config.Labels)
 	for _, mnt := range info.Mounts {
-		if mnt.Destination == "/data" {
+		if mnt.Destination == target {
 			return mnt.Source, nil
 		}
 	}
 
-	return "", fmt.Errorf("no /data mount found for server: %s", serverID)
+	return "", fmt.Errorf("no %s mount found for server: %s", target, serverID)
 }
 
-// Helper function placeholders - implement with proper imports
-func decodeStats(reader io.Reader, stats *types.StatsJSON) error {
-	// Decode JSON stats from reader
-	return nil
+// dataMountTarget returns where a container has its data directory mounted
+func dataMountTarget(labels map[string]string) string {
+	if target := labels[DataMountLabel]; target != "" {
+		return target
+	}
+	return DefaultDataMount
 }
 
-func stdcopy(stdout, stderr io.Writer, reader io.Reader) (int64, error) {
-	// Use github.com/docker/docker/pkg/stdcopy.StdCopy
-	return io.Copy(stdout, reader)
+// GetDiskLimit returns the disk limit in bytes recorded on a server's
+// container, or 0 if the server has no limit
+func (m *Manager) GetDiskLimit(ctx context.Context, serverID string) (int64, error) {
+	ctr, err := m.GetContainerByServerID(ctx, serverID)
+	if err != nil {
+		return 0, err
+	}
+
+	return parseDiskLimit(ctr.Labels)
+}
+
+// parseDiskLimit reads the disk limit label of a container in bytes
+func parseDiskLimit(labels map[string]string) (int64, error) {
+	raw, exists := labels[DiskLimitLabel]
+	if !exists {
+		return 0, nil // Created before limits were recorded
+	}
+
+	diskMB, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid disk limit label %q: %w", raw, err)
+	}
+	return diskMB * 1024 * 1024, nil
+}
+
+// copyOutput copies container output to stdout/stderr. TTY output is passed
+// through untouched; non-TTY output is demultiplexed from Docker's framed stream.
+func copyOutput(tty bool, stdout, stderr io.Writer, reader io.Reader) (int64, error) {
+	if tty {
+		return io.Copy(stdout, reader)
+	}
+	return stdcopy.StdCopy(stdout, stderr, reader)
 }
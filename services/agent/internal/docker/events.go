@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// ContainerEvent is a lifecycle event of a managed container
+type ContainerEvent struct {
+	ContainerID string
+	ServerID    string
+	Action      string // start, die, oom, health_status or restart
+	ExitCode    int    // Set for die
+	Health      string // Set for health_status: starting, healthy or unhealthy
+	Time        time.Time
+}
+
+// watchedActions are the container actions WatchEvents reports
+var watchedActions = map[string]bool{
+	"start":         true,
+	"die":           true,
+	"oom":           true,
+	"health_status": true,
+	"restart":       true,
+}
+
+// WatchEvents calls fn for every lifecycle event of a managed container until
+// ctx is done or Docker ends the stream, and returns the reason it stopped.
+// A non-zero since replays the events Docker still has from that time on, so
+// a watcher can resume after reconnecting without missing any.
+func (m *Manager) WatchEvents(ctx context.Context, since time.Time, fn func(ContainerEvent)) error {
+	opts := types.EventsOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", "container"),
+			filters.Arg("label", "ironhost.managed=true"),
+		),
+	}
+	if !since.IsZero() {
+		opts.Since = fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond())
+	}
+
+	msgs, errs := m.client.Events(ctx, opts)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			if err == nil {
+				err = fmt.Errorf("event stream closed")
+			}
+			return err
+		case msg := <-msgs:
+			// Health changes arrive as "health_status: healthy"
+			action, health, _ := strings.Cut(string(msg.Action), ":")
+			if !watchedActions[action] {
+				continue
+			}
+
+			ev := ContainerEvent{
+				ContainerID: msg.Actor.ID,
+				ServerID:    msg.Actor.Attributes["ironhost.server.id"],
+				Action:      action,
+				Health:      strings.TrimSpace(health),
+				Time:        time.Unix(0, msg.TimeNano),
+			}
+			if action == "die" {
+				ev.ExitCode, _ = strconv.Atoi(msg.Actor.Attributes["exitCode"])
+			}
+			fn(ev)
+		}
+	}
+}
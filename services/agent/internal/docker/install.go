@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+)
+
+// InstallMount is where installer containers see the server's data
+// directory, as Pterodactyl install scripts expect. The directory is also
+// mounted at the server's own data mount.
+const InstallMount = "/mnt/server"
+
+// InstallerLabel marks installer containers with the server they install.
+// They deliberately lack ironhost.managed, so they never count as the server's
+// runtime container.
+const InstallerLabel = "ironhost.installer.server.id"
+
+// InstallConfig describes a throwaway container that runs a template's install script
+type InstallConfig struct {
+	ServerID    string
+	Image       string
+	Entrypoint  string // Shell that runs the script, e.g. bash (empty = sh)
+	Script      string
+	Environment map[string]string
+	DataPath    string // Host path of the server's data directory
+	DataMount   string // Where the server's container mounts it (empty = DefaultDataMount)
+	MemoryMB    int64
+	CPUPercent  int
+}
+
+// RunInstaller runs an install script to completion in a new container with
+// the server's data directory mounted, copying its output to stdout and
+// stderr. The container is removed afterwards. It returns the script's exit
+// code; err is only set if the script could not be run.
+func (m *Manager) RunInstaller(ctx context.Context, cfg InstallConfig, stdout, stderr io.Writer) (int, error) {
+	if err := m.PullImage(ctx, cfg.Image); err != nil {
+		return -1, err
+	}
+
+	entrypoint := cfg.Entrypoint
+	if entrypoint == "" {
+		entrypoint = "sh"
+	}
+	dataMount := cfg.DataMount
+	if dataMount == "" {
+		dataMount = DefaultDataMount
+	}
+
+	env := make([]string, 0, len(cfg.Environment)+1)
+	env = append(env, fmt.Sprintf("SERVER_MEMORY=%d", cfg.MemoryMB))
+	for key, value := range cfg.Environment {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	mounts := []mount.Mount{{Type: mount.TypeBind, Source: cfg.DataPath, Target: InstallMount}}
+	if dataMount != InstallMount {
+		mounts = append(mounts, mount.Mount{Type: mount.TypeBind, Source: cfg.DataPath, Target: dataMount})
+	}
+
+	// A leftover installer of an interrupted run would block the name
+	name := fmt.Sprintf("ironhost-install-%s", cfg.ServerID)
+	_ = m.RemoveContainer(ctx, name, true)
+
+	resp, err := m.client.ContainerCreate(ctx,
+		&container.Config{
+			Image:        cfg.Image,
+			Env:          env,
+			Entrypoint:   []string{entrypoint, "-c"},
+			Cmd:          []string{cfg.Script},
+			WorkingDir:   InstallMount,
+			Labels:       map[string]string{InstallerLabel: cfg.ServerID},
+			AttachStdout: true,
+			AttachStderr: true,
+		},
+		&container.HostConfig{
+			Resources: Resources{MemoryMB: cfg.MemoryMB, CPUPercent: cfg.CPUPercent}.container(),
+			Mounts:    mounts,
+		},
+		&network.NetworkingConfig{},
+		nil, // platform
+		name,
+	)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create installer container: %w", err)
+	}
+	defer func() {
+		// The caller's context may be gone by now; the container must go regardless
+		_ = m.RemoveContainer(context.Background(), resp.ID, true)
+	}()
+
+	// Attach and wait before starting so no output and no exit is missed
+	attach, err := m.attachOutput(ctx, resp.ID)
+	if err != nil {
+		return -1, err
+	}
+	defer attach.Close()
+
+	statusCh, errCh := m.client.ContainerWait(ctx, resp.ID, container.WaitConditionNextExit)
+
+	if err := m.StartContainer(ctx, resp.ID); err != nil {
+		return -1, err
+	}
+
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		_, _ = copyOutput(false, stdout, stderr, attach.Reader)
+	}()
+
+	var exitCode int
+	select {
+	case st := <-statusCh:
+		if st.Error != nil {
+			return -1, fmt.Errorf("installer container failed: %s", st.Error.Message)
+		}
+		exitCode = int(st.StatusCode)
+	case err := <-errCh:
+		return -1, fmt.Errorf("failed to wait for installer container: %w", err)
+	}
+
+	// The attach stream ends once the remaining output has been delivered
+	select {
+	case <-copied:
+	case <-ctx.Done():
+	}
+	return exitCode, nil
+}
@@ -0,0 +1,146 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/docker/go-connections/nat"
+)
+
+// PrimaryPortLabel records a server's game port (e.g. "25565/tcp") on its
+// container, as the container port of the primary allocation
+const PrimaryPortLabel = "ironhost.primary_port"
+
+// DefaultGamePort is the container port of servers whose template sets none
+const DefaultGamePort = 25565
+
+// ErrContainerRunning is returned for changes that need a stopped container
+var ErrContainerRunning = errors.New("container is running")
+
+// PortBinding publishes a container port on a host address
+type PortBinding struct {
+	HostIP        string // Host address to bind (empty = all addresses)
+	HostPort      int
+	ContainerPort int
+	Protocol      string // tcp or udp (empty = tcp)
+	Primary       bool   // The game port, which network probes use by default
+}
+
+// port returns the container side of the binding in Docker's notation
+func (b PortBinding) port() (nat.Port, error) {
+	protocol := b.Protocol
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	if protocol != "tcp" && protocol != "udp" {
+		return "", fmt.Errorf("invalid protocol %q for port %d", protocol, b.HostPort)
+	}
+	if b.HostPort < 1 || b.HostPort > 65535 || b.ContainerPort < 1 || b.ContainerPort > 65535 {
+		return "", fmt.Errorf("invalid port mapping %d:%d", b.HostPort, b.ContainerPort)
+	}
+	return nat.NewPort(protocol, strconv.Itoa(b.ContainerPort))
+}
+
+// publish converts port bindings to Docker's exposed ports and bindings, and
+// returns the primary port for PrimaryPortLabel. Each container port and
+// protocol may be published only once.
+func publish(bindings []PortBinding) (nat.PortSet, nat.PortMap, string, error) {
+	exposed := make(nat.PortSet, len(bindings))
+	portMap := make(nat.PortMap, len(bindings))
+	hostPorts := make(map[string]bool, len(bindings))
+	var primary string
+	for _, b := range bindings {
+		port, err := b.port()
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if _, dup := portMap[port]; dup {
+			return nil, nil, "", fmt.Errorf("container port %s is published twice", port)
+		}
+		hostIP := b.HostIP
+		if hostIP == "" {
+			hostIP = "0.0.0.0"
+		}
+		hostKey := fmt.Sprintf("%s:%d/%s", hostIP, b.HostPort, port.Proto())
+		if hostPorts[hostKey] {
+			return nil, nil, "", fmt.Errorf("host port %s is bound twice", hostKey)
+		}
+		hostPorts[hostKey] = true
+
+		exposed[port] = struct{}{}
+		portMap[port] = []nat.PortBinding{{HostIP: hostIP, HostPort: strconv.Itoa(b.HostPort)}}
+		if b.Primary {
+			if primary != "" {
+				return nil, nil, "", errors.New("only one port can be primary")
+			}
+			primary = string(port)
+		}
+	}
+	return exposed, portMap, primary, nil
+}
+
+// samePorts reports whether two port maps publish the same ports on the same
+// host addresses
+func samePorts(a, b nat.PortMap) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for port, bindings := range a {
+		other, ok := b[port]
+		if !ok || len(other) != len(bindings) {
+			return false
+		}
+		for i := range bindings {
+			if bindings[i] != other[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// SetPorts replaces the ports a stopped container publishes. Published ports
+// cannot be changed on an existing container, so it is recreated with the
+// same settings otherwise. It returns the ID of the container to use from
+// now on.
+func (m *Manager) SetPorts(ctx context.Context, containerID string, bindings []PortBinding) (string, error) {
+	exposed, portMap, primary, err := publish(bindings)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := m.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container %s: %w", containerID, err)
+	}
+	cfg := info.Config
+	if samePorts(info.HostConfig.PortBindings, portMap) && cfg.Labels[PrimaryPortLabel] == primary {
+		return containerID, nil
+	}
+	if info.State != nil && info.State.Running {
+		return "", ErrContainerRunning
+	}
+
+	// Ports the image exposes itself stay exposed; ones published before go
+	for port := range info.HostConfig.PortBindings {
+		delete(cfg.ExposedPorts, port)
+	}
+	if cfg.ExposedPorts == nil {
+		cfg.ExposedPorts = make(nat.PortSet, len(exposed))
+	}
+	for port := range exposed {
+		cfg.ExposedPorts[port] = struct{}{}
+	}
+	if cfg.Labels == nil {
+		cfg.Labels = make(map[string]string)
+	}
+	delete(cfg.Labels, PrimaryPortLabel)
+	if primary != "" {
+		cfg.Labels[PrimaryPortLabel] = primary
+	}
+	info.HostConfig.PortBindings = portMap
+
+	return m.recreate(ctx, info, cfg)
+}
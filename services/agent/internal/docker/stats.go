@@ -0,0 +1,138 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ContainerStats is a decoded resource usage sample for a single container
+type ContainerStats struct {
+	CPUPercent       float64   // CPU usage as percentage (100 = 1 core)
+	MemoryUsageBytes int64     // Memory in use, excluding reclaimable page cache
+	MemoryLimitBytes int64     // Memory limit enforced by the cgroup
+	NetworkRxBytes   int64     // Cumulative bytes received across all interfaces
+	NetworkTxBytes   int64     // Cumulative bytes sent across all interfaces
+	BlockReadBytes   int64     // Cumulative bytes read from block devices
+	BlockWriteBytes  int64     // Cumulative bytes written to block devices
+	ReadAt           time.Time // When Docker took the sample
+}
+
+// GetContainerStats returns a single resource usage sample for a container.
+// Docker fills in the previous CPU sample for one-shot requests, so the CPU
+// percentage is computed over the daemon's own sampling interval.
+func (m *Manager) GetContainerStats(ctx context.Context, containerID string) (*ContainerStats, error) {
+	resp, err := m.client.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats for container %s: %w", containerID, err)
+	}
+	defer resp.Body.Close()
+
+	var raw types.StatsJSON
+	if err := decodeStats(resp.Body, &raw); err != nil {
+		return nil, err
+	}
+
+	return NewContainerStats(&raw), nil
+}
+
+// StreamContainerStats follows Docker's streaming stats endpoint and calls fn
+// for every sample the daemon emits (roughly once per second). It returns
+// when the context is cancelled, the container goes away, or fn returns an error.
+func (m *Manager) StreamContainerStats(ctx context.Context, containerID string, fn func(*ContainerStats) error) error {
+	resp, err := m.client.ContainerStats(ctx, containerID, true)
+	if err != nil {
+		return fmt.Errorf("failed to stream stats for container %s: %w", containerID, err)
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var raw types.StatsJSON
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to decode container stats: %w", err)
+		}
+
+		if err := fn(NewContainerStats(&raw)); err != nil {
+			return err
+		}
+	}
+}
+
+// NewContainerStats converts a raw Docker stats payload into a ContainerStats sample
+func NewContainerStats(raw *types.StatsJSON) *ContainerStats {
+	stats := &ContainerStats{
+		CPUPercent:       CalculateCPUPercent(raw),
+		MemoryUsageBytes: memoryUsage(raw),
+		MemoryLimitBytes: int64(raw.MemoryStats.Limit),
+		ReadAt:           raw.Read,
+	}
+
+	for _, nw := range raw.Networks {
+		stats.NetworkRxBytes += int64(nw.RxBytes)
+		stats.NetworkTxBytes += int64(nw.TxBytes)
+	}
+
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch entry.Op {
+		case "read", "Read":
+			stats.BlockReadBytes += int64(entry.Value)
+		case "write", "Write":
+			stats.BlockWriteBytes += int64(entry.Value)
+		}
+	}
+
+	return stats
+}
+
+// CalculateCPUPercent computes CPU usage from the delta between the current
+// and previous samples, scaled by the number of online CPUs. This matches the
+// figure reported by `docker stats`.
+func CalculateCPUPercent(raw *types.StatsJSON) float64 {
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+
+	onlineCPUs := float64(raw.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		// Older daemons only report per-CPU usage
+		onlineCPUs = float64(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	if cpuDelta <= 0 || systemDelta <= 0 || onlineCPUs == 0 {
+		return 0.0
+	}
+
+	return (cpuDelta / systemDelta) * onlineCPUs * 100.0
+}
+
+// memoryUsage returns memory usage minus the inactive page cache, which the
+// kernel can reclaim at any time. cgroup v1 reports it as total_inactive_file,
+// cgroup v2 as inactive_file.
+func memoryUsage(raw *types.StatsJSON) int64 {
+	usage := raw.MemoryStats.Usage
+
+	cache, ok := raw.MemoryStats.Stats["total_inactive_file"]
+	if !ok {
+		cache = raw.MemoryStats.Stats["inactive_file"]
+	}
+	if cache < usage {
+		usage -= cache
+	}
+
+	return int64(usage)
+}
+
+// decodeStats decodes a single JSON stats object from the Docker stats stream
+func decodeStats(reader io.Reader, stats *types.StatsJSON) error {
+	if err := json.NewDecoder(reader).Decode(stats); err != nil {
+		return fmt.Errorf("failed to decode container stats: %w", err)
+	}
+	return nil
+}
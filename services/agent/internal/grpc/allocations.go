@@ -0,0 +1,125 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ironhost/agent/internal/docker"
+	agentpb "github.com/ironhost/agent/internal/grpc/ironhost/v1"
+)
+
+// ── Allocations ──
+// Every allocation of a server is published as its own port mapping, bound to
+// the allocation's address. The primary allocation carries the game port of
+// the server's template; extra ones (query, voice chat, Bedrock through
+// Geyser, ...) map to the same port inside the container unless they say
+// otherwise.
+
+// portBindings converts a server's allocations to port mappings. The primary
+// allocation defaults to gamePort and protocol, the template's game port.
+// Without allocations the game port is published on the same host port, as
+// servers were before allocations existed.
+func portBindings(allocations []*agentpb.Allocation, gamePort int, protocol string) ([]docker.PortBinding, error) {
+	if gamePort == 0 {
+		gamePort = docker.DefaultGamePort
+	}
+	if len(allocations) == 0 {
+		return []docker.PortBinding{{HostPort: gamePort, ContainerPort: gamePort, Protocol: protocol, Primary: true}}, nil
+	}
+
+	primary := -1
+	for i, a := range allocations {
+		if a.IsPrimary {
+			if primary >= 0 {
+				return nil, errors.New("only one allocation can be primary")
+			}
+			primary = i
+		}
+	}
+	if primary < 0 {
+		primary = 0
+	}
+
+	bindings := make([]docker.PortBinding, 0, len(allocations))
+	for i, a := range allocations {
+		b := docker.PortBinding{
+			HostIP:        bindAddress(a.IpAddress),
+			HostPort:      int(a.Port),
+			ContainerPort: int(a.ContainerPort),
+			Protocol:      a.Protocol,
+			Primary:       i == primary,
+		}
+		if b.Primary {
+			if b.ContainerPort == 0 {
+				b.ContainerPort = gamePort
+			}
+			if b.Protocol == "" {
+				b.Protocol = protocol
+			}
+		}
+		if b.ContainerPort == 0 {
+			b.ContainerPort = b.HostPort
+		}
+		if b.Protocol != "" && b.Protocol != "tcp" && b.Protocol != "udp" {
+			return nil, fmt.Errorf("allocation %d: protocol must be tcp or udp", b.HostPort)
+		}
+		bindings = append(bindings, b)
+	}
+	return bindings, nil
+}
+
+// bindAddress returns the host address to bind an allocation to. Allocations
+// may name their node by hostname, which Docker cannot bind; those are
+// published on all addresses.
+func bindAddress(ip string) string {
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+	return ip
+}
+
+// UpdateServerAllocations replaces the ports a stopped server publishes. The
+// container is recreated with its other settings unchanged.
+func (s *AgentService) UpdateServerAllocations(ctx context.Context, req *agentpb.UpdateServerAllocationsRequest) (*agentpb.ServerActionResponse, error) {
+	fmt.Printf("🔌 Received UpdateServerAllocations request for: %s\n", req.ServerId)
+	if len(req.Allocations) == 0 {
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: "at least one allocation is required"}, nil
+	}
+	if s.isInstalling(req.ServerId) {
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: errInstalling.Error()}, nil
+	}
+
+	// The master sends the primary's container port and protocol itself
+	ports, err := portBindings(req.Allocations, 0, "")
+	if err != nil {
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
+	// A server without a container gets its ports when it is created
+	containerID, err := s.getContainerID(req.ServerId)
+	if err != nil {
+		fmt.Printf("❌ UpdateServerAllocations: container not found: %v\n", err)
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	newID, err := s.dockerMgr.SetPorts(ctx, containerID, ports)
+	if errors.Is(err, docker.ErrContainerRunning) {
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: "stop the server before changing its allocations"}, nil
+	}
+	if err != nil {
+		fmt.Printf("❌ UpdateServerAllocations: failed: %v\n", err)
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	if newID != containerID {
+		fmt.Printf("🔁 Recreated container for %s with new ports: %s\n", req.ServerId, newID)
+		s.setContainer(req.ServerId, newID)
+	}
+
+	fmt.Printf("✅ UpdateServerAllocations: success for %s\n", req.ServerId)
+	return &agentpb.ServerActionResponse{Success: true}, nil
+}
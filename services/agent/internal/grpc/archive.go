@@ -0,0 +1,185 @@
+package grpc
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ironhost/agent/internal/archive"
+	agentpb "github.com/ironhost/agent/internal/grpc/ironhost/v1"
+	"github.com/ironhost/agent/internal/sandbox"
+	"github.com/ironhost/agent/internal/sysinfo"
+)
+
+// maxArchiveFiles caps the number of entries a single compress or decompress may write
+const maxArchiveFiles = 50000
+
+// archiveFormat maps the wire format to the archive package, falling back to
+// the file extension when the client leaves it unspecified
+func archiveFormat(format agentpb.ArchiveFormat, name string) archive.Format {
+	switch format {
+	case agentpb.ArchiveFormat_ARCHIVE_FORMAT_ZIP:
+		return archive.FormatZip
+	case agentpb.ArchiveFormat_ARCHIVE_FORMAT_TAR_GZ:
+		return archive.FormatTarGz
+	default:
+		return archive.DetectFormat(name)
+	}
+}
+
+// archiveLimits returns how much an archive operation may write: whatever is
+// left of the server's disk limit, or the free space on the host when the
+// server has no limit
+func (s *AgentService) archiveLimits(serverID, root string) (archive.Limits, error) {
+	limits := archive.Limits{MaxFiles: maxArchiveFiles}
+
+	s.trackDisk(serverID)
+	if remaining, limited := s.quota.Remaining(serverID); limited {
+		if remaining <= 0 {
+			return limits, status.Error(codes.ResourceExhausted, "server disk limit reached")
+		}
+		limits.MaxBytes = remaining
+		return limits, nil
+	}
+
+	limits.MaxBytes = sysinfo.GetAvailableDisk(root)
+	return limits, nil
+}
+
+// archiveError converts an archive failure into a gRPC status error
+func archiveError(action string, err error) error {
+	switch {
+	case errors.Is(err, archive.ErrUnsafePath):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, archive.ErrTooLarge), errors.Is(err, archive.ErrTooManyFiles):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, archive.ErrUnknownFormat):
+		return status.Error(codes.InvalidArgument, "unsupported archive format (use .zip or .tar.gz)")
+	case errors.Is(err, zip.ErrFormat), errors.Is(err, gzip.ErrHeader):
+		return status.Error(codes.InvalidArgument, "file is not a valid archive")
+	default:
+		return fileError(action, err)
+	}
+}
+
+// CompressFiles packs files and directories into a zip or tar.gz archive
+// inside the server's data directory
+func (s *AgentService) CompressFiles(ctx context.Context, req *agentpb.CompressFilesRequest) (*agentpb.ArchiveResponse, error) {
+	if len(req.Paths) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no paths to compress")
+	}
+
+	destination := sandbox.Clean(req.Destination)
+	if destination == "." {
+		return nil, status.Error(codes.InvalidArgument, "destination is required")
+	}
+
+	format := archiveFormat(req.Format, destination)
+	if format == archive.FormatUnknown {
+		return nil, archiveError("compress", archive.ErrUnknownFormat)
+	}
+
+	root, err := s.openServerRoot(req.ServerId)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	if info, err := root.Stat(destination); err == nil && info.IsDir() {
+		return nil, status.Error(codes.FailedPrecondition, "destination is a directory")
+	}
+
+	limits, err := s.archiveLimits(req.ServerId, root.Dir())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := root.MkdirAll(path.Dir(destination), 0755); err != nil {
+		return nil, fileError("create directory", err)
+	}
+	out, err := root.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fileError("create archive", err)
+	}
+
+	stats, err := archive.Create(out, format, root, req.Paths, destination, limits)
+	if err == nil {
+		var info os.FileInfo
+		if info, err = out.Stat(); err == nil {
+			s.accountDisk(req.ServerId, info.Size())
+		}
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		root.Remove(destination)
+		return nil, archiveError("compress", err)
+	}
+
+	return &agentpb.ArchiveResponse{
+		Path:      destination,
+		FileCount: int32(stats.Files),
+		Size:      stats.Bytes,
+	}, nil
+}
+
+// DecompressFile extracts a zip or tar.gz archive inside the server's data directory
+func (s *AgentService) DecompressFile(ctx context.Context, req *agentpb.DecompressFileRequest) (*agentpb.ArchiveResponse, error) {
+	archivePath := sandbox.Clean(req.Path)
+
+	// Extract next to the archive unless told otherwise
+	destination := sandbox.Clean(req.Destination)
+	if req.Destination == "" {
+		destination = path.Dir(archivePath)
+	}
+
+	format := archiveFormat(req.Format, archivePath)
+	if format == archive.FormatUnknown {
+		return nil, archiveError("decompress", archive.ErrUnknownFormat)
+	}
+
+	root, err := s.openServerRoot(req.ServerId)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	info, err := root.Stat(archivePath)
+	if err != nil {
+		return nil, fileError("open archive", err)
+	}
+	if info.IsDir() {
+		return nil, status.Error(codes.FailedPrecondition, "path is a directory")
+	}
+
+	limits, err := s.archiveLimits(req.ServerId, root.Dir())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := root.MkdirAll(destination, 0755); err != nil {
+		return nil, fileError("create directory", err)
+	}
+
+	stats, err := archive.Extract(root, archivePath, format, destination, limits)
+	if err != nil {
+		return nil, archiveError("decompress", err)
+	}
+	s.accountDisk(req.ServerId, stats.Bytes)
+
+	if destination == "." {
+		destination = ""
+	}
+	return &agentpb.ArchiveResponse{
+		Path:      destination,
+		FileCount: int32(stats.Files),
+		Size:      stats.Bytes,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ironhost/agent/internal/docker"
+	"github.com/ironhost/agent/internal/rcon"
+)
+
+// runCommand sends a console command to a server. Servers with an RCON
+// password get it over a pooled RCON connection, which returns the command's
+// output; others, or ones whose RCON listener is not up, have it written to
+// their stdin and answer in the console output.
+func (s *AgentService) runCommand(ctx context.Context, serverID, containerID, command string) (string, error) {
+	rt, err := s.dockerMgr.GetRuntimeInfo(ctx, containerID)
+	if err != nil {
+		return "", err
+	}
+
+	if password := rt.Env[docker.RCONPasswordEnv]; password != "" && rt.IPAddress != "" {
+		port := rcon.DefaultPort
+		if p, err := strconv.Atoi(rt.Env[docker.RCONPortEnv]); err == nil {
+			port = p
+		}
+
+		output, err := s.rcon.Execute(ctx, serverID, rt.Addr(port), password, command)
+		if err == nil {
+			return output, nil
+		}
+		if !rcon.IsUnavailable(err) {
+			return "", fmt.Errorf("rcon: %w", err)
+		}
+		fmt.Printf("⚠️  RCON unavailable for %s, writing to stdin: %v\n", serverID, err)
+	}
+
+	if err := s.dockerMgr.WriteStdin(ctx, containerID, command); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+// stopServer stops a server's container. A server with a stop command gets it
+// first so the game can save and exit by itself; Docker's SIGTERM, then
+// SIGKILL, follow if it is still running after timeoutSeconds.
+func (s *AgentService) stopServer(ctx context.Context, serverID, containerID string, timeoutSeconds int) error {
+	rt, err := s.dockerMgr.GetRuntimeInfo(ctx, containerID)
+	if err == nil && rt.Running && rt.StopCommand != "" {
+		if _, err := s.runCommand(ctx, serverID, containerID, rt.StopCommand); err != nil {
+			fmt.Printf("⚠️  Stop command failed for %s, sending SIGTERM: %v\n", serverID, err)
+		} else {
+			waitCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+			err := s.dockerMgr.WaitStopped(waitCtx, containerID)
+			cancel()
+			if err == nil {
+				return nil
+			}
+			fmt.Printf("⚠️  Server %s did not stop after %q, sending SIGTERM\n", serverID, rt.StopCommand)
+		}
+	}
+	return s.dockerMgr.StopContainer(ctx, containerID, timeoutSeconds)
+}
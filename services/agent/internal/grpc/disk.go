@@ -0,0 +1,82 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ironhost/agent/internal/sandbox"
+)
+
+// ── Disk Quota ──
+// Servers are registered with the quota manager lazily, the first time their
+// usage is needed, using the disk limit recorded on their container. Writes
+// made by the agent itself are accounted for as they happen so quota checks
+// do not have to wait for the next background measurement.
+
+// trackDisk makes sure the quota manager knows a server's data directory and limit
+func (s *AgentService) trackDisk(serverID string) {
+	if s.quota.Tracked(serverID) {
+		return
+	}
+
+	limit, err := s.dockerMgr.GetDiskLimit(context.Background(), serverID)
+	if err != nil {
+		limit = 0 // No container yet; nothing to enforce against
+	}
+	s.quota.Track(serverID, s.getServerRoot(serverID), limit)
+}
+
+// diskUsage returns the bytes used by a server's data directory
+func (s *AgentService) diskUsage(serverID string) int64 {
+	s.trackDisk(serverID)
+	return s.quota.Usage(serverID)
+}
+
+// releaseDisk lifts a server's disk limit once the server is deleted or never
+// got a container. The data directory is left in place.
+func (s *AgentService) releaseDisk(serverID, dir string) {
+	if err := s.quota.Release(serverID, dir); err != nil {
+		fmt.Printf("⚠️  Failed to release disk limit of %s: %v\n", serverID, err)
+	}
+}
+
+// reserveDisk accounts for bytes the agent is about to write for a server,
+// refusing the write if it would exceed the server's disk limit
+func (s *AgentService) reserveDisk(serverID string, bytes int64) error {
+	s.trackDisk(serverID)
+	if err := s.quota.Reserve(serverID, bytes); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return nil
+}
+
+// accountDisk records bytes written, or freed if negative, by the agent
+func (s *AgentService) accountDisk(serverID string, bytes int64) {
+	s.trackDisk(serverID)
+	s.quota.Add(serverID, bytes)
+}
+
+// treeSize sums the regular files at or below name, without following symlinks
+func treeSize(root *sandbox.Root, name string) int64 {
+	if info, err := root.Lstat(name); err != nil || info.Mode()&fs.ModeSymlink != 0 {
+		return 0
+	}
+
+	var total int64
+	fs.WalkDir(root.FS(), sandbox.Clean(name), func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
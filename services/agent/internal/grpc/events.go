@@ -0,0 +1,198 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ironhost/agent/internal/console"
+	"github.com/ironhost/agent/internal/docker"
+	agentpb "github.com/ironhost/agent/internal/grpc/ironhost/v1"
+)
+
+// ── Lifecycle Events ──
+// A single Docker event subscription is shared by every WatchEvents stream.
+// Docker actions are translated into ServerStatus transitions so the master
+// learns about crashes, OOM kills and restarts it did not ask for.
+
+const (
+	// eventSubscriberBuffer is how many events a watcher may fall behind before it is dropped
+	eventSubscriberBuffer = 64
+	// eventRetryDelay is how long to wait before resubscribing after Docker ends the stream
+	eventRetryDelay = 5 * time.Second
+)
+
+// eventHub fans server events out to WatchEvents streams
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[chan *agentpb.ServerEvent]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: make(map[chan *agentpb.ServerEvent]struct{})}
+}
+
+// subscribe registers a watcher. Its channel is closed if it falls behind.
+func (h *eventHub) subscribe() chan *agentpb.ServerEvent {
+	ch := make(chan *agentpb.ServerEvent, eventSubscriberBuffer)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan *agentpb.ServerEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (h *eventHub) publish(ev *agentpb.ServerEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// A watcher that misses events would hold a wrong status; drop it
+			// so it reconnects and starts again from a snapshot
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// RunEventWatcher follows Docker events for managed containers until ctx is
+// done, resubscribing from the last event seen whenever the stream breaks
+func (s *AgentService) RunEventWatcher(ctx context.Context) {
+	var last time.Time
+	for {
+		err := s.dockerMgr.WatchEvents(ctx, last, func(ev docker.ContainerEvent) {
+			last = ev.Time
+			s.handleContainerEvent(ev)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("⚠️  Docker event stream ended: %v (retrying in %s)\n", err, eventRetryDelay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventRetryDelay):
+		}
+	}
+}
+
+// handleContainerEvent translates a Docker event and publishes it
+func (s *AgentService) handleContainerEvent(ev docker.ContainerEvent) {
+	if ev.ServerID == "" {
+		return // Not tied to a server; reconciliation reports it as an orphan
+	}
+
+	out := &agentpb.ServerEvent{
+		ServerId:    ev.ServerID,
+		ContainerId: ev.ContainerID,
+		Action:      ev.Action,
+		Health:      ev.Health,
+		Timestamp:   ev.Time.Unix(),
+	}
+
+	switch ev.Action {
+	case "start":
+		// Running follows as a separate "ready" event once a readiness probe passes
+		out.Status = agentpb.ServerStatus_SERVER_STATUS_STARTING
+		s.setContainer(ev.ServerID, ev.ContainerID)
+		s.watchReadiness(ev.ServerID, ev.ContainerID)
+	case "restart":
+		out.Status = agentpb.ServerStatus_SERVER_STATUS_STARTING
+	case "health_status":
+		if ev.Health == "healthy" {
+			defer s.markReady(ev.ServerID, ev.ContainerID, "health check passed")
+		}
+	case "die":
+		s.stopReadiness(ev.ServerID)
+		s.rcon.Remove(ev.ServerID)
+		out.Status = agentpb.ServerStatus_SERVER_STATUS_OFFLINE
+		if s.isInstalling(ev.ServerID) {
+			out.Status = agentpb.ServerStatus_SERVER_STATUS_INSTALLING // Stopped for a reinstall
+		}
+		out.ExitCode = int32(ev.ExitCode)
+		if ev.ExitCode != 0 {
+			s.console.Publish(ev.ServerID, console.StreamStderr, fmt.Sprintf("[IronHost] Server exited with code %d", ev.ExitCode))
+		}
+	case "oom":
+		// The container's die event follows if the server did not survive
+		s.console.Publish(ev.ServerID, console.StreamStderr, "[IronHost] Server ran out of memory")
+	}
+
+	fmt.Printf("📣 Event for %s: %s -> %s\n", ev.ServerID, ev.Action, out.Status)
+	s.events.publish(out)
+}
+
+// WatchEvents streams server lifecycle events until the client disconnects
+func (s *AgentService) WatchEvents(req *agentpb.WatchEventsRequest, stream agentpb.AgentService_WatchEventsServer) error {
+	// Subscribe before taking the snapshot so no event falls between the two
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+
+	if req.Snapshot {
+		rec, err := s.reconcile(stream.Context())
+		if err != nil {
+			return status.Error(codes.Unavailable, err.Error())
+		}
+		now := time.Now().Unix()
+		for serverID, c := range rec.servers {
+			ev := &agentpb.ServerEvent{
+				ServerId:    serverID,
+				ContainerId: c.ID,
+				Action:      "snapshot",
+				Status:      s.serverStatus(serverID, c.State, c.Health),
+				Health:      c.Health,
+				Timestamp:   now,
+			}
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev, ok := <-ch:
+			if !ok {
+				return status.Error(codes.Aborted, "event watcher fell behind")
+			}
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// containerStatus derives a server's status from its container's Docker
+// state and health check status, before readiness is taken into account
+func containerStatus(state, health string) agentpb.ServerStatus {
+	switch state {
+	case "running":
+		if health == "starting" {
+			return agentpb.ServerStatus_SERVER_STATUS_STARTING
+		}
+		return agentpb.ServerStatus_SERVER_STATUS_RUNNING
+	case "restarting":
+		return agentpb.ServerStatus_SERVER_STATUS_STARTING
+	case "removing":
+		return agentpb.ServerStatus_SERVER_STATUS_STOPPING
+	default:
+		return agentpb.ServerStatus_SERVER_STATUS_OFFLINE
+	}
+}
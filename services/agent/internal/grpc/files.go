@@ -2,40 +2,34 @@ package grpc
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"syscall"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	agentpb "github.com/ironhost/agent/internal/grpc/ironhost/v1"
+	"github.com/ironhost/agent/internal/sandbox"
 )
 
 // ── File Management ──
 // These methods operate on the host filesystem at the server's data directory.
 // The data directory is resolved by inspecting the Docker container's /data mount,
 // falling back to {dataDir}/servers/{serverID} if no container is found.
-// They are called by the Agent's SendCommand handler when the command starts
-// with the special "__file:" prefix.
-
-// FileInfo represents a file or directory entry
-type FileInfo struct {
-	Name        string `json:"name"`
-	Path        string `json:"path"`
-	IsDirectory bool   `json:"is_directory"`
-	Size        int64  `json:"size"`
-	ModifiedAt  int64  `json:"modified_at"`
-}
+// Paths are resolved through the sandbox package, so symlinks and hardlinks
+// planted by the server cannot lead outside that directory.
+// Failures are reported as gRPC status errors so the master can map them to
+// HTTP responses: NotFound, PermissionDenied, ResourceExhausted, and so on.
 
-// FileResponse is the generic response for file operations
-type FileResponse struct {
-	Success     bool       `json:"success"`
-	Error       string     `json:"error,omitempty"`
-	Files       []FileInfo `json:"files,omitempty"`
-	Content     string     `json:"content,omitempty"`
-	FileName    string     `json:"file_name,omitempty"`
-	FileSize    int64      `json:"file_size,omitempty"`
-	CurrentPath string     `json:"current_path,omitempty"`
-}
+// maxReadFileSize is the largest file ReadFile returns inline
+const maxReadFileSize = 1024 * 1024
 
 // getServerRoot returns the host-side root directory for a server's files.
 // It first tries to resolve the path from the Docker container's /data mount,
@@ -53,92 +47,73 @@ func (s *AgentService) getServerRoot(serverID string) string {
 	return fallback
 }
 
-// resolveServerPath returns the absolute path within a server's data directory.
-// It prevents path traversal attacks.
-func (s *AgentService) resolveServerPath(serverID, relPath string) (string, error) {
-	if strings.ContainsAny(serverID, "/\\..") {
-		return "", fmt.Errorf("invalid server ID")
+// openServerRoot opens a server's data directory as a sandbox root, creating
+// it if needed. Every file operation goes through the returned root so that
+// paths, symlinks and hardlinks cannot reach outside it. The caller must Close it.
+func (s *AgentService) openServerRoot(serverID string) (*sandbox.Root, error) {
+	if serverID == "" || strings.ContainsAny(serverID, "/\\..") {
+		return nil, status.Error(codes.InvalidArgument, "invalid server ID")
 	}
 
 	serverRoot := s.getServerRoot(serverID)
-	target := filepath.Join(serverRoot, filepath.Clean("/"+relPath))
-
-	absTarget, err := filepath.Abs(target)
-	if err != nil {
-		return "", fmt.Errorf("invalid path")
-	}
-	absRoot, err := filepath.Abs(serverRoot)
-	if err != nil {
-		return "", fmt.Errorf("invalid server root")
+	if err := os.MkdirAll(serverRoot, 0755); err != nil {
+		return nil, fileError("create directory", err)
 	}
 
-	log.Printf("📁 resolveServerPath: serverRoot=%q absRoot=%q absTarget=%q", serverRoot, absRoot, absTarget)
-
-	if !strings.HasPrefix(absTarget, absRoot) {
-		return "", fmt.Errorf("access denied: path traversal")
+	root, err := sandbox.Open(serverRoot)
+	if err != nil {
+		return nil, fileError("open server root", err)
 	}
-
-	return absTarget, nil
+	return root, nil
 }
 
-// HandleFileCommand processes a file management command and returns JSON response.
-// Command format: "__file:<operation>" with JSON body.
-// Operations: list, read, write, delete, rename
-func (s *AgentService) HandleFileCommand(serverID, operation, body string) string {
-	log.Printf("📂 HandleFileCommand: serverID=%q op=%q body=%q", serverID, operation, body)
-	var resp FileResponse
-
-	switch operation {
-	case "list":
-		resp = s.fileList(serverID, body)
-	case "read":
-		resp = s.fileRead(serverID, body)
-	case "write":
-		resp = s.fileWrite(serverID, body)
-	case "delete":
-		resp = s.fileDelete(serverID, body)
-	case "rename":
-		resp = s.fileRename(serverID, body)
+// fileError converts a filesystem error into a gRPC status error
+func fileError(action string, err error) error {
+	msg := fmt.Sprintf("failed to %s: %v", action, err)
+
+	switch {
+	case errors.Is(err, sandbox.ErrEscape), errors.Is(err, sandbox.ErrHardlink),
+		errors.Is(err, sandbox.ErrRoot), errors.Is(err, syscall.ELOOP):
+		return status.Error(codes.PermissionDenied, msg)
+	case errors.Is(err, os.ErrNotExist):
+		return status.Error(codes.NotFound, msg)
+	case errors.Is(err, os.ErrPermission):
+		return status.Error(codes.PermissionDenied, msg)
+	case errors.Is(err, os.ErrExist):
+		return status.Error(codes.AlreadyExists, msg)
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT):
+		return status.Error(codes.ResourceExhausted, msg)
 	default:
-		resp = FileResponse{Error: "unknown file operation: " + operation}
+		return status.Error(codes.Internal, msg)
 	}
-
-	data, _ := json.Marshal(resp)
-	log.Printf("📂 HandleFileCommand result: %s", string(data))
-	return string(data)
 }
 
-func (s *AgentService) fileList(serverID, pathStr string) FileResponse {
-	dirPath, err := s.resolveServerPath(serverID, pathStr)
+// ListFiles lists the entries of a directory in the server's data directory
+func (s *AgentService) ListFiles(ctx context.Context, req *agentpb.ListFilesRequest) (*agentpb.ListFilesResponse, error) {
+	root, err := s.openServerRoot(req.ServerId)
 	if err != nil {
-		return FileResponse{Error: err.Error()}
+		return nil, err
 	}
+	defer root.Close()
+
+	currentPath := filepath.ToSlash(req.Path)
 
-	entries, err := os.ReadDir(dirPath)
+	entries, err := root.ReadDir(req.Path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// Auto-create server data directory if it doesn't exist yet
-			if mkErr := os.MkdirAll(dirPath, 0755); mkErr != nil {
-				return FileResponse{Error: "failed to create directory: " + mkErr.Error()}
-			}
-			// Return empty listing for newly created directory
-			return FileResponse{
-				Success:     true,
-				Files:       []FileInfo{},
-				CurrentPath: filepath.ToSlash(pathStr),
-			}
-		}
-		return FileResponse{Error: "failed to read directory: " + err.Error()}
+		return nil, fileError("read directory", err)
 	}
 
-	files := make([]FileInfo, 0, len(entries))
+	files := make([]*agentpb.FileInfo, 0, len(entries))
 	for _, entry := range entries {
+		if isPartialUpload(entry) {
+			continue // Partial uploads stay hidden until they complete
+		}
 		info, err := entry.Info()
 		if err != nil {
 			continue
 		}
-		relPath := filepath.Join(pathStr, entry.Name())
-		files = append(files, FileInfo{
+		relPath := filepath.Join(req.Path, entry.Name())
+		files = append(files, &agentpb.FileInfo{
 			Name:        entry.Name(),
 			Path:        filepath.ToSlash(relPath),
 			IsDirectory: entry.IsDir(),
@@ -147,116 +122,141 @@ func (s *AgentService) fileList(serverID, pathStr string) FileResponse {
 		})
 	}
 
-	return FileResponse{
-		Success:     true,
-		Files:       files,
-		CurrentPath: filepath.ToSlash(pathStr),
-	}
+	return &agentpb.ListFilesResponse{Files: files, CurrentPath: currentPath}, nil
 }
 
-func (s *AgentService) fileRead(serverID, pathStr string) FileResponse {
-	filePath, err := s.resolveServerPath(serverID, pathStr)
+// ReadFile returns the content of a text file of at most 1MB
+func (s *AgentService) ReadFile(ctx context.Context, req *agentpb.ReadFileRequest) (*agentpb.ReadFileResponse, error) {
+	root, err := s.openServerRoot(req.ServerId)
 	if err != nil {
-		return FileResponse{Error: err.Error()}
+		return nil, err
 	}
+	defer root.Close()
 
-	info, err := os.Stat(filePath)
+	file, err := root.Open(req.Path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return FileResponse{Error: "file not found"}
-		}
-		return FileResponse{Error: "failed to stat file: " + err.Error()}
+		return nil, fileError("open file", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fileError("stat file", err)
 	}
 
 	if info.IsDir() {
-		return FileResponse{Error: "path is a directory"}
+		return nil, status.Error(codes.FailedPrecondition, "path is a directory")
 	}
 
-	// 1MB limit
-	if info.Size() > 1024*1024 {
-		return FileResponse{Error: "file too large (max 1MB)"}
+	if info.Size() > maxReadFileSize {
+		return nil, status.Error(codes.ResourceExhausted, "file too large (max 1MB)")
 	}
 
-	content, err := os.ReadFile(filePath)
+	content, err := io.ReadAll(io.LimitReader(file, maxReadFileSize))
 	if err != nil {
-		return FileResponse{Error: "failed to read file: " + err.Error()}
+		return nil, fileError("read file", err)
 	}
 
-	return FileResponse{
-		Success:  true,
-		Content:  string(content),
-		FileName: filepath.Base(filePath),
-		FileSize: info.Size(),
-	}
+	return &agentpb.ReadFileResponse{
+		Content: string(content),
+		Name:    path.Base(sandbox.Clean(req.Path)),
+		Size:    info.Size(),
+	}, nil
 }
 
-func (s *AgentService) fileWrite(serverID, bodyJSON string) FileResponse {
-	var req struct {
-		Path    string `json:"path"`
-		Content string `json:"content"`
-	}
-	if err := json.Unmarshal([]byte(bodyJSON), &req); err != nil {
-		return FileResponse{Error: "invalid request body"}
+// WriteFile writes text content to a file, creating parent directories as needed
+func (s *AgentService) WriteFile(ctx context.Context, req *agentpb.WriteFileRequest) (*agentpb.ServerActionResponse, error) {
+	name := sandbox.Clean(req.Path)
+	if name == "." {
+		return nil, status.Error(codes.InvalidArgument, "path is required")
 	}
 
-	filePath, err := s.resolveServerPath(serverID, req.Path)
+	root, err := s.openServerRoot(req.ServerId)
 	if err != nil {
-		return FileResponse{Error: err.Error()}
+		return nil, err
 	}
+	defer root.Close()
 
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return FileResponse{Error: "failed to create directory: " + err.Error()}
+	// Only the growth of the file counts against the disk limit
+	var existing int64
+	if info, err := root.Stat(name); err == nil && info.Mode().IsRegular() {
+		existing = info.Size()
+	}
+	growth := int64(len(req.Content)) - existing
+	if err := s.reserveDisk(req.ServerId, growth); err != nil {
+		return nil, err
 	}
 
-	if err := os.WriteFile(filePath, []byte(req.Content), 0644); err != nil {
-		return FileResponse{Error: "failed to write file: " + err.Error()}
+	if err := root.MkdirAll(path.Dir(name), 0755); err != nil {
+		s.accountDisk(req.ServerId, -growth)
+		return nil, fileError("create directory", err)
 	}
 
-	return FileResponse{Success: true}
+	file, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		s.accountDisk(req.ServerId, -growth)
+		return nil, fileError("write file", err)
+	}
+	n, err := file.WriteString(req.Content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// The file was truncated, so only what made it to disk still counts
+		s.accountDisk(req.ServerId, int64(n-len(req.Content)))
+		return nil, fileError("write file", err)
+	}
+
+	return &agentpb.ServerActionResponse{Success: true}, nil
 }
 
-func (s *AgentService) fileDelete(serverID, pathStr string) FileResponse {
-	filePath, err := s.resolveServerPath(serverID, pathStr)
+// DeleteFile removes a file or directory tree
+func (s *AgentService) DeleteFile(ctx context.Context, req *agentpb.DeleteFileRequest) (*agentpb.ServerActionResponse, error) {
+	name := sandbox.Clean(req.Path)
+	if name == "." {
+		return nil, status.Error(codes.PermissionDenied, "cannot delete server root directory")
+	}
+
+	root, err := s.openServerRoot(req.ServerId)
 	if err != nil {
-		return FileResponse{Error: err.Error()}
+		return nil, err
 	}
+	defer root.Close()
 
-	serverRoot := filepath.Join(s.dataDir, "servers", serverID)
-	absRoot, _ := filepath.Abs(serverRoot)
-	absTarget, _ := filepath.Abs(filePath)
-	if absTarget == absRoot {
-		return FileResponse{Error: "cannot delete server root directory"}
+	if _, err := root.Lstat(name); err != nil {
+		return nil, fileError("delete", err)
 	}
 
-	if err := os.RemoveAll(filePath); err != nil {
-		return FileResponse{Error: "failed to delete: " + err.Error()}
+	freed := treeSize(root, name)
+	if err := root.RemoveAll(name); err != nil {
+		return nil, fileError("delete", err)
 	}
+	s.accountDisk(req.ServerId, -freed)
 
-	return FileResponse{Success: true}
+	return &agentpb.ServerActionResponse{Success: true}, nil
 }
 
-func (s *AgentService) fileRename(serverID, bodyJSON string) FileResponse {
-	var req struct {
-		OldPath string `json:"old_path"`
-		NewPath string `json:"new_path"`
-	}
-	if err := json.Unmarshal([]byte(bodyJSON), &req); err != nil {
-		return FileResponse{Error: "invalid request body"}
+// RenameFile moves a file or directory within the server's data directory
+func (s *AgentService) RenameFile(ctx context.Context, req *agentpb.RenameFileRequest) (*agentpb.ServerActionResponse, error) {
+	oldName := sandbox.Clean(req.OldPath)
+	newName := sandbox.Clean(req.NewPath)
+	if oldName == "." || newName == "." {
+		return nil, status.Error(codes.PermissionDenied, "cannot rename server root directory")
 	}
 
-	oldPath, err := s.resolveServerPath(serverID, req.OldPath)
+	root, err := s.openServerRoot(req.ServerId)
 	if err != nil {
-		return FileResponse{Error: "invalid old path: " + err.Error()}
+		return nil, err
 	}
-	newPath, err := s.resolveServerPath(serverID, req.NewPath)
-	if err != nil {
-		return FileResponse{Error: "invalid new path: " + err.Error()}
+	defer root.Close()
+
+	if _, err := root.Lstat(newName); err == nil {
+		return nil, status.Error(codes.AlreadyExists, "destination already exists")
 	}
 
-	if err := os.Rename(oldPath, newPath); err != nil {
-		return FileResponse{Error: "failed to rename: " + err.Error()}
+	if err := root.Rename(oldName, newName); err != nil {
+		return nil, fileError("rename", err)
 	}
 
-	return FileResponse{Success: true}
+	return &agentpb.ServerActionResponse{Success: true}, nil
 }
@@ -0,0 +1,63 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	agentpb "github.com/ironhost/agent/internal/grpc/ironhost/v1"
+	"github.com/ironhost/agent/internal/minecraft"
+)
+
+// gameStatusTimeout bounds pinging and querying a server for its ServerState
+const gameStatusTimeout = 2 * time.Second
+
+// addGameStatus fills in the player counts, version and MOTD of a running
+// Minecraft server. The Server List Ping always answers once the server is up;
+// Query, when enabled, adds the full player list. Servers that answer neither
+// are left without game status.
+func (s *AgentService) addGameStatus(ctx context.Context, state *agentpb.ServerState, containerID string) {
+	rt, err := s.dockerMgr.GetRuntimeInfo(ctx, containerID)
+	if err != nil || rt.PrimaryPort == 0 || !minecraft.IsServerImage(rt.Image) {
+		return
+	}
+	addr := rt.Addr(rt.PrimaryPort)
+
+	ctx, cancel := context.WithTimeout(ctx, gameStatusTimeout)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		ping  *minecraft.Status
+		query *minecraft.QueryStatus
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ping, _ = minecraft.Ping(ctx, addr)
+	}()
+	go func() {
+		defer wg.Done()
+		query, _ = minecraft.Query(ctx, addr)
+	}()
+	wg.Wait()
+
+	switch {
+	case ping != nil:
+		state.PlayersOnline = int32(ping.Players.Online)
+		state.PlayersMax = int32(ping.Players.Max)
+		state.Version = ping.Version.Name
+		state.Motd = ping.MOTD()
+		for _, p := range ping.Players.Sample {
+			state.Players = append(state.Players, p.Name)
+		}
+	case query != nil:
+		state.PlayersOnline = int32(query.NumPlayers)
+		state.PlayersMax = int32(query.MaxPlayers)
+		state.Version = query.Version
+		state.Motd = query.MOTD
+	}
+	if query != nil {
+		state.Players = query.Players
+	}
+}
@@ -0,0 +1,153 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ironhost/agent/internal/console"
+	"github.com/ironhost/agent/internal/docker"
+	agentpb "github.com/ironhost/agent/internal/grpc/ironhost/v1"
+)
+
+// ── Install Scripts ──
+// A template's install script runs in a throwaway container before the
+// server's own container is created, or again on ReinstallServer. Its output
+// is published to the server's console, so viewers see the install as it
+// happens. While it runs the server reports INSTALLING and cannot be started.
+
+// errInstalling is returned for lifecycle actions attempted during an install
+var errInstalling = errors.New("server is being installed")
+
+// beginInstall registers a running install and returns its context, which
+// DeleteServer cancels. It fails if the server is already being installed.
+func (s *AgentService) beginInstall(ctx context.Context, serverID string) (context.Context, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, running := s.installs[serverID]; running {
+		return nil, nil, errInstalling
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.installs[serverID] = cancel
+	end := func() {
+		s.mu.Lock()
+		delete(s.installs, serverID)
+		s.mu.Unlock()
+		cancel()
+		// Viewers following the installer's output move on to the server's container
+		s.console.Interrupt(serverID)
+	}
+	return ctx, end, nil
+}
+
+// isInstalling reports whether a server's install script is running
+func (s *AgentService) isInstalling(serverID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, running := s.installs[serverID]
+	return running
+}
+
+// cancelInstall stops a server's running install, if any
+func (s *AgentService) cancelInstall(serverID string) {
+	s.mu.RLock()
+	cancel, running := s.installs[serverID]
+	s.mu.RUnlock()
+	if running {
+		cancel()
+	}
+}
+
+// runInstall runs an install script with the server's data directory mounted
+// and returns its exit code. The caller must hold the install (beginInstall).
+func (s *AgentService) runInstall(ctx context.Context, cfg docker.InstallConfig) (int, error) {
+	serverID := cfg.ServerID
+	fmt.Printf("📦 Running install script for %s in %s\n", serverID, cfg.Image)
+	s.console.Publish(serverID, console.StreamStdout, fmt.Sprintf("[IronHost] Running install script in %s", cfg.Image))
+
+	stdout := s.console.Writer(serverID, console.StreamStdout)
+	stderr := s.console.Writer(serverID, console.StreamStderr)
+	exitCode, err := s.dockerMgr.RunInstaller(ctx, cfg, stdout, stderr)
+	stdout.Close()
+	stderr.Close()
+
+	if err != nil {
+		fmt.Printf("❌ Install script for %s could not run: %v\n", serverID, err)
+		s.console.Publish(serverID, console.StreamStderr, fmt.Sprintf("[IronHost] Install failed: %v", err))
+		return exitCode, err
+	}
+	if exitCode != 0 {
+		fmt.Printf("❌ Install script for %s exited with code %d\n", serverID, exitCode)
+		s.console.Publish(serverID, console.StreamStderr, fmt.Sprintf("[IronHost] Install script exited with code %d", exitCode))
+		return exitCode, nil
+	}
+
+	fmt.Printf("✅ Install script for %s finished\n", serverID)
+	s.console.Publish(serverID, console.StreamStdout, "[IronHost] Install script finished")
+	return 0, nil
+}
+
+// ReinstallServer stops a server and runs its install script again on the
+// existing data directory. Unlike a reset nothing is deleted; the server is
+// left stopped for the user to start.
+func (s *AgentService) ReinstallServer(ctx context.Context, req *agentpb.ReinstallServerRequest) (*agentpb.InstallResponse, error) {
+	fmt.Printf("📦 Received ReinstallServer request for: %s\n", req.ServerId)
+	if req.Install == nil || req.Install.Script == "" || req.Install.Image == "" {
+		return &agentpb.InstallResponse{Success: false, ErrorMessage: "an install image and script are required"}, nil
+	}
+
+	// A server whose first install failed has no container; the master
+	// creates it instead, which runs the install again
+	containerID, err := s.getContainerID(req.ServerId)
+	if err != nil {
+		fmt.Printf("❌ ReinstallServer: container not found: %v\n", err)
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	// Registered before stopping so the container's exit reports INSTALLING
+	installCtx, end, err := s.beginInstall(ctx, req.ServerId)
+	if err != nil {
+		return &agentpb.InstallResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	defer end()
+
+	if err := s.stopServer(installCtx, req.ServerId, containerID, 30); err != nil {
+		fmt.Printf("❌ ReinstallServer: stop failed: %v\n", err)
+		return &agentpb.InstallResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
+	env := make(map[string]string, len(req.Environment))
+	for _, e := range req.Environment {
+		env[e.Key] = e.Value
+	}
+	cfg := docker.InstallConfig{
+		ServerID:    req.ServerId,
+		Image:       req.Install.Image,
+		Entrypoint:  req.Install.Entrypoint,
+		Script:      req.Install.Script,
+		Environment: env,
+		DataPath:    s.getServerRoot(req.ServerId),
+		DataMount:   req.DataMount,
+	}
+	if req.Limits != nil {
+		cfg.MemoryMB = req.Limits.MemoryMb
+		cfg.CPUPercent = int(req.Limits.CpuPercent)
+	}
+
+	exitCode, err := s.runInstall(installCtx, cfg)
+	if err != nil {
+		return &agentpb.InstallResponse{Success: false, ErrorMessage: err.Error(), ExitCode: int32(exitCode)}, nil
+	}
+	if exitCode != 0 {
+		return &agentpb.InstallResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("install script exited with code %d", exitCode),
+			ExitCode:     int32(exitCode),
+		}, nil
+	}
+	return &agentpb.InstallResponse{Success: true}, nil
+}
@@ -22,6 +22,154 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Output stream a console line was read from
+type ConsoleStream int32
+
+const (
+	ConsoleStream_CONSOLE_STREAM_UNSPECIFIED ConsoleStream = 0
+	ConsoleStream_CONSOLE_STREAM_STDOUT      ConsoleStream = 1
+	ConsoleStream_CONSOLE_STREAM_STDERR      ConsoleStream = 2 // Only distinguishable for containers without a TTY
+)
+
+// Enum value maps for ConsoleStream.
+var (
+	ConsoleStream_name = map[int32]string{
+		0: "CONSOLE_STREAM_UNSPECIFIED",
+		1: "CONSOLE_STREAM_STDOUT",
+		2: "CONSOLE_STREAM_STDERR",
+	}
+	ConsoleStream_value = map[string]int32{
+		"CONSOLE_STREAM_UNSPECIFIED": 0,
+		"CONSOLE_STREAM_STDOUT":      1,
+		"CONSOLE_STREAM_STDERR":      2,
+	}
+)
+
+func (x ConsoleStream) Enum() *ConsoleStream {
+	p := new(ConsoleStream)
+	*p = x
+	return p
+}
+
+func (x ConsoleStream) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ConsoleStream) Descriptor() protoreflect.EnumDescriptor {
+	return file_ironhost_v1_agent_proto_enumTypes[0].Descriptor()
+}
+
+func (ConsoleStream) Type() protoreflect.EnumType {
+	return &file_ironhost_v1_agent_proto_enumTypes[0]
+}
+
+func (x ConsoleStream) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ConsoleStream.Descriptor instead.
+func (ConsoleStream) EnumDescriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{0}
+}
+
+type ArchiveFormat int32
+
+const (
+	ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED ArchiveFormat = 0 // Detect from the file extension
+	ArchiveFormat_ARCHIVE_FORMAT_ZIP         ArchiveFormat = 1
+	ArchiveFormat_ARCHIVE_FORMAT_TAR_GZ      ArchiveFormat = 2
+)
+
+// Enum value maps for ArchiveFormat.
+var (
+	ArchiveFormat_name = map[int32]string{
+		0: "ARCHIVE_FORMAT_UNSPECIFIED",
+		1: "ARCHIVE_FORMAT_ZIP",
+		2: "ARCHIVE_FORMAT_TAR_GZ",
+	}
+	ArchiveFormat_value = map[string]int32{
+		"ARCHIVE_FORMAT_UNSPECIFIED": 0,
+		"ARCHIVE_FORMAT_ZIP":         1,
+		"ARCHIVE_FORMAT_TAR_GZ":      2,
+	}
+)
+
+func (x ArchiveFormat) Enum() *ArchiveFormat {
+	p := new(ArchiveFormat)
+	*p = x
+	return p
+}
+
+func (x ArchiveFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ArchiveFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_ironhost_v1_agent_proto_enumTypes[1].Descriptor()
+}
+
+func (ArchiveFormat) Type() protoreflect.EnumType {
+	return &file_ironhost_v1_agent_proto_enumTypes[1]
+}
+
+func (x ArchiveFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ArchiveFormat.Descriptor instead.
+func (ArchiveFormat) EnumDescriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{1}
+}
+
+type OrphanKind int32
+
+const (
+	OrphanKind_ORPHAN_KIND_UNSPECIFIED    OrphanKind = 0
+	OrphanKind_ORPHAN_KIND_CONTAINER      OrphanKind = 1 // Managed container with no matching server
+	OrphanKind_ORPHAN_KIND_DATA_DIRECTORY OrphanKind = 2 // Directory under {dataDir}/servers with no container
+)
+
+// Enum value maps for OrphanKind.
+var (
+	OrphanKind_name = map[int32]string{
+		0: "ORPHAN_KIND_UNSPECIFIED",
+		1: "ORPHAN_KIND_CONTAINER",
+		2: "ORPHAN_KIND_DATA_DIRECTORY",
+	}
+	OrphanKind_value = map[string]int32{
+		"ORPHAN_KIND_UNSPECIFIED":    0,
+		"ORPHAN_KIND_CONTAINER":      1,
+		"ORPHAN_KIND_DATA_DIRECTORY": 2,
+	}
+)
+
+func (x OrphanKind) Enum() *OrphanKind {
+	p := new(OrphanKind)
+	*p = x
+	return p
+}
+
+func (x OrphanKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrphanKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_ironhost_v1_agent_proto_enumTypes[2].Descriptor()
+}
+
+func (OrphanKind) Type() protoreflect.EnumType {
+	return &file_ironhost_v1_agent_proto_enumTypes[2]
+}
+
+func (x OrphanKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrphanKind.Descriptor instead.
+func (OrphanKind) EnumDescriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{2}
+}
+
 // Request to create a new game server container
 type CreateServerRequest struct {
 	state    protoimpl.MessageState `protogen:"open.v1"`
@@ -37,8 +185,21 @@ type CreateServerRequest struct {
 	Environment []*EnvVar `protobuf:"bytes,6,rep,name=environment,proto3" json:"environment,omitempty"`
 	// Volume mount path on host
 	DataDirectory string `protobuf:"bytes,7,opt,name=data_directory,json=dataDirectory,proto3" json:"data_directory,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	// Readiness probes; the server is running once any passes (empty = image defaults)
+	ReadinessProbes []*ReadinessProbe `protobuf:"bytes,8,rep,name=readiness_probes,json=readinessProbes,proto3" json:"readiness_probes,omitempty"`
+	// From the server's template
+	ContainerPort  int32  `protobuf:"varint,9,opt,name=container_port,json=containerPort,proto3" json:"container_port,omitempty"`    // Game port inside the container, unless the primary allocation sets one (0 = 25565)
+	Protocol       string `protobuf:"bytes,10,opt,name=protocol,proto3" json:"protocol,omitempty"`                                   // Of the game port unless the primary allocation sets one, "tcp" (default) or "udp"
+	StartupCommand string `protobuf:"bytes,11,opt,name=startup_command,json=startupCommand,proto3" json:"startup_command,omitempty"` // Run with /bin/sh -c instead of the image's command (empty = image default)
+	StopCommand    string `protobuf:"bytes,12,opt,name=stop_command,json=stopCommand,proto3" json:"stop_command,omitempty"`          // Console command that stops the server gracefully (empty = SIGTERM)
+	DataMount      string `protobuf:"bytes,13,opt,name=data_mount,json=dataMount,proto3" json:"data_mount,omitempty"`                // Container path the data directory is mounted at (empty = /data)
+	// Run before the server's container is created, which only happens if it succeeds
+	Install *InstallScript `protobuf:"bytes,14,opt,name=install,proto3" json:"install,omitempty"`
+	// How console commands reach the server: "rcon" (default) or "stdin". Only
+	// RCON servers get a generated RCON_PASSWORD.
+	CommandTransport string `protobuf:"bytes,15,opt,name=command_transport,json=commandTransport,proto3" json:"command_transport,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *CreateServerRequest) Reset() {
@@ -120,13 +281,71 @@ func (x *CreateServerRequest) GetDataDirectory() string {
 	return ""
 }
 
+func (x *CreateServerRequest) GetReadinessProbes() []*ReadinessProbe {
+	if x != nil {
+		return x.ReadinessProbes
+	}
+	return nil
+}
+
+func (x *CreateServerRequest) GetContainerPort() int32 {
+	if x != nil {
+		return x.ContainerPort
+	}
+	return 0
+}
+
+func (x *CreateServerRequest) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *CreateServerRequest) GetStartupCommand() string {
+	if x != nil {
+		return x.StartupCommand
+	}
+	return ""
+}
+
+func (x *CreateServerRequest) GetStopCommand() string {
+	if x != nil {
+		return x.StopCommand
+	}
+	return ""
+}
+
+func (x *CreateServerRequest) GetDataMount() string {
+	if x != nil {
+		return x.DataMount
+	}
+	return ""
+}
+
+func (x *CreateServerRequest) GetInstall() *InstallScript {
+	if x != nil {
+		return x.Install
+	}
+	return nil
+}
+
+func (x *CreateServerRequest) GetCommandTransport() string {
+	if x != nil {
+		return x.CommandTransport
+	}
+	return ""
+}
+
 type CreateServerResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	ContainerId   string                 `protobuf:"bytes,2,opt,name=container_id,json=containerId,proto3" json:"container_id,omitempty"`
-	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	ContainerId     string                 `protobuf:"bytes,2,opt,name=container_id,json=containerId,proto3" json:"container_id,omitempty"`
+	ErrorMessage    string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	Installed       bool                   `protobuf:"varint,4,opt,name=installed,proto3" json:"installed,omitempty"`                                      // An install script ran
+	InstallExitCode int32                  `protobuf:"varint,5,opt,name=install_exit_code,json=installExitCode,proto3" json:"install_exit_code,omitempty"` // Its exit code, if installed
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *CreateServerResponse) Reset() {
@@ -180,28 +399,46 @@ func (x *CreateServerResponse) GetErrorMessage() string {
 	return ""
 }
 
-type StopServerRequest struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	ServerId       string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
-	TimeoutSeconds int32                  `protobuf:"varint,2,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"` // Graceful shutdown timeout
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+func (x *CreateServerResponse) GetInstalled() bool {
+	if x != nil {
+		return x.Installed
+	}
+	return false
 }
 
-func (x *StopServerRequest) Reset() {
-	*x = StopServerRequest{}
+func (x *CreateServerResponse) GetInstallExitCode() int32 {
+	if x != nil {
+		return x.InstallExitCode
+	}
+	return 0
+}
+
+// InstallScript runs once in a throwaway container with the server's data
+// directory mounted at /mnt/server and at the data mount. Its output appears
+// in the server's console stream.
+type InstallScript struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Image         string                 `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	Entrypoint    string                 `protobuf:"bytes,2,opt,name=entrypoint,proto3" json:"entrypoint,omitempty"` // Shell that runs the script (empty = sh)
+	Script        string                 `protobuf:"bytes,3,opt,name=script,proto3" json:"script,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InstallScript) Reset() {
+	*x = InstallScript{}
 	mi := &file_ironhost_v1_agent_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StopServerRequest) String() string {
+func (x *InstallScript) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StopServerRequest) ProtoMessage() {}
+func (*InstallScript) ProtoMessage() {}
 
-func (x *StopServerRequest) ProtoReflect() protoreflect.Message {
+func (x *InstallScript) ProtoReflect() protoreflect.Message {
 	mi := &file_ironhost_v1_agent_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -213,47 +450,57 @@ func (x *StopServerRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StopServerRequest.ProtoReflect.Descriptor instead.
-func (*StopServerRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use InstallScript.ProtoReflect.Descriptor instead.
+func (*InstallScript) Descriptor() ([]byte, []int) {
 	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *StopServerRequest) GetServerId() string {
+func (x *InstallScript) GetImage() string {
 	if x != nil {
-		return x.ServerId
+		return x.Image
 	}
 	return ""
 }
 
-func (x *StopServerRequest) GetTimeoutSeconds() int32 {
+func (x *InstallScript) GetEntrypoint() string {
 	if x != nil {
-		return x.TimeoutSeconds
+		return x.Entrypoint
 	}
-	return 0
+	return ""
 }
 
-type ServerActionResponse struct {
+func (x *InstallScript) GetScript() string {
+	if x != nil {
+		return x.Script
+	}
+	return ""
+}
+
+type ReinstallServerRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	ErrorMessage  string                 `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Install       *InstallScript         `protobuf:"bytes,2,opt,name=install,proto3" json:"install,omitempty"`
+	Environment   []*EnvVar              `protobuf:"bytes,3,rep,name=environment,proto3" json:"environment,omitempty"`
+	Limits        *ResourceLimits        `protobuf:"bytes,4,opt,name=limits,proto3" json:"limits,omitempty"`                        // Limits of the installer container
+	DataMount     string                 `protobuf:"bytes,5,opt,name=data_mount,json=dataMount,proto3" json:"data_mount,omitempty"` // The server's data mount (empty = /data)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ServerActionResponse) Reset() {
-	*x = ServerActionResponse{}
+func (x *ReinstallServerRequest) Reset() {
+	*x = ReinstallServerRequest{}
 	mi := &file_ironhost_v1_agent_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ServerActionResponse) String() string {
+func (x *ReinstallServerRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ServerActionResponse) ProtoMessage() {}
+func (*ReinstallServerRequest) ProtoMessage() {}
 
-func (x *ServerActionResponse) ProtoReflect() protoreflect.Message {
+func (x *ReinstallServerRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_ironhost_v1_agent_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -265,46 +512,69 @@ func (x *ServerActionResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ServerActionResponse.ProtoReflect.Descriptor instead.
-func (*ServerActionResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use ReinstallServerRequest.ProtoReflect.Descriptor instead.
+func (*ReinstallServerRequest) Descriptor() ([]byte, []int) {
 	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *ServerActionResponse) GetSuccess() bool {
+func (x *ReinstallServerRequest) GetServerId() string {
 	if x != nil {
-		return x.Success
+		return x.ServerId
 	}
-	return false
+	return ""
 }
 
-func (x *ServerActionResponse) GetErrorMessage() string {
+func (x *ReinstallServerRequest) GetInstall() *InstallScript {
 	if x != nil {
-		return x.ErrorMessage
+		return x.Install
+	}
+	return nil
+}
+
+func (x *ReinstallServerRequest) GetEnvironment() []*EnvVar {
+	if x != nil {
+		return x.Environment
+	}
+	return nil
+}
+
+func (x *ReinstallServerRequest) GetLimits() *ResourceLimits {
+	if x != nil {
+		return x.Limits
+	}
+	return nil
+}
+
+func (x *ReinstallServerRequest) GetDataMount() string {
+	if x != nil {
+		return x.DataMount
 	}
 	return ""
 }
 
-type ListServersResponse struct {
+type InstallResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Servers       []*ServerState         `protobuf:"bytes,1,rep,name=servers,proto3" json:"servers,omitempty"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"` // The script ran and exited with 0
+	ErrorMessage  string                 `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	ExitCode      int32                  `protobuf:"varint,3,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListServersResponse) Reset() {
-	*x = ListServersResponse{}
+func (x *InstallResponse) Reset() {
+	*x = InstallResponse{}
 	mi := &file_ironhost_v1_agent_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListServersResponse) String() string {
+func (x *InstallResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListServersResponse) ProtoMessage() {}
+func (*InstallResponse) ProtoMessage() {}
 
-func (x *ListServersResponse) ProtoReflect() protoreflect.Message {
+func (x *InstallResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_ironhost_v1_agent_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -316,42 +586,54 @@ func (x *ListServersResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListServersResponse.ProtoReflect.Descriptor instead.
-func (*ListServersResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use InstallResponse.ProtoReflect.Descriptor instead.
+func (*InstallResponse) Descriptor() ([]byte, []int) {
 	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *ListServersResponse) GetServers() []*ServerState {
+func (x *InstallResponse) GetSuccess() bool {
 	if x != nil {
-		return x.Servers
+		return x.Success
 	}
-	return nil
+	return false
 }
 
-type ConsoleOutput struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
-	Line          string                 `protobuf:"bytes,2,opt,name=line,proto3" json:"line,omitempty"`
-	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	IsError       bool                   `protobuf:"varint,4,opt,name=is_error,json=isError,proto3" json:"is_error,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *InstallResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
 }
 
-func (x *ConsoleOutput) Reset() {
-	*x = ConsoleOutput{}
+func (x *InstallResponse) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+type StopServerRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ServerId       string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	TimeoutSeconds int32                  `protobuf:"varint,2,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"` // Graceful shutdown timeout
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StopServerRequest) Reset() {
+	*x = StopServerRequest{}
 	mi := &file_ironhost_v1_agent_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ConsoleOutput) String() string {
+func (x *StopServerRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ConsoleOutput) ProtoMessage() {}
+func (*StopServerRequest) ProtoMessage() {}
 
-func (x *ConsoleOutput) ProtoReflect() protoreflect.Message {
+func (x *StopServerRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_ironhost_v1_agent_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -363,62 +645,1635 @@ func (x *ConsoleOutput) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ConsoleOutput.ProtoReflect.Descriptor instead.
-func (*ConsoleOutput) Descriptor() ([]byte, []int) {
+// Deprecated: Use StopServerRequest.ProtoReflect.Descriptor instead.
+func (*StopServerRequest) Descriptor() ([]byte, []int) {
 	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *ConsoleOutput) GetServerId() string {
+func (x *StopServerRequest) GetServerId() string {
 	if x != nil {
 		return x.ServerId
 	}
 	return ""
 }
 
-func (x *ConsoleOutput) GetLine() string {
+func (x *StopServerRequest) GetTimeoutSeconds() int32 {
 	if x != nil {
-		return x.Line
+		return x.TimeoutSeconds
 	}
-	return ""
+	return 0
 }
 
-func (x *ConsoleOutput) GetTimestamp() int64 {
-	if x != nil {
-		return x.Timestamp
-	}
-	return 0
+type UpdateServerResourcesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Limits        *ResourceLimits        `protobuf:"bytes,2,opt,name=limits,proto3" json:"limits,omitempty"` // Memory and CPU apply live; MEMORY env and disk label on next start
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateServerResourcesRequest) Reset() {
+	*x = UpdateServerResourcesRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateServerResourcesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateServerResourcesRequest) ProtoMessage() {}
+
+func (x *UpdateServerResourcesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateServerResourcesRequest.ProtoReflect.Descriptor instead.
+func (*UpdateServerResourcesRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateServerResourcesRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *UpdateServerResourcesRequest) GetLimits() *ResourceLimits {
+	if x != nil {
+		return x.Limits
+	}
+	return nil
+}
+
+type UpdateServerAllocationsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Allocations   []*Allocation          `protobuf:"bytes,2,rep,name=allocations,proto3" json:"allocations,omitempty"` // Replaces every published port
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateServerAllocationsRequest) Reset() {
+	*x = UpdateServerAllocationsRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateServerAllocationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateServerAllocationsRequest) ProtoMessage() {}
+
+func (x *UpdateServerAllocationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateServerAllocationsRequest.ProtoReflect.Descriptor instead.
+func (*UpdateServerAllocationsRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateServerAllocationsRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *UpdateServerAllocationsRequest) GetAllocations() []*Allocation {
+	if x != nil {
+		return x.Allocations
+	}
+	return nil
+}
+
+type ServerActionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServerActionResponse) Reset() {
+	*x = ServerActionResponse{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerActionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerActionResponse) ProtoMessage() {}
+
+func (x *ServerActionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerActionResponse.ProtoReflect.Descriptor instead.
+func (*ServerActionResponse) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ServerActionResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ServerActionResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+type ListServersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Servers       []*ServerState         `protobuf:"bytes,1,rep,name=servers,proto3" json:"servers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListServersResponse) Reset() {
+	*x = ListServersResponse{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListServersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListServersResponse) ProtoMessage() {}
+
+func (x *ListServersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListServersResponse.ProtoReflect.Descriptor instead.
+func (*ListServersResponse) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListServersResponse) GetServers() []*ServerState {
+	if x != nil {
+		return x.Servers
+	}
+	return nil
+}
+
+type StreamServerStatsRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ServerId        string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	IntervalSeconds int32                  `protobuf:"varint,2,opt,name=interval_seconds,json=intervalSeconds,proto3" json:"interval_seconds,omitempty"` // Minimum time between samples (defaults to 2s)
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *StreamServerStatsRequest) Reset() {
+	*x = StreamServerStatsRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamServerStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamServerStatsRequest) ProtoMessage() {}
+
+func (x *StreamServerStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamServerStatsRequest.ProtoReflect.Descriptor instead.
+func (*StreamServerStatsRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StreamServerStatsRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *StreamServerStatsRequest) GetIntervalSeconds() int32 {
+	if x != nil {
+		return x.IntervalSeconds
+	}
+	return 0
+}
+
+type StreamConsoleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	AfterOffset   int64                  `protobuf:"varint,2,opt,name=after_offset,json=afterOffset,proto3" json:"after_offset,omitempty"` // Replay buffered lines after this offset (0 = whole buffer)
+	Epoch         string                 `protobuf:"bytes,3,opt,name=epoch,proto3" json:"epoch,omitempty"`                                 // Epoch after_offset belongs to; on a mismatch the whole buffer is replayed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamConsoleRequest) Reset() {
+	*x = StreamConsoleRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamConsoleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamConsoleRequest) ProtoMessage() {}
+
+func (x *StreamConsoleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamConsoleRequest.ProtoReflect.Descriptor instead.
+func (*StreamConsoleRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StreamConsoleRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *StreamConsoleRequest) GetAfterOffset() int64 {
+	if x != nil {
+		return x.AfterOffset
+	}
+	return 0
+}
+
+func (x *StreamConsoleRequest) GetEpoch() string {
+	if x != nil {
+		return x.Epoch
+	}
+	return ""
+}
+
+type AttachConsoleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`           // Required in the first message, ignored afterwards
+	AfterOffset   int64                  `protobuf:"varint,2,opt,name=after_offset,json=afterOffset,proto3" json:"after_offset,omitempty"` // Replay buffered lines after this offset (first message only)
+	Input         []byte                 `protobuf:"bytes,3,opt,name=input,proto3" json:"input,omitempty"`                                 // Written to stdin as-is; include the trailing newline
+	Epoch         string                 `protobuf:"bytes,4,opt,name=epoch,proto3" json:"epoch,omitempty"`                                 // Epoch after_offset belongs to (first message only)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AttachConsoleRequest) Reset() {
+	*x = AttachConsoleRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AttachConsoleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AttachConsoleRequest) ProtoMessage() {}
+
+func (x *AttachConsoleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AttachConsoleRequest.ProtoReflect.Descriptor instead.
+func (*AttachConsoleRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AttachConsoleRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *AttachConsoleRequest) GetAfterOffset() int64 {
+	if x != nil {
+		return x.AfterOffset
+	}
+	return 0
+}
+
+func (x *AttachConsoleRequest) GetInput() []byte {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+func (x *AttachConsoleRequest) GetEpoch() string {
+	if x != nil {
+		return x.Epoch
+	}
+	return ""
+}
+
+type ConsoleOutput struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Line          string                 `protobuf:"bytes,2,opt,name=line,proto3" json:"line,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	IsError       bool                   `protobuf:"varint,4,opt,name=is_error,json=isError,proto3" json:"is_error,omitempty"`
+	Stream        ConsoleStream          `protobuf:"varint,5,opt,name=stream,proto3,enum=ironhost.v1.ConsoleStream" json:"stream,omitempty"`
+	Offset        int64                  `protobuf:"varint,6,opt,name=offset,proto3" json:"offset,omitempty"` // Per-server line sequence number, used to resume after reconnecting
+	Epoch         string                 `protobuf:"bytes,7,opt,name=epoch,proto3" json:"epoch,omitempty"`    // Changes when offsets start over, e.g. after an agent restart
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsoleOutput) Reset() {
+	*x = ConsoleOutput{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsoleOutput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsoleOutput) ProtoMessage() {}
+
+func (x *ConsoleOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsoleOutput.ProtoReflect.Descriptor instead.
+func (*ConsoleOutput) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ConsoleOutput) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *ConsoleOutput) GetLine() string {
+	if x != nil {
+		return x.Line
+	}
+	return ""
+}
+
+func (x *ConsoleOutput) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
 }
 
 func (x *ConsoleOutput) GetIsError() bool {
 	if x != nil {
-		return x.IsError
+		return x.IsError
+	}
+	return false
+}
+
+func (x *ConsoleOutput) GetStream() ConsoleStream {
+	if x != nil {
+		return x.Stream
+	}
+	return ConsoleStream_CONSOLE_STREAM_UNSPECIFIED
+}
+
+func (x *ConsoleOutput) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ConsoleOutput) GetEpoch() string {
+	if x != nil {
+		return x.Epoch
+	}
+	return ""
+}
+
+type SendCommandRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Command       string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendCommandRequest) Reset() {
+	*x = SendCommandRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendCommandRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendCommandRequest) ProtoMessage() {}
+
+func (x *SendCommandRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendCommandRequest.ProtoReflect.Descriptor instead.
+func (*SendCommandRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SendCommandRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *SendCommandRequest) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+type NodeStats struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	NodeId               string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	TotalMemoryBytes     int64                  `protobuf:"varint,2,opt,name=total_memory_bytes,json=totalMemoryBytes,proto3" json:"total_memory_bytes,omitempty"`
+	AvailableMemoryBytes int64                  `protobuf:"varint,3,opt,name=available_memory_bytes,json=availableMemoryBytes,proto3" json:"available_memory_bytes,omitempty"`
+	TotalDiskBytes       int64                  `protobuf:"varint,4,opt,name=total_disk_bytes,json=totalDiskBytes,proto3" json:"total_disk_bytes,omitempty"`
+	AvailableDiskBytes   int64                  `protobuf:"varint,5,opt,name=available_disk_bytes,json=availableDiskBytes,proto3" json:"available_disk_bytes,omitempty"`
+	CpuUsagePercent      float64                `protobuf:"fixed64,6,opt,name=cpu_usage_percent,json=cpuUsagePercent,proto3" json:"cpu_usage_percent,omitempty"`
+	RunningContainers    int32                  `protobuf:"varint,7,opt,name=running_containers,json=runningContainers,proto3" json:"running_containers,omitempty"`
+	UptimeSeconds        int64                  `protobuf:"varint,8,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *NodeStats) Reset() {
+	*x = NodeStats{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeStats) ProtoMessage() {}
+
+func (x *NodeStats) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeStats.ProtoReflect.Descriptor instead.
+func (*NodeStats) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *NodeStats) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *NodeStats) GetTotalMemoryBytes() int64 {
+	if x != nil {
+		return x.TotalMemoryBytes
+	}
+	return 0
+}
+
+func (x *NodeStats) GetAvailableMemoryBytes() int64 {
+	if x != nil {
+		return x.AvailableMemoryBytes
+	}
+	return 0
+}
+
+func (x *NodeStats) GetTotalDiskBytes() int64 {
+	if x != nil {
+		return x.TotalDiskBytes
+	}
+	return 0
+}
+
+func (x *NodeStats) GetAvailableDiskBytes() int64 {
+	if x != nil {
+		return x.AvailableDiskBytes
+	}
+	return 0
+}
+
+func (x *NodeStats) GetCpuUsagePercent() float64 {
+	if x != nil {
+		return x.CpuUsagePercent
+	}
+	return 0
+}
+
+func (x *NodeStats) GetRunningContainers() int32 {
+	if x != nil {
+		return x.RunningContainers
+	}
+	return 0
+}
+
+func (x *NodeStats) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+type PingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingResponse) Reset() {
+	*x = PingResponse{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingResponse) ProtoMessage() {}
+
+func (x *PingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
+func (*PingResponse) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PingResponse) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *PingResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *PingResponse) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+type FileInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"` // relative path within the server directory
+	IsDirectory   bool                   `protobuf:"varint,3,opt,name=is_directory,json=isDirectory,proto3" json:"is_directory,omitempty"`
+	Size          int64                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`                               // file size in bytes
+	ModifiedAt    int64                  `protobuf:"varint,5,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"` // unix timestamp
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileInfo) Reset() {
+	*x = FileInfo{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileInfo) ProtoMessage() {}
+
+func (x *FileInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *FileInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FileInfo) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileInfo) GetIsDirectory() bool {
+	if x != nil {
+		return x.IsDirectory
+	}
+	return false
+}
+
+func (x *FileInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileInfo) GetModifiedAt() int64 {
+	if x != nil {
+		return x.ModifiedAt
+	}
+	return 0
+}
+
+type ListFilesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"` // relative directory path (empty = root)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesRequest) Reset() {
+	*x = ListFilesRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesRequest) ProtoMessage() {}
+
+func (x *ListFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesRequest.ProtoReflect.Descriptor instead.
+func (*ListFilesRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListFilesRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *ListFilesRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type ListFilesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Files         []*FileInfo            `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	CurrentPath   string                 `protobuf:"bytes,2,opt,name=current_path,json=currentPath,proto3" json:"current_path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesResponse) Reset() {
+	*x = ListFilesResponse{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesResponse) ProtoMessage() {}
+
+func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesResponse.ProtoReflect.Descriptor instead.
+func (*ListFilesResponse) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListFilesResponse) GetFiles() []*FileInfo {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *ListFilesResponse) GetCurrentPath() string {
+	if x != nil {
+		return x.CurrentPath
+	}
+	return ""
+}
+
+type ReadFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"` // relative file path
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadFileRequest) Reset() {
+	*x = ReadFileRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadFileRequest) ProtoMessage() {}
+
+func (x *ReadFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadFileRequest.ProtoReflect.Descriptor instead.
+func (*ReadFileRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ReadFileRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *ReadFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type ReadFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Content       string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadFileResponse) Reset() {
+	*x = ReadFileResponse{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadFileResponse) ProtoMessage() {}
+
+func (x *ReadFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadFileResponse.ProtoReflect.Descriptor instead.
+func (*ReadFileResponse) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ReadFileResponse) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ReadFileResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ReadFileResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type WriteFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Content       string                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteFileRequest) Reset() {
+	*x = WriteFileRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteFileRequest) ProtoMessage() {}
+
+func (x *WriteFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteFileRequest.ProtoReflect.Descriptor instead.
+func (*WriteFileRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *WriteFileRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *WriteFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *WriteFileRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+type DeleteFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteFileRequest) Reset() {
+	*x = DeleteFileRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFileRequest) ProtoMessage() {}
+
+func (x *DeleteFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFileRequest.ProtoReflect.Descriptor instead.
+func (*DeleteFileRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *DeleteFileRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *DeleteFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type RenameFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	OldPath       string                 `protobuf:"bytes,2,opt,name=old_path,json=oldPath,proto3" json:"old_path,omitempty"`
+	NewPath       string                 `protobuf:"bytes,3,opt,name=new_path,json=newPath,proto3" json:"new_path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameFileRequest) Reset() {
+	*x = RenameFileRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameFileRequest) ProtoMessage() {}
+
+func (x *RenameFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameFileRequest.ProtoReflect.Descriptor instead.
+func (*RenameFileRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *RenameFileRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *RenameFileRequest) GetOldPath() string {
+	if x != nil {
+		return x.OldPath
+	}
+	return ""
+}
+
+func (x *RenameFileRequest) GetNewPath() string {
+	if x != nil {
+		return x.NewPath
+	}
+	return ""
+}
+
+// The first message of an upload identifies the file; later messages only carry data
+type UploadFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Offset        int64                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"` // Resume a partial upload at this byte offset (0 = start over)
+	Sha256        string                 `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`  // Optional hex SHA-256 of the complete file, verified before it is committed
+	Data          []byte                 `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
+	Crc32C        uint32                 `protobuf:"varint,6,opt,name=crc32c,proto3" json:"crc32c,omitempty"` // CRC-32C of data
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadFileRequest) Reset() {
+	*x = UploadFileRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadFileRequest) ProtoMessage() {}
+
+func (x *UploadFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadFileRequest.ProtoReflect.Descriptor instead.
+func (*UploadFileRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *UploadFileRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *UploadFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *UploadFileRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *UploadFileRequest) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *UploadFileRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *UploadFileRequest) GetCrc32C() uint32 {
+	if x != nil {
+		return x.Crc32C
+	}
+	return 0
+}
+
+type UploadFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Size          int64                  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        string                 `protobuf:"bytes,2,opt,name=sha256,proto3" json:"sha256,omitempty"` // Hex SHA-256 of the committed file
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadFileResponse) Reset() {
+	*x = UploadFileResponse{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadFileResponse) ProtoMessage() {}
+
+func (x *UploadFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadFileResponse.ProtoReflect.Descriptor instead.
+func (*UploadFileResponse) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *UploadFileResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *UploadFileResponse) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+type UploadStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadStatusRequest) Reset() {
+	*x = UploadStatusRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadStatusRequest) ProtoMessage() {}
+
+func (x *UploadStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadStatusRequest.ProtoReflect.Descriptor instead.
+func (*UploadStatusRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *UploadStatusRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *UploadStatusRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type UploadStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Offset        int64                  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"` // Bytes of an interrupted upload already stored (0 = none)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadStatusResponse) Reset() {
+	*x = UploadStatusResponse{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadStatusResponse) ProtoMessage() {}
+
+func (x *UploadStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadStatusResponse.ProtoReflect.Descriptor instead.
+func (*UploadStatusResponse) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *UploadStatusResponse) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type DownloadFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Offset        int64                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"` // Start reading at this byte offset
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadFileRequest) Reset() {
+	*x = DownloadFileRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadFileRequest) ProtoMessage() {}
+
+func (x *DownloadFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadFileRequest.ProtoReflect.Descriptor instead.
+func (*DownloadFileRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *DownloadFileRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *DownloadFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *DownloadFileRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type FileChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"` // Position of data within the file
+	Crc32C        uint32                 `protobuf:"varint,3,opt,name=crc32c,proto3" json:"crc32c,omitempty"` // CRC-32C of data
+	Size          int64                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`     // Total file size
+	Name          string                 `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`      // Base name of the file
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileChunk) Reset() {
+	*x = FileChunk{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileChunk) ProtoMessage() {}
+
+func (x *FileChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileChunk.ProtoReflect.Descriptor instead.
+func (*FileChunk) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *FileChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *FileChunk) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *FileChunk) GetCrc32C() uint32 {
+	if x != nil {
+		return x.Crc32C
+	}
+	return 0
+}
+
+func (x *FileChunk) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileChunk) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type CompressFilesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Paths         []string               `protobuf:"bytes,2,rep,name=paths,proto3" json:"paths,omitempty"`             // Files and directories to include (relative)
+	Destination   string                 `protobuf:"bytes,3,opt,name=destination,proto3" json:"destination,omitempty"` // Archive path to create (relative)
+	Format        ArchiveFormat          `protobuf:"varint,4,opt,name=format,proto3,enum=ironhost.v1.ArchiveFormat" json:"format,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompressFilesRequest) Reset() {
+	*x = CompressFilesRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompressFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompressFilesRequest) ProtoMessage() {}
+
+func (x *CompressFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompressFilesRequest.ProtoReflect.Descriptor instead.
+func (*CompressFilesRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *CompressFilesRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *CompressFilesRequest) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *CompressFilesRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *CompressFilesRequest) GetFormat() ArchiveFormat {
+	if x != nil {
+		return x.Format
 	}
-	return false
+	return ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED
 }
 
-type SendCommandRequest struct {
+type DecompressFileRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
-	Command       string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`               // Archive to extract (relative)
+	Destination   string                 `protobuf:"bytes,3,opt,name=destination,proto3" json:"destination,omitempty"` // Directory to extract into (empty = the archive's directory)
+	Format        ArchiveFormat          `protobuf:"varint,4,opt,name=format,proto3,enum=ironhost.v1.ArchiveFormat" json:"format,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SendCommandRequest) Reset() {
-	*x = SendCommandRequest{}
-	mi := &file_ironhost_v1_agent_proto_msgTypes[6]
+func (x *DecompressFileRequest) Reset() {
+	*x = DecompressFileRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SendCommandRequest) String() string {
+func (x *DecompressFileRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SendCommandRequest) ProtoMessage() {}
+func (*DecompressFileRequest) ProtoMessage() {}
 
-func (x *SendCommandRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_ironhost_v1_agent_proto_msgTypes[6]
+func (x *DecompressFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -429,54 +2284,63 @@ func (x *SendCommandRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SendCommandRequest.ProtoReflect.Descriptor instead.
-func (*SendCommandRequest) Descriptor() ([]byte, []int) {
-	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use DecompressFileRequest.ProtoReflect.Descriptor instead.
+func (*DecompressFileRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{32}
 }
 
-func (x *SendCommandRequest) GetServerId() string {
+func (x *DecompressFileRequest) GetServerId() string {
 	if x != nil {
 		return x.ServerId
 	}
 	return ""
 }
 
-func (x *SendCommandRequest) GetCommand() string {
+func (x *DecompressFileRequest) GetPath() string {
 	if x != nil {
-		return x.Command
+		return x.Path
 	}
 	return ""
 }
 
-type NodeStats struct {
-	state                protoimpl.MessageState `protogen:"open.v1"`
-	NodeId               string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
-	TotalMemoryBytes     int64                  `protobuf:"varint,2,opt,name=total_memory_bytes,json=totalMemoryBytes,proto3" json:"total_memory_bytes,omitempty"`
-	AvailableMemoryBytes int64                  `protobuf:"varint,3,opt,name=available_memory_bytes,json=availableMemoryBytes,proto3" json:"available_memory_bytes,omitempty"`
-	TotalDiskBytes       int64                  `protobuf:"varint,4,opt,name=total_disk_bytes,json=totalDiskBytes,proto3" json:"total_disk_bytes,omitempty"`
-	AvailableDiskBytes   int64                  `protobuf:"varint,5,opt,name=available_disk_bytes,json=availableDiskBytes,proto3" json:"available_disk_bytes,omitempty"`
-	CpuUsagePercent      float64                `protobuf:"fixed64,6,opt,name=cpu_usage_percent,json=cpuUsagePercent,proto3" json:"cpu_usage_percent,omitempty"`
-	RunningContainers    int32                  `protobuf:"varint,7,opt,name=running_containers,json=runningContainers,proto3" json:"running_containers,omitempty"`
-	UptimeSeconds        int64                  `protobuf:"varint,8,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
-	unknownFields        protoimpl.UnknownFields
-	sizeCache            protoimpl.SizeCache
+func (x *DecompressFileRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
 }
 
-func (x *NodeStats) Reset() {
-	*x = NodeStats{}
-	mi := &file_ironhost_v1_agent_proto_msgTypes[7]
+func (x *DecompressFileRequest) GetFormat() ArchiveFormat {
+	if x != nil {
+		return x.Format
+	}
+	return ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED
+}
+
+type ArchiveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`                             // Archive created, or directory extracted into
+	FileCount     int32                  `protobuf:"varint,2,opt,name=file_count,json=fileCount,proto3" json:"file_count,omitempty"` // Files and directories written
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`                            // Uncompressed bytes written
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ArchiveResponse) Reset() {
+	*x = ArchiveResponse{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *NodeStats) String() string {
+func (x *ArchiveResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*NodeStats) ProtoMessage() {}
+func (*ArchiveResponse) ProtoMessage() {}
 
-func (x *NodeStats) ProtoReflect() protoreflect.Message {
-	mi := &file_ironhost_v1_agent_proto_msgTypes[7]
+func (x *ArchiveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -487,91 +2351,242 @@ func (x *NodeStats) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use NodeStats.ProtoReflect.Descriptor instead.
-func (*NodeStats) Descriptor() ([]byte, []int) {
-	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use ArchiveResponse.ProtoReflect.Descriptor instead.
+func (*ArchiveResponse) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{33}
 }
 
-func (x *NodeStats) GetNodeId() string {
+func (x *ArchiveResponse) GetPath() string {
 	if x != nil {
-		return x.NodeId
+		return x.Path
 	}
 	return ""
 }
 
-func (x *NodeStats) GetTotalMemoryBytes() int64 {
+func (x *ArchiveResponse) GetFileCount() int32 {
 	if x != nil {
-		return x.TotalMemoryBytes
+		return x.FileCount
 	}
 	return 0
 }
 
-func (x *NodeStats) GetAvailableMemoryBytes() int64 {
+func (x *ArchiveResponse) GetSize() int64 {
 	if x != nil {
-		return x.AvailableMemoryBytes
+		return x.Size
 	}
 	return 0
 }
 
-func (x *NodeStats) GetTotalDiskBytes() int64 {
+type WatchEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Snapshot      bool                   `protobuf:"varint,1,opt,name=snapshot,proto3" json:"snapshot,omitempty"` // Start with one event per server giving its current status
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[34]
 	if x != nil {
-		return x.TotalDiskBytes
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *NodeStats) GetAvailableDiskBytes() int64 {
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *WatchEventsRequest) GetSnapshot() bool {
 	if x != nil {
-		return x.AvailableDiskBytes
+		return x.Snapshot
 	}
-	return 0
+	return false
 }
 
-func (x *NodeStats) GetCpuUsagePercent() float64 {
+// A lifecycle change of a server's container, translated from a Docker event
+type ServerEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	ContainerId   string                 `protobuf:"bytes,2,opt,name=container_id,json=containerId,proto3" json:"container_id,omitempty"`
+	Action        string                 `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`                                // Docker action (start, die, oom, health_status, restart) or "snapshot"
+	Status        ServerStatus           `protobuf:"varint,4,opt,name=status,proto3,enum=ironhost.v1.ServerStatus" json:"status,omitempty"` // Status the server moved to, UNSPECIFIED if unchanged
+	ExitCode      int32                  `protobuf:"varint,5,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`           // Set for die
+	Health        string                 `protobuf:"bytes,6,opt,name=health,proto3" json:"health,omitempty"`                                // Set for health_status: starting, healthy or unhealthy
+	Timestamp     int64                  `protobuf:"varint,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServerEvent) Reset() {
+	*x = ServerEvent{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerEvent) ProtoMessage() {}
+
+func (x *ServerEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[35]
 	if x != nil {
-		return x.CpuUsagePercent
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *NodeStats) GetRunningContainers() int32 {
+// Deprecated: Use ServerEvent.ProtoReflect.Descriptor instead.
+func (*ServerEvent) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *ServerEvent) GetServerId() string {
 	if x != nil {
-		return x.RunningContainers
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *ServerEvent) GetContainerId() string {
+	if x != nil {
+		return x.ContainerId
+	}
+	return ""
+}
+
+func (x *ServerEvent) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *ServerEvent) GetStatus() ServerStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ServerStatus_SERVER_STATUS_UNSPECIFIED
+}
+
+func (x *ServerEvent) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
 	}
 	return 0
 }
 
-func (x *NodeStats) GetUptimeSeconds() int64 {
+func (x *ServerEvent) GetHealth() string {
 	if x != nil {
-		return x.UptimeSeconds
+		return x.Health
+	}
+	return ""
+}
+
+func (x *ServerEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
 	}
 	return 0
 }
 
-type PingResponse struct {
+type GetOrphansRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Servers the master has on this node. Managed containers for any other
+	// server are reported as orphans. Left empty, only orphans the agent can
+	// detect on its own are reported.
+	KnownServerIds []string `protobuf:"bytes,1,rep,name=known_server_ids,json=knownServerIds,proto3" json:"known_server_ids,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetOrphansRequest) Reset() {
+	*x = GetOrphansRequest{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrphansRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrphansRequest) ProtoMessage() {}
+
+func (x *GetOrphansRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrphansRequest.ProtoReflect.Descriptor instead.
+func (*GetOrphansRequest) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *GetOrphansRequest) GetKnownServerIds() []string {
+	if x != nil {
+		return x.KnownServerIds
+	}
+	return nil
+}
+
+type Orphan struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
-	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
-	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Kind          OrphanKind             `protobuf:"varint,1,opt,name=kind,proto3,enum=ironhost.v1.OrphanKind" json:"kind,omitempty"`
+	ServerId      string                 `protobuf:"bytes,2,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`          // Empty if the container has no server ID label
+	ContainerId   string                 `protobuf:"bytes,3,opt,name=container_id,json=containerId,proto3" json:"container_id,omitempty"` // Set for containers
+	Path          string                 `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`                                  // Host path of the data directory
+	Reason        string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`                              // Why it is considered orphaned
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PingResponse) Reset() {
-	*x = PingResponse{}
-	mi := &file_ironhost_v1_agent_proto_msgTypes[8]
+func (x *Orphan) Reset() {
+	*x = Orphan{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[37]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PingResponse) String() string {
+func (x *Orphan) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PingResponse) ProtoMessage() {}
+func (*Orphan) ProtoMessage() {}
 
-func (x *PingResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_ironhost_v1_agent_proto_msgTypes[8]
+func (x *Orphan) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[37]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -582,28 +2597,94 @@ func (x *PingResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
-func (*PingResponse) Descriptor() ([]byte, []int) {
-	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use Orphan.ProtoReflect.Descriptor instead.
+func (*Orphan) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{37}
 }
 
-func (x *PingResponse) GetNodeId() string {
+func (x *Orphan) GetKind() OrphanKind {
 	if x != nil {
-		return x.NodeId
+		return x.Kind
+	}
+	return OrphanKind_ORPHAN_KIND_UNSPECIFIED
+}
+
+func (x *Orphan) GetServerId() string {
+	if x != nil {
+		return x.ServerId
 	}
 	return ""
 }
 
-func (x *PingResponse) GetVersion() string {
+func (x *Orphan) GetContainerId() string {
 	if x != nil {
-		return x.Version
+		return x.ContainerId
 	}
 	return ""
 }
 
-func (x *PingResponse) GetTimestamp() int64 {
+func (x *Orphan) GetPath() string {
 	if x != nil {
-		return x.Timestamp
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Orphan) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type GetOrphansResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Orphans       []*Orphan              `protobuf:"bytes,1,rep,name=orphans,proto3" json:"orphans,omitempty"`
+	ReconciledAt  int64                  `protobuf:"varint,2,opt,name=reconciled_at,json=reconciledAt,proto3" json:"reconciled_at,omitempty"` // Unix time of the reconciliation the report is based on
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOrphansResponse) Reset() {
+	*x = GetOrphansResponse{}
+	mi := &file_ironhost_v1_agent_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrphansResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrphansResponse) ProtoMessage() {}
+
+func (x *GetOrphansResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_agent_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrphansResponse.ProtoReflect.Descriptor instead.
+func (*GetOrphansResponse) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_agent_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *GetOrphansResponse) GetOrphans() []*Orphan {
+	if x != nil {
+		return x.Orphans
+	}
+	return nil
+}
+
+func (x *GetOrphansResponse) GetReconciledAt() int64 {
+	if x != nil {
+		return x.ReconciledAt
 	}
 	return 0
 }
@@ -612,7 +2693,7 @@ var File_ironhost_v1_agent_proto protoreflect.FileDescriptor
 
 const file_ironhost_v1_agent_proto_rawDesc = "" +
 	"\n" +
-	"\x17ironhost/v1/agent.proto\x12\vironhost.v1\x1a\x18ironhost/v1/common.proto\x1a\x1bgoogle/protobuf/empty.proto\"\xb7\x02\n" +
+	"\x17ironhost/v1/agent.proto\x12\vironhost.v1\x1a\x18ironhost/v1/common.proto\x1a\x1bgoogle/protobuf/empty.proto\"\x90\x05\n" +
 	"\x13CreateServerRequest\x12\x1b\n" +
 	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12!\n" +
@@ -620,24 +2701,74 @@ const file_ironhost_v1_agent_proto_rawDesc = "" +
 	"\x06limits\x18\x04 \x01(\v2\x1b.ironhost.v1.ResourceLimitsR\x06limits\x129\n" +
 	"\vallocations\x18\x05 \x03(\v2\x17.ironhost.v1.AllocationR\vallocations\x125\n" +
 	"\venvironment\x18\x06 \x03(\v2\x13.ironhost.v1.EnvVarR\venvironment\x12%\n" +
-	"\x0edata_directory\x18\a \x01(\tR\rdataDirectory\"x\n" +
+	"\x0edata_directory\x18\a \x01(\tR\rdataDirectory\x12F\n" +
+	"\x10readiness_probes\x18\b \x03(\v2\x1b.ironhost.v1.ReadinessProbeR\x0freadinessProbes\x12%\n" +
+	"\x0econtainer_port\x18\t \x01(\x05R\rcontainerPort\x12\x1a\n" +
+	"\bprotocol\x18\n" +
+	" \x01(\tR\bprotocol\x12'\n" +
+	"\x0fstartup_command\x18\v \x01(\tR\x0estartupCommand\x12!\n" +
+	"\fstop_command\x18\f \x01(\tR\vstopCommand\x12\x1d\n" +
+	"\n" +
+	"data_mount\x18\r \x01(\tR\tdataMount\x124\n" +
+	"\ainstall\x18\x0e \x01(\v2\x1a.ironhost.v1.InstallScriptR\ainstall\x12+\n" +
+	"\x11command_transport\x18\x0f \x01(\tR\x10commandTransport\"\xc2\x01\n" +
 	"\x14CreateServerResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12!\n" +
 	"\fcontainer_id\x18\x02 \x01(\tR\vcontainerId\x12#\n" +
-	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"Y\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\x12\x1c\n" +
+	"\tinstalled\x18\x04 \x01(\bR\tinstalled\x12*\n" +
+	"\x11install_exit_code\x18\x05 \x01(\x05R\x0finstallExitCode\"]\n" +
+	"\rInstallScript\x12\x14\n" +
+	"\x05image\x18\x01 \x01(\tR\x05image\x12\x1e\n" +
+	"\n" +
+	"entrypoint\x18\x02 \x01(\tR\n" +
+	"entrypoint\x12\x16\n" +
+	"\x06script\x18\x03 \x01(\tR\x06script\"\xf6\x01\n" +
+	"\x16ReinstallServerRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x124\n" +
+	"\ainstall\x18\x02 \x01(\v2\x1a.ironhost.v1.InstallScriptR\ainstall\x125\n" +
+	"\venvironment\x18\x03 \x03(\v2\x13.ironhost.v1.EnvVarR\venvironment\x123\n" +
+	"\x06limits\x18\x04 \x01(\v2\x1b.ironhost.v1.ResourceLimitsR\x06limits\x12\x1d\n" +
+	"\n" +
+	"data_mount\x18\x05 \x01(\tR\tdataMount\"m\n" +
+	"\x0fInstallResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12#\n" +
+	"\rerror_message\x18\x02 \x01(\tR\ferrorMessage\x12\x1b\n" +
+	"\texit_code\x18\x03 \x01(\x05R\bexitCode\"Y\n" +
 	"\x11StopServerRequest\x12\x1b\n" +
 	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12'\n" +
-	"\x0ftimeout_seconds\x18\x02 \x01(\x05R\x0etimeoutSeconds\"U\n" +
+	"\x0ftimeout_seconds\x18\x02 \x01(\x05R\x0etimeoutSeconds\"p\n" +
+	"\x1cUpdateServerResourcesRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x123\n" +
+	"\x06limits\x18\x02 \x01(\v2\x1b.ironhost.v1.ResourceLimitsR\x06limits\"x\n" +
+	"\x1eUpdateServerAllocationsRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x129\n" +
+	"\vallocations\x18\x02 \x03(\v2\x17.ironhost.v1.AllocationR\vallocations\"U\n" +
 	"\x14ServerActionResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12#\n" +
 	"\rerror_message\x18\x02 \x01(\tR\ferrorMessage\"I\n" +
 	"\x13ListServersResponse\x122\n" +
-	"\aservers\x18\x01 \x03(\v2\x18.ironhost.v1.ServerStateR\aservers\"y\n" +
+	"\aservers\x18\x01 \x03(\v2\x18.ironhost.v1.ServerStateR\aservers\"b\n" +
+	"\x18StreamServerStatsRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12)\n" +
+	"\x10interval_seconds\x18\x02 \x01(\x05R\x0fintervalSeconds\"l\n" +
+	"\x14StreamConsoleRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12!\n" +
+	"\fafter_offset\x18\x02 \x01(\x03R\vafterOffset\x12\x14\n" +
+	"\x05epoch\x18\x03 \x01(\tR\x05epoch\"\x82\x01\n" +
+	"\x14AttachConsoleRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12!\n" +
+	"\fafter_offset\x18\x02 \x01(\x03R\vafterOffset\x12\x14\n" +
+	"\x05input\x18\x03 \x01(\fR\x05input\x12\x14\n" +
+	"\x05epoch\x18\x04 \x01(\tR\x05epoch\"\xdb\x01\n" +
 	"\rConsoleOutput\x12\x1b\n" +
 	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x12\n" +
 	"\x04line\x18\x02 \x01(\tR\x04line\x12\x1c\n" +
 	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\x12\x19\n" +
-	"\bis_error\x18\x04 \x01(\bR\aisError\"K\n" +
+	"\bis_error\x18\x04 \x01(\bR\aisError\x122\n" +
+	"\x06stream\x18\x05 \x01(\x0e2\x1a.ironhost.v1.ConsoleStreamR\x06stream\x12\x16\n" +
+	"\x06offset\x18\x06 \x01(\x03R\x06offset\x12\x14\n" +
+	"\x05epoch\x18\a \x01(\tR\x05epoch\"K\n" +
 	"\x12SendCommandRequest\x12\x1b\n" +
 	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x18\n" +
 	"\acommand\x18\x02 \x01(\tR\acommand\"\xe6\x02\n" +
@@ -653,20 +2784,147 @@ const file_ironhost_v1_agent_proto_rawDesc = "" +
 	"\fPingResponse\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x18\n" +
 	"\aversion\x18\x02 \x01(\tR\aversion\x12\x1c\n" +
-	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp2\xd0\x06\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\"\x8a\x01\n" +
+	"\bFileInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12!\n" +
+	"\fis_directory\x18\x03 \x01(\bR\visDirectory\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\x12\x1f\n" +
+	"\vmodified_at\x18\x05 \x01(\x03R\n" +
+	"modifiedAt\"C\n" +
+	"\x10ListFilesRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\"c\n" +
+	"\x11ListFilesResponse\x12+\n" +
+	"\x05files\x18\x01 \x03(\v2\x15.ironhost.v1.FileInfoR\x05files\x12!\n" +
+	"\fcurrent_path\x18\x02 \x01(\tR\vcurrentPath\"B\n" +
+	"\x0fReadFileRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\"T\n" +
+	"\x10ReadFileResponse\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\"]\n" +
+	"\x10WriteFileRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\tR\acontent\"D\n" +
+	"\x11DeleteFileRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\"f\n" +
+	"\x11RenameFileRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x19\n" +
+	"\bold_path\x18\x02 \x01(\tR\aoldPath\x12\x19\n" +
+	"\bnew_path\x18\x03 \x01(\tR\anewPath\"\xa0\x01\n" +
+	"\x11UploadFileRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06sha256\x18\x04 \x01(\tR\x06sha256\x12\x12\n" +
+	"\x04data\x18\x05 \x01(\fR\x04data\x12\x16\n" +
+	"\x06crc32c\x18\x06 \x01(\rR\x06crc32c\"@\n" +
+	"\x12UploadFileResponse\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x02 \x01(\tR\x06sha256\"F\n" +
+	"\x13UploadStatusRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\".\n" +
+	"\x14UploadStatusResponse\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x03R\x06offset\"^\n" +
+	"\x13DownloadFileRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x03R\x06offset\"w\n" +
+	"\tFileChunk\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06crc32c\x18\x03 \x01(\rR\x06crc32c\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\x12\x12\n" +
+	"\x04name\x18\x05 \x01(\tR\x04name\"\x9f\x01\n" +
+	"\x14CompressFilesRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x14\n" +
+	"\x05paths\x18\x02 \x03(\tR\x05paths\x12 \n" +
+	"\vdestination\x18\x03 \x01(\tR\vdestination\x122\n" +
+	"\x06format\x18\x04 \x01(\x0e2\x1a.ironhost.v1.ArchiveFormatR\x06format\"\x9e\x01\n" +
+	"\x15DecompressFileRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12 \n" +
+	"\vdestination\x18\x03 \x01(\tR\vdestination\x122\n" +
+	"\x06format\x18\x04 \x01(\x0e2\x1a.ironhost.v1.ArchiveFormatR\x06format\"X\n" +
+	"\x0fArchiveResponse\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1d\n" +
+	"\n" +
+	"file_count\x18\x02 \x01(\x05R\tfileCount\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\"0\n" +
+	"\x12WatchEventsRequest\x12\x1a\n" +
+	"\bsnapshot\x18\x01 \x01(\bR\bsnapshot\"\xeb\x01\n" +
+	"\vServerEvent\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12!\n" +
+	"\fcontainer_id\x18\x02 \x01(\tR\vcontainerId\x12\x16\n" +
+	"\x06action\x18\x03 \x01(\tR\x06action\x121\n" +
+	"\x06status\x18\x04 \x01(\x0e2\x19.ironhost.v1.ServerStatusR\x06status\x12\x1b\n" +
+	"\texit_code\x18\x05 \x01(\x05R\bexitCode\x12\x16\n" +
+	"\x06health\x18\x06 \x01(\tR\x06health\x12\x1c\n" +
+	"\ttimestamp\x18\a \x01(\x03R\ttimestamp\"=\n" +
+	"\x11GetOrphansRequest\x12(\n" +
+	"\x10known_server_ids\x18\x01 \x03(\tR\x0eknownServerIds\"\xa1\x01\n" +
+	"\x06Orphan\x12+\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x17.ironhost.v1.OrphanKindR\x04kind\x12\x1b\n" +
+	"\tserver_id\x18\x02 \x01(\tR\bserverId\x12!\n" +
+	"\fcontainer_id\x18\x03 \x01(\tR\vcontainerId\x12\x12\n" +
+	"\x04path\x18\x04 \x01(\tR\x04path\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\"h\n" +
+	"\x12GetOrphansResponse\x12-\n" +
+	"\aorphans\x18\x01 \x03(\v2\x13.ironhost.v1.OrphanR\aorphans\x12#\n" +
+	"\rreconciled_at\x18\x02 \x01(\x03R\freconciledAt*e\n" +
+	"\rConsoleStream\x12\x1e\n" +
+	"\x1aCONSOLE_STREAM_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15CONSOLE_STREAM_STDOUT\x10\x01\x12\x19\n" +
+	"\x15CONSOLE_STREAM_STDERR\x10\x02*b\n" +
+	"\rArchiveFormat\x12\x1e\n" +
+	"\x1aARCHIVE_FORMAT_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12ARCHIVE_FORMAT_ZIP\x10\x01\x12\x19\n" +
+	"\x15ARCHIVE_FORMAT_TAR_GZ\x10\x02*d\n" +
+	"\n" +
+	"OrphanKind\x12\x1b\n" +
+	"\x17ORPHAN_KIND_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15ORPHAN_KIND_CONTAINER\x10\x01\x12\x1e\n" +
+	"\x1aORPHAN_KIND_DATA_DIRECTORY\x10\x022\xbc\x12\n" +
 	"\fAgentService\x12S\n" +
 	"\fCreateServer\x12 .ironhost.v1.CreateServerRequest\x1a!.ironhost.v1.CreateServerResponse\x12O\n" +
 	"\vStartServer\x12\x1d.ironhost.v1.ServerIdentifier\x1a!.ironhost.v1.ServerActionResponse\x12O\n" +
 	"\n" +
 	"StopServer\x12\x1e.ironhost.v1.StopServerRequest\x1a!.ironhost.v1.ServerActionResponse\x12Q\n" +
 	"\rRestartServer\x12\x1d.ironhost.v1.ServerIdentifier\x1a!.ironhost.v1.ServerActionResponse\x12P\n" +
-	"\fDeleteServer\x12\x1d.ironhost.v1.ServerIdentifier\x1a!.ironhost.v1.ServerActionResponse\x12J\n" +
+	"\fDeleteServer\x12\x1d.ironhost.v1.ServerIdentifier\x1a!.ironhost.v1.ServerActionResponse\x12e\n" +
+	"\x15UpdateServerResources\x12).ironhost.v1.UpdateServerResourcesRequest\x1a!.ironhost.v1.ServerActionResponse\x12i\n" +
+	"\x17UpdateServerAllocations\x12+.ironhost.v1.UpdateServerAllocationsRequest\x1a!.ironhost.v1.ServerActionResponse\x12T\n" +
+	"\x0fReinstallServer\x12#.ironhost.v1.ReinstallServerRequest\x1a\x1c.ironhost.v1.InstallResponse\x12J\n" +
 	"\x0fGetServerStatus\x12\x1d.ironhost.v1.ServerIdentifier\x1a\x18.ironhost.v1.ServerState\x12G\n" +
-	"\vListServers\x12\x16.google.protobuf.Empty\x1a .ironhost.v1.ListServersResponse\x12L\n" +
-	"\rStreamConsole\x12\x1d.ironhost.v1.ServerIdentifier\x1a\x1a.ironhost.v1.ConsoleOutput0\x01\x12F\n" +
-	"\vSendCommand\x12\x1f.ironhost.v1.SendCommandRequest\x1a\x16.google.protobuf.Empty\x12>\n" +
+	"\vListServers\x12\x16.google.protobuf.Empty\x1a .ironhost.v1.ListServersResponse\x12V\n" +
+	"\x11StreamServerStats\x12%.ironhost.v1.StreamServerStatsRequest\x1a\x18.ironhost.v1.ServerState0\x01\x12J\n" +
+	"\vWatchEvents\x12\x1f.ironhost.v1.WatchEventsRequest\x1a\x18.ironhost.v1.ServerEvent0\x01\x12P\n" +
+	"\rStreamConsole\x12!.ironhost.v1.StreamConsoleRequest\x1a\x1a.ironhost.v1.ConsoleOutput0\x01\x12R\n" +
+	"\rAttachConsole\x12!.ironhost.v1.AttachConsoleRequest\x1a\x1a.ironhost.v1.ConsoleOutput(\x010\x01\x12Q\n" +
+	"\vSendCommand\x12\x1f.ironhost.v1.SendCommandRequest\x1a!.ironhost.v1.ServerActionResponse\x12K\n" +
+	"\aGetLogs\x12\x1d.ironhost.v1.ServerIdentifier\x1a!.ironhost.v1.ServerActionResponse\x12J\n" +
+	"\tListFiles\x12\x1d.ironhost.v1.ListFilesRequest\x1a\x1e.ironhost.v1.ListFilesResponse\x12G\n" +
+	"\bReadFile\x12\x1c.ironhost.v1.ReadFileRequest\x1a\x1d.ironhost.v1.ReadFileResponse\x12M\n" +
+	"\tWriteFile\x12\x1d.ironhost.v1.WriteFileRequest\x1a!.ironhost.v1.ServerActionResponse\x12O\n" +
+	"\n" +
+	"DeleteFile\x12\x1e.ironhost.v1.DeleteFileRequest\x1a!.ironhost.v1.ServerActionResponse\x12O\n" +
+	"\n" +
+	"RenameFile\x12\x1e.ironhost.v1.RenameFileRequest\x1a!.ironhost.v1.ServerActionResponse\x12O\n" +
+	"\n" +
+	"UploadFile\x12\x1e.ironhost.v1.UploadFileRequest\x1a\x1f.ironhost.v1.UploadFileResponse(\x01\x12V\n" +
+	"\x0fGetUploadStatus\x12 .ironhost.v1.UploadStatusRequest\x1a!.ironhost.v1.UploadStatusResponse\x12J\n" +
+	"\fDownloadFile\x12 .ironhost.v1.DownloadFileRequest\x1a\x16.ironhost.v1.FileChunk0\x01\x12P\n" +
+	"\rCompressFiles\x12!.ironhost.v1.CompressFilesRequest\x1a\x1c.ironhost.v1.ArchiveResponse\x12R\n" +
+	"\x0eDecompressFile\x12\".ironhost.v1.DecompressFileRequest\x1a\x1c.ironhost.v1.ArchiveResponse\x12>\n" +
 	"\fGetNodeStats\x12\x16.google.protobuf.Empty\x1a\x16.ironhost.v1.NodeStats\x129\n" +
-	"\x04Ping\x12\x16.google.protobuf.Empty\x1a\x19.ironhost.v1.PingResponseB'Z%github.com/ironhost/proto/ironhost/v1b\x06proto3"
+	"\x04Ping\x12\x16.google.protobuf.Empty\x1a\x19.ironhost.v1.PingResponse\x12M\n" +
+	"\n" +
+	"GetOrphans\x12\x1e.ironhost.v1.GetOrphansRequest\x1a\x1f.ironhost.v1.GetOrphansResponseB'Z%github.com/ironhost/proto/ironhost/v1b\x06proto3"
 
 var (
 	file_ironhost_v1_agent_proto_rawDescOnce sync.Once
@@ -680,56 +2938,142 @@ func file_ironhost_v1_agent_proto_rawDescGZIP() []byte {
 	return file_ironhost_v1_agent_proto_rawDescData
 }
 
-var file_ironhost_v1_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_ironhost_v1_agent_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_ironhost_v1_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 39)
 var file_ironhost_v1_agent_proto_goTypes = []any{
-	(*CreateServerRequest)(nil),  // 0: ironhost.v1.CreateServerRequest
-	(*CreateServerResponse)(nil), // 1: ironhost.v1.CreateServerResponse
-	(*StopServerRequest)(nil),    // 2: ironhost.v1.StopServerRequest
-	(*ServerActionResponse)(nil), // 3: ironhost.v1.ServerActionResponse
-	(*ListServersResponse)(nil),  // 4: ironhost.v1.ListServersResponse
-	(*ConsoleOutput)(nil),        // 5: ironhost.v1.ConsoleOutput
-	(*SendCommandRequest)(nil),   // 6: ironhost.v1.SendCommandRequest
-	(*NodeStats)(nil),            // 7: ironhost.v1.NodeStats
-	(*PingResponse)(nil),         // 8: ironhost.v1.PingResponse
-	(*ResourceLimits)(nil),       // 9: ironhost.v1.ResourceLimits
-	(*Allocation)(nil),           // 10: ironhost.v1.Allocation
-	(*EnvVar)(nil),               // 11: ironhost.v1.EnvVar
-	(*ServerState)(nil),          // 12: ironhost.v1.ServerState
-	(*ServerIdentifier)(nil),     // 13: ironhost.v1.ServerIdentifier
-	(*emptypb.Empty)(nil),        // 14: google.protobuf.Empty
+	(ConsoleStream)(0),                     // 0: ironhost.v1.ConsoleStream
+	(ArchiveFormat)(0),                     // 1: ironhost.v1.ArchiveFormat
+	(OrphanKind)(0),                        // 2: ironhost.v1.OrphanKind
+	(*CreateServerRequest)(nil),            // 3: ironhost.v1.CreateServerRequest
+	(*CreateServerResponse)(nil),           // 4: ironhost.v1.CreateServerResponse
+	(*InstallScript)(nil),                  // 5: ironhost.v1.InstallScript
+	(*ReinstallServerRequest)(nil),         // 6: ironhost.v1.ReinstallServerRequest
+	(*InstallResponse)(nil),                // 7: ironhost.v1.InstallResponse
+	(*StopServerRequest)(nil),              // 8: ironhost.v1.StopServerRequest
+	(*UpdateServerResourcesRequest)(nil),   // 9: ironhost.v1.UpdateServerResourcesRequest
+	(*UpdateServerAllocationsRequest)(nil), // 10: ironhost.v1.UpdateServerAllocationsRequest
+	(*ServerActionResponse)(nil),           // 11: ironhost.v1.ServerActionResponse
+	(*ListServersResponse)(nil),            // 12: ironhost.v1.ListServersResponse
+	(*StreamServerStatsRequest)(nil),       // 13: ironhost.v1.StreamServerStatsRequest
+	(*StreamConsoleRequest)(nil),           // 14: ironhost.v1.StreamConsoleRequest
+	(*AttachConsoleRequest)(nil),           // 15: ironhost.v1.AttachConsoleRequest
+	(*ConsoleOutput)(nil),                  // 16: ironhost.v1.ConsoleOutput
+	(*SendCommandRequest)(nil),             // 17: ironhost.v1.SendCommandRequest
+	(*NodeStats)(nil),                      // 18: ironhost.v1.NodeStats
+	(*PingResponse)(nil),                   // 19: ironhost.v1.PingResponse
+	(*FileInfo)(nil),                       // 20: ironhost.v1.FileInfo
+	(*ListFilesRequest)(nil),               // 21: ironhost.v1.ListFilesRequest
+	(*ListFilesResponse)(nil),              // 22: ironhost.v1.ListFilesResponse
+	(*ReadFileRequest)(nil),                // 23: ironhost.v1.ReadFileRequest
+	(*ReadFileResponse)(nil),               // 24: ironhost.v1.ReadFileResponse
+	(*WriteFileRequest)(nil),               // 25: ironhost.v1.WriteFileRequest
+	(*DeleteFileRequest)(nil),              // 26: ironhost.v1.DeleteFileRequest
+	(*RenameFileRequest)(nil),              // 27: ironhost.v1.RenameFileRequest
+	(*UploadFileRequest)(nil),              // 28: ironhost.v1.UploadFileRequest
+	(*UploadFileResponse)(nil),             // 29: ironhost.v1.UploadFileResponse
+	(*UploadStatusRequest)(nil),            // 30: ironhost.v1.UploadStatusRequest
+	(*UploadStatusResponse)(nil),           // 31: ironhost.v1.UploadStatusResponse
+	(*DownloadFileRequest)(nil),            // 32: ironhost.v1.DownloadFileRequest
+	(*FileChunk)(nil),                      // 33: ironhost.v1.FileChunk
+	(*CompressFilesRequest)(nil),           // 34: ironhost.v1.CompressFilesRequest
+	(*DecompressFileRequest)(nil),          // 35: ironhost.v1.DecompressFileRequest
+	(*ArchiveResponse)(nil),                // 36: ironhost.v1.ArchiveResponse
+	(*WatchEventsRequest)(nil),             // 37: ironhost.v1.WatchEventsRequest
+	(*ServerEvent)(nil),                    // 38: ironhost.v1.ServerEvent
+	(*GetOrphansRequest)(nil),              // 39: ironhost.v1.GetOrphansRequest
+	(*Orphan)(nil),                         // 40: ironhost.v1.Orphan
+	(*GetOrphansResponse)(nil),             // 41: ironhost.v1.GetOrphansResponse
+	(*ResourceLimits)(nil),                 // 42: ironhost.v1.ResourceLimits
+	(*Allocation)(nil),                     // 43: ironhost.v1.Allocation
+	(*EnvVar)(nil),                         // 44: ironhost.v1.EnvVar
+	(*ReadinessProbe)(nil),                 // 45: ironhost.v1.ReadinessProbe
+	(*ServerState)(nil),                    // 46: ironhost.v1.ServerState
+	(ServerStatus)(0),                      // 47: ironhost.v1.ServerStatus
+	(*ServerIdentifier)(nil),               // 48: ironhost.v1.ServerIdentifier
+	(*emptypb.Empty)(nil),                  // 49: google.protobuf.Empty
 }
 var file_ironhost_v1_agent_proto_depIdxs = []int32{
-	9,  // 0: ironhost.v1.CreateServerRequest.limits:type_name -> ironhost.v1.ResourceLimits
-	10, // 1: ironhost.v1.CreateServerRequest.allocations:type_name -> ironhost.v1.Allocation
-	11, // 2: ironhost.v1.CreateServerRequest.environment:type_name -> ironhost.v1.EnvVar
-	12, // 3: ironhost.v1.ListServersResponse.servers:type_name -> ironhost.v1.ServerState
-	0,  // 4: ironhost.v1.AgentService.CreateServer:input_type -> ironhost.v1.CreateServerRequest
-	13, // 5: ironhost.v1.AgentService.StartServer:input_type -> ironhost.v1.ServerIdentifier
-	2,  // 6: ironhost.v1.AgentService.StopServer:input_type -> ironhost.v1.StopServerRequest
-	13, // 7: ironhost.v1.AgentService.RestartServer:input_type -> ironhost.v1.ServerIdentifier
-	13, // 8: ironhost.v1.AgentService.DeleteServer:input_type -> ironhost.v1.ServerIdentifier
-	13, // 9: ironhost.v1.AgentService.GetServerStatus:input_type -> ironhost.v1.ServerIdentifier
-	14, // 10: ironhost.v1.AgentService.ListServers:input_type -> google.protobuf.Empty
-	13, // 11: ironhost.v1.AgentService.StreamConsole:input_type -> ironhost.v1.ServerIdentifier
-	6,  // 12: ironhost.v1.AgentService.SendCommand:input_type -> ironhost.v1.SendCommandRequest
-	14, // 13: ironhost.v1.AgentService.GetNodeStats:input_type -> google.protobuf.Empty
-	14, // 14: ironhost.v1.AgentService.Ping:input_type -> google.protobuf.Empty
-	1,  // 15: ironhost.v1.AgentService.CreateServer:output_type -> ironhost.v1.CreateServerResponse
-	3,  // 16: ironhost.v1.AgentService.StartServer:output_type -> ironhost.v1.ServerActionResponse
-	3,  // 17: ironhost.v1.AgentService.StopServer:output_type -> ironhost.v1.ServerActionResponse
-	3,  // 18: ironhost.v1.AgentService.RestartServer:output_type -> ironhost.v1.ServerActionResponse
-	3,  // 19: ironhost.v1.AgentService.DeleteServer:output_type -> ironhost.v1.ServerActionResponse
-	12, // 20: ironhost.v1.AgentService.GetServerStatus:output_type -> ironhost.v1.ServerState
-	4,  // 21: ironhost.v1.AgentService.ListServers:output_type -> ironhost.v1.ListServersResponse
-	5,  // 22: ironhost.v1.AgentService.StreamConsole:output_type -> ironhost.v1.ConsoleOutput
-	14, // 23: ironhost.v1.AgentService.SendCommand:output_type -> google.protobuf.Empty
-	7,  // 24: ironhost.v1.AgentService.GetNodeStats:output_type -> ironhost.v1.NodeStats
-	8,  // 25: ironhost.v1.AgentService.Ping:output_type -> ironhost.v1.PingResponse
-	15, // [15:26] is the sub-list for method output_type
-	4,  // [4:15] is the sub-list for method input_type
-	4,  // [4:4] is the sub-list for extension type_name
-	4,  // [4:4] is the sub-list for extension extendee
-	0,  // [0:4] is the sub-list for field type_name
+	42, // 0: ironhost.v1.CreateServerRequest.limits:type_name -> ironhost.v1.ResourceLimits
+	43, // 1: ironhost.v1.CreateServerRequest.allocations:type_name -> ironhost.v1.Allocation
+	44, // 2: ironhost.v1.CreateServerRequest.environment:type_name -> ironhost.v1.EnvVar
+	45, // 3: ironhost.v1.CreateServerRequest.readiness_probes:type_name -> ironhost.v1.ReadinessProbe
+	5,  // 4: ironhost.v1.CreateServerRequest.install:type_name -> ironhost.v1.InstallScript
+	5,  // 5: ironhost.v1.ReinstallServerRequest.install:type_name -> ironhost.v1.InstallScript
+	44, // 6: ironhost.v1.ReinstallServerRequest.environment:type_name -> ironhost.v1.EnvVar
+	42, // 7: ironhost.v1.ReinstallServerRequest.limits:type_name -> ironhost.v1.ResourceLimits
+	42, // 8: ironhost.v1.UpdateServerResourcesRequest.limits:type_name -> ironhost.v1.ResourceLimits
+	43, // 9: ironhost.v1.UpdateServerAllocationsRequest.allocations:type_name -> ironhost.v1.Allocation
+	46, // 10: ironhost.v1.ListServersResponse.servers:type_name -> ironhost.v1.ServerState
+	0,  // 11: ironhost.v1.ConsoleOutput.stream:type_name -> ironhost.v1.ConsoleStream
+	20, // 12: ironhost.v1.ListFilesResponse.files:type_name -> ironhost.v1.FileInfo
+	1,  // 13: ironhost.v1.CompressFilesRequest.format:type_name -> ironhost.v1.ArchiveFormat
+	1,  // 14: ironhost.v1.DecompressFileRequest.format:type_name -> ironhost.v1.ArchiveFormat
+	47, // 15: ironhost.v1.ServerEvent.status:type_name -> ironhost.v1.ServerStatus
+	2,  // 16: ironhost.v1.Orphan.kind:type_name -> ironhost.v1.OrphanKind
+	40, // 17: ironhost.v1.GetOrphansResponse.orphans:type_name -> ironhost.v1.Orphan
+	3,  // 18: ironhost.v1.AgentService.CreateServer:input_type -> ironhost.v1.CreateServerRequest
+	48, // 19: ironhost.v1.AgentService.StartServer:input_type -> ironhost.v1.ServerIdentifier
+	8,  // 20: ironhost.v1.AgentService.StopServer:input_type -> ironhost.v1.StopServerRequest
+	48, // 21: ironhost.v1.AgentService.RestartServer:input_type -> ironhost.v1.ServerIdentifier
+	48, // 22: ironhost.v1.AgentService.DeleteServer:input_type -> ironhost.v1.ServerIdentifier
+	9,  // 23: ironhost.v1.AgentService.UpdateServerResources:input_type -> ironhost.v1.UpdateServerResourcesRequest
+	10, // 24: ironhost.v1.AgentService.UpdateServerAllocations:input_type -> ironhost.v1.UpdateServerAllocationsRequest
+	6,  // 25: ironhost.v1.AgentService.ReinstallServer:input_type -> ironhost.v1.ReinstallServerRequest
+	48, // 26: ironhost.v1.AgentService.GetServerStatus:input_type -> ironhost.v1.ServerIdentifier
+	49, // 27: ironhost.v1.AgentService.ListServers:input_type -> google.protobuf.Empty
+	13, // 28: ironhost.v1.AgentService.StreamServerStats:input_type -> ironhost.v1.StreamServerStatsRequest
+	37, // 29: ironhost.v1.AgentService.WatchEvents:input_type -> ironhost.v1.WatchEventsRequest
+	14, // 30: ironhost.v1.AgentService.StreamConsole:input_type -> ironhost.v1.StreamConsoleRequest
+	15, // 31: ironhost.v1.AgentService.AttachConsole:input_type -> ironhost.v1.AttachConsoleRequest
+	17, // 32: ironhost.v1.AgentService.SendCommand:input_type -> ironhost.v1.SendCommandRequest
+	48, // 33: ironhost.v1.AgentService.GetLogs:input_type -> ironhost.v1.ServerIdentifier
+	21, // 34: ironhost.v1.AgentService.ListFiles:input_type -> ironhost.v1.ListFilesRequest
+	23, // 35: ironhost.v1.AgentService.ReadFile:input_type -> ironhost.v1.ReadFileRequest
+	25, // 36: ironhost.v1.AgentService.WriteFile:input_type -> ironhost.v1.WriteFileRequest
+	26, // 37: ironhost.v1.AgentService.DeleteFile:input_type -> ironhost.v1.DeleteFileRequest
+	27, // 38: ironhost.v1.AgentService.RenameFile:input_type -> ironhost.v1.RenameFileRequest
+	28, // 39: ironhost.v1.AgentService.UploadFile:input_type -> ironhost.v1.UploadFileRequest
+	30, // 40: ironhost.v1.AgentService.GetUploadStatus:input_type -> ironhost.v1.UploadStatusRequest
+	32, // 41: ironhost.v1.AgentService.DownloadFile:input_type -> ironhost.v1.DownloadFileRequest
+	34, // 42: ironhost.v1.AgentService.CompressFiles:input_type -> ironhost.v1.CompressFilesRequest
+	35, // 43: ironhost.v1.AgentService.DecompressFile:input_type -> ironhost.v1.DecompressFileRequest
+	49, // 44: ironhost.v1.AgentService.GetNodeStats:input_type -> google.protobuf.Empty
+	49, // 45: ironhost.v1.AgentService.Ping:input_type -> google.protobuf.Empty
+	39, // 46: ironhost.v1.AgentService.GetOrphans:input_type -> ironhost.v1.GetOrphansRequest
+	4,  // 47: ironhost.v1.AgentService.CreateServer:output_type -> ironhost.v1.CreateServerResponse
+	11, // 48: ironhost.v1.AgentService.StartServer:output_type -> ironhost.v1.ServerActionResponse
+	11, // 49: ironhost.v1.AgentService.StopServer:output_type -> ironhost.v1.ServerActionResponse
+	11, // 50: ironhost.v1.AgentService.RestartServer:output_type -> ironhost.v1.ServerActionResponse
+	11, // 51: ironhost.v1.AgentService.DeleteServer:output_type -> ironhost.v1.ServerActionResponse
+	11, // 52: ironhost.v1.AgentService.UpdateServerResources:output_type -> ironhost.v1.ServerActionResponse
+	11, // 53: ironhost.v1.AgentService.UpdateServerAllocations:output_type -> ironhost.v1.ServerActionResponse
+	7,  // 54: ironhost.v1.AgentService.ReinstallServer:output_type -> ironhost.v1.InstallResponse
+	46, // 55: ironhost.v1.AgentService.GetServerStatus:output_type -> ironhost.v1.ServerState
+	12, // 56: ironhost.v1.AgentService.ListServers:output_type -> ironhost.v1.ListServersResponse
+	46, // 57: ironhost.v1.AgentService.StreamServerStats:output_type -> ironhost.v1.ServerState
+	38, // 58: ironhost.v1.AgentService.WatchEvents:output_type -> ironhost.v1.ServerEvent
+	16, // 59: ironhost.v1.AgentService.StreamConsole:output_type -> ironhost.v1.ConsoleOutput
+	16, // 60: ironhost.v1.AgentService.AttachConsole:output_type -> ironhost.v1.ConsoleOutput
+	11, // 61: ironhost.v1.AgentService.SendCommand:output_type -> ironhost.v1.ServerActionResponse
+	11, // 62: ironhost.v1.AgentService.GetLogs:output_type -> ironhost.v1.ServerActionResponse
+	22, // 63: ironhost.v1.AgentService.ListFiles:output_type -> ironhost.v1.ListFilesResponse
+	24, // 64: ironhost.v1.AgentService.ReadFile:output_type -> ironhost.v1.ReadFileResponse
+	11, // 65: ironhost.v1.AgentService.WriteFile:output_type -> ironhost.v1.ServerActionResponse
+	11, // 66: ironhost.v1.AgentService.DeleteFile:output_type -> ironhost.v1.ServerActionResponse
+	11, // 67: ironhost.v1.AgentService.RenameFile:output_type -> ironhost.v1.ServerActionResponse
+	29, // 68: ironhost.v1.AgentService.UploadFile:output_type -> ironhost.v1.UploadFileResponse
+	31, // 69: ironhost.v1.AgentService.GetUploadStatus:output_type -> ironhost.v1.UploadStatusResponse
+	33, // 70: ironhost.v1.AgentService.DownloadFile:output_type -> ironhost.v1.FileChunk
+	36, // 71: ironhost.v1.AgentService.CompressFiles:output_type -> ironhost.v1.ArchiveResponse
+	36, // 72: ironhost.v1.AgentService.DecompressFile:output_type -> ironhost.v1.ArchiveResponse
+	18, // 73: ironhost.v1.AgentService.GetNodeStats:output_type -> ironhost.v1.NodeStats
+	19, // 74: ironhost.v1.AgentService.Ping:output_type -> ironhost.v1.PingResponse
+	41, // 75: ironhost.v1.AgentService.GetOrphans:output_type -> ironhost.v1.GetOrphansResponse
+	47, // [47:76] is the sub-list for method output_type
+	18, // [18:47] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
 }
 
 func init() { file_ironhost_v1_agent_proto_init() }
@@ -743,13 +3087,14 @@ func file_ironhost_v1_agent_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ironhost_v1_agent_proto_rawDesc), len(file_ironhost_v1_agent_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   9,
+			NumEnums:      3,
+			NumMessages:   39,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_ironhost_v1_agent_proto_goTypes,
 		DependencyIndexes: file_ironhost_v1_agent_proto_depIdxs,
+		EnumInfos:         file_ironhost_v1_agent_proto_enumTypes,
 		MessageInfos:      file_ironhost_v1_agent_proto_msgTypes,
 	}.Build()
 	File_ironhost_v1_agent_proto = out.File
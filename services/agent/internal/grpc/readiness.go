@@ -0,0 +1,171 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	agentpb "github.com/ironhost/agent/internal/grpc/ironhost/v1"
+	"github.com/ironhost/agent/internal/readiness"
+)
+
+// ── Readiness ──
+// A started server is reported as starting until one of its readiness probes
+// passes (or Docker reports its health check healthy), then as running. The
+// probes are stored on the container, falling back to defaults for its image.
+
+// readinessState tracks probing for the current run of a server's container
+type readinessState struct {
+	containerID string
+	ready       bool
+	cancel      context.CancelFunc
+}
+
+// probesFromProto converts readiness probes from the wire format
+func probesFromProto(in []*agentpb.ReadinessProbe) ([]readiness.Probe, error) {
+	probes := make([]readiness.Probe, 0, len(in))
+	for _, p := range in {
+		probe := readiness.Probe{Pattern: p.Pattern, Port: int(p.Port)}
+		switch p.Kind {
+		case agentpb.ProbeKind_PROBE_KIND_LOG:
+			probe.Kind = readiness.KindLog
+		case agentpb.ProbeKind_PROBE_KIND_TCP:
+			probe.Kind = readiness.KindTCP
+		case agentpb.ProbeKind_PROBE_KIND_MINECRAFT_PING:
+			probe.Kind = readiness.KindMinecraftPing
+		default:
+			return nil, fmt.Errorf("unknown readiness probe kind %s", p.Kind)
+		}
+		probes = append(probes, probe)
+	}
+	return probes, readiness.Validate(probes)
+}
+
+// isReady reports whether a server's readiness probes have passed since its container started
+func (s *AgentService) isReady(serverID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rs, ok := s.readiness[serverID]
+	return ok && rs.ready
+}
+
+// watchReadiness starts probing a server whose container has just started,
+// unless that container is already being probed
+func (s *AgentService) watchReadiness(serverID, containerID string) {
+	s.mu.Lock()
+	if rs, ok := s.readiness[serverID]; ok {
+		if rs.containerID == containerID && (rs.ready || rs.cancel != nil) {
+			s.mu.Unlock()
+			return
+		}
+		if rs.cancel != nil {
+			rs.cancel()
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.readiness[serverID] = &readinessState{containerID: containerID, cancel: cancel}
+	s.mu.Unlock()
+
+	go s.probeReadiness(ctx, serverID, containerID)
+}
+
+// stopReadiness forgets a server's readiness when its container stops
+func (s *AgentService) stopReadiness(serverID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rs, ok := s.readiness[serverID]; ok {
+		if rs.cancel != nil {
+			rs.cancel()
+		}
+		delete(s.readiness, serverID)
+	}
+}
+
+// markReady records that a server is up and tells event watchers. It returns
+// false if the container has been replaced or stopped in the meantime.
+func (s *AgentService) markReady(serverID, containerID, reason string) bool {
+	s.mu.Lock()
+	rs, ok := s.readiness[serverID]
+	if ok && rs.containerID == containerID && rs.ready {
+		s.mu.Unlock()
+		return true // Already reported
+	}
+	if ok && rs.containerID != containerID {
+		s.mu.Unlock()
+		return false
+	}
+	if ok && rs.cancel != nil {
+		rs.cancel()
+	}
+	s.readiness[serverID] = &readinessState{containerID: containerID, ready: true}
+	s.mu.Unlock()
+
+	fmt.Printf("🟢 Server %s is ready (%s)\n", serverID, reason)
+	s.events.publish(&agentpb.ServerEvent{
+		ServerId:    serverID,
+		ContainerId: containerID,
+		Action:      "ready",
+		Status:      agentpb.ServerStatus_SERVER_STATUS_RUNNING,
+		Timestamp:   time.Now().Unix(),
+	})
+	return true
+}
+
+// probeReadiness runs a container's probes until one passes or it stops
+func (s *AgentService) probeReadiness(ctx context.Context, serverID, containerID string) {
+	rt, err := s.dockerMgr.GetRuntimeInfo(ctx, containerID)
+	if err != nil {
+		fmt.Printf("⚠️  Readiness: %v\n", err)
+		return
+	}
+
+	probes, err := readiness.Decode(rt.Readiness)
+	if err != nil {
+		fmt.Printf("⚠️  Readiness: server %s: %v; using image defaults\n", serverID, err)
+		probes = nil
+	}
+	if len(probes) == 0 {
+		if rt.PrimaryPort == 0 {
+			// The image defaults probe a TCP game port, and there is none
+			s.markReady(serverID, containerID, "no TCP game port to probe")
+			return
+		}
+		probes = readiness.Defaults(rt.Image)
+	}
+
+	target := readiness.Target{
+		PrimaryPort: rt.PrimaryPort,
+		Addr:        rt.Addr,
+		FollowLogs: func(ctx context.Context, w io.Writer) error {
+			// Only this run's output: an earlier run's "Done" line must not count
+			since := rt.StartedAt
+			if since.IsZero() {
+				since = time.Now()
+			}
+			return s.dockerMgr.StreamLogs(ctx, containerID, since, 0, w, w)
+		},
+	}
+
+	probe, err := readiness.Wait(ctx, probes, target)
+	if err != nil {
+		if ctx.Err() == nil {
+			fmt.Printf("⚠️  Readiness: server %s never became ready: %v\n", serverID, err)
+		}
+		return
+	}
+	s.markReady(serverID, containerID, probe.String()+" probe passed")
+}
+
+// serverStatus derives a server's status from its container's Docker state,
+// health check status and readiness
+func (s *AgentService) serverStatus(serverID, state, health string) agentpb.ServerStatus {
+	if s.isInstalling(serverID) {
+		return agentpb.ServerStatus_SERVER_STATUS_INSTALLING
+	}
+	status := containerStatus(state, health)
+	if status == agentpb.ServerStatus_SERVER_STATUS_RUNNING && health != "healthy" && !s.isReady(serverID) {
+		return agentpb.ServerStatus_SERVER_STATUS_STARTING
+	}
+	return status
+}
@@ -0,0 +1,253 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ironhost/agent/internal/docker"
+	agentpb "github.com/ironhost/agent/internal/grpc/ironhost/v1"
+)
+
+// ── Reconciliation ──
+// The agent keeps no state of its own across restarts. Which servers it hosts
+// is rebuilt from the labels on Docker containers at startup and then on a
+// timer, which also catches containers created or removed behind its back.
+// Anything that does not line up (containers without a server, data
+// directories without a container) is kept as an orphan report for admins.
+
+// reconcileInterval is how often the agent re-reads its state from Docker
+const reconcileInterval = time.Minute
+
+// reconciliation is the outcome of one pass over the host
+type reconciliation struct {
+	servers map[string]docker.ManagedContainer // By server ID
+	orphans []*agentpb.Orphan
+	at      time.Time
+}
+
+// Reconcile rebuilds the agent's view of its servers from Docker. It is run
+// once at startup before the agent starts serving, then by RunReconciler.
+func (s *AgentService) Reconcile(ctx context.Context) error {
+	rec, err := s.reconcile(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🔎 Reconciled %d servers, %d orphans\n", len(rec.servers), len(rec.orphans))
+	return nil
+}
+
+// reconcile rebuilds the server to container map from Docker, registers the
+// servers' data directories with the quota manager and records orphans
+func (s *AgentService) reconcile(ctx context.Context) (*reconciliation, error) {
+	s.reconcileMu.Lock()
+	defer s.reconcileMu.Unlock()
+
+	s.mu.RLock()
+	listedAt := s.containerSeq
+	s.mu.RUnlock()
+
+	containers, err := s.dockerMgr.ListManagedContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &reconciliation{
+		servers: make(map[string]docker.ManagedContainer),
+		at:      time.Now(),
+	}
+	inUse := make(map[string]bool) // Data directories mounted by any container
+
+	for _, c := range containers {
+		if c.DataPath != "" {
+			inUse[absPath(c.DataPath)] = true
+		}
+		if c.ServerID == "" {
+			rec.orphans = append(rec.orphans, containerOrphan(c, "container has no ironhost.server.id label"))
+			continue
+		}
+
+		prev, dup := rec.servers[c.ServerID]
+		if !dup {
+			rec.servers[c.ServerID] = c
+			continue
+		}
+		// Keep the running container, or else the newest; the other is a leftover
+		keep, drop := prev, c
+		if preferContainer(c, prev) {
+			keep, drop = c, prev
+		}
+		rec.servers[c.ServerID] = keep
+		rec.orphans = append(rec.orphans, containerOrphan(drop, fmt.Sprintf("duplicate of container %s for the same server", shortID(keep.ID))))
+	}
+
+	serversDir := filepath.Join(s.dataDir, "servers")
+	entries, err := os.ReadDir(serversDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", serversDir, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := absPath(filepath.Join(serversDir, entry.Name()))
+		if _, exists := rec.servers[entry.Name()]; exists || inUse[dir] {
+			continue
+		}
+		rec.orphans = append(rec.orphans, &agentpb.Orphan{
+			Kind:     agentpb.OrphanKind_ORPHAN_KIND_DATA_DIRECTORY,
+			ServerId: entry.Name(),
+			Path:     dir,
+			Reason:   "no container uses this data directory",
+		})
+	}
+
+	containerIDs := make(map[string]string, len(rec.servers))
+	for serverID, c := range rec.servers {
+		containerIDs[serverID] = c.ID
+
+		// Catch up on starts and stops the event stream missed, e.g. while the agent was down
+		if c.State == "running" {
+			s.watchReadiness(serverID, c.ID)
+		} else {
+			s.stopReadiness(serverID)
+		}
+		if !s.quota.Tracked(serverID) {
+			dir := c.DataPath
+			if dir == "" {
+				dir = filepath.Join(s.dataDir, "servers", serverID)
+			}
+			s.quota.Track(serverID, dir, c.DiskLimit)
+		}
+	}
+
+	// Containers created, recreated or deleted since the listing began are
+	// newer than it; keep what was recorded for those servers
+	s.mu.Lock()
+	for serverID, seq := range s.changedAt {
+		if seq <= listedAt {
+			delete(s.changedAt, serverID) // Covered by this listing
+			continue
+		}
+		if id, ok := s.containers[serverID]; ok {
+			containerIDs[serverID] = id
+		} else {
+			delete(containerIDs, serverID)
+		}
+	}
+	s.containers = containerIDs
+	s.reconciled = rec
+	s.mu.Unlock()
+
+	return rec, nil
+}
+
+// RunReconciler reconciles periodically until ctx is done
+func (s *AgentService) RunReconciler(ctx context.Context) {
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	lastOrphans := -1
+	s.mu.RLock()
+	if s.reconciled != nil {
+		lastOrphans = len(s.reconciled.orphans)
+	}
+	s.mu.RUnlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		rec, err := s.reconcile(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				fmt.Printf("⚠️  Reconcile failed: %v\n", err)
+			}
+			continue
+		}
+		if len(rec.orphans) != lastOrphans {
+			fmt.Printf("🔎 Reconciled %d servers, %d orphans\n", len(rec.servers), len(rec.orphans))
+			lastOrphans = len(rec.orphans)
+		}
+	}
+}
+
+// GetOrphans reconciles with Docker and reports containers and data
+// directories that do not belong to any server
+func (s *AgentService) GetOrphans(ctx context.Context, req *agentpb.GetOrphansRequest) (*agentpb.GetOrphansResponse, error) {
+	rec, err := s.reconcile(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	orphans := append([]*agentpb.Orphan(nil), rec.orphans...)
+
+	// Servers the master no longer knows about
+	if len(req.KnownServerIds) > 0 {
+		known := make(map[string]bool, len(req.KnownServerIds))
+		for _, id := range req.KnownServerIds {
+			known[id] = true
+		}
+		for serverID, c := range rec.servers {
+			if !known[serverID] {
+				orphans = append(orphans, containerOrphan(c, "server is not known to the master"))
+			}
+		}
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Kind != orphans[j].Kind {
+			return orphans[i].Kind < orphans[j].Kind
+		}
+		if orphans[i].ServerId != orphans[j].ServerId {
+			return orphans[i].ServerId < orphans[j].ServerId
+		}
+		return orphans[i].ContainerId < orphans[j].ContainerId
+	})
+
+	return &agentpb.GetOrphansResponse{Orphans: orphans, ReconciledAt: rec.at.Unix()}, nil
+}
+
+// containerOrphan describes a container that belongs to no server
+func containerOrphan(c docker.ManagedContainer, reason string) *agentpb.Orphan {
+	return &agentpb.Orphan{
+		Kind:        agentpb.OrphanKind_ORPHAN_KIND_CONTAINER,
+		ServerId:    c.ServerID,
+		ContainerId: c.ID,
+		Path:        c.DataPath,
+		Reason:      reason,
+	}
+}
+
+// preferContainer reports whether a should be kept over b when both claim the same server
+func preferContainer(a, b docker.ManagedContainer) bool {
+	if (a.State == "running") != (b.State == "running") {
+		return a.State == "running"
+	}
+	return a.Created.After(b.Created)
+}
+
+// absPath makes a path absolute for comparison, leaving it as is on failure
+func absPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return filepath.Clean(p)
+}
+
+// shortID returns the 12 character form Docker shows for container IDs
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
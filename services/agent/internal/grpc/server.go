@@ -6,17 +6,23 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/ironhost/agent/internal/console"
 	"github.com/ironhost/agent/internal/docker"
 	agentpb "github.com/ironhost/agent/internal/grpc/ironhost/v1"
+	"github.com/ironhost/agent/internal/quota"
+	"github.com/ironhost/agent/internal/rcon"
+	"github.com/ironhost/agent/internal/readiness"
 	"github.com/ironhost/agent/internal/sysinfo"
 )
 
@@ -25,20 +31,39 @@ type AgentService struct {
 	agentpb.UnimplementedAgentServiceServer
 	nodeID    string
 	dockerMgr *docker.Manager
+	quota     *quota.Manager
 	dataDir   string
-
-	// Track container IDs by server ID
-	containers map[string]string
-	mu         sync.RWMutex
+	console   *console.Hub
+	events    *eventHub
+	rcon      *rcon.Pool
+
+	// Track container IDs by server ID, rebuilt from Docker by reconcile.
+	// Every change bumps containerSeq and records it in changedAt, so a
+	// reconcile keeps changes made while it was listing Docker.
+	containers   map[string]string
+	changedAt    map[string]uint64
+	containerSeq uint64
+	readiness    map[string]*readinessState    // By server ID, for containers that are up
+	installs     map[string]context.CancelFunc // By server ID, while an install script runs
+	reconciled   *reconciliation               // Latest reconciliation, nil before the first
+	mu           sync.RWMutex
+	reconcileMu  sync.Mutex // One reconcile at a time, so an older listing never replaces a newer one
 }
 
 // NewAgentService creates a new agent service instance
-func NewAgentService(nodeID string, dockerMgr *docker.Manager, dataDir string) *AgentService {
+func NewAgentService(nodeID string, dockerMgr *docker.Manager, quotaMgr *quota.Manager, dataDir string) *AgentService {
 	return &AgentService{
 		nodeID:     nodeID,
 		dockerMgr:  dockerMgr,
+		quota:      quotaMgr,
 		dataDir:    dataDir,
+		console:    console.NewHub(dockerMgr.StreamLogs, console.DefaultBufferLines),
+		events:     newEventHub(),
+		rcon:       rcon.NewPool(),
 		containers: make(map[string]string),
+		changedAt:  make(map[string]uint64),
+		readiness:  make(map[string]*readinessState),
+		installs:   make(map[string]context.CancelFunc),
 	}
 }
 
@@ -61,10 +86,24 @@ func (s *AgentService) CreateServer(ctx context.Context, req *agentpb.CreateServ
 		env[e.Key] = e.Value
 	}
 
-	// Get primary port from allocations
-	port := 25565 // Default Minecraft port
-	if len(req.Allocations) > 0 {
-		port = int(req.Allocations[0].Port)
+	if req.Protocol != "" && req.Protocol != "tcp" && req.Protocol != "udp" {
+		return &agentpb.CreateServerResponse{Success: false, ErrorMessage: "protocol must be tcp or udp"}, nil
+	}
+	ports, err := portBindings(req.Allocations, int(req.ContainerPort), req.Protocol)
+	if err != nil {
+		return &agentpb.CreateServerResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	if req.DataMount != "" && !filepath.IsAbs(req.DataMount) {
+		return &agentpb.CreateServerResponse{Success: false, ErrorMessage: "data mount must be an absolute path"}, nil
+	}
+
+	probes, err := probesFromProto(req.ReadinessProbes)
+	if err != nil {
+		return &agentpb.CreateServerResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	encodedProbes, err := readiness.Encode(probes)
+	if err != nil {
+		return &agentpb.CreateServerResponse{Success: false, ErrorMessage: err.Error()}, nil
 	}
 
 	// Data directory for this server
@@ -80,6 +119,10 @@ func (s *AgentService) CreateServer(ctx context.Context, req *agentpb.CreateServ
 	}
 	fmt.Printf("📂 Data Path resolved to: %s\n", dataPath)
 
+	// Only a directory this call creates is removed again if it fails
+	_, err = os.Stat(dataPath)
+	madeDir := os.IsNotExist(err)
+
 	// Create the directory on the host (Agent's filesystem)
 	if err := os.MkdirAll(dataPath, 0755); err != nil {
 		fmt.Printf("❌ Failed to create data directory: %v\n", err)
@@ -89,16 +132,92 @@ func (s *AgentService) CreateServer(ctx context.Context, req *agentpb.CreateServ
 		}, nil
 	}
 
+	// A server that already has a container keeps its disk limit if this fails
+	_, err = s.getContainerID(serverID)
+	fresh := err != nil
+
+	// Enforce the disk limit on the directory before anything is written to it
+	if err := s.quota.Apply(serverID, dataPath, req.Limits.DiskMb*1024*1024); err != nil {
+		fmt.Printf("❌ Failed to apply disk limit: %v\n", err)
+		return &agentpb.CreateServerResponse{
+			Success:      false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	// Until the container exists nothing else would lift the limit, so any
+	// failure from here releases it. Existing files are never touched; in
+	// loopback mode they live in the image, so it only goes with a new directory.
+	created := false
+	defer func() {
+		if created || !fresh {
+			return
+		}
+		if madeDir || s.quota.Mode() != quota.ModeLoopback {
+			s.releaseDisk(serverID, dataPath)
+		}
+		if madeDir {
+			if err := os.RemoveAll(dataPath); err != nil {
+				fmt.Printf("⚠️  Failed to remove data directory of %s: %v\n", serverID, err)
+			}
+		}
+	}()
+
 	// Create container config
 	cfg := docker.ServerConfig{
 		ServerID:    serverID,
 		Name:        req.Name,
 		Image:       req.DockerImage, // Defaults to itzg/minecraft-server in docker pkg
 		MemoryMB:    req.Limits.MemoryMb,
+		DiskMB:      req.Limits.DiskMb,
 		CPUPercent:  int(req.Limits.CpuPercent),
+		IOWeight:    uint16(req.Limits.IoWeight),
 		Environment: env,
-		Port:        port,
+		Ports:       ports,
 		DataPath:    dataPath,
+		Readiness:   encodedProbes,
+
+		StartupCommand: req.StartupCommand,
+		StopCommand:    req.StopCommand,
+		DataMount:      req.DataMount,
+		RCON:           req.CommandTransport != "stdin",
+	}
+
+	// The install script must succeed before the server gets a container
+	var installed bool
+	var installExitCode int
+	if req.Install != nil && req.Install.Script != "" {
+		installCtx, end, err := s.beginInstall(ctx, serverID)
+		if err != nil {
+			return &agentpb.CreateServerResponse{Success: false, ErrorMessage: err.Error()}, nil
+		}
+		installExitCode, err = s.runInstall(installCtx, docker.InstallConfig{
+			ServerID:    serverID,
+			Image:       req.Install.Image,
+			Entrypoint:  req.Install.Entrypoint,
+			Script:      req.Install.Script,
+			Environment: env,
+			DataPath:    dataPath,
+			DataMount:   req.DataMount,
+			MemoryMB:    cfg.MemoryMB,
+			CPUPercent:  cfg.CPUPercent,
+		})
+		end()
+		if err != nil {
+			return &agentpb.CreateServerResponse{
+				Success:      false,
+				ErrorMessage: fmt.Sprintf("install failed: %v", err),
+			}, nil
+		}
+		installed = true
+		if installExitCode != 0 {
+			return &agentpb.CreateServerResponse{
+				Success:         false,
+				ErrorMessage:    fmt.Sprintf("install script exited with code %d", installExitCode),
+				Installed:       true,
+				InstallExitCode: int32(installExitCode),
+			}, nil
+		}
 	}
 
 	fmt.Printf("⬇️  Pulling image: %s\n", cfg.Image)
@@ -123,11 +242,10 @@ func (s *AgentService) CreateServer(ctx context.Context, req *agentpb.CreateServ
 	}
 
 	fmt.Printf("✅ Container created! ID: %s\n", containerID)
+	created = true
 
 	// Track the container
-	s.mu.Lock()
-	s.containers[serverID] = containerID
-	s.mu.Unlock()
+	s.setContainer(serverID, containerID)
 
 	// Start the container
 	fmt.Printf("▶️  Starting container %s...\n", containerID)
@@ -141,20 +259,31 @@ func (s *AgentService) CreateServer(ctx context.Context, req *agentpb.CreateServ
 
 	fmt.Printf("🎉 Server %s is now RUNNING!\n", cfg.Name)
 	return &agentpb.CreateServerResponse{
-		Success:     true,
-		ContainerId: containerID,
+		Success:         true,
+		ContainerId:     containerID,
+		Installed:       installed,
+		InstallExitCode: int32(installExitCode),
 	}, nil
 }
 
 // StartServer starts a stopped server container
 func (s *AgentService) StartServer(ctx context.Context, req *agentpb.ServerIdentifier) (*agentpb.ServerActionResponse, error) {
 	fmt.Printf("▶️  Received StartServer request for: %s\n", req.ServerId)
+	if s.isInstalling(req.ServerId) {
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: errInstalling.Error()}, nil
+	}
 	containerID, err := s.getContainerID(req.ServerId)
 	if err != nil {
 		fmt.Printf("❌ StartServer: container not found: %v\n", err)
 		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
 	}
 
+	containerID, err = s.syncLimits(ctx, req.ServerId, containerID)
+	if err != nil {
+		fmt.Printf("❌ StartServer: failed to apply new limits: %v\n", err)
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
 	if err := s.dockerMgr.StartContainer(ctx, containerID); err != nil {
 		fmt.Printf("❌ StartServer: failed: %v\n", err)
 		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
@@ -178,7 +307,7 @@ func (s *AgentService) StopServer(ctx context.Context, req *agentpb.StopServerRe
 		timeout = int(req.TimeoutSeconds)
 	}
 
-	if err := s.dockerMgr.StopContainer(ctx, containerID, timeout); err != nil {
+	if err := s.stopServer(ctx, req.ServerId, containerID, timeout); err != nil {
 		fmt.Printf("❌ StopServer: failed: %v\n", err)
 		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
 	}
@@ -190,6 +319,9 @@ func (s *AgentService) StopServer(ctx context.Context, req *agentpb.StopServerRe
 // RestartServer restarts a server container
 func (s *AgentService) RestartServer(ctx context.Context, req *agentpb.ServerIdentifier) (*agentpb.ServerActionResponse, error) {
 	fmt.Printf("🔄 Received RestartServer request for: %s\n", req.ServerId)
+	if s.isInstalling(req.ServerId) {
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: errInstalling.Error()}, nil
+	}
 	containerID, err := s.getContainerID(req.ServerId)
 	if err != nil {
 		fmt.Printf("❌ RestartServer: container not found: %v\n", err)
@@ -197,11 +329,17 @@ func (s *AgentService) RestartServer(ctx context.Context, req *agentpb.ServerIde
 	}
 
 	// Stop then start
-	if err := s.dockerMgr.StopContainer(ctx, containerID, 30); err != nil {
+	if err := s.stopServer(ctx, req.ServerId, containerID, 30); err != nil {
 		fmt.Printf("❌ RestartServer: stop failed: %v\n", err)
 		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
 	}
 
+	containerID, err = s.syncLimits(ctx, req.ServerId, containerID)
+	if err != nil {
+		fmt.Printf("❌ RestartServer: failed to apply new limits: %v\n", err)
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
 	if err := s.dockerMgr.StartContainer(ctx, containerID); err != nil {
 		fmt.Printf("❌ RestartServer: start failed: %v\n", err)
 		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
@@ -211,27 +349,93 @@ func (s *AgentService) RestartServer(ctx context.Context, req *agentpb.ServerIde
 	return &agentpb.ServerActionResponse{Success: true}, nil
 }
 
-// DeleteServer removes a server container
+// DeleteServer removes a server's container and lifts its disk limit. The data
+// directory is kept. A server without a container (its create or install
+// failed) is cleaned up all the same.
 func (s *AgentService) DeleteServer(ctx context.Context, req *agentpb.ServerIdentifier) (*agentpb.ServerActionResponse, error) {
 	fmt.Printf("🗑️  Received DeleteServer request for: %s\n", req.ServerId)
+	s.cancelInstall(req.ServerId)
+
+	// Resolve the data directory while the container still records its mount
+	serverRoot := s.getServerRoot(req.ServerId)
+
+	// A server whose create or install failed has no container, but can still
+	// have a disk limit to lift
+	if containerID, err := s.getContainerID(req.ServerId); err == nil {
+		if err := s.dockerMgr.RemoveContainer(ctx, containerID, true); err != nil {
+			fmt.Printf("❌ DeleteServer: failed: %v\n", err)
+			return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
+		}
+	} else {
+		fmt.Printf("ℹ️  DeleteServer: %s has no container, releasing its disk limit only\n", req.ServerId)
+	}
+
+	// Remove from tracking
+	s.setContainer(req.ServerId, "")
+	s.console.Remove(req.ServerId)
+	s.stopReadiness(req.ServerId)
+	s.rcon.Remove(req.ServerId)
+	s.releaseDisk(req.ServerId, serverRoot)
+
+	fmt.Printf("✅ DeleteServer: success for %s\n", req.ServerId)
+	return &agentpb.ServerActionResponse{Success: true}, nil
+}
+
+// UpdateServerResources changes a server's limits. Memory, CPU and IO weight
+// are applied to the running container straight away and the disk limit to its
+// data directory; the JVM's MEMORY env follows on the next start or restart.
+// A server without a container, e.g. after a failed first install, accepts
+// any valid limits.
+func (s *AgentService) UpdateServerResources(ctx context.Context, req *agentpb.UpdateServerResourcesRequest) (*agentpb.ServerActionResponse, error) {
+	fmt.Printf("📐 Received UpdateServerResources request for: %s\n", req.ServerId)
+	limits := req.Limits
+	if limits == nil {
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: "limits are required"}, nil
+	}
+	if limits.MemoryMb <= 0 || limits.CpuPercent <= 0 || limits.DiskMb < 0 {
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: "memory and CPU limits must be positive"}, nil
+	}
+	if limits.IoWeight != 0 && (limits.IoWeight < 10 || limits.IoWeight > 1000) {
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: "io_weight must be between 10 and 1000"}, nil
+	}
+
 	containerID, err := s.getContainerID(req.ServerId)
 	if err != nil {
-		fmt.Printf("❌ DeleteServer: container not found: %v\n", err)
-		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
+		// Nothing to change yet: the master sends the stored limits with
+		// CreateServer when the container is made
+		fmt.Printf("ℹ️  UpdateServerResources: %s has no container, limits apply when it is created\n", req.ServerId)
+		return &agentpb.ServerActionResponse{Success: true}, nil
 	}
 
-	// Force remove the container
-	if err := s.dockerMgr.RemoveContainer(ctx, containerID, true); err != nil {
-		fmt.Printf("❌ DeleteServer: failed: %v\n", err)
-		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
+	// Disk first: growing a loopback image is the step most likely to fail,
+	// and a refused disk change should leave the container untouched
+	s.trackDisk(req.ServerId)
+	serverRoot := s.getServerRoot(req.ServerId)
+	prevDisk := s.quota.Limit(req.ServerId)
+	newDisk := limits.DiskMb * 1024 * 1024
+	if newDisk != prevDisk {
+		if err := s.quota.Apply(req.ServerId, serverRoot, newDisk); err != nil {
+			fmt.Printf("❌ UpdateServerResources: %v\n", err)
+			return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
+		}
 	}
 
-	// Remove from tracking
-	s.mu.Lock()
-	delete(s.containers, req.ServerId)
-	s.mu.Unlock()
+	err = s.dockerMgr.UpdateResources(ctx, containerID, docker.Resources{
+		MemoryMB:   limits.MemoryMb,
+		CPUPercent: int(limits.CpuPercent),
+		IOWeight:   uint16(limits.IoWeight),
+	})
+	if err != nil {
+		fmt.Printf("❌ UpdateServerResources: failed: %v\n", err)
+		if newDisk != prevDisk {
+			if rbErr := s.quota.Apply(req.ServerId, serverRoot, prevDisk); rbErr != nil {
+				fmt.Printf("⚠️  UpdateServerResources: failed to restore disk limit: %v\n", rbErr)
+			}
+		}
+		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
 
-	fmt.Printf("✅ DeleteServer: success for %s\n", req.ServerId)
+	fmt.Printf("✅ UpdateServerResources: success for %s\n", req.ServerId)
 	return &agentpb.ServerActionResponse{Success: true}, nil
 }
 
@@ -245,7 +449,7 @@ func (s *AgentService) GetServerStatus(ctx context.Context, req *agentpb.ServerI
 		}, nil
 	}
 
-	stats, err := s.dockerMgr.GetContainerStats(ctx, containerID)
+	state, health, err := s.dockerMgr.GetContainerState(ctx, containerID)
 	if err != nil {
 		return &agentpb.ServerState{
 			ServerId: req.ServerId,
@@ -253,64 +457,198 @@ func (s *AgentService) GetServerStatus(ctx context.Context, req *agentpb.ServerI
 		}, nil
 	}
 
-	return &agentpb.ServerState{
-		ServerId:         req.ServerId,
-		Status:           agentpb.ServerStatus_SERVER_STATUS_RUNNING,
-		MemoryUsageBytes: int64(stats.MemoryStats.Usage),
-		CpuUsagePercent:  calculateCPUPercent(stats),
-		LastUpdated:      timestamppb.Now(),
-	}, nil
+	return s.serverState(ctx, req.ServerId, containerID, s.serverStatus(req.ServerId, state, health)), nil
 }
 
-// ListServers returns all servers on this node
+// ListServers returns all servers on this node, as found in Docker right now
 func (s *AgentService) ListServers(ctx context.Context, _ *emptypb.Empty) (*agentpb.ListServersResponse, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	rec, err := s.reconcile(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
 
-	servers := make([]*agentpb.ServerState, 0, len(s.containers))
-	for serverID := range s.containers {
-		state, _ := s.GetServerStatus(ctx, &agentpb.ServerIdentifier{ServerId: serverID})
-		servers = append(servers, state)
+	serverIDs := make([]string, 0, len(rec.servers))
+	for serverID := range rec.servers {
+		serverIDs = append(serverIDs, serverID)
+	}
+	sort.Strings(serverIDs)
+
+	// One-shot stats take a moment per container, so collect them in parallel
+	servers := make([]*agentpb.ServerState, len(serverIDs))
+	var wg sync.WaitGroup
+	for i, serverID := range serverIDs {
+		c := rec.servers[serverID]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			servers[i] = s.serverState(ctx, serverID, c.ID, s.serverStatus(serverID, c.State, c.Health))
+		}()
 	}
+	wg.Wait()
 
 	return &agentpb.ListServersResponse{Servers: servers}, nil
 }
 
-// StreamConsole streams server console output
-func (s *AgentService) StreamConsole(req *agentpb.ServerIdentifier, stream agentpb.AgentService_StreamConsoleServer) error {
+// serverState reports a server's status, with live resource usage while its
+// container is up and game status once it is running
+func (s *AgentService) serverState(ctx context.Context, serverID, containerID string, st agentpb.ServerStatus) *agentpb.ServerState {
+	if st == agentpb.ServerStatus_SERVER_STATUS_RUNNING || st == agentpb.ServerStatus_SERVER_STATUS_STARTING {
+		if stats, err := s.dockerMgr.GetContainerStats(ctx, containerID); err == nil {
+			state := newServerState(serverID, stats, s.diskUsage(serverID))
+			state.Status = st
+			if st == agentpb.ServerStatus_SERVER_STATUS_RUNNING {
+				s.addGameStatus(ctx, state, containerID)
+			}
+			return state
+		}
+	}
+
+	return &agentpb.ServerState{
+		ServerId:       serverID,
+		Status:         st,
+		DiskUsageBytes: s.diskUsage(serverID),
+		LastUpdated:    timestamppb.Now(),
+	}
+}
+
+// StreamServerStats pushes resource usage samples for a server at the requested interval.
+// Samples come from Docker's streaming stats; disk usage is the quota manager's
+// cached figure, which is re-measured in the background.
+func (s *AgentService) StreamServerStats(req *agentpb.StreamServerStatsRequest, stream agentpb.AgentService_StreamServerStatsServer) error {
 	containerID, err := s.getContainerID(req.ServerId)
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	interval := 2 * time.Second
+	if req.IntervalSeconds > 0 {
+		interval = time.Duration(req.IntervalSeconds) * time.Second
+	}
+
+	ctx := stream.Context()
+	var lastSent time.Time
+
+	return s.dockerMgr.StreamContainerStats(ctx, containerID, func(stats *docker.ContainerStats) error {
+		now := time.Now()
+		if now.Sub(lastSent) < interval {
+			return nil
+		}
+		lastSent = now
+
+		return stream.Send(newServerState(req.ServerId, stats, s.diskUsage(req.ServerId)))
+	})
+}
+
+// StreamConsole streams server console output line by line. Lines buffered
+// after req.AfterOffset are replayed first, so a client that reconnects with
+// the last epoch and offset it saw resumes without gaps or duplicates.
+func (s *AgentService) StreamConsole(req *agentpb.StreamConsoleRequest, stream agentpb.AgentService_StreamConsoleServer) error {
+	var backlog []console.Line
+	var sub *console.Subscription
+	if s.isInstalling(req.ServerId) {
+		// The installer's output is published to the console as it runs
+		backlog, sub = s.console.Watch(req.ServerId, req.Epoch, req.AfterOffset)
+	} else {
+		containerID, err := s.getContainerID(req.ServerId)
+		if err != nil {
+			return status.Error(codes.NotFound, err.Error())
+		}
+		backlog, sub = s.console.Subscribe(req.ServerId, containerID, req.Epoch, req.AfterOffset)
+	}
+	defer sub.Close()
+
+	for _, line := range backlog {
+		if err := stream.Send(newConsoleOutput(req.ServerId, line)); err != nil {
+			return err
+		}
+	}
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-sub.Lines:
+			if !ok {
+				// Follower stopped or we fell behind; the client resumes from its last offset
+				return nil
+			}
+			if err := stream.Send(newConsoleOutput(req.ServerId, line)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// AttachConsole writes the client's input to a server's stdin and streams its
+// console output back, like StreamConsole. The stream ends when the container
+// stops; the client reattaches after its last offset. Output keeps flowing
+// after the client closes its side.
+func (s *AgentService) AttachConsole(stream agentpb.AgentService_AttachConsoleServer) error {
+	first, err := stream.Recv()
 	if err != nil {
 		return err
 	}
+	serverID := first.ServerId
+	if s.isInstalling(serverID) {
+		return status.Error(codes.FailedPrecondition, errInstalling.Error())
+	}
+	containerID, err := s.getContainerID(serverID)
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
 
 	ctx := stream.Context()
+	stdin, err := s.dockerMgr.AttachStdin(ctx, containerID)
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer stdin.Close()
 
-	// Create a pipe to capture logs
-	pr, pw := io.Pipe()
+	backlog, sub := s.console.Subscribe(serverID, containerID, first.Epoch, first.AfterOffset)
+	defer sub.Close()
 
+	// Input runs until the client closes its side or a write fails
+	inputErr := make(chan error, 1)
 	go func() {
-		s.dockerMgr.StreamLogs(ctx, containerID, pw, pw)
-		pw.Close()
+		req := first
+		for {
+			if len(req.Input) > 0 {
+				if _, err := stdin.Write(req.Input); err != nil {
+					inputErr <- status.Errorf(codes.Unavailable, "failed to write to stdin: %v", err)
+					return
+				}
+			}
+			if req, err = stream.Recv(); err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				inputErr <- err
+				return
+			}
+		}
 	}()
 
-	buf := make([]byte, 4096)
+	for _, line := range backlog {
+		if err := stream.Send(newConsoleOutput(serverID, line)); err != nil {
+			return err
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		default:
-			n, err := pr.Read(buf)
+		case err := <-inputErr:
 			if err != nil {
 				return err
 			}
-
-			output := &agentpb.ConsoleOutput{
-				ServerId:  req.ServerId,
-				Line:      string(buf[:n]),
-				Timestamp: time.Now().Unix(),
+			inputErr = nil // Client is done sending; keep streaming output
+		case line, ok := <-sub.Lines:
+			if !ok {
+				return nil
 			}
-
-			if err := stream.Send(output); err != nil {
+			if err := stream.Send(newConsoleOutput(serverID, line)); err != nil {
 				return err
 			}
 		}
@@ -321,25 +659,13 @@ func (s *AgentService) StreamConsole(req *agentpb.ServerIdentifier, stream agent
 func (s *AgentService) SendCommand(ctx context.Context, req *agentpb.SendCommandRequest) (*agentpb.ServerActionResponse, error) {
 	fmt.Printf("💬 Received SendCommand for %s: %s\n", req.ServerId, req.Command)
 
-	// ── File management commands (prefix: __file:operation:body) ──
-	if strings.HasPrefix(req.Command, "__file:") {
-		parts := strings.SplitN(req.Command[7:], ":", 2)
-		operation := parts[0]
-		body := ""
-		if len(parts) > 1 {
-			body = parts[1]
-		}
-		result := s.HandleFileCommand(req.ServerId, operation, body)
-		return &agentpb.ServerActionResponse{Success: true, ErrorMessage: result}, nil
-	}
-
 	containerID, err := s.getContainerID(req.ServerId)
 	if err != nil {
 		fmt.Printf("❌ SendCommand: container not found: %v\n", err)
 		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
 	}
 
-	output, err := s.dockerMgr.SendCommand(ctx, containerID, req.Command)
+	output, err := s.runCommand(ctx, req.ServerId, containerID, req.Command)
 	if err != nil {
 		fmt.Printf("❌ SendCommand: failed: %v\n", err)
 		return &agentpb.ServerActionResponse{Success: false, ErrorMessage: err.Error()}, nil
@@ -393,6 +719,19 @@ func (s *AgentService) Ping(ctx context.Context, _ *emptypb.Empty) (*agentpb.Pin
 	}, nil
 }
 
+// setContainer records a server's container, or that it has none ("")
+func (s *AgentService) setContainer(serverID, containerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.containerSeq++
+	s.changedAt[serverID] = s.containerSeq
+	if containerID == "" {
+		delete(s.containers, serverID)
+	} else {
+		s.containers[serverID] = containerID
+	}
+}
+
 // Helper to get container ID from server ID
 func (s *AgentService) getContainerID(serverID string) (string, error) {
 	s.mu.RLock()
@@ -407,18 +746,61 @@ func (s *AgentService) getContainerID(serverID string) (string, error) {
 			return "", fmt.Errorf("server not found: %s", serverID)
 		}
 
-		s.mu.Lock()
-		s.containers[serverID] = container.ID
-		s.mu.Unlock()
-
+		s.setContainer(serverID, container.ID)
 		return container.ID, nil
 	}
 
 	return containerID, nil
 }
 
-// Helper to calculate CPU percentage from stats
-func calculateCPUPercent(stats interface{}) float64 {
-	// TODO: Implement proper CPU calculation
-	return 0.0
+// syncLimits recreates a stopped server's container if its MEMORY env or disk
+// label no longer match its limits, and returns the container ID to start
+func (s *AgentService) syncLimits(ctx context.Context, serverID, containerID string) (string, error) {
+	s.trackDisk(serverID)
+	diskMB := s.quota.Limit(serverID) / 1024 / 1024
+
+	newID, err := s.dockerMgr.SyncLimits(ctx, containerID, diskMB)
+	if err != nil {
+		return "", err
+	}
+	if newID != containerID {
+		fmt.Printf("🔁 Recreated container for %s with updated limits: %s\n", serverID, newID)
+		s.setContainer(serverID, newID)
+	}
+	return newID, nil
+}
+
+// newConsoleOutput converts a buffered console line to its wire form
+func newConsoleOutput(serverID string, line console.Line) *agentpb.ConsoleOutput {
+	stream := agentpb.ConsoleStream_CONSOLE_STREAM_STDOUT
+	if line.Stream == console.StreamStderr {
+		stream = agentpb.ConsoleStream_CONSOLE_STREAM_STDERR
+	}
+
+	return &agentpb.ConsoleOutput{
+		ServerId:  serverID,
+		Line:      line.Text,
+		Timestamp: line.Timestamp.Unix(),
+		IsError:   line.Stream == console.StreamStderr,
+		Stream:    stream,
+		Offset:    line.Offset,
+		Epoch:     line.Epoch,
+	}
+}
+
+// Helper to build a running ServerState from a container stats sample
+func newServerState(serverID string, stats *docker.ContainerStats, diskUsage int64) *agentpb.ServerState {
+	return &agentpb.ServerState{
+		ServerId:         serverID,
+		Status:           agentpb.ServerStatus_SERVER_STATUS_RUNNING,
+		MemoryUsageBytes: stats.MemoryUsageBytes,
+		MemoryLimitBytes: stats.MemoryLimitBytes,
+		DiskUsageBytes:   diskUsage,
+		CpuUsagePercent:  stats.CPUPercent,
+		NetworkRxBytes:   stats.NetworkRxBytes,
+		NetworkTxBytes:   stats.NetworkTxBytes,
+		BlockReadBytes:   stats.BlockReadBytes,
+		BlockWriteBytes:  stats.BlockWriteBytes,
+		LastUpdated:      timestamppb.Now(),
+	}
 }
@@ -0,0 +1,290 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	agentpb "github.com/ironhost/agent/internal/grpc/ironhost/v1"
+	"github.com/ironhost/agent/internal/sandbox"
+)
+
+// ── File Transfer ──
+// Uploads are written to a hidden partial file next to the destination and
+// only renamed into place once every chunk has arrived and the checksum
+// matches, so a server never sees a half-written plugin jar. An interrupted
+// upload keeps its partial file and can be resumed from GetUploadStatus.
+
+// transferChunkSize is the size of each FileChunk sent by DownloadFile
+const transferChunkSize = 256 * 1024
+
+// crc32cTable is the Castagnoli table used for per-chunk checksums
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// partialUploadPath returns where an in-progress upload of name is stored
+func partialUploadPath(name string) string {
+	return path.Join(path.Dir(name), "."+path.Base(name)+".upload")
+}
+
+// isPartialUpload reports whether a directory entry is an in-progress upload
+func isPartialUpload(entry fs.DirEntry) bool {
+	name := entry.Name()
+	return !entry.IsDir() && len(name) > len("..upload") &&
+		strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".upload")
+}
+
+// UploadFile receives a file as a stream of chunks. The first message names
+// the file and, when resuming, the offset the client continues from.
+func (s *AgentService) UploadFile(stream grpc.ClientStreamingServer[agentpb.UploadFileRequest, agentpb.UploadFileResponse]) error {
+	first, err := stream.Recv()
+	if err != nil {
+		if err == io.EOF {
+			return status.Error(codes.InvalidArgument, "empty upload")
+		}
+		return err
+	}
+
+	name := sandbox.Clean(first.Path)
+	if name == "." {
+		return status.Error(codes.InvalidArgument, "path is required")
+	}
+	if first.Offset < 0 {
+		return status.Error(codes.InvalidArgument, "invalid offset")
+	}
+
+	root, err := s.openServerRoot(first.ServerId)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	if info, err := root.Stat(name); err == nil && info.IsDir() {
+		return status.Error(codes.FailedPrecondition, "path is a directory")
+	}
+
+	if err := root.MkdirAll(path.Dir(name), 0755); err != nil {
+		return fileError("create directory", err)
+	}
+
+	partPath := partialUploadPath(name)
+	var dropped int64
+	if info, err := root.Stat(partPath); err == nil && info.Size() > first.Offset {
+		dropped = info.Size() - first.Offset
+	}
+	part, err := openPartialUpload(root, partPath, first.Offset)
+	if err != nil {
+		return err
+	}
+	// Resuming drops whatever was stored past the offset
+	s.accountDisk(first.ServerId, -dropped)
+
+	// Write chunks as they arrive; the partial file always ends on a verified chunk
+	msg := first
+	for {
+		if len(msg.Data) > 0 {
+			if crc32.Checksum(msg.Data, crc32cTable) != msg.Crc32C {
+				part.Close()
+				return status.Error(codes.DataLoss, "chunk checksum mismatch")
+			}
+			if err := s.reserveDisk(first.ServerId, int64(len(msg.Data))); err != nil {
+				part.Close()
+				return err
+			}
+			if n, err := part.Write(msg.Data); err != nil {
+				part.Close()
+				s.accountDisk(first.ServerId, int64(n-len(msg.Data)))
+				return fileError("write file", err)
+			}
+		}
+
+		msg, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Client went away: keep what we have so the upload can resume
+			part.Close()
+			return err
+		}
+	}
+
+	if err := part.Close(); err != nil {
+		return fileError("write file", err)
+	}
+
+	size, sum, err := hashFile(root, partPath)
+	if err != nil {
+		return fileError("read file", err)
+	}
+
+	if first.Sha256 != "" && !strings.EqualFold(first.Sha256, sum) {
+		root.Remove(partPath)
+		s.accountDisk(first.ServerId, -size)
+		return status.Errorf(codes.DataLoss, "checksum mismatch: expected %s, got %s", first.Sha256, sum)
+	}
+
+	// The file being replaced no longer counts against the disk limit
+	var replaced int64
+	if info, err := root.Lstat(name); err == nil && info.Mode().IsRegular() {
+		replaced = info.Size()
+	}
+	if err := root.Rename(partPath, name); err != nil {
+		return fileError("write file", err)
+	}
+	s.accountDisk(first.ServerId, -replaced)
+
+	return stream.SendAndClose(&agentpb.UploadFileResponse{Size: size, Sha256: sum})
+}
+
+// openPartialUpload opens the partial file for an upload, positioned at offset.
+// Resuming past the data actually stored is rejected.
+func openPartialUpload(root *sandbox.Root, partPath string, offset int64) (*os.File, error) {
+	if offset == 0 {
+		part, err := root.OpenFile(partPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, fileError("create file", err)
+		}
+		return part, nil
+	}
+
+	info, err := root.Stat(partPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, status.Error(codes.FailedPrecondition, "no partial upload to resume")
+		}
+		return nil, fileError("stat file", err)
+	}
+	if info.Size() < offset {
+		return nil, status.Errorf(codes.OutOfRange, "cannot resume at %d: only %d bytes received", offset, info.Size())
+	}
+
+	part, err := root.OpenFile(partPath, os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fileError("open file", err)
+	}
+	// Drop anything after the resume point, e.g. a chunk the client will resend
+	if err := part.Truncate(offset); err != nil {
+		part.Close()
+		return nil, fileError("truncate file", err)
+	}
+	if _, err := part.Seek(offset, io.SeekStart); err != nil {
+		part.Close()
+		return nil, fileError("seek file", err)
+	}
+	return part, nil
+}
+
+// GetUploadStatus reports how many bytes of an interrupted upload are stored
+func (s *AgentService) GetUploadStatus(ctx context.Context, req *agentpb.UploadStatusRequest) (*agentpb.UploadStatusResponse, error) {
+	name := sandbox.Clean(req.Path)
+	if name == "." {
+		return nil, status.Error(codes.InvalidArgument, "path is required")
+	}
+
+	root, err := s.openServerRoot(req.ServerId)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	info, err := root.Stat(partialUploadPath(name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &agentpb.UploadStatusResponse{Offset: 0}, nil
+		}
+		return nil, fileError("stat file", err)
+	}
+
+	return &agentpb.UploadStatusResponse{Offset: info.Size()}, nil
+}
+
+// DownloadFile streams a file in chunks, starting at the requested offset
+func (s *AgentService) DownloadFile(req *agentpb.DownloadFileRequest, stream grpc.ServerStreamingServer[agentpb.FileChunk]) error {
+	root, err := s.openServerRoot(req.ServerId)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	file, err := root.Open(req.Path)
+	if err != nil {
+		return fileError("open file", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fileError("stat file", err)
+	}
+	if info.IsDir() {
+		return status.Error(codes.FailedPrecondition, "path is a directory")
+	}
+
+	size := info.Size()
+	if req.Offset < 0 || req.Offset > size {
+		return status.Errorf(codes.OutOfRange, "offset %d is outside the file (%d bytes)", req.Offset, size)
+	}
+	if _, err := file.Seek(req.Offset, io.SeekStart); err != nil {
+		return fileError("seek file", err)
+	}
+
+	name := path.Base(sandbox.Clean(req.Path))
+	offset := req.Offset
+	buf := make([]byte, transferChunkSize)
+
+	// Always send at least one chunk so the client learns the size of empty files
+	sent := false
+	for {
+		n, readErr := io.ReadFull(file, buf)
+		if n > 0 || !sent {
+			data := buf[:n]
+			if err := stream.Send(&agentpb.FileChunk{
+				Data:   data,
+				Offset: offset,
+				Crc32C: crc32.Checksum(data, crc32cTable),
+				Size:   size,
+				Name:   name,
+			}); err != nil {
+				return err
+			}
+			offset += int64(n)
+			sent = true
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if readErr != nil {
+			return fileError("read file", readErr)
+		}
+	}
+}
+
+// hashFile returns the size and hex SHA-256 of a file beneath root
+func hashFile(root *sandbox.Root, name string) (int64, string, error) {
+	file, err := root.Open(name)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to hash %s: %w", name, err)
+	}
+
+	return size, hex.EncodeToString(hasher.Sum(nil)), nil
+}
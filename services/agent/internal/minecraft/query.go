@@ -0,0 +1,181 @@
+package minecraft
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// The Query protocol (GameSpy4 over UDP) is only answered when the server has
+// enable-query=true. Unlike the Server List Ping it lists every online player.
+
+const (
+	queryTypeHandshake = 0x09
+	queryTypeStat      = 0x00
+	// sessionIDMask keeps the bits of the session ID servers echo back
+	sessionIDMask = 0x0F0F0F0F
+	// maxDatagram is the largest query response read
+	maxDatagram = 64 * 1024
+)
+
+var queryMagic = []byte{0xFE, 0xFD}
+
+// Section markers in a full stat response
+var (
+	statPadding   = []byte("splitnum\x00\x80\x00")
+	playerPadding = []byte("\x01player_\x00\x00")
+)
+
+// QueryStatus is a server's answer to a full stat query
+type QueryStatus struct {
+	MOTD       string
+	GameType   string
+	GameID     string
+	Version    string
+	Plugins    string // Server software and plugins, e.g. "Paper on 1.20.4: EssentialsX 2.20"
+	Map        string // Default world name
+	NumPlayers int
+	MaxPlayers int
+	HostPort   int
+	HostIP     string
+	Players    []string
+}
+
+// Query requests full stats from the query listener at addr (host:port)
+func Query(ctx context.Context, addr string) (*QueryStatus, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var idBytes [4]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, err
+	}
+	sessionID := int32(binary.BigEndian.Uint32(idBytes[:]) & sessionIDMask)
+
+	// Handshake: the server answers with a challenge token for the stat request
+	resp, err := queryExchange(conn, queryTypeHandshake, sessionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("query handshake failed: %w", err)
+	}
+	token, err := strconv.ParseInt(string(bytes.TrimRight(resp, "\x00")), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid challenge token: %w", err)
+	}
+
+	// Full stat: challenge token followed by four bytes of padding
+	payload := make([]byte, 8)
+	binary.BigEndian.PutUint32(payload, uint32(int32(token)))
+	resp, err = queryExchange(conn, queryTypeStat, sessionID, payload)
+	if err != nil {
+		return nil, fmt.Errorf("query stat failed: %w", err)
+	}
+	return parseFullStat(resp)
+}
+
+// queryExchange sends one request and returns the body of the matching response
+func queryExchange(conn net.Conn, packetType byte, sessionID int32, payload []byte) ([]byte, error) {
+	req := make([]byte, 0, 7+len(payload))
+	req = append(req, queryMagic...)
+	req = append(req, packetType)
+	req = binary.BigEndian.AppendUint32(req, uint32(sessionID))
+	req = append(req, payload...)
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, maxDatagram)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	buf = buf[:n]
+	if len(buf) < 5 {
+		return nil, errors.New("response too short")
+	}
+	if buf[0] != packetType || int32(binary.BigEndian.Uint32(buf[1:5])) != sessionID {
+		return nil, errors.New("response does not match the request")
+	}
+	return buf[5:], nil
+}
+
+// parseFullStat decodes the key/value section and the player list of a full stat response
+func parseFullStat(body []byte) (*QueryStatus, error) {
+	rest, ok := bytes.CutPrefix(body, statPadding)
+	if !ok {
+		return nil, errors.New("malformed stat response")
+	}
+
+	st := &QueryStatus{}
+	for {
+		var key, value string
+		key, rest = cutString(rest)
+		if key == "" {
+			break
+		}
+		value, rest = cutString(rest)
+		switch key {
+		case "hostname":
+			st.MOTD = StripFormatting(value)
+		case "gametype":
+			st.GameType = value
+		case "game_id":
+			st.GameID = value
+		case "version":
+			st.Version = value
+		case "plugins":
+			st.Plugins = value
+		case "map":
+			st.Map = value
+		case "numplayers":
+			st.NumPlayers, _ = strconv.Atoi(value)
+		case "maxplayers":
+			st.MaxPlayers, _ = strconv.Atoi(value)
+		case "hostport":
+			st.HostPort, _ = strconv.Atoi(value)
+		case "hostip":
+			st.HostIP = value
+		}
+	}
+
+	rest, ok = bytes.CutPrefix(rest, playerPadding)
+	if !ok {
+		return st, nil // Some servers stop after the key/value section
+	}
+	for {
+		var name string
+		name, rest = cutString(rest)
+		if name == "" {
+			break
+		}
+		st.Players = append(st.Players, name)
+	}
+	return st, nil
+}
+
+// cutString splits off a null-terminated ISO-8859-1 string
+func cutString(b []byte) (string, []byte) {
+	s, rest, _ := bytes.Cut(b, []byte{0})
+	runes := make([]rune, len(s))
+	for i, c := range s {
+		runes[i] = rune(c)
+	}
+	return string(runes), rest
+}
@@ -0,0 +1,136 @@
+package minecraft
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fullStat is the body of a full stat response, after type and session ID
+const fullStat = "splitnum\x00\x80\x00" +
+	"hostname\x00\xa7aA \xa7lMinecraft\xa7r Server\x00" +
+	"gametype\x00SMP\x00" +
+	"game_id\x00MINECRAFT\x00" +
+	"version\x001.20.4\x00" +
+	"plugins\x00Paper on 1.20.4: EssentialsX 2.20.1; LuckPerms 5.4.102\x00" +
+	"map\x00world\x00" +
+	"numplayers\x002\x00" +
+	"maxplayers\x0020\x00" +
+	"hostport\x0025565\x00" +
+	"hostip\x00127.0.0.1\x00" +
+	"\x00" +
+	"\x01player_\x00\x00" +
+	"barneygale\x00Vivalahelvig\x00" +
+	"\x00"
+
+// queryServer answers the handshake with token and the stat request with
+// stat, checking both requests byte for byte
+func queryServer(t *testing.T, token string, wantToken []byte, stat string) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+
+		// Handshake: magic, type 9, session ID
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if n != 7 || !bytes.Equal(buf[:3], []byte{0xfe, 0xfd, 0x09}) {
+			t.Errorf("handshake = % x", buf[:n])
+			return
+		}
+		session := append([]byte(nil), buf[3:7]...)
+		if binary.BigEndian.Uint32(session)&^sessionIDMask != 0 {
+			t.Errorf("session ID % x has bits servers do not echo", session)
+		}
+		pc.WriteTo(append(append([]byte{0x09}, session...), token+"\x00"...), addr)
+
+		// Full stat: magic, type 0, session ID, token, four bytes of padding
+		n, addr, err = pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		want := append(append([]byte{0xfe, 0xfd, 0x00}, session...), wantToken...)
+		want = append(want, 0, 0, 0, 0)
+		if !bytes.Equal(buf[:n], want) {
+			t.Errorf("stat request = % x, want % x", buf[:n], want)
+			return
+		}
+		pc.WriteTo(append(append([]byte{0x00}, session...), stat...), addr)
+	}()
+	return pc.LocalAddr().String()
+}
+
+func TestQuery(t *testing.T) {
+	for _, tc := range []struct {
+		token     string
+		wantToken []byte
+	}{
+		{"9513307", []byte{0x00, 0x91, 0x29, 0x5b}},
+		{"-12345", []byte{0xff, 0xff, 0xcf, 0xc7}},
+	} {
+		addr := queryServer(t, tc.token, tc.wantToken, fullStat)
+		st, err := Query(context.Background(), addr)
+		if err != nil {
+			t.Fatalf("token %s: %v", tc.token, err)
+		}
+		want := &QueryStatus{
+			MOTD:       "A Minecraft Server",
+			GameType:   "SMP",
+			GameID:     "MINECRAFT",
+			Version:    "1.20.4",
+			Plugins:    "Paper on 1.20.4: EssentialsX 2.20.1; LuckPerms 5.4.102",
+			Map:        "world",
+			NumPlayers: 2,
+			MaxPlayers: 20,
+			HostPort:   25565,
+			HostIP:     "127.0.0.1",
+			Players:    []string{"barneygale", "Vivalahelvig"},
+		}
+		if !reflect.DeepEqual(st, want) {
+			t.Errorf("token %s: status =\n%+v\nwant\n%+v", tc.token, st, want)
+		}
+	}
+}
+
+func TestQueryBadToken(t *testing.T) {
+	addr := queryServer(t, "token", nil, "")
+	if _, err := Query(context.Background(), addr); err == nil || !strings.Contains(err.Error(), "invalid challenge token") {
+		t.Fatalf("Query returned %v, want an invalid challenge token error", err)
+	}
+}
+
+func TestParseFullStat(t *testing.T) {
+	// Some servers stop after the key/value section
+	kvOnly, _, _ := strings.Cut(fullStat, "\x01player_")
+	st, err := parseFullStat([]byte(kvOnly))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.NumPlayers != 2 || st.Players != nil {
+		t.Errorf("without a player section: %d players, list %q", st.NumPlayers, st.Players)
+	}
+
+	// Names are ISO-8859-1
+	st, err = parseFullStat([]byte("splitnum\x00\x80\x00\x00\x01player_\x00\x00J\xfcrgen\x00\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(st.Players, []string{"Jürgen"}) {
+		t.Errorf("players = %q, want Jürgen", st.Players)
+	}
+
+	if _, err := parseFullStat([]byte("hostname\x00A Minecraft Server\x00")); err == nil {
+		t.Error("a response without the splitnum padding was accepted")
+	}
+}
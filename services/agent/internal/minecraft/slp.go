@@ -0,0 +1,207 @@
+// Package minecraft talks to Minecraft: Java Edition servers over the network.
+package minecraft
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// handshakeProtocol is sent in the handshake. Servers answer a status
+	// request whatever the version, and -1 is the convention for "just pinging".
+	handshakeProtocol = -1
+	// maxPacketLength caps a status response; real ones are a few KB, with a favicon
+	maxPacketLength = 1 << 21
+	// defaultTimeout applies when ctx has no deadline
+	defaultTimeout = 5 * time.Second
+)
+
+var errVarIntTooLong = errors.New("varint is too long")
+
+// Status is a server's answer to a Server List Ping
+type Status struct {
+	Version struct {
+		Name     string `json:"name"`
+		Protocol int    `json:"protocol"`
+	} `json:"version"`
+	Players struct {
+		Max    int      `json:"max"`
+		Online int      `json:"online"`
+		Sample []Player `json:"sample"` // Up to 12 online players, chosen by the server
+	} `json:"players"`
+	Description json.RawMessage `json:"description"` // MOTD as a chat component
+	Latency     time.Duration   `json:"-"`
+}
+
+// Player is an entry of the player sample
+type Player struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// MOTD returns the message of the day as plain text
+func (s *Status) MOTD() string {
+	return PlainText(s.Description)
+}
+
+// IsServerImage reports whether image is a Java Edition server image
+func IsServerImage(image string) bool {
+	name, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i] // Drop the tag
+	}
+	return strings.HasSuffix(name, "itzg/minecraft-server")
+}
+
+// Ping performs the Server List Ping handshake against addr (host:port) and
+// returns the server's status
+func Ping(ctx context.Context, addr string) (*Status, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q: %w", portStr, err)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	start := time.Now()
+
+	// Handshake (next state 1 = status), then status request
+	var handshake bytes.Buffer
+	writeVarInt(&handshake, 0x00)
+	writeVarInt(&handshake, handshakeProtocol)
+	writeString(&handshake, host)
+	binary.Write(&handshake, binary.BigEndian, uint16(port))
+	writeVarInt(&handshake, 1)
+
+	var out bytes.Buffer
+	writePacket(&out, handshake.Bytes())
+	writePacket(&out, []byte{0x00})
+	if _, err := conn.Write(out.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to send status request: %w", err)
+	}
+
+	r := bufio.NewReader(conn)
+	payload, err := readPacket(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status response: %w", err)
+	}
+
+	body := bytes.NewReader(payload)
+	id, err := readVarInt(body)
+	if err != nil {
+		return nil, err
+	}
+	if id != 0x00 {
+		return nil, fmt.Errorf("unexpected packet 0x%02x in status response", id)
+	}
+	raw, err := readString(body)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Latency: time.Since(start)}
+	if err := json.Unmarshal([]byte(raw), status); err != nil {
+		return nil, fmt.Errorf("invalid status JSON: %w", err)
+	}
+	return status, nil
+}
+
+// writePacket frames a packet with its length
+func writePacket(w *bytes.Buffer, payload []byte) {
+	writeVarInt(w, int32(len(payload)))
+	w.Write(payload)
+}
+
+// readPacket reads one length-prefixed packet
+func readPacket(r io.ByteReader) ([]byte, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if length <= 0 || length > maxPacketLength {
+		return nil, fmt.Errorf("invalid packet length %d", length)
+	}
+
+	payload := make([]byte, length)
+	for i := range payload {
+		if payload[i], err = r.ReadByte(); err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+func writeVarInt(w *bytes.Buffer, v int32) {
+	u := uint32(v)
+	for {
+		if u&^0x7F == 0 {
+			w.WriteByte(byte(u))
+			return
+		}
+		w.WriteByte(byte(u&0x7F | 0x80))
+		u >>= 7
+	}
+}
+
+func readVarInt(r io.ByteReader) (int32, error) {
+	var result uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		result |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(result), nil
+		}
+	}
+	return 0, errVarIntTooLong
+}
+
+func writeString(w *bytes.Buffer, s string) {
+	writeVarInt(w, int32(len(s)))
+	w.WriteString(s)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return "", err
+	}
+	if length < 0 || int(length) > r.Len() {
+		return "", fmt.Errorf("invalid string length %d", length)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,172 @@
+package minecraft
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unhex decodes hex with spaces between bytes
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestVarInt(t *testing.T) {
+	// Encodings from the protocol documentation
+	for _, tc := range []struct {
+		value   int32
+		encoded string
+	}{
+		{0, "00"},
+		{1, "01"},
+		{2, "02"},
+		{127, "7f"},
+		{128, "80 01"},
+		{255, "ff 01"},
+		{25565, "dd c7 01"},
+		{2097151, "ff ff 7f"},
+		{2147483647, "ff ff ff ff 07"},
+		{-1, "ff ff ff ff 0f"},
+		{-2147483648, "80 80 80 80 08"},
+	} {
+		want := unhex(t, tc.encoded)
+		var buf bytes.Buffer
+		writeVarInt(&buf, tc.value)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("writeVarInt(%d) = % x, want % x", tc.value, buf.Bytes(), want)
+		}
+		got, err := readVarInt(bytes.NewReader(want))
+		if err != nil || got != tc.value {
+			t.Errorf("readVarInt(% x) = %d, %v, want %d", want, got, err, tc.value)
+		}
+	}
+
+	if _, err := readVarInt(bytes.NewReader(unhex(t, "ff ff ff ff ff 01"))); !errors.Is(err, errVarIntTooLong) {
+		t.Errorf("readVarInt of six bytes returned %v, want errVarIntTooLong", err)
+	}
+	if _, err := readVarInt(bytes.NewReader(unhex(t, "ff ff"))); !errors.Is(err, io.EOF) {
+		t.Errorf("readVarInt of a cut-off varint returned %v, want EOF", err)
+	}
+}
+
+// statusJSON is a 1.20.4 server's status, 238 bytes
+const statusJSON = `{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":1,"sample":[{"name":"Notch","id":"069a79f4-44e9-4726-a5be-fca90e38aaf5"}]},"description":{"text":"A ","extra":[{"text":"Minecraft","bold":true},{"text":" Server"}]}}`
+
+// slpServer accepts one connection, checks the client sent want and answers
+// with response, a few bytes at a time so frames span several reads
+func slpServer(t *testing.T, want func(port int) []byte, response []byte) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		expected := want(ln.Addr().(*net.TCPAddr).Port)
+		got := make([]byte, len(expected))
+		if _, err := io.ReadFull(conn, got); err != nil {
+			t.Errorf("reading request: %v", err)
+			return
+		}
+		if !bytes.Equal(got, expected) {
+			t.Errorf("request = % x, want % x", got, expected)
+			return
+		}
+		for len(response) > 0 {
+			n := min(len(response), 7)
+			conn.Write(response[:n])
+			response = response[n:]
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// handshake returns the handshake and status request Ping sends to 127.0.0.1
+func handshake(t *testing.T) func(port int) []byte {
+	return func(port int) []byte {
+		return unhex(t, "13 00 ff ff ff ff 0f 09 "+hex.EncodeToString([]byte("127.0.0.1"))+
+			" "+hex.EncodeToString([]byte{byte(port >> 8), byte(port)})+" 01"+
+			" 01 00")
+	}
+}
+
+func TestPing(t *testing.T) {
+	// Packet length 241, packet 0x00, string length 238
+	response := append(unhex(t, "f1 01 00 ee 01"), statusJSON...)
+	addr := slpServer(t, handshake(t), response)
+
+	status, err := Ping(context.Background(), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Version.Name != "1.20.4" || status.Version.Protocol != 765 {
+		t.Errorf("version = %+v", status.Version)
+	}
+	if status.Players.Max != 20 || status.Players.Online != 1 || len(status.Players.Sample) != 1 ||
+		status.Players.Sample[0] != (Player{Name: "Notch", ID: "069a79f4-44e9-4726-a5be-fca90e38aaf5"}) {
+		t.Errorf("players = %+v", status.Players)
+	}
+	if motd := status.MOTD(); motd != "A Minecraft Server" {
+		t.Errorf("MOTD = %q", motd)
+	}
+	if status.Latency <= 0 {
+		t.Errorf("latency = %s", status.Latency)
+	}
+}
+
+func TestPingRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		response string
+		err      string
+	}{
+		{"empty packet", "00", "invalid packet length 0"},
+		{"wrong packet", "03 01 01 7b", "unexpected packet 0x01"},
+		{"string past the packet", "03 00 05 7b", "invalid string length 5"},
+		{"not JSON", "03 00 01 7b", "invalid status JSON"},
+		{"over 2 MB", "81 80 80 01", "invalid packet length 2097153"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			addr := slpServer(t, handshake(t), unhex(t, tc.response))
+			_, err := Ping(context.Background(), addr)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("Ping returned %v, want an error containing %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestIsServerImage(t *testing.T) {
+	for image, want := range map[string]bool{
+		"itzg/minecraft-server":                  true,
+		"itzg/minecraft-server:java21":           true,
+		"docker.io/itzg/minecraft-server:latest": true,
+		"itzg/minecraft-server@sha256:0123abcd":  true,
+		"localhost:5000/itzg/minecraft-server":   true,
+		"itzg/minecraft-bedrock-server":          false,
+		"ghcr.io/pterodactyl/yolks:java_21":      false,
+	} {
+		if got := IsServerImage(image); got != want {
+			t.Errorf("IsServerImage(%q) = %v, want %v", image, got, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package minecraft
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// chatComponent is the subset of Minecraft's JSON text format needed to
+// recover the plain text
+type chatComponent struct {
+	Text  string          `json:"text"`
+	Extra []chatComponent `json:"extra"`
+}
+
+// UnmarshalJSON accepts a component object, a bare string or an array of components
+func (c *chatComponent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		c.Text = text
+		return nil
+	}
+
+	var list []chatComponent
+	if err := json.Unmarshal(data, &list); err == nil {
+		c.Extra = list
+		return nil
+	}
+
+	type component chatComponent // Without the method, to avoid recursing
+	return json.Unmarshal(data, (*component)(c))
+}
+
+func (c *chatComponent) writeTo(b *strings.Builder) {
+	b.WriteString(c.Text)
+	for i := range c.Extra {
+		c.Extra[i].writeTo(b)
+	}
+}
+
+// PlainText flattens a JSON text component to plain text without formatting codes
+func PlainText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var c chatComponent
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return ""
+	}
+	var b strings.Builder
+	c.writeTo(&b)
+	return StripFormatting(b.String())
+}
+
+// StripFormatting removes legacy § formatting codes, e.g. "§aGreen" → "Green"
+func StripFormatting(s string) string {
+	if !strings.ContainsRune(s, '§') {
+		return s
+	}
+	var b strings.Builder
+	skip := false
+	for _, r := range s {
+		switch {
+		case skip:
+			skip = false
+		case r == '§':
+			skip = true
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,180 @@
+//go:build linux
+
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Loopback images: on filesystems without project quotas each server gets a
+// sparse ext4 image of exactly its limit, mounted over its data directory.
+// Mounts do not survive a reboot, so restore re-mounts every image on startup.
+
+type loopbackBackend struct {
+	dataDir  string
+	imageDir string
+}
+
+// newLoopback checks that the agent can create and mount loop devices
+func newLoopback(dataDir string) (*loopbackBackend, error) {
+	if os.Geteuid() != 0 {
+		return nil, errors.New("loopback images require root")
+	}
+	if _, err := os.Stat("/dev/loop-control"); err != nil {
+		return nil, fmt.Errorf("no loop device support: %w", err)
+	}
+	for _, tool := range []string{"mkfs.ext4", "mount", "umount", "losetup", "resize2fs"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return nil, err
+		}
+	}
+
+	return &loopbackBackend{
+		dataDir:  dataDir,
+		imageDir: filepath.Join(dataDir, "images"),
+	}, nil
+}
+
+func (b *loopbackBackend) imagePath(serverID string) string {
+	return filepath.Join(b.imageDir, serverID+".img")
+}
+
+func (b *loopbackBackend) apply(serverID, dir string, limitBytes int64) error {
+	image := b.imagePath(serverID)
+	if isMountPoint(dir) {
+		return b.grow(image, dir, limitBytes)
+	}
+
+	if _, err := os.Stat(image); os.IsNotExist(err) {
+		// Mounting a fresh image would hide whatever the directory already holds
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("%s is not empty", dir)
+		}
+		if err := b.createImage(image, limitBytes); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if err := run("mount", "-o", "loop,nosuid,nodev", image, dir); err != nil {
+		return err
+	}
+	return b.grow(image, dir, limitBytes)
+}
+
+// createImage creates a sparse ext4 image of the given size
+func (b *loopbackBackend) createImage(image string, sizeBytes int64) error {
+	if err := os.MkdirAll(b.imageDir, 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(image, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	err = f.Truncate(sizeBytes)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// No reserved blocks: the whole image belongs to the server
+		err = run("mkfs.ext4", "-q", "-F", "-m", "0", image)
+	}
+	if err != nil {
+		os.Remove(image)
+		return err
+	}
+	return nil
+}
+
+// grow enlarges a mounted image to limitBytes. ext4 cannot shrink while
+// mounted, so a lower limit is refused.
+func (b *loopbackBackend) grow(image, dir string, limitBytes int64) error {
+	info, err := os.Stat(image)
+	if err != nil {
+		return err
+	}
+	if limitBytes == info.Size() {
+		return nil
+	}
+	if limitBytes < info.Size() {
+		return fmt.Errorf("cannot shrink %s below its current %d bytes", image, info.Size())
+	}
+
+	mi, err := mountFor(dir)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(mi.source, "/dev/loop") {
+		return fmt.Errorf("%s is mounted from %s, not a loop device", dir, mi.source)
+	}
+
+	if err := os.Truncate(image, limitBytes); err != nil {
+		return err
+	}
+	if err := run("losetup", "-c", mi.source); err != nil {
+		return err
+	}
+	return run("resize2fs", mi.source)
+}
+
+// release unmounts and deletes a server's image, and with it the server's files
+func (b *loopbackBackend) release(serverID, dir string) error {
+	if isMountPoint(dir) {
+		if err := run("umount", dir); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(b.imagePath(serverID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// restore mounts every image that is not mounted, e.g. after a host reboot
+func (b *loopbackBackend) restore() error {
+	entries, err := os.ReadDir(b.imageDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		serverID, ok := strings.CutSuffix(entry.Name(), ".img")
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(b.dataDir, "servers", serverID)
+		if isMountPoint(dir) {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := run("mount", "-o", "loop,nosuid,nodev", b.imagePath(serverID), dir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// usage always walks the directory: statfs would count ext4's own metadata
+// against the server
+func (b *loopbackBackend) usage(dir string) (int64, bool) {
+	return 0, false
+}
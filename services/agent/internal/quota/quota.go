@@ -0,0 +1,323 @@
+// Package quota measures and enforces per-server disk limits.
+//
+// Enforcement happens at two levels. The kernel enforces the limit on
+// everything the container writes, using an XFS project quota on the data
+// directory where the filesystem supports it, or otherwise by mounting a
+// fixed-size loopback ext4 image over it. On top of that the agent checks its
+// own file operations (editor saves, uploads, archive extraction) against a
+// cached usage figure so clients get a clear error before the kernel's ENOSPC.
+//
+// Usage is measured incrementally: the agent's own writes adjust the cached
+// figure immediately, and a background loop re-measures each server's
+// directory periodically to pick up what the game server wrote itself.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Mode selects how disk limits are enforced on the host
+type Mode string
+
+const (
+	ModeAuto     Mode = "auto"     // XFS if available, else loopback, else none
+	ModeXFS      Mode = "xfs"      // XFS project quotas on the data directory
+	ModeLoopback Mode = "loopback" // A fixed-size ext4 image mounted over the data directory
+	ModeNone     Mode = "none"     // Agent-side checks only
+)
+
+// ErrQuotaExceeded is returned when a write would take a server over its disk limit
+var ErrQuotaExceeded = errors.New("disk quota exceeded")
+
+const (
+	// usageRefreshInterval is how old a usage figure may get before it is re-measured
+	usageRefreshInterval = 60 * time.Second
+	// scanTick is how often the background loop looks for stale figures
+	scanTick = 10 * time.Second
+)
+
+// backend enforces limits in the kernel
+type backend interface {
+	// apply sets or changes the limit on a server's data directory
+	apply(serverID, dir string, limitBytes int64) error
+	// release removes the limit when a server is deleted
+	release(serverID, dir string) error
+	// restore re-establishes limits after an agent or host restart
+	restore() error
+	// usage returns the directory's usage if the backend accounts for it cheaply
+	usage(dir string) (int64, bool)
+}
+
+// Manager tracks disk usage for each server and applies host-level limits
+type Manager struct {
+	dataDir string
+	mode    Mode
+	backend backend
+
+	mu      sync.Mutex
+	servers map[string]*server
+}
+
+// server is the usage state of one server's data directory
+type server struct {
+	dir        string
+	limit      int64 // Bytes (0 = unlimited)
+	used       int64 // Bytes
+	measuredAt time.Time
+}
+
+// New creates a quota manager for servers under dataDir. With ModeAuto the
+// best mode the host supports is chosen; an explicit mode fails if the host
+// cannot provide it.
+func New(dataDir string, mode Mode) (*Manager, error) {
+	if mode == "" {
+		mode = ModeAuto
+	}
+
+	b, resolved, err := newBackend(dataDir, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		dataDir: dataDir,
+		mode:    resolved,
+		backend: b,
+		servers: make(map[string]*server),
+	}
+
+	if m.backend != nil {
+		if err := m.backend.restore(); err != nil {
+			log.Printf("⚠️  quota: failed to restore %s limits: %v", resolved, err)
+		}
+	}
+	return m, nil
+}
+
+// Mode returns the enforcement mode in use
+func (m *Manager) Mode() Mode {
+	return m.mode
+}
+
+// Apply enforces limitBytes on a server's data directory at the host level
+// and starts tracking its usage. It is a no-op for the kernel side in ModeNone.
+func (m *Manager) Apply(serverID, dir string, limitBytes int64) error {
+	if m.backend != nil && limitBytes > 0 {
+		if err := m.backend.apply(serverID, dir, limitBytes); err != nil {
+			return fmt.Errorf("failed to apply %s disk limit: %w", m.mode, err)
+		}
+	}
+	m.Track(serverID, dir, limitBytes)
+	return nil
+}
+
+// Release removes the host-level limit of a deleted server and stops tracking
+// it. In loopback mode this unmounts and deletes the server's image, and with
+// it the server's files.
+func (m *Manager) Release(serverID, dir string) error {
+	m.Forget(serverID)
+	if m.backend == nil {
+		return nil
+	}
+	return m.backend.release(serverID, dir)
+}
+
+// Track starts tracking a server's usage, or updates its directory and limit
+func (m *Manager) Track(serverID, dir string, limitBytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if srv, ok := m.servers[serverID]; ok {
+		if srv.dir != dir {
+			srv.measuredAt = time.Time{} // Force a re-measure
+		}
+		srv.dir = dir
+		srv.limit = limitBytes
+		return
+	}
+	m.servers[serverID] = &server{dir: dir, limit: limitBytes}
+}
+
+// Tracked reports whether a server's usage is being tracked
+func (m *Manager) Tracked(serverID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.servers[serverID]
+	return ok
+}
+
+// Forget stops tracking a server
+func (m *Manager) Forget(serverID string) {
+	m.mu.Lock()
+	delete(m.servers, serverID)
+	m.mu.Unlock()
+}
+
+// Limit returns a server's disk limit in bytes (0 = unlimited)
+func (m *Manager) Limit(serverID string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if srv, ok := m.servers[serverID]; ok {
+		return srv.limit
+	}
+	return 0
+}
+
+// Usage returns a server's disk usage in bytes, measuring it first if it
+// has never been measured
+func (m *Manager) Usage(serverID string) int64 {
+	m.mu.Lock()
+	srv, ok := m.servers[serverID]
+	if !ok {
+		m.mu.Unlock()
+		return 0
+	}
+	measured := !srv.measuredAt.IsZero()
+	used := srv.used
+	m.mu.Unlock()
+
+	if measured {
+		return used
+	}
+	return m.Refresh(serverID)
+}
+
+// Remaining returns how many bytes a server may still write, and false if
+// it has no limit
+func (m *Manager) Remaining(serverID string) (int64, bool) {
+	used := m.Usage(serverID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	srv, ok := m.servers[serverID]
+	if !ok || srv.limit <= 0 {
+		return 0, false
+	}
+	return srv.limit - used, true
+}
+
+// Reserve accounts for bytes about to be written by the agent, failing with
+// ErrQuotaExceeded if they do not fit. A negative value releases space.
+func (m *Manager) Reserve(serverID string, bytes int64) error {
+	m.Usage(serverID) // Make sure there is a figure to check against
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	srv, ok := m.servers[serverID]
+	if !ok {
+		return nil
+	}
+	if bytes > 0 && srv.limit > 0 && srv.used+bytes > srv.limit {
+		return fmt.Errorf("%w: %d of %d bytes used, %d more requested", ErrQuotaExceeded, srv.used, srv.limit, bytes)
+	}
+	srv.add(bytes)
+	return nil
+}
+
+// Add accounts for bytes the agent has written, or freed if negative,
+// without checking the limit
+func (m *Manager) Add(serverID string, bytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if srv, ok := m.servers[serverID]; ok {
+		srv.add(bytes)
+	}
+}
+
+func (srv *server) add(bytes int64) {
+	srv.used += bytes
+	if srv.used < 0 {
+		srv.used = 0
+	}
+}
+
+// Refresh re-measures a server's usage now and returns it
+func (m *Manager) Refresh(serverID string) int64 {
+	m.mu.Lock()
+	srv, ok := m.servers[serverID]
+	if !ok {
+		m.mu.Unlock()
+		return 0
+	}
+	dir := srv.dir
+	m.mu.Unlock()
+
+	used := m.measure(dir)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if srv, ok := m.servers[serverID]; ok && srv.dir == dir {
+		srv.used = used
+		srv.measuredAt = time.Now()
+	}
+	return used
+}
+
+// Run re-measures stale usage figures in the background until ctx is done
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(scanTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, serverID := range m.stale() {
+			if ctx.Err() != nil {
+				return
+			}
+			m.Refresh(serverID)
+		}
+	}
+}
+
+// stale lists servers whose usage figure is older than usageRefreshInterval
+func (m *Manager) stale() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []string
+	for id, srv := range m.servers {
+		if time.Since(srv.measuredAt) >= usageRefreshInterval {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (m *Manager) measure(dir string) int64 {
+	if m.backend != nil {
+		if used, ok := m.backend.usage(dir); ok {
+			return used
+		}
+	}
+	return DirSize(dir)
+}
+
+// DirSize sums the size of all regular files under a directory without
+// following symlinks
+func DirSize(root string) int64 {
+	var total int64
+	filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
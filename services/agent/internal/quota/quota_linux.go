@@ -0,0 +1,171 @@
+//go:build linux
+
+package quota
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// newBackend picks the enforcement backend for dataDir
+func newBackend(dataDir string, mode Mode) (backend, Mode, error) {
+	switch mode {
+	case ModeNone:
+		return nil, ModeNone, nil
+	case ModeXFS:
+		b, err := newXFS(dataDir)
+		if err != nil {
+			return nil, "", err
+		}
+		return b, ModeXFS, nil
+	case ModeLoopback:
+		b, err := newLoopback(dataDir)
+		if err != nil {
+			return nil, "", err
+		}
+		return b, ModeLoopback, nil
+	case ModeAuto:
+		xfs, err := newXFS(dataDir)
+		if err == nil {
+			return xfs, ModeXFS, nil
+		}
+		log.Printf("quota: XFS project quotas unavailable: %v", err)
+
+		loop, err := newLoopback(dataDir)
+		if err == nil {
+			return loop, ModeLoopback, nil
+		}
+		log.Printf("quota: loopback images unavailable: %v", err)
+
+		log.Printf("⚠️  quota: no host-level disk quota support, limits are only checked by the agent")
+		return nil, ModeNone, nil
+	default:
+		return nil, "", fmt.Errorf("unknown disk quota mode %q", mode)
+	}
+}
+
+// mountInfo is one line of /proc/self/mountinfo
+type mountInfo struct {
+	mountPoint string
+	fsType     string
+	source     string
+	options    []string // Superblock options, e.g. prjquota
+}
+
+func (mi mountInfo) hasOption(names ...string) bool {
+	for _, opt := range mi.options {
+		for _, name := range names {
+			if opt == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mounts parses /proc/self/mountinfo
+func mounts() ([]mountInfo, error) {
+	data, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []mountInfo
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, f := range fields {
+			if f == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 5 || sep < 0 || len(fields) < sep+4 {
+			continue
+		}
+		result = append(result, mountInfo{
+			mountPoint: unescapeMount(fields[4]),
+			fsType:     fields[sep+1],
+			source:     unescapeMount(fields[sep+2]),
+			options:    strings.Split(fields[sep+3], ","),
+		})
+	}
+	return result, scanner.Err()
+}
+
+// unescapeMount decodes the octal escapes (\040 for space) used in mountinfo
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			var c byte
+			if _, err := fmt.Sscanf(s[i+1:i+4], "%03o", &c); err == nil {
+				b.WriteByte(c)
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// mountFor returns the mount containing path (the longest matching mount point)
+func mountFor(path string) (mountInfo, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return mountInfo{}, err
+	}
+
+	all, err := mounts()
+	if err != nil {
+		return mountInfo{}, err
+	}
+
+	var best mountInfo
+	found := false
+	for _, mi := range all {
+		if resolved == mi.mountPoint || mi.mountPoint == "/" ||
+			strings.HasPrefix(resolved, mi.mountPoint+string(filepath.Separator)) {
+			// Later entries shadow earlier ones at the same mount point
+			if !found || len(mi.mountPoint) >= len(best.mountPoint) {
+				best = mi
+				found = true
+			}
+		}
+	}
+	if !found {
+		return mountInfo{}, fmt.Errorf("no mount found for %s", path)
+	}
+	return best, nil
+}
+
+// isMountPoint reports whether dir is itself a mount point
+func isMountPoint(dir string) bool {
+	mi, err := mountFor(dir)
+	if err != nil {
+		return false
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	return err == nil && mi.mountPoint == resolved
+}
+
+// run executes a host tool and includes its output in any error
+func run(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
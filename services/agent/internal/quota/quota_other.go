@@ -0,0 +1,17 @@
+//go:build !linux
+
+package quota
+
+import "fmt"
+
+// newBackend only supports agent-side checks off Linux
+func newBackend(dataDir string, mode Mode) (backend, Mode, error) {
+	switch mode {
+	case ModeAuto, ModeNone:
+		return nil, ModeNone, nil
+	case ModeXFS, ModeLoopback:
+		return nil, "", fmt.Errorf("disk quota mode %q is only supported on Linux", mode)
+	default:
+		return nil, "", fmt.Errorf("unknown disk quota mode %q", mode)
+	}
+}
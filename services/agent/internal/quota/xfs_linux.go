@@ -0,0 +1,277 @@
+//go:build linux
+
+package quota
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// XFS project quotas: each server's data directory is given its own project
+// ID with the inherit flag set, so everything created below it is charged to
+// that project, and a hard block limit is set on the project. The limit lives
+// in the filesystem, so it survives agent and host restarts.
+
+const (
+	fsIocGetXattr      = 0x801c581f // FS_IOC_FSGETXATTR
+	fsIocSetXattr      = 0x401c5820 // FS_IOC_FSSETXATTR
+	fsXflagProjInherit = 0x00000200 // FS_XFLAG_PROJINHERIT
+
+	qXGetQuota = 0x5803 // Q_XGETQUOTA
+	qXSetQLim  = 0x5804 // Q_XSETQLIM
+	prjQuota   = 2      // PRJQUOTA
+
+	fsDquotVersion = 1      // FS_DQUOT_VERSION
+	fsProjQuota    = 2      // FS_PROJ_QUOTA
+	fsDqBSoft      = 1 << 2 // FS_DQ_BSOFT
+	fsDqBHard      = 1 << 3 // FS_DQ_BHARD
+
+	// basicBlock is the unit of XFS quota block counts
+	basicBlock = 512
+	// firstProjectID leaves low project IDs to anything else on the host using them
+	firstProjectID = 1000
+)
+
+// fsxattr mirrors struct fsxattr from linux/fs.h
+type fsxattr struct {
+	xflags     uint32
+	extsize    uint32
+	nextents   uint32
+	projid     uint32
+	cowextsize uint32
+	pad        [8]byte
+}
+
+// fsDiskQuota mirrors struct fs_disk_quota from linux/dqblk_xfs.h
+type fsDiskQuota struct {
+	version      int8
+	flags        int8
+	fieldmask    uint16
+	id           uint32
+	blkHardlimit uint64
+	blkSoftlimit uint64
+	inoHardlimit uint64
+	inoSoftlimit uint64
+	bcount       uint64
+	icount       uint64
+	itimer       int32
+	btimer       int32
+	iwarns       uint16
+	bwarns       uint16
+	itimerHi     int8
+	btimerHi     int8
+	rtbtimerHi   int8
+	padding2     int8
+	rtbHardlimit uint64
+	rtbSoftlimit uint64
+	rtbcount     uint64
+	rtbtimer     int32
+	rtbwarns     uint16
+	padding3     int16
+	padding4     [8]byte
+}
+
+type xfsBackend struct {
+	dataDir string
+	device  string // Block device of the filesystem, for quotactl
+
+	mu sync.Mutex // Serialises project ID allocation
+}
+
+// newXFS checks that dataDir is on XFS mounted with project quotas and that
+// the agent may manage them
+func newXFS(dataDir string) (*xfsBackend, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	mi, err := mountFor(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if mi.fsType != "xfs" {
+		return nil, fmt.Errorf("%s is on %s, not xfs", dataDir, mi.fsType)
+	}
+	if !mi.hasOption("prjquota", "pquota") {
+		return nil, fmt.Errorf("%s is not mounted with prjquota", mi.mountPoint)
+	}
+
+	b := &xfsBackend{dataDir: dataDir, device: mi.source}
+	var q fsDiskQuota
+	if err := b.quotactl(qXGetQuota, 0, &q); err != nil && err != unix.ENOENT {
+		return nil, fmt.Errorf("quotactl on %s: %w", mi.source, err)
+	}
+	return b, nil
+}
+
+func (b *xfsBackend) quotactl(cmd int, id uint32, q *fsDiskQuota) error {
+	device, err := unix.BytePtrFromString(b.device)
+	if err != nil {
+		return err
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL,
+		uintptr(cmd<<8|prjQuota), uintptr(unsafe.Pointer(device)),
+		uintptr(id), uintptr(unsafe.Pointer(q)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (b *xfsBackend) apply(serverID, dir string, limitBytes int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id, err := projectID(dir)
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		if id, err = b.nextProjectID(); err != nil {
+			return err
+		}
+		if err := setProject(dir, id); err != nil {
+			return err
+		}
+	}
+
+	return b.setLimit(id, limitBytes)
+}
+
+func (b *xfsBackend) release(serverID, dir string) error {
+	id, err := projectID(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if id == 0 {
+		return nil
+	}
+	return b.setLimit(id, 0)
+}
+
+// restore has nothing to do: project IDs and limits are stored by XFS
+func (b *xfsBackend) restore() error {
+	return nil
+}
+
+func (b *xfsBackend) usage(dir string) (int64, bool) {
+	id, err := projectID(dir)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	var q fsDiskQuota
+	if err := b.quotactl(qXGetQuota, id, &q); err != nil {
+		return 0, false
+	}
+	return int64(q.bcount) * basicBlock, true
+}
+
+// setLimit sets the hard (and soft) block limit of a project; 0 removes it
+func (b *xfsBackend) setLimit(id uint32, limitBytes int64) error {
+	blocks := uint64((limitBytes + basicBlock - 1) / basicBlock)
+	q := fsDiskQuota{
+		version:      fsDquotVersion,
+		flags:        fsProjQuota,
+		fieldmask:    fsDqBSoft | fsDqBHard,
+		id:           id,
+		blkHardlimit: blocks,
+		blkSoftlimit: blocks,
+	}
+	if err := b.quotactl(qXSetQLim, id, &q); err != nil {
+		return fmt.Errorf("failed to set limit on project %d: %w", id, err)
+	}
+	return nil
+}
+
+// nextProjectID returns one more than the highest project ID in use by a
+// server directory
+func (b *xfsBackend) nextProjectID() (uint32, error) {
+	next := uint32(firstProjectID)
+	entries, err := os.ReadDir(filepath.Join(b.dataDir, "servers"))
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id, err := projectID(filepath.Join(b.dataDir, "servers", entry.Name()))
+		if err == nil && id >= next {
+			next = id + 1
+		}
+	}
+	return next, nil
+}
+
+func getXattr(fd int) (fsxattr, error) {
+	var attr fsxattr
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), fsIocGetXattr, uintptr(unsafe.Pointer(&attr)))
+	if errno != 0 {
+		return attr, errno
+	}
+	return attr, nil
+}
+
+func setXattr(fd int, attr fsxattr) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), fsIocSetXattr, uintptr(unsafe.Pointer(&attr)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// projectID returns the project ID of a directory (0 if it has none)
+func projectID(dir string) (uint32, error) {
+	fd, err := unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return 0, &fs.PathError{Op: "open", Path: dir, Err: err}
+	}
+	defer unix.Close(fd)
+
+	attr, err := getXattr(fd)
+	if err != nil {
+		return 0, &fs.PathError{Op: "getxattr", Path: dir, Err: err}
+	}
+	return attr.projid, nil
+}
+
+// setProject assigns a project ID to dir and everything already below it,
+// marking directories so new files inherit it
+func setProject(dir string, id uint32) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil // Symlinks and special files cannot be opened safely
+		}
+
+		fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NOFOLLOW|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return &fs.PathError{Op: "open", Path: path, Err: err}
+		}
+		defer unix.Close(fd)
+
+		attr, err := getXattr(fd)
+		if err != nil {
+			return &fs.PathError{Op: "getxattr", Path: path, Err: err}
+		}
+		attr.projid = id
+		if d.IsDir() {
+			attr.xflags |= fsXflagProjInherit
+		}
+		if err := setXattr(fd, attr); err != nil {
+			return &fs.PathError{Op: "setxattr", Path: path, Err: err}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,117 @@
+package rcon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// Pool keeps one persistent connection per server, so commands do not pay for
+// a new TCP connection and login each time
+type Pool struct {
+	mu    sync.Mutex
+	conns map[string]*pooledConn // By server ID
+}
+
+type pooledConn struct {
+	addr     string
+	password string
+	conn     *Conn
+}
+
+// NewPool creates an empty pool
+func NewPool() *Pool {
+	return &Pool{conns: make(map[string]*pooledConn)}
+}
+
+// Execute runs a command on a server, connecting (or reconnecting after the
+// server restarted or its address or password changed) as needed
+func (p *Pool) Execute(ctx context.Context, serverID, addr, password, command string) (string, error) {
+	conn, reused, err := p.get(ctx, serverID, addr, password)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := conn.Execute(ctx, command)
+	if err == nil || !reused || errors.Is(err, ErrCommandTooLong) || ctx.Err() != nil {
+		if err != nil {
+			p.drop(serverID, conn)
+		}
+		return out, err
+	}
+
+	// A pooled connection may have been closed by a server restart: retry once
+	// on a fresh one
+	p.drop(serverID, conn)
+	if conn, _, err = p.get(ctx, serverID, addr, password); err != nil {
+		return "", err
+	}
+	out, err = conn.Execute(ctx, command)
+	if err != nil {
+		p.drop(serverID, conn)
+	}
+	return out, err
+}
+
+// Remove closes a server's connection, e.g. when its container stops
+func (p *Pool) Remove(serverID string) {
+	p.mu.Lock()
+	pc, ok := p.conns[serverID]
+	delete(p.conns, serverID)
+	p.mu.Unlock()
+
+	if ok {
+		pc.conn.Close()
+	}
+}
+
+// get returns the server's pooled connection, dialling a new one if there is
+// none for addr and password. reused reports whether it was already open.
+func (p *Pool) get(ctx context.Context, serverID, addr, password string) (conn *Conn, reused bool, err error) {
+	p.mu.Lock()
+	pc, ok := p.conns[serverID]
+	if ok && pc.addr == addr && pc.password == password {
+		p.mu.Unlock()
+		return pc.conn, true, nil
+	}
+	if ok {
+		delete(p.conns, serverID)
+	}
+	p.mu.Unlock()
+	if ok {
+		pc.conn.Close()
+	}
+
+	conn, err = Dial(ctx, addr, password)
+	if err != nil {
+		return nil, false, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if existing, ok := p.conns[serverID]; ok && existing.addr == addr && existing.password == password {
+		// Another command connected meanwhile: use that one
+		conn.Close()
+		return existing.conn, true, nil
+	}
+	p.conns[serverID] = &pooledConn{addr: addr, password: password, conn: conn}
+	return conn, false, nil
+}
+
+// drop removes conn from the pool if it is still the server's connection
+func (p *Pool) drop(serverID string, conn *Conn) {
+	p.mu.Lock()
+	if pc, ok := p.conns[serverID]; ok && pc.conn == conn {
+		delete(p.conns, serverID)
+	}
+	p.mu.Unlock()
+	conn.Close()
+}
+
+// IsUnavailable reports whether err means nothing is listening for RCON,
+// as opposed to a failed login or command
+func IsUnavailable(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
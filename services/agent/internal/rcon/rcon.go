@@ -0,0 +1,266 @@
+// Package rcon implements the Source RCON protocol that Minecraft (and many
+// other game servers) use for remote console commands.
+package rcon
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Packet types. Command responses and auth responses share type 2, as in the
+// protocol itself.
+const (
+	typeResponseValue = 0
+	typeExecCommand   = 2
+	typeAuthResponse  = 2
+	typeAuth          = 3
+)
+
+const (
+	// DefaultPort is the RCON port game images listen on unless configured otherwise
+	DefaultPort = 25575
+	// defaultTimeout applies to dialling and to each command when ctx has no deadline
+	defaultTimeout = 10 * time.Second
+	// maxBodyLength caps what a server may send in one packet; Minecraft splits
+	// responses at 4096 bytes
+	maxBodyLength = 64 * 1024
+	// maxCommandLength is the longest command servers accept in one packet
+	maxCommandLength = 1446
+	// splitLength is the body length Minecraft splits responses at. A shorter
+	// first packet is the whole response.
+	splitLength = 4096
+)
+
+var (
+	// ErrAuth means the server rejected the password
+	ErrAuth = errors.New("rcon authentication failed")
+	// ErrCommandTooLong means the command does not fit in one request packet
+	ErrCommandTooLong = fmt.Errorf("rcon command longer than %d bytes", maxCommandLength)
+)
+
+// Conn is an authenticated RCON connection. Commands on one connection run one
+// at a time; it is safe for concurrent use.
+type Conn struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	r      *bufio.Reader
+	nextID int32
+	broken bool
+}
+
+// Dial connects to addr (host:port) and authenticates with password
+func Dial(ctx context.Context, addr, password string) (*Conn, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{conn: nc, r: bufio.NewReader(nc), nextID: 1}
+	if err := c.auth(ctx, password); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// auth logs in. Some servers send an empty response value before the auth
+// response, which is skipped.
+func (c *Conn) auth(ctx context.Context, password string) error {
+	c.setDeadline(ctx)
+	id := c.newID()
+	if err := c.write(id, typeAuth, password); err != nil {
+		return err
+	}
+	for {
+		pkt, err := c.read()
+		if err != nil {
+			return err
+		}
+		if pkt.typ != typeAuthResponse {
+			continue
+		}
+		if pkt.id == -1 {
+			return ErrAuth
+		}
+		if pkt.id != id {
+			return fmt.Errorf("unexpected rcon auth response id %d", pkt.id)
+		}
+		return nil
+	}
+}
+
+// Execute runs a command and returns its complete output. Long output arrives
+// split over several packets; if the first one is full, a marker request
+// tells where it ends, since servers answer requests in order. The marker is
+// only sent once the command has been answered: Minecraft reads each packet
+// with a single read and drops the connection if two arrive together.
+func (c *Conn) Execute(ctx context.Context, command string) (string, error) {
+	if len(command) > maxCommandLength {
+		return "", ErrCommandTooLong
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.broken {
+		return "", net.ErrClosed
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+	c.setDeadline(ctx)
+
+	id := c.newID()
+	if err := c.write(id, typeExecCommand, command); err != nil {
+		return "", c.fail(err)
+	}
+
+	first, err := c.readResponse(id)
+	if err != nil {
+		return "", c.fail(err)
+	}
+	if len(first) < splitLength {
+		return first, nil
+	}
+
+	marker := c.newID()
+	if err := c.write(marker, typeResponseValue, ""); err != nil {
+		return "", c.fail(err)
+	}
+
+	var out strings.Builder
+	out.WriteString(first)
+	for {
+		pkt, err := c.read()
+		if err != nil {
+			return "", c.fail(err)
+		}
+		switch pkt.id {
+		case id:
+			out.WriteString(pkt.body)
+		case marker:
+			// Source servers echo the marker and follow it with one more empty
+			// packet; Minecraft answers it once with "Unknown request". Either
+			// way the command's output is complete.
+			if pkt.body == "" {
+				c.drainTrailer()
+			}
+			return out.String(), nil
+		}
+	}
+}
+
+// readResponse returns the body of the first packet answering request id,
+// skipping leftovers of earlier exchanges
+func (c *Conn) readResponse(id int32) (string, error) {
+	for {
+		pkt, err := c.read()
+		if err != nil {
+			return "", err
+		}
+		if pkt.id == id {
+			return pkt.body, nil
+		}
+	}
+}
+
+// drainTrailer reads the extra packet Source servers send after echoing an
+// empty response value, so it is not mistaken for the next command's output
+func (c *Conn) drainTrailer() {
+	c.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := c.read(); err != nil {
+		var ne net.Error
+		if !errors.As(err, &ne) || !ne.Timeout() {
+			c.fail(err)
+		}
+	}
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.broken = true
+	return c.conn.Close()
+}
+
+// fail marks the connection unusable: after an error mid-exchange, responses
+// can no longer be matched to requests
+func (c *Conn) fail(err error) error {
+	c.broken = true
+	c.conn.Close()
+	return err
+}
+
+func (c *Conn) newID() int32 {
+	id := c.nextID
+	c.nextID++
+	if c.nextID <= 0 {
+		c.nextID = 1 // -1 signals a failed login
+	}
+	return id
+}
+
+func (c *Conn) setDeadline(ctx context.Context) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+	}
+}
+
+type packet struct {
+	id   int32
+	typ  int32
+	body string
+}
+
+// write sends one packet: length, request ID, type, then the body followed by
+// two null bytes (the body's terminator and an empty string)
+func (c *Conn) write(id, typ int32, body string) error {
+	buf := make([]byte, 0, 14+len(body))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(10+len(body)))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(id))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(typ))
+	buf = append(buf, body...)
+	buf = append(buf, 0, 0)
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func (c *Conn) read() (packet, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return packet{}, err
+	}
+	length := int32(binary.LittleEndian.Uint32(header[0:4]))
+	if length < 10 || length > maxBodyLength+10 {
+		return packet{}, fmt.Errorf("invalid rcon packet length %d", length)
+	}
+
+	body := make([]byte, length-8)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{
+		id:   int32(binary.LittleEndian.Uint32(header[4:8])),
+		typ:  int32(binary.LittleEndian.Uint32(header[8:12])),
+		body: strings.TrimRight(string(body), "\x00"),
+	}, nil
+}
+
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, defaultTimeout)
+}
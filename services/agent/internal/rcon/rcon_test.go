@@ -0,0 +1,159 @@
+package rcon
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// vanillaServer answers RCON like Minecraft's RconClient: every packet must
+// arrive in its own read of at most 1460 bytes, or the connection is dropped.
+// Output longer than 4096 bytes is split over several packets.
+type vanillaServer struct {
+	t        *testing.T
+	ln       net.Listener
+	password string
+	respond  func(command string) string
+}
+
+func newVanillaServer(t *testing.T, password string, respond func(string) string) *vanillaServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &vanillaServer{t: t, ln: ln, password: password, respond: respond}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *vanillaServer) addr() string { return s.ln.Addr().String() }
+
+func (s *vanillaServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *vanillaServer) handle(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 1460)
+	for {
+		// Lets packets sent back to back pile up, as they do on a busy link
+		time.Sleep(20 * time.Millisecond)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		if n < 10 {
+			s.t.Errorf("short packet of %d bytes", n)
+			return
+		}
+		if length := int(binary.LittleEndian.Uint32(buf[0:4])); length != n-4 {
+			s.t.Errorf("packet length %d does not match the %d bytes read: packets were coalesced", length, n-4)
+			return
+		}
+		id := int32(binary.LittleEndian.Uint32(buf[4:8]))
+		typ := int32(binary.LittleEndian.Uint32(buf[8:12]))
+		body := string(buf[12 : n-2])
+
+		switch typ {
+		case typeAuth:
+			if body != s.password {
+				id = -1
+			}
+			s.send(conn, id, typeAuthResponse, "")
+		case typeExecCommand:
+			out := s.respond(body)
+			for {
+				chunk := out[:min(len(out), splitLength)]
+				s.send(conn, id, typeResponseValue, chunk)
+				out = out[len(chunk):]
+				if out == "" {
+					break
+				}
+			}
+		default:
+			s.send(conn, id, typeResponseValue, fmt.Sprintf("Unknown request %x", typ))
+		}
+	}
+}
+
+func (s *vanillaServer) send(conn net.Conn, id, typ int32, body string) {
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(10+len(body)))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(id))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(typ))
+	buf = append(buf, body...)
+	buf = append(buf, 0, 0)
+	conn.Write(buf)
+}
+
+func dial(t *testing.T, s *vanillaServer, password string) *Conn {
+	t.Helper()
+	c, err := Dial(context.Background(), s.addr(), password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestExecute(t *testing.T) {
+	long := strings.Repeat("0123456789", 1000)
+	for _, tc := range []struct {
+		name    string
+		command string
+		output  string
+	}{
+		{"empty", "save-all", ""},
+		{"single packet", "list", "There are 0 of a max of 20 players online: "},
+		{"exactly one full packet", "help", strings.Repeat("x", splitLength)},
+		{"several packets", "dump", long},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newVanillaServer(t, "secret", func(command string) string {
+				if command != tc.command {
+					t.Errorf("server got command %q, want %q", command, tc.command)
+				}
+				return tc.output
+			})
+			c := dial(t, s, "secret")
+
+			// Twice, so leftovers of the first exchange would show in the second
+			for i := 0; i < 2; i++ {
+				out, err := c.Execute(context.Background(), tc.command)
+				if err != nil {
+					t.Fatalf("Execute #%d: %v", i+1, err)
+				}
+				if out != tc.output {
+					t.Fatalf("Execute #%d returned %d bytes, want %d", i+1, len(out), len(tc.output))
+				}
+			}
+		})
+	}
+}
+
+func TestAuthFailure(t *testing.T) {
+	s := newVanillaServer(t, "secret", func(string) string { return "" })
+	if _, err := Dial(context.Background(), s.addr(), "wrong"); !errors.Is(err, ErrAuth) {
+		t.Fatalf("Dial with a wrong password returned %v, want ErrAuth", err)
+	}
+}
+
+func TestCommandTooLong(t *testing.T) {
+	s := newVanillaServer(t, "secret", func(string) string { return "" })
+	c := dial(t, s, "secret")
+	if _, err := c.Execute(context.Background(), strings.Repeat("a", maxCommandLength+1)); !errors.Is(err, ErrCommandTooLong) {
+		t.Fatalf("Execute returned %v, want ErrCommandTooLong", err)
+	}
+}
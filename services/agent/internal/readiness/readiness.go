@@ -0,0 +1,262 @@
+// Package readiness decides when a game server has finished starting. A
+// container that is running may still be generating its world for minutes;
+// probes watch its output or connect to its port to tell when players can
+// actually join.
+package readiness
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/ironhost/agent/internal/minecraft"
+)
+
+// Kind selects what a probe checks
+type Kind string
+
+const (
+	KindLog           Kind = "log"            // A line of output matches Pattern
+	KindTCP           Kind = "tcp"            // The game port accepts connections
+	KindMinecraftPing Kind = "minecraft_ping" // The game port answers a Server List Ping
+)
+
+// MinecraftDonePattern matches the line Minecraft prints once the world is loaded,
+// e.g. "Done (12.345s)! For help, type "help""
+const MinecraftDonePattern = `Done \([0-9.,]+m?s\)! For help`
+
+const (
+	// pollInterval is how often the network probes retry
+	pollInterval = 2 * time.Second
+	// dialTimeout bounds a single network probe attempt
+	dialTimeout = 2 * time.Second
+)
+
+// errMatched stops the log stream once the pattern is seen
+var errMatched = errors.New("pattern matched")
+
+// Probe is one readiness check. A server is ready once any of its probes passes.
+type Probe struct {
+	Kind    Kind   `json:"kind"`
+	Pattern string `json:"pattern,omitempty"` // Regular expression, for log probes
+	Port    int    `json:"port,omitempty"`    // Container port for network probes (0 = the primary port)
+}
+
+func (p Probe) String() string {
+	switch p.Kind {
+	case KindLog:
+		return fmt.Sprintf("log /%s/", p.Pattern)
+	case KindTCP, KindMinecraftPing:
+		if p.Port != 0 {
+			return fmt.Sprintf("%s :%d", p.Kind, p.Port)
+		}
+	}
+	return string(p.Kind)
+}
+
+// Defaults returns the probes for an image that does not specify its own
+func Defaults(image string) []Probe {
+	if minecraft.IsServerImage(image) {
+		return []Probe{
+			{Kind: KindLog, Pattern: MinecraftDonePattern},
+			{Kind: KindMinecraftPing},
+		}
+	}
+	return []Probe{{Kind: KindTCP}}
+}
+
+// Validate checks that probes are well formed
+func Validate(probes []Probe) error {
+	for i, p := range probes {
+		switch p.Kind {
+		case KindLog:
+			if p.Pattern == "" {
+				return fmt.Errorf("probe %d: log probes need a pattern", i)
+			}
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				return fmt.Errorf("probe %d: invalid pattern: %w", i, err)
+			}
+		case KindTCP, KindMinecraftPing:
+			if p.Port < 0 || p.Port > 65535 {
+				return fmt.Errorf("probe %d: invalid port %d", i, p.Port)
+			}
+		default:
+			return fmt.Errorf("probe %d: unknown kind %q", i, p.Kind)
+		}
+	}
+	return nil
+}
+
+// Encode serializes probes for storage in a container label
+func Encode(probes []Probe) (string, error) {
+	if len(probes) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(probes)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Decode parses probes stored by Encode
+func Decode(s string) ([]Probe, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var probes []Probe
+	if err := json.Unmarshal([]byte(s), &probes); err != nil {
+		return nil, fmt.Errorf("invalid readiness probes: %w", err)
+	}
+	return probes, Validate(probes)
+}
+
+// Target is the started container the probes run against
+type Target struct {
+	// PrimaryPort is the container port network probes use by default
+	PrimaryPort int
+	// Addr returns the address to dial for a container port
+	Addr func(containerPort int) string
+	// FollowLogs writes the container's output since it started to w until
+	// ctx is done, the container stops, or w returns an error
+	FollowLogs func(ctx context.Context, w io.Writer) error
+}
+
+// Wait runs the probes until one passes and returns it. It fails if ctx is
+// done first or if every probe has given up, e.g. because the container stopped.
+func Wait(ctx context.Context, probes []Probe, t Target) (Probe, error) {
+	if len(probes) == 0 {
+		return Probe{}, errors.New("no readiness probes")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		probe Probe
+		err   error
+	}
+	results := make(chan result, len(probes))
+	for _, p := range probes {
+		go func() {
+			results <- result{p, run(ctx, p, t)}
+		}()
+	}
+
+	var errs []error
+	for range probes {
+		r := <-results
+		if r.err == nil {
+			return r.probe, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", r.probe, r.err))
+	}
+	if ctx.Err() != nil {
+		return Probe{}, ctx.Err()
+	}
+	return Probe{}, errors.Join(errs...)
+}
+
+// run blocks until a probe passes (nil) or can no longer pass
+func run(ctx context.Context, p Probe, t Target) error {
+	port := p.Port
+	if port == 0 {
+		port = t.PrimaryPort
+	}
+
+	switch p.Kind {
+	case KindLog:
+		return waitForLog(ctx, p.Pattern, t)
+	case KindTCP:
+		return poll(ctx, func(ctx context.Context) error {
+			d := net.Dialer{Timeout: dialTimeout}
+			conn, err := d.DialContext(ctx, "tcp", t.Addr(port))
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		})
+	case KindMinecraftPing:
+		return poll(ctx, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+			defer cancel()
+			_, err := minecraft.Ping(ctx, t.Addr(port))
+			return err
+		})
+	default:
+		return fmt.Errorf("unknown probe kind %q", p.Kind)
+	}
+}
+
+// poll retries check every pollInterval until it succeeds or ctx is done
+func poll(ctx context.Context, check func(context.Context) error) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := check(ctx); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitForLog follows the container's output until a line matches pattern
+func waitForLog(ctx context.Context, pattern string, t Target) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+
+	m := &lineMatcher{re: re}
+	err = t.FollowLogs(ctx, m)
+	if m.matched {
+		return nil
+	}
+	if err == nil {
+		err = errors.New("log stream ended without a match")
+	}
+	return err
+}
+
+// lineMatcher is an io.Writer that reports whether any complete line matches re
+type lineMatcher struct {
+	re      *regexp.Regexp
+	partial []byte
+	matched bool
+}
+
+func (m *lineMatcher) Write(p []byte) (int, error) {
+	m.partial = append(m.partial, p...)
+	for {
+		i := bytes.IndexByte(m.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := m.partial[:i]
+		m.partial = m.partial[i+1:]
+		if m.re.Match(line) {
+			m.matched = true
+			return len(p), errMatched
+		}
+	}
+	// A line that never ends is checked as it grows rather than buffered forever
+	if len(m.partial) > 64*1024 {
+		if m.re.Match(m.partial) {
+			m.matched = true
+			return len(p), errMatched
+		}
+		m.partial = m.partial[:0]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,141 @@
+// Package sandbox confines file operations to a single directory tree.
+//
+// A server's data directory is writable by the game process running inside
+// its container, so anything in it may be hostile: symlinks pointing at
+// /etc, hardlinks to files elsewhere on the host, or directories swapped for
+// symlinks between two checks. Every path is therefore resolved component by
+// component relative to an open handle on the root, and symlinks are only
+// followed while they stay beneath it. On Linux this uses openat2 with
+// RESOLVE_BENEATH, falling back to a manual openat walk on older kernels.
+package sandbox
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// maxSymlinks bounds symlink expansion during a walk, like the kernel's ELOOP limit
+const maxSymlinks = 40
+
+var (
+	// ErrEscape is returned when a path or symlink resolves outside the root
+	ErrEscape = errors.New("path escapes the sandbox root")
+	// ErrHardlink is returned when opening a file that has more than one
+	// hard link, since the other link may live outside the root
+	ErrHardlink = errors.New("file has multiple hard links")
+	// ErrRoot is returned when removing or renaming the root itself
+	ErrRoot = errors.New("operation not permitted on the sandbox root")
+)
+
+// Clean turns a client-supplied path into a slash-separated path relative to
+// the root. Leading slashes and ".." components cannot climb above the root;
+// the root itself is ".".
+func Clean(name string) string {
+	cleaned := path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	if cleaned == "/" {
+		return "."
+	}
+	return cleaned[1:]
+}
+
+// splitPath splits a slash-separated path into its components
+func splitPath(p string) []string {
+	return strings.Split(p, "/")
+}
+
+// Dir returns the host path of the root directory
+func (r *Root) Dir() string {
+	return r.dir
+}
+
+// Open opens a file beneath the root for reading
+func (r *Root) Open(name string) (*os.File, error) {
+	return r.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens a file beneath the root. Regular files with more than one
+// hard link are refused, and O_TRUNC is only applied after that check so a
+// planted hardlink cannot be used to clobber a file outside the root.
+func (r *Root) OpenFile(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	truncate := flag&os.O_TRUNC != 0
+	f, err := r.openFile(name, flag&^os.O_TRUNC, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.Mode().IsRegular() && linkCount(info) > 1 {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrHardlink}
+	}
+
+	if truncate {
+		if err := f.Truncate(0); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// MkdirAll creates a directory beneath the root along with any missing parents
+func (r *Root) MkdirAll(name string, perm fs.FileMode) error {
+	rel := Clean(name)
+	if rel == "." {
+		return nil
+	}
+
+	parts := splitPath(rel)
+	for i := range parts {
+		dir := strings.Join(parts[:i+1], "/")
+		err := r.Mkdir(dir, perm)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+
+		info, err := r.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: dir, Err: syscall.ENOTDIR}
+		}
+	}
+	return nil
+}
+
+// FS returns the root as an fs.FS, so fs.WalkDir can be used without ever
+// leaving it. Symlinked directories are reported as symlinks, not descended into.
+func (r *Root) FS() fs.FS {
+	return rootFS{r}
+}
+
+type rootFS struct {
+	r *Root
+}
+
+func (f rootFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return f.r.Open(name)
+}
+
+func (f rootFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return f.r.ReadDir(name)
+}
+
+func (f rootFS) Stat(name string) (fs.FileInfo, error) {
+	return f.r.Stat(name)
+}
@@ -0,0 +1,356 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Root is an open handle on a directory that file operations are confined to
+type Root struct {
+	dir string
+	fd  int
+}
+
+// openat2Unsupported is set once the kernel reports openat2 as missing
+// (Linux < 5.6, or a seccomp profile that blocks it)
+var openat2Unsupported atomic.Bool
+
+// Open opens dir as a sandbox root. The caller must Close it.
+func Open(dir string) (*Root, error) {
+	fd, err := unix.Open(dir, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: dir, Err: err}
+	}
+	return &Root{dir: dir, fd: fd}, nil
+}
+
+// Close releases the root handle
+func (r *Root) Close() error {
+	return unix.Close(r.fd)
+}
+
+// openat resolves name beneath the root and opens it with the given flags.
+// With O_NOFOLLOW a symlink in the final component is not followed.
+func (r *Root) openat(name string, flags int, mode uint32) (int, error) {
+	rel := Clean(name)
+	if flags&unix.O_CREAT == 0 {
+		mode = 0 // openat2 rejects a mode it would not use
+	}
+
+	if !openat2Unsupported.Load() {
+		how := &unix.OpenHow{
+			Flags:   uint64(flags | unix.O_CLOEXEC),
+			Mode:    uint64(mode),
+			Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+		}
+		for {
+			fd, err := unix.Openat2(r.fd, rel, how)
+			switch err {
+			case nil:
+				return fd, nil
+			case unix.EAGAIN, unix.EINTR:
+				continue // A concurrent rename raced the lookup; retry
+			case unix.EXDEV:
+				return -1, ErrEscape
+			case unix.ENOSYS:
+				openat2Unsupported.Store(true)
+			default:
+				return -1, err
+			}
+			break
+		}
+	}
+
+	return r.walk(rel, flags, mode)
+}
+
+// walk is the fallback for kernels without openat2. It opens one component
+// at a time with O_NOFOLLOW, expanding symlinks by hand and tracking the
+// directories it has entered, so ".." and symlinks can never climb above the root.
+func (r *Root) walk(rel string, flags int, mode uint32) (int, error) {
+	var stack []int // Directories entered below the root
+	defer func() {
+		for _, fd := range stack {
+			unix.Close(fd)
+		}
+	}()
+	current := func() int {
+		if len(stack) == 0 {
+			return r.fd
+		}
+		return stack[len(stack)-1]
+	}
+
+	parts := splitPath(rel)
+	links := 0
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(stack) == 0 {
+				return -1, ErrEscape
+			}
+			unix.Close(stack[len(stack)-1])
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		last := len(parts) == 0
+		follow := !last || flags&unix.O_NOFOLLOW == 0
+
+		var st unix.Stat_t
+		err := unix.Fstatat(current(), part, &st, unix.AT_SYMLINK_NOFOLLOW)
+		if err == nil && st.Mode&unix.S_IFMT == unix.S_IFLNK && follow {
+			links++
+			if links > maxSymlinks {
+				return -1, unix.ELOOP
+			}
+			target, err := readlinkat(current(), part)
+			if err != nil {
+				return -1, err
+			}
+			if strings.HasPrefix(target, "/") {
+				return -1, ErrEscape
+			}
+			parts = append(splitPath(target), parts...)
+			continue
+		}
+		if err != nil && !(last && err == unix.ENOENT) {
+			return -1, err
+		}
+
+		if last {
+			return unix.Openat(current(), part, flags|unix.O_NOFOLLOW|unix.O_CLOEXEC, mode)
+		}
+
+		fd, err := unix.Openat(current(), part, unix.O_PATH|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return -1, err
+		}
+		stack = append(stack, fd)
+	}
+
+	// The path resolved to a directory (or the root itself)
+	return unix.Openat(current(), ".", flags|unix.O_CLOEXEC, mode)
+}
+
+func readlinkat(dirfd int, name string) (string, error) {
+	for size := 256; ; size *= 2 {
+		buf := make([]byte, size)
+		n, err := unix.Readlinkat(dirfd, name, buf)
+		if err != nil {
+			return "", err
+		}
+		if n < size {
+			return string(buf[:n]), nil
+		}
+	}
+}
+
+// parent opens the directory containing name and returns it with the base name
+func (r *Root) parent(name string) (int, string, error) {
+	rel := Clean(name)
+	if rel == "." {
+		return -1, "", ErrRoot
+	}
+
+	fd, err := r.openat(path.Dir(rel), unix.O_PATH|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return -1, "", err
+	}
+	return fd, path.Base(rel), nil
+}
+
+func (r *Root) openFile(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	fd, err := r.openat(name, flag, uint32(perm.Perm()))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return os.NewFile(uintptr(fd), path.Join(r.dir, Clean(name))), nil
+}
+
+// statFd returns file info for an fd and closes it
+func statFd(fd int, name string) (fs.FileInfo, error) {
+	f := os.NewFile(uintptr(fd), name)
+	defer f.Close()
+	return f.Stat()
+}
+
+// Stat returns file info for name, following symlinks that stay beneath the root
+func (r *Root) Stat(name string) (fs.FileInfo, error) {
+	fd, err := r.openat(name, unix.O_PATH, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return statFd(fd, path.Base(Clean(name)))
+}
+
+// Lstat returns file info for name without following a final symlink
+func (r *Root) Lstat(name string) (fs.FileInfo, error) {
+	fd, err := r.openat(name, unix.O_PATH|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	return statFd(fd, path.Base(Clean(name)))
+}
+
+// ReadDir lists a directory beneath the root, sorted by name. Entry info is
+// taken from the directory handle, so symlinks are reported as symlinks.
+func (r *Root) ReadDir(name string) ([]fs.DirEntry, error) {
+	fd, err := r.openat(name, unix.O_RDONLY|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	dir := os.NewFile(uintptr(fd), name)
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, entryName := range names {
+		entryFd, err := unix.Openat(fd, entryName, unix.O_PATH|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+		if err != nil {
+			continue // Removed while listing
+		}
+		info, err := statFd(entryFd, entryName)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return entries, nil
+}
+
+// Mkdir creates a single directory beneath the root
+func (r *Root) Mkdir(name string, perm fs.FileMode) error {
+	dirfd, base, err := r.parent(name)
+	if err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	defer unix.Close(dirfd)
+
+	if err := unix.Mkdirat(dirfd, base, uint32(perm.Perm())); err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Remove removes a file, symlink or empty directory beneath the root
+func (r *Root) Remove(name string) error {
+	dirfd, base, err := r.parent(name)
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	defer unix.Close(dirfd)
+
+	var st unix.Stat_t
+	if err := unix.Fstatat(dirfd, base, &st, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+
+	flags := 0
+	if st.Mode&unix.S_IFMT == unix.S_IFDIR {
+		flags = unix.AT_REMOVEDIR
+	}
+	if err := unix.Unlinkat(dirfd, base, flags); err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+// RemoveAll removes name and everything below it. Symlinks are removed,
+// never followed. A missing path is not an error.
+func (r *Root) RemoveAll(name string) error {
+	dirfd, base, err := r.parent(name)
+	if err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			return nil
+		}
+		return &fs.PathError{Op: "removeall", Path: name, Err: err}
+	}
+	defer unix.Close(dirfd)
+
+	if err := removeAllAt(dirfd, base); err != nil {
+		return &fs.PathError{Op: "removeall", Path: name, Err: err}
+	}
+	return nil
+}
+
+func removeAllAt(dirfd int, name string) error {
+	var st unix.Stat_t
+	if err := unix.Fstatat(dirfd, name, &st, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		if err == unix.ENOENT {
+			return nil
+		}
+		return err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		return unix.Unlinkat(dirfd, name, 0)
+	}
+
+	fd, err := unix.Openat(dirfd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	dir := os.NewFile(uintptr(fd), name)
+	names, err := dir.Readdirnames(-1)
+	if err == nil {
+		for _, child := range names {
+			if err = removeAllAt(fd, child); err != nil {
+				break
+			}
+		}
+	}
+	dir.Close()
+	if err != nil {
+		return err
+	}
+
+	return unix.Unlinkat(dirfd, name, unix.AT_REMOVEDIR)
+}
+
+// Rename moves oldname to newname, both beneath the root
+func (r *Root) Rename(oldname, newname string) error {
+	oldDir, oldBase, err := r.parent(oldname)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	defer unix.Close(oldDir)
+
+	newDir, newBase, err := r.parent(newname)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	defer unix.Close(newDir)
+
+	if err := unix.Renameat(oldDir, oldBase, newDir, newBase); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	return nil
+}
+
+// linkCount returns the number of hard links to a file
+func linkCount(info fs.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Nlink)
+	}
+	return 1
+}
@@ -0,0 +1,172 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+)
+
+// Root is a directory that file operations are confined to. Without openat
+// this is path based, so it cannot rule out a directory being swapped for a
+// symlink mid-operation; the agent only runs on Linux in production.
+type Root struct {
+	dir string
+}
+
+// Open opens dir as a sandbox root. The caller must Close it.
+func Open(dir string) (*Root, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: dir, Err: syscall.ENOTDIR}
+	}
+	return &Root{dir: dir}, nil
+}
+
+// Close releases the root
+func (r *Root) Close() error {
+	return nil
+}
+
+// resolve expands symlinks in name with Lstat/Readlink, refusing any that
+// lead outside the root, and returns the resulting host path
+func (r *Root) resolve(name string, followFinal bool) (string, error) {
+	var resolved []string
+	parts := splitPath(Clean(name))
+	links := 0
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return "", ErrEscape
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		current := filepath.Join(r.dir, filepath.FromSlash(path.Join(append(resolved, part)...)))
+		if len(parts) > 0 || followFinal {
+			info, err := os.Lstat(current)
+			if err == nil && info.Mode()&fs.ModeSymlink != 0 {
+				links++
+				if links > maxSymlinks {
+					return "", syscall.ELOOP
+				}
+				target, err := os.Readlink(current)
+				if err != nil {
+					return "", err
+				}
+				target = filepath.ToSlash(target)
+				if path.IsAbs(target) || filepath.VolumeName(target) != "" {
+					return "", ErrEscape
+				}
+				parts = append(splitPath(target), parts...)
+				continue
+			}
+		}
+		resolved = append(resolved, part)
+	}
+
+	return filepath.Join(r.dir, filepath.FromSlash(path.Join(resolved...))), nil
+}
+
+func (r *Root) openFile(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	p, err := r.resolve(name, true)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return os.OpenFile(p, flag, perm)
+}
+
+// Stat returns file info for name, following symlinks that stay beneath the root
+func (r *Root) Stat(name string) (fs.FileInfo, error) {
+	p, err := r.resolve(name, true)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return os.Stat(p)
+}
+
+// Lstat returns file info for name without following a final symlink
+func (r *Root) Lstat(name string) (fs.FileInfo, error) {
+	p, err := r.resolve(name, false)
+	if err != nil {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	return os.Lstat(p)
+}
+
+// ReadDir lists a directory beneath the root, sorted by name
+func (r *Root) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := r.resolve(name, true)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return os.ReadDir(p)
+}
+
+// Mkdir creates a single directory beneath the root
+func (r *Root) Mkdir(name string, perm fs.FileMode) error {
+	p, err := r.resolve(name, false)
+	if err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return os.Mkdir(p, perm)
+}
+
+// Remove removes a file, symlink or empty directory beneath the root
+func (r *Root) Remove(name string) error {
+	if Clean(name) == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: ErrRoot}
+	}
+	p, err := r.resolve(name, false)
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return os.Remove(p)
+}
+
+// RemoveAll removes name and everything below it. Symlinks are removed,
+// never followed. A missing path is not an error.
+func (r *Root) RemoveAll(name string) error {
+	if Clean(name) == "." {
+		return &fs.PathError{Op: "removeall", Path: name, Err: ErrRoot}
+	}
+	p, err := r.resolve(name, false)
+	if err != nil {
+		return &fs.PathError{Op: "removeall", Path: name, Err: err}
+	}
+	return os.RemoveAll(p)
+}
+
+// Rename moves oldname to newname, both beneath the root
+func (r *Root) Rename(oldname, newname string) error {
+	if Clean(oldname) == "." || Clean(newname) == "." {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: ErrRoot}
+	}
+	oldPath, err := r.resolve(oldname, false)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	newPath, err := r.resolve(newname, false)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+// linkCount is not available portably; hardlink checks are Linux only
+func linkCount(info fs.FileInfo) uint64 {
+	return 1
+}
@@ -0,0 +1,241 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// forEachResolver runs fn once with openat2 and once with the manual walk
+func forEachResolver(t *testing.T, fn func(t *testing.T)) {
+	for _, mode := range []struct {
+		name     string
+		fallback bool
+	}{
+		{"openat2", false},
+		{"walk", true},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			prev := openat2Unsupported.Load()
+			openat2Unsupported.Store(mode.fallback)
+			defer openat2Unsupported.Store(prev)
+			fn(t)
+		})
+	}
+}
+
+// setup creates base/root as the sandbox and base/outside holding a secret
+// file, and returns the opened root and the base directory
+func setup(t *testing.T) (*Root, string) {
+	t.Helper()
+	base := t.TempDir()
+	for _, dir := range []string{"root", "outside", "root2"} {
+		if err := os.Mkdir(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(base, "outside", "secret"), "secret")
+	writeFile(t, filepath.Join(base, "root2", "secret"), "secret")
+	writeFile(t, filepath.Join(base, "root", "file"), "inside")
+
+	root, err := Open(filepath.Join(base, "root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { root.Close() })
+	return root, base
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readAll(t *testing.T, root *Root, name string) (string, error) {
+	t.Helper()
+	f, err := root.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	return string(data), err
+}
+
+func TestClean(t *testing.T) {
+	for in, want := range map[string]string{
+		"":               ".",
+		"/":              ".",
+		"..":             ".",
+		"../../etc":      "etc",
+		"/a/../../b":     "b",
+		"a//b/./c/":      "a/b/c",
+		"..\\..\\secret": "secret",
+	} {
+		if got := Clean(in); got != want {
+			t.Errorf("Clean(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOpenInside(t *testing.T) {
+	forEachResolver(t, func(t *testing.T) {
+		root, base := setup(t)
+		if err := os.Mkdir(filepath.Join(base, "root", "dir"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("../file", filepath.Join(base, "root", "dir", "link")); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{"file", "/file", "dir/../file", "dir/link"} {
+			got, err := readAll(t, root, name)
+			if err != nil || got != "inside" {
+				t.Errorf("read %q = %q, %v; want %q", name, got, err, "inside")
+			}
+		}
+	})
+}
+
+func TestDotDotCannotEscape(t *testing.T) {
+	forEachResolver(t, func(t *testing.T) {
+		root, _ := setup(t)
+		for _, name := range []string{"../outside/secret", "../../outside/secret", "/../outside/secret"} {
+			if _, err := readAll(t, root, name); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("read %q: got %v, want not exist", name, err)
+			}
+		}
+	})
+}
+
+func TestPrefixCollision(t *testing.T) {
+	forEachResolver(t, func(t *testing.T) {
+		root, base := setup(t)
+		// "root2" shares a prefix with "root"; a string prefix check would let this through
+		if err := os.Symlink("../root2/secret", filepath.Join(base, "root", "link")); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := readAll(t, root, "../root2/secret"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("read ../root2/secret: got %v, want not exist", err)
+		}
+		if _, err := readAll(t, root, "link"); !errors.Is(err, ErrEscape) {
+			t.Errorf("read link: got %v, want ErrEscape", err)
+		}
+	})
+}
+
+func TestSymlinkEscape(t *testing.T) {
+	forEachResolver(t, func(t *testing.T) {
+		root, base := setup(t)
+		outside := filepath.Join(base, "outside")
+		links := map[string]string{
+			"abs":      filepath.Join(outside, "secret"),
+			"rel":      "../outside/secret",
+			"dirlink":  outside,
+			"loop":     "loop",
+			"absroot":  "/",
+			"relchain": "dirlink/secret",
+		}
+		for name, target := range links {
+			if err := os.Symlink(target, filepath.Join(base, "root", name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, name := range []string{"abs", "rel", "dirlink/secret", "absroot/etc/passwd", "relchain"} {
+			if _, err := readAll(t, root, name); !errors.Is(err, ErrEscape) {
+				t.Errorf("read %q: got %v, want ErrEscape", name, err)
+			}
+		}
+		if _, err := readAll(t, root, "loop"); err == nil {
+			t.Error("read loop: expected an error")
+		}
+
+		// Creating through an escaping directory symlink must not touch the outside
+		if _, err := root.OpenFile("dirlink/new", os.O_CREATE|os.O_WRONLY, 0644); !errors.Is(err, ErrEscape) {
+			t.Errorf("create dirlink/new: got %v, want ErrEscape", err)
+		}
+		if err := root.MkdirAll("dirlink/sub", 0755); !errors.Is(err, ErrEscape) {
+			t.Errorf("mkdir dirlink/sub: got %v, want ErrEscape", err)
+		}
+		if err := root.Rename("file", "dirlink/stolen"); !errors.Is(err, ErrEscape) {
+			t.Errorf("rename into dirlink: got %v, want ErrEscape", err)
+		}
+		for _, name := range []string{"new", "sub", "stolen"} {
+			if _, err := os.Lstat(filepath.Join(outside, name)); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("%s was created outside the root", name)
+			}
+		}
+
+		// Lstat and ReadDir see the links themselves
+		info, err := root.Lstat("abs")
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("lstat abs = %v, %v; want a symlink", info, err)
+		}
+		entries, err := root.ReadDir(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			if _, ok := links[entry.Name()]; ok && entry.Type()&os.ModeSymlink == 0 {
+				t.Errorf("ReadDir reported %s as %v, want a symlink", entry.Name(), entry.Type())
+			}
+		}
+	})
+}
+
+func TestHardlinkRefused(t *testing.T) {
+	forEachResolver(t, func(t *testing.T) {
+		root, base := setup(t)
+		secret := filepath.Join(base, "outside", "secret")
+		if err := os.Link(secret, filepath.Join(base, "root", "hard")); err != nil {
+			t.Skipf("hardlinks not supported: %v", err)
+		}
+
+		if _, err := readAll(t, root, "hard"); !errors.Is(err, ErrHardlink) {
+			t.Errorf("read hard: got %v, want ErrHardlink", err)
+		}
+		if _, err := root.OpenFile("hard", os.O_WRONLY|os.O_TRUNC, 0644); !errors.Is(err, ErrHardlink) {
+			t.Errorf("truncate hard: got %v, want ErrHardlink", err)
+		}
+
+		data, err := os.ReadFile(secret)
+		if err != nil || string(data) != "secret" {
+			t.Errorf("outside file changed: %q, %v", data, err)
+		}
+	})
+}
+
+func TestRemoveAllDoesNotFollow(t *testing.T) {
+	forEachResolver(t, func(t *testing.T) {
+		root, base := setup(t)
+		dir := filepath.Join(base, "root", "dir")
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join(base, "outside"), filepath.Join(dir, "link")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := root.RemoveAll("dir"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Lstat(dir); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("dir still exists: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(base, "outside", "secret")); err != nil {
+			t.Errorf("outside file removed: %v", err)
+		}
+
+		if err := root.RemoveAll("/"); !errors.Is(err, ErrRoot) {
+			t.Errorf("remove root: got %v, want ErrRoot", err)
+		}
+	})
+}
@@ -60,6 +60,43 @@ function getWebSocketUrl(serverId: string): string {
 
 type WsStatus = 'connecting' | 'connected' | 'disconnected' | 'error';
 
+interface StatsSample {
+    cpu: number;
+    memory: number;
+    memoryLimit: number;
+    rx: number;
+    tx: number;
+}
+
+// Number of stats samples kept for the resource graphs (~2 minutes at 2s)
+const STATS_HISTORY = 60;
+
+function formatBytes(bytes: number): string {
+    if (bytes < 1024) return `${bytes} B`;
+    const units = ['KB', 'MB', 'GB', 'TB'];
+    let value = bytes / 1024;
+    let i = 0;
+    while (value >= 1024 && i < units.length - 1) {
+        value /= 1024;
+        i++;
+    }
+    return `${value.toFixed(1)} ${units[i]}`;
+}
+
+/** Minimal SVG sparkline for a series of values */
+function Sparkline({ values, max, className }: { values: number[]; max: number; className: string }) {
+    if (values.length < 2) return <svg className="w-full h-8" />;
+    const top = Math.max(max, ...values, 1);
+    const points = values
+        .map((v, i) => `${(i / (STATS_HISTORY - 1)) * 100},${30 - (v / top) * 28}`)
+        .join(' ');
+    return (
+        <svg viewBox="0 0 100 30" preserveAspectRatio="none" className="w-full h-8">
+            <polyline points={points} fill="none" strokeWidth="1.5" vectorEffect="non-scaling-stroke" className={className} />
+        </svg>
+    );
+}
+
 export default function ConsolePage({ params }: { params: Promise<{ id: string }> }) {
     const { id } = use(params);
     const [server, setServer] = useState<Server | null>(null);
@@ -68,11 +105,16 @@ export default function ConsolePage({ params }: { params: Promise<{ id: string }
     const [isSending, setIsSending] = useState(false);
     const [wsStatus, setWsStatus] = useState<WsStatus>('connecting');
     const [serverStatus, setServerStatus] = useState<string>('');
+    const [stats, setStats] = useState<StatsSample[]>([]);
 
     const terminalRef = useRef<HTMLDivElement>(null);
     const autoScroll = useRef(true);
     const wsRef = useRef<WebSocket | null>(null);
     const reconnectTimer = useRef<ReturnType<typeof setTimeout> | null>(null);
+    // Epoch and offset of the last console line received, used to resume after
+    // a reconnect. Offsets start over (with a new epoch) when the agent restarts.
+    const lastEpoch = useRef('');
+    const lastOffset = useRef(0);
 
     // ── Fetch server info once for header ──
     useEffect(() => {
@@ -105,7 +147,14 @@ export default function ConsolePage({ params }: { params: Promise<{ id: string }
             token = data.session?.access_token || '';
         } catch { /* */ }
 
-        const url = getWebSocketUrl(id) + (token ? `?token=${encodeURIComponent(token)}` : '');
+        const params = new URLSearchParams();
+        if (token) params.set('token', token);
+        if (lastOffset.current > 0) {
+            params.set('offset', String(lastOffset.current));
+            params.set('epoch', lastEpoch.current);
+        }
+        const query = params.toString();
+        const url = getWebSocketUrl(id) + (query ? `?${query}` : '');
         const ws = new WebSocket(url);
         wsRef.current = ws;
 
@@ -119,14 +168,24 @@ export default function ConsolePage({ params }: { params: Promise<{ id: string }
 
                 switch (msg.type) {
                     case 'log': {
-                        // Real-time log line from the Agent's Docker stream
+                        // Real-time log line from the Agent's console buffer
+                        if (msg.offset) {
+                            if (msg.epoch !== lastEpoch.current) {
+                                // The agent's buffer started over; its offsets are new
+                                lastEpoch.current = msg.epoch || '';
+                                lastOffset.current = 0;
+                            }
+                            if (msg.offset <= lastOffset.current) break; // Already shown
+                            lastOffset.current = msg.offset;
+                        }
                         const rawLine = msg.line || '';
-                        // The Agent may send multi-line chunks
                         const subLines = rawLine.split('\n');
                         const cleaned = subLines
                             .filter((l: string) => !isNoisy(l))
                             .map(clean)
-                            .filter((l: string) => l.trim().length > 0);
+                            .filter((l: string) => l.trim().length > 0)
+                            // stderr lines are shown in red via the §c prefix
+                            .map((l: string) => msg.stream === 'stderr' && !l.startsWith('§c') ? `§c ${l}` : l);
                         if (cleaned.length > 0) {
                             setLines(prev => [...prev, ...cleaned]);
                         }
@@ -135,6 +194,18 @@ export default function ConsolePage({ params }: { params: Promise<{ id: string }
                     case 'status':
                         setServerStatus(msg.status);
                         break;
+                    case 'stats': {
+                        // Resource sample pushed by the Agent's StreamServerStats
+                        const sample: StatsSample = {
+                            cpu: msg.cpu_usage_percent || 0,
+                            memory: msg.memory_usage_bytes || 0,
+                            memoryLimit: msg.memory_limit_bytes || 0,
+                            rx: msg.network_rx_bytes || 0,
+                            tx: msg.network_tx_bytes || 0,
+                        };
+                        setStats(prev => [...prev, sample].slice(-STATS_HISTORY));
+                        break;
+                    }
                     case 'command_result': {
                         // Output from a command we sent
                         const output = (msg.output || '').trim().replace(/[\x00-\x08]/g, '');
@@ -227,6 +298,9 @@ export default function ConsolePage({ params }: { params: Promise<{ id: string }
         }
     };
 
+    const latest = stats.length > 0 ? stats[stats.length - 1] : null;
+    const cpuLimit = (server?.cpu_limit || 100);
+
     const statusIndicator = wsStatus === 'connected'
         ? 'status-running'
         : wsStatus === 'connecting'
@@ -277,8 +351,41 @@ export default function ConsolePage({ params }: { params: Promise<{ id: string }
                 </div>
             </div>
 
+            {/* Resource graphs */}
+            {latest && (
+                <div className="grid grid-cols-3 gap-3">
+                    <div className="glass-card rounded-xl p-3">
+                        <div className="flex justify-between text-xs text-muted-foreground">
+                            <span>CPU</span>
+                            <span className="text-foreground">{latest.cpu.toFixed(1)}%</span>
+                        </div>
+                        <Sparkline values={stats.map(s => s.cpu)} max={cpuLimit} className="stroke-blue-400" />
+                    </div>
+                    <div className="glass-card rounded-xl p-3">
+                        <div className="flex justify-between text-xs text-muted-foreground">
+                            <span>Memory</span>
+                            <span className="text-foreground">
+                                {formatBytes(latest.memory)}{latest.memoryLimit > 0 ? ` / ${formatBytes(latest.memoryLimit)}` : ''}
+                            </span>
+                        </div>
+                        <Sparkline values={stats.map(s => s.memory)} max={latest.memoryLimit} className="stroke-green-400" />
+                    </div>
+                    <div className="glass-card rounded-xl p-3">
+                        <div className="flex justify-between text-xs text-muted-foreground">
+                            <span>Network</span>
+                            <span className="text-foreground">↓ {formatBytes(latest.rx)} ↑ {formatBytes(latest.tx)}</span>
+                        </div>
+                        <Sparkline
+                            values={stats.map((s, i) => i === 0 ? 0 : Math.max(0, (s.rx + s.tx) - (stats[i - 1].rx + stats[i - 1].tx)))}
+                            max={0}
+                            className="stroke-purple-400"
+                        />
+                    </div>
+                </div>
+            )}
+
             {/* Terminal */}
-            <div className="flex flex-col h-[calc(100%-4rem)] glass-card rounded-xl overflow-hidden">
+            <div className={`flex flex-col ${latest ? 'h-[calc(100%-10rem)]' : 'h-[calc(100%-4rem)]'} glass-card rounded-xl overflow-hidden`}>
                 <div
                     ref={terminalRef}
                     onScroll={handleScroll}
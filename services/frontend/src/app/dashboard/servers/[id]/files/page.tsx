@@ -24,6 +24,11 @@ function formatSize(bytes: number): string {
     return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
 }
 
+function isArchive(file: FileEntry): boolean {
+    const name = file.name.toLowerCase();
+    return !file.is_directory && (name.endsWith('.zip') || name.endsWith('.tar.gz') || name.endsWith('.tgz'));
+}
+
 function formatDate(timestamp: number): string {
     if (!timestamp) return '';
     return new Date(timestamp * 1000).toLocaleString();
@@ -46,6 +51,9 @@ export default function FilesPage({ params }: { params: Promise<{ id: string }>
     const [deleteTarget, setDeleteTarget] = useState<string | null>(null);
     const [deleting, setDeleting] = useState(false);
 
+    // Upload progress
+    const [upload, setUpload] = useState<{ name: string; loaded: number; total: number } | null>(null);
+
     // Load server info
     useEffect(() => {
         serversApi.get(id).then(setServer).catch(() => { });
@@ -141,6 +149,69 @@ export default function FilesPage({ params }: { params: Promise<{ id: string }>
         }
     };
 
+    // Upload files into the current directory, resuming any interrupted upload
+    const uploadFiles = async (picked: File[]) => {
+        for (const file of picked) {
+            const target = currentPath ? `${currentPath}/${file.name}` : file.name;
+            try {
+                let offset = await filesApi.uploadStatus(id, target);
+                if (offset >= file.size) offset = 0;
+                setUpload({ name: file.name, loaded: offset, total: file.size });
+                await filesApi.upload(id, currentPath, file.slice(offset), file.name, offset, (loaded) => {
+                    setUpload({ name: file.name, loaded, total: file.size });
+                });
+            } catch (err: unknown) {
+                const msg = err instanceof Error ? err.message : 'Failed to upload';
+                setError(`${file.name}: ${msg}`);
+            }
+        }
+        setUpload(null);
+        loadFiles(currentPath);
+    };
+
+    // Download a file through the browser
+    const downloadFile = async (file: FileEntry) => {
+        try {
+            const blob = await filesApi.download(id, file.path);
+            const url = URL.createObjectURL(blob);
+            const a = document.createElement('a');
+            a.href = url;
+            a.download = file.name;
+            a.click();
+            URL.revokeObjectURL(url);
+        } catch (err: unknown) {
+            const msg = err instanceof Error ? err.message : 'Failed to download';
+            setError(msg);
+        }
+    };
+
+    // Pack a file or directory into a zip next to it
+    const compressEntry = async (file: FileEntry) => {
+        const destination = currentPath ? `${currentPath}/${file.name}.zip` : `${file.name}.zip`;
+        try {
+            setLoading(true);
+            await filesApi.compress(id, [file.path], destination, 'zip');
+            loadFiles(currentPath);
+        } catch (err: unknown) {
+            const msg = err instanceof Error ? err.message : 'Failed to compress';
+            setError(msg);
+            setLoading(false);
+        }
+    };
+
+    // Extract an archive into the current directory
+    const extractEntry = async (file: FileEntry) => {
+        try {
+            setLoading(true);
+            await filesApi.decompress(id, file.path, currentPath);
+            loadFiles(currentPath);
+        } catch (err: unknown) {
+            const msg = err instanceof Error ? err.message : 'Failed to extract';
+            setError(msg);
+            setLoading(false);
+        }
+    };
+
     // Breadcrumbs
     const pathParts = currentPath ? currentPath.split('/').filter(Boolean) : [];
 
@@ -168,17 +239,38 @@ export default function FilesPage({ params }: { params: Promise<{ id: string }>
                         <p className="text-sm text-muted-foreground">{server?.name || 'Loading...'}</p>
                     </div>
                 </div>
-                <button
-                    onClick={() => loadFiles(currentPath)}
-                    className="flex items-center gap-1.5 px-3 py-1.5 text-sm font-medium rounded-lg text-muted-foreground hover:text-foreground hover:bg-muted/50 transition-colors"
-                >
-                    <svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2">
-                        <path d="M21 12a9 9 0 0 0-9-9 9.75 9.75 0 0 0-6.74 2.74L3 8" />
-                        <path d="M3 3v5h5" /><path d="M3 12a9 9 0 0 0 9 9 9.75 9.75 0 0 0 6.74-2.74L21 16" />
-                        <path d="M16 21h5v-5" />
-                    </svg>
-                    Refresh
-                </button>
+                <div className="flex items-center gap-2">
+                    <label
+                        className={`flex items-center gap-1.5 px-3 py-1.5 text-sm font-medium rounded-lg bg-primary-500/10 text-primary-400 hover:bg-primary-500/20 transition-colors ${upload ? 'opacity-50 pointer-events-none' : 'cursor-pointer'}`}
+                    >
+                        <svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2">
+                            <path d="M21 15v4a2 2 0 0 1-2 2H5a2 2 0 0 1-2-2v-4" /><polyline points="17 8 12 3 7 8" /><line x1="12" y1="3" x2="12" y2="15" />
+                        </svg>
+                        {upload ? `${upload.name} ${upload.total ? Math.round(upload.loaded / upload.total * 100) : 100}%` : 'Upload'}
+                        <input
+                            type="file"
+                            multiple
+                            className="hidden"
+                            onChange={(e) => {
+                                // Copy the list before resetting the input so the same file can be picked again
+                                const picked = Array.from(e.target.files || []);
+                                e.target.value = '';
+                                uploadFiles(picked);
+                            }}
+                        />
+                    </label>
+                    <button
+                        onClick={() => loadFiles(currentPath)}
+                        className="flex items-center gap-1.5 px-3 py-1.5 text-sm font-medium rounded-lg text-muted-foreground hover:text-foreground hover:bg-muted/50 transition-colors"
+                    >
+                        <svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2">
+                            <path d="M21 12a9 9 0 0 0-9-9 9.75 9.75 0 0 0-6.74 2.74L3 8" />
+                            <path d="M3 3v5h5" /><path d="M3 12a9 9 0 0 0 9 9 9.75 9.75 0 0 0 6.74-2.74L21 16" />
+                            <path d="M16 21h5v-5" />
+                        </svg>
+                        Refresh
+                    </button>
+                </div>
             </div>
 
             {/* Error banner */}
@@ -305,7 +397,44 @@ export default function FilesPage({ params }: { params: Promise<{ id: string }>
                                             <td className="py-2.5 px-4 text-right text-sm text-muted-foreground">
                                                 {formatDate(file.modified_at)}
                                             </td>
-                                            <td className="py-2.5 px-4 text-right">
+                                            <td className="py-2.5 px-4 text-right whitespace-nowrap">
+                                                {isArchive(file) ? (
+                                                    <button
+                                                        onClick={(e) => {
+                                                            e.stopPropagation();
+                                                            extractEntry(file);
+                                                        }}
+                                                        className="opacity-0 group-hover:opacity-100 px-1.5 py-0.5 mr-1 text-xs text-primary-400 hover:text-primary-300 hover:bg-primary-500/10 rounded transition-all"
+                                                        title="Extract here"
+                                                    >
+                                                        Extract
+                                                    </button>
+                                                ) : (
+                                                    <button
+                                                        onClick={(e) => {
+                                                            e.stopPropagation();
+                                                            compressEntry(file);
+                                                        }}
+                                                        className="opacity-0 group-hover:opacity-100 px-1.5 py-0.5 mr-1 text-xs text-primary-400 hover:text-primary-300 hover:bg-primary-500/10 rounded transition-all"
+                                                        title="Compress to .zip"
+                                                    >
+                                                        Zip
+                                                    </button>
+                                                )}
+                                                {!file.is_directory && (
+                                                    <button
+                                                        onClick={(e) => {
+                                                            e.stopPropagation();
+                                                            downloadFile(file);
+                                                        }}
+                                                        className="opacity-0 group-hover:opacity-100 p-1 mr-1 text-primary-400 hover:text-primary-300 hover:bg-primary-500/10 rounded transition-all"
+                                                        title="Download"
+                                                    >
+                                                        <svg xmlns="http://www.w3.org/2000/svg" width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2">
+                                                            <path d="M21 15v4a2 2 0 0 1-2 2H5a2 2 0 0 1-2-2v-4" /><polyline points="7 10 12 15 17 10" /><line x1="12" y1="15" x2="12" y2="3" />
+                                                        </svg>
+                                                    </button>
+                                                )}
                                                 <button
                                                     onClick={(e) => {
                                                         e.stopPropagation();
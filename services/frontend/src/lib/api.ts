@@ -179,6 +179,23 @@ export const serversApi = {
         return data.logs || [];
     },
 
+    getStats: async (id: string) => {
+        const { data } = await api.get<{
+            stats: {
+                server_id: string;
+                cpu_usage_percent: number;
+                memory_usage_bytes: number;
+                memory_limit_bytes: number;
+                disk_usage_bytes: number;
+                network_rx_bytes: number;
+                network_tx_bytes: number;
+                block_read_bytes: number;
+                block_write_bytes: number;
+            };
+        }>(`/servers/${id}/stats`);
+        return data.stats;
+    },
+
     update: async (id: string, updateData: {
         name?: string;
         memory_limit?: number;
@@ -233,6 +250,51 @@ export const filesApi = {
         const { data } = await api.post<{ success: boolean }>(`/servers/${serverId}/files/rename`, { old_path: oldPath, new_path: newPath });
         return data;
     },
+
+    // Streams a file of any size into directory `path`. Pass `offset` (from
+    // uploadStatus) together with file.slice(offset) to resume an interrupted upload.
+    upload: async (serverId: string, path: string, file: Blob, fileName: string, offset = 0, onProgress?: (loaded: number) => void) => {
+        const form = new FormData();
+        form.append('path', path);
+        if (offset > 0) form.append('offset', String(offset));
+        form.append('file', file, fileName);
+        const { data } = await api.post<{ success: boolean; path: string; size: number; sha256: string }>(
+            `/servers/${serverId}/files/upload`, form, {
+                headers: { 'Content-Type': 'multipart/form-data' },
+                onUploadProgress: (e) => onProgress?.(offset + e.loaded),
+            }
+        );
+        return data;
+    },
+
+    uploadStatus: async (serverId: string, path: string) => {
+        const { data } = await api.get<{ success: boolean; offset: number }>(
+            `/servers/${serverId}/files/upload`, { params: { path } }
+        );
+        return data.offset;
+    },
+
+    compress: async (serverId: string, paths: string[], destination: string, format?: 'zip' | 'tar.gz') => {
+        const { data } = await api.post<{ success: boolean; path: string; file_count: number; size: number }>(
+            `/servers/${serverId}/files/compress`, { paths, destination, format }
+        );
+        return data;
+    },
+
+    decompress: async (serverId: string, path: string, destination = '') => {
+        const { data } = await api.post<{ success: boolean; path: string; file_count: number; size: number }>(
+            `/servers/${serverId}/files/decompress`, { path, destination }
+        );
+        return data;
+    },
+
+    download: async (serverId: string, path: string) => {
+        const { data } = await api.get<Blob>(`/servers/${serverId}/files/download`, {
+            params: { path },
+            responseType: 'blob',
+        });
+        return data;
+    },
 };
 
 // Nodes API
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ironhost/master/internal/eggs"
+)
+
+// importEgg implements the import-egg subcommand: it converts Pterodactyl egg
+// files into server templates and stores them. It returns the exit code.
+//
+//	ironhost-master [db flags] import-egg [-port N] [-dry-run] egg.json...
+func importEgg(args []string) int {
+	fs := flag.NewFlagSet("import-egg", flag.ExitOnError)
+	port := fs.Int("port", 0, fmt.Sprintf("Game port inside the container (default %d)", eggs.DefaultContainerPort))
+	dryRun := fs.Bool("dry-run", false, "Print the templates instead of storing them")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ironhost-master [db flags] import-egg [-port N] [-dry-run] egg.json...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	ctx := context.Background()
+	var create func(*eggs.Result) error
+	if *dryRun {
+		create = func(result *eggs.Result) error {
+			out, err := json.MarshalIndent(result.Template, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+	} else {
+		db, err := connectDatabase()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+			return 1
+		}
+		defer db.Close()
+
+		create = func(result *eggs.Result) error {
+			if err := db.CreateTemplate(ctx, result.Template); err != nil {
+				return err
+			}
+			fmt.Printf("Created template %q (%s)\n", result.Template.Name, result.Template.ID)
+			return nil
+		}
+	}
+
+	failed := 0
+	for _, file := range fs.Args() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			failed++
+			continue
+		}
+
+		result, err := eggs.Import(data, eggs.Options{ContainerPort: *port})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			failed++
+			continue
+		}
+		for _, w := range result.Warnings {
+			fmt.Fprintf(os.Stderr, "%s: warning: %s\n", file, w)
+		}
+
+		if err := create(result); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: failed to create template: %v\n", file, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
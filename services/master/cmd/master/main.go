@@ -19,6 +19,7 @@ import (
 	"github.com/ironhost/master/internal/api"
 	"github.com/ironhost/master/internal/database"
 	mastergrpc "github.com/ironhost/master/internal/grpc"
+	"github.com/ironhost/master/internal/monitor"
 )
 
 var (
@@ -30,11 +31,19 @@ var (
 	dbName     = flag.String("db-name", "ironhost", "PostgreSQL database name")
 	redisAddr  = flag.String("redis-addr", "localhost:6379", "Redis address")
 	certDir    = flag.String("cert-dir", "/etc/ironhost/certs", "Directory containing mTLS certificates")
+
+	reconcileInterval    = flag.Duration("reconcile-interval", time.Minute, "How often server statuses are reconciled with the nodes")
+	nodeFailureThreshold = flag.Int("node-failure-threshold", 3, "Failed probes in a row before a node is marked unreachable")
 )
 
 func main() {
 	flag.Parse()
 
+	// Subcommands run against the database and exit
+	if flag.Arg(0) == "import-egg" {
+		os.Exit(importEgg(flag.Args()[1:]))
+	}
+
 	// Check for PORT environment variable (for Render/Heroku)
 	if envPort := os.Getenv("PORT"); envPort != "" {
 		port, err := strconv.Atoi(envPort)
@@ -47,25 +56,7 @@ func main() {
 	log.Println("IronHost Master Control Plane starting...")
 
 	// Initialize database connection
-	var db *database.DB
-	var err error
-
-	// Check for DATABASE_URL environment variable (for Supabase/production)
-	if dbURL := os.Getenv("DATABASE_URL"); dbURL != "" {
-		log.Println("Using DATABASE_URL from environment")
-		db, err = database.NewConnectionFromURL(dbURL, 10)
-	} else {
-		// Fall back to flag-based config (for local development)
-		dbConfig := database.Config{
-			Host:     *dbHost,
-			Port:     *dbPort,
-			User:     *dbUser,
-			Password: *dbPassword,
-			Database: *dbName,
-		}
-		db, err = database.NewConnection(dbConfig)
-	}
-
+	db, err := connectDatabase()
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -80,14 +71,19 @@ func main() {
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		ErrorHandler: api.ErrorHandler,
+		// File uploads are streamed to the agent in chunks, so large multipart
+		// bodies are spooled to disk instead of being buffered in memory
+		BodyLimit:         1024 * 1024 * 1024,
+		StreamRequestBody: true,
 	})
 
 	// Middleware
 	// Middleware - Register CORS first to handle preflight requests
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "https://www.parivartan.tech, https://parivartan.tech, https://iron-host.vercel.app, http://localhost:3000",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Range",
 		AllowMethods:     "GET, POST, HEAD, PUT, DELETE, PATCH, OPTIONS",
+		ExposeHeaders:    "Content-Disposition, Content-Length, Content-Range, Accept-Ranges",
 		AllowCredentials: true,
 	}))
 
@@ -117,13 +113,19 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Keep server statuses in line with the nodes: lifecycle events as they
+	// happen, and a periodic full comparison for anything the events miss
+	go monitor.NewEventWatcher(db, grpcPool).Run(ctx)
+	go monitor.NewReconciler(db, grpcPool, *reconcileInterval, *nodeFailureThreshold).Run(ctx)
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
 	log.Println("Received shutdown signal, gracefully stopping...")
+	cancel()
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, 30*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
 	if err := app.ShutdownWithContext(shutdownCtx); err != nil {
@@ -133,3 +135,21 @@ func main() {
 	grpcPool.CloseAll()
 	log.Println("Master Control Plane shutdown complete")
 }
+
+// connectDatabase connects to PostgreSQL using DATABASE_URL if set, or the -db-* flags
+func connectDatabase() (*database.DB, error) {
+	// Check for DATABASE_URL environment variable (for Supabase/production)
+	if dbURL := os.Getenv("DATABASE_URL"); dbURL != "" {
+		log.Println("Using DATABASE_URL from environment")
+		return database.NewConnectionFromURL(dbURL, 10)
+	}
+
+	// Fall back to flag-based config (for local development)
+	return database.NewConnection(database.Config{
+		Host:     *dbHost,
+		Port:     *dbPort,
+		User:     *dbUser,
+		Password: *dbPassword,
+		Database: *dbName,
+	})
+}
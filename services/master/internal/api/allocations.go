@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/ironhost/master/internal/database"
+)
+
+// AllocationHandler handles allocation-related API requests
+type AllocationHandler struct {
+	db *database.DB
+}
+
+// NewAllocationHandler creates a new allocation handler
+func NewAllocationHandler(db *database.DB) *AllocationHandler {
+	return &AllocationHandler{db: db}
+}
+
+// List returns allocations, filtered by the node_id, server_id, assigned and
+// protocol query parameters
+func (h *AllocationHandler) List(c *fiber.Ctx) error {
+	var filter database.AllocationFilter
+	if v := c.Query("node_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid node_id")
+		}
+		filter.NodeID = &id
+	}
+	if v := c.Query("server_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid server_id")
+		}
+		filter.ServerID = &id
+	}
+	if v := c.Query("assigned"); v != "" {
+		assigned, err := strconv.ParseBool(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "assigned must be true or false")
+		}
+		filter.Assigned = &assigned
+	}
+	if v := c.Query("protocol"); v != "" {
+		if v != "tcp" && v != "udp" {
+			return fiber.NewError(fiber.StatusBadRequest, "protocol must be tcp or udp")
+		}
+		filter.Protocol = v
+	}
+
+	allocations, err := h.db.ListAllocations(c.Context(), filter)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list allocations")
+	}
+	return c.JSON(fiber.Map{"allocations": allocations})
+}
+
+// Create adds a range of free port allocations on a node. The address
+// defaults to the node's hostname, which binds all of its addresses.
+func (h *AllocationHandler) Create(c *fiber.Ctx) error {
+	var req struct {
+		NodeID    uuid.UUID `json:"node_id"`
+		IPAddress string    `json:"ip_address"`
+		PortStart int       `json:"port_start"`
+		PortEnd   int       `json:"port_end"` // Optional, defaults to port_start
+		Protocol  string    `json:"protocol"` // tcp (default) or udp
+		Notes     string    `json:"notes"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	if req.PortEnd == 0 {
+		req.PortEnd = req.PortStart
+	}
+	if req.PortStart < 1 || req.PortEnd > 65535 || req.PortStart > req.PortEnd {
+		return fiber.NewError(fiber.StatusBadRequest, "ports must be a range within 1-65535")
+	}
+	if count := req.PortEnd - req.PortStart + 1; count > database.MaxAllocationBatch {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("at most %d ports can be added at once", database.MaxAllocationBatch))
+	}
+	if req.Protocol == "" {
+		req.Protocol = "tcp"
+	}
+	if req.Protocol != "tcp" && req.Protocol != "udp" {
+		return fiber.NewError(fiber.StatusBadRequest, "protocol must be tcp or udp")
+	}
+	if len(req.Notes) > 255 {
+		return fiber.NewError(fiber.StatusBadRequest, "notes must be at most 255 characters")
+	}
+
+	node, err := h.db.GetNodeByID(c.Context(), req.NodeID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "node not found")
+	}
+	if req.IPAddress == "" {
+		req.IPAddress = node.FQDN
+	} else if net.ParseIP(req.IPAddress) == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "ip_address must be an IP address")
+	}
+
+	allocations, err := h.db.CreateAllocations(c.Context(), node.ID, req.IPAddress, req.Protocol, req.PortStart, req.PortEnd, req.Notes)
+	switch {
+	case errors.Is(err, database.ErrAllocationOverlap):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, database.ErrNodeNotFound):
+		return fiber.NewError(fiber.StatusBadRequest, "node not found")
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to create allocations: "+err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":     "allocations created",
+		"count":       len(allocations),
+		"allocations": allocations,
+	})
+}
+
+// Update changes an allocation's notes
+func (h *AllocationHandler) Update(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid allocation ID")
+	}
+
+	var req struct {
+		Notes string `json:"notes"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if len(req.Notes) > 255 {
+		return fiber.NewError(fiber.StatusBadRequest, "notes must be at most 255 characters")
+	}
+
+	allocation, err := h.db.UpdateAllocationNotes(c.Context(), id, req.Notes)
+	if errors.Is(err, database.ErrAllocationNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "allocation not found")
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to update allocation")
+	}
+	return c.JSON(fiber.Map{"allocation": allocation})
+}
+
+// Delete removes an allocation no server holds
+func (h *AllocationHandler) Delete(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid allocation ID")
+	}
+
+	switch err := h.db.DeleteAllocation(c.Context(), id); {
+	case errors.Is(err, database.ErrAllocationNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "allocation not found")
+	case errors.Is(err, database.ErrAllocationAssigned):
+		return fiber.NewError(fiber.StatusConflict, "allocation is assigned to a server; release it first")
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete allocation")
+	}
+	return c.JSON(fiber.Map{"message": "allocation deleted"})
+}
@@ -1,14 +1,22 @@
 package api
 
 import (
+	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
+	"hash/crc32"
+	"io"
 	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/ironhost/master/internal/database"
 	mastergrpc "github.com/ironhost/master/internal/grpc"
@@ -26,98 +34,126 @@ func NewFileHandler(db *database.DB, grpcPool *mastergrpc.ClientPool) *FileHandl
 	return &FileHandler{db: db, grpcPool: grpcPool}
 }
 
-// sendFileCommand sends a __file: prefixed command to the Agent and returns the parsed JSON response
-func (h *FileHandler) sendFileCommand(c *fiber.Ctx, serverID uuid.UUID, operation, body string) (map[string]interface{}, error) {
+// agentForServer verifies that the caller owns the server and returns an
+// authenticated Agent client for the node hosting it
+func (h *FileHandler) agentForServer(c *fiber.Ctx) (agentpb.AgentServiceClient, context.Context, string, error) {
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, nil, "", fiber.NewError(fiber.StatusBadRequest, "invalid server ID")
+	}
+
 	userID := c.Locals("userID").(uuid.UUID)
 
 	server, err := h.db.GetServer(c.Context(), serverID)
 	if err != nil || server.UserID != userID {
-		return nil, fiber.NewError(fiber.StatusNotFound, "server not found")
+		return nil, nil, "", fiber.NewError(fiber.StatusNotFound, "server not found")
 	}
 
 	node, err := h.db.GetNodeByID(c.Context(), server.NodeID)
 	if err != nil {
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "node not found")
+		return nil, nil, "", fiber.NewError(fiber.StatusInternalServerError, "node not found")
 	}
 
 	conn, err := h.grpcPool.GetClient(node.GetAddress(), node.Scheme == "http")
 	if err != nil {
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to connect to agent")
+		return nil, nil, "", fiber.NewError(fiber.StatusInternalServerError, "failed to connect to agent")
 	}
 
 	client := agentpb.NewAgentServiceClient(conn)
 	ctx := metadata.AppendToOutgoingContext(c.Context(), "authorization", "Bearer "+node.DaemonTokenHash)
 
-	command := fmt.Sprintf("__file:%s:%s", operation, body)
-	resp, err := client.SendCommand(ctx, &agentpb.SendCommandRequest{
-		ServerId: server.ID.String(),
-		Command:  command,
-	})
-	if err != nil {
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "file operation failed: "+err.Error())
-	}
+	return client, ctx, server.ID.String(), nil
+}
 
-	// Parse JSON response from Agent
-	var result map[string]interface{}
-	if err := json.Unmarshal([]byte(resp.ErrorMessage), &result); err != nil {
-		log.Printf("File operation response parse error: %v, raw: %s", err, resp.ErrorMessage)
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "invalid response from agent")
+// fileRPCError maps a gRPC status returned by the Agent's file RPCs to an HTTP error
+func fileRPCError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return fiber.NewError(fiber.StatusInternalServerError, "file operation failed: "+err.Error())
 	}
 
-	// Check for errors in the response
-	if errMsg, ok := result["error"].(string); ok && errMsg != "" {
-		return nil, fiber.NewError(fiber.StatusBadRequest, errMsg)
+	code := fiber.StatusInternalServerError
+	switch st.Code() {
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		code = fiber.StatusBadRequest
+	case codes.NotFound:
+		code = fiber.StatusNotFound
+	case codes.PermissionDenied:
+		code = fiber.StatusForbidden
+	case codes.AlreadyExists:
+		code = fiber.StatusConflict
+	case codes.ResourceExhausted:
+		code = fiber.StatusRequestEntityTooLarge
+	case codes.Unavailable, codes.DeadlineExceeded:
+		code = fiber.StatusServiceUnavailable
 	}
 
-	return result, nil
+	return fiber.NewError(code, st.Message())
 }
 
 // ListFiles lists files in a server's directory
 // GET /servers/:id/files?path=
 func (h *FileHandler) ListFiles(c *fiber.Ctx) error {
-	id, err := uuid.Parse(c.Params("id"))
+	client, ctx, serverID, err := h.agentForServer(c)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid server ID")
+		return err
 	}
 
-	path := c.Query("path", "")
-	result, err := h.sendFileCommand(c, id, "list", path)
+	resp, err := client.ListFiles(ctx, &agentpb.ListFilesRequest{
+		ServerId: serverID,
+		Path:     c.Query("path", ""),
+	})
 	if err != nil {
-		return err
+		return fileRPCError(err)
 	}
 
-	return c.JSON(result)
+	files := make([]fiber.Map, 0, len(resp.Files))
+	for _, f := range resp.Files {
+		files = append(files, fiber.Map{
+			"name":         f.Name,
+			"path":         f.Path,
+			"is_directory": f.IsDirectory,
+			"size":         f.Size,
+			"modified_at":  f.ModifiedAt,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":      true,
+		"files":        files,
+		"current_path": resp.CurrentPath,
+	})
 }
 
 // ReadFile reads a file's content
 // GET /servers/:id/files/content?path=
 func (h *FileHandler) ReadFile(c *fiber.Ctx) error {
-	id, err := uuid.Parse(c.Params("id"))
-	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid server ID")
-	}
-
-	path := c.Query("path", "")
-	if path == "" {
+	filePath := c.Query("path", "")
+	if filePath == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "path is required")
 	}
 
-	result, err := h.sendFileCommand(c, id, "read", path)
+	client, ctx, serverID, err := h.agentForServer(c)
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(result)
+	resp, err := client.ReadFile(ctx, &agentpb.ReadFileRequest{ServerId: serverID, Path: filePath})
+	if err != nil {
+		return fileRPCError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"content":   resp.Content,
+		"file_name": resp.Name,
+		"file_size": resp.Size,
+	})
 }
 
 // WriteFile writes content to a file
 // PUT /servers/:id/files/content
 func (h *FileHandler) WriteFile(c *fiber.Ctx) error {
-	id, err := uuid.Parse(c.Params("id"))
-	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid server ID")
-	}
-
 	var req struct {
 		Path    string `json:"path"`
 		Content string `json:"content"`
@@ -125,61 +161,401 @@ func (h *FileHandler) WriteFile(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
 	}
+	if req.Path == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "path is required")
+	}
 
-	body, _ := json.Marshal(req)
-	result, err := h.sendFileCommand(c, id, "write", string(body))
+	client, ctx, serverID, err := h.agentForServer(c)
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(result)
+	if _, err := client.WriteFile(ctx, &agentpb.WriteFileRequest{
+		ServerId: serverID,
+		Path:     req.Path,
+		Content:  req.Content,
+	}); err != nil {
+		return fileRPCError(err)
+	}
+
+	return c.JSON(fiber.Map{"success": true})
 }
 
 // DeleteFile deletes a file or directory
 // DELETE /servers/:id/files?path=
 func (h *FileHandler) DeleteFile(c *fiber.Ctx) error {
-	id, err := uuid.Parse(c.Params("id"))
-	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid server ID")
-	}
-
-	path := c.Query("path", "")
-	if path == "" {
+	filePath := c.Query("path", "")
+	if filePath == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "path is required")
 	}
 
-	result, err := h.sendFileCommand(c, id, "delete", path)
+	client, ctx, serverID, err := h.agentForServer(c)
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(result)
+	if _, err := client.DeleteFile(ctx, &agentpb.DeleteFileRequest{ServerId: serverID, Path: filePath}); err != nil {
+		return fileRPCError(err)
+	}
+
+	return c.JSON(fiber.Map{"success": true})
 }
 
 // RenameFile renames a file or directory
 // POST /servers/:id/files/rename
 func (h *FileHandler) RenameFile(c *fiber.Ctx) error {
-	id, err := uuid.Parse(c.Params("id"))
+	var req struct {
+		OldPath string `json:"old_path"`
+		NewPath string `json:"new_path"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if req.OldPath == "" || req.NewPath == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "old_path and new_path are required")
+	}
+
+	client, ctx, serverID, err := h.agentForServer(c)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid server ID")
+		return err
+	}
+
+	if _, err := client.RenameFile(ctx, &agentpb.RenameFileRequest{
+		ServerId: serverID,
+		OldPath:  req.OldPath,
+		NewPath:  req.NewPath,
+	}); err != nil {
+		return fileRPCError(err)
 	}
 
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// archiveFormats maps the format names accepted by the API to the Agent's enum
+var archiveFormats = map[string]agentpb.ArchiveFormat{
+	"":       agentpb.ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED,
+	"zip":    agentpb.ArchiveFormat_ARCHIVE_FORMAT_ZIP,
+	"tar.gz": agentpb.ArchiveFormat_ARCHIVE_FORMAT_TAR_GZ,
+}
+
+// CompressFiles packs files and directories into an archive
+// POST /servers/:id/files/compress
+func (h *FileHandler) CompressFiles(c *fiber.Ctx) error {
 	var req struct {
-		OldPath string `json:"old_path"`
-		NewPath string `json:"new_path"`
+		Paths       []string `json:"paths"`
+		Destination string   `json:"destination"`
+		Format      string   `json:"format"` // "zip" or "tar.gz"; detected from destination if empty
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
 	}
+	if len(req.Paths) == 0 || req.Destination == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "paths and destination are required")
+	}
+	format, ok := archiveFormats[req.Format]
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "format must be zip or tar.gz")
+	}
 
-	body, _ := json.Marshal(req)
-	result, err := h.sendFileCommand(c, id, "rename", string(body))
+	client, ctx, serverID, err := h.agentForServer(c)
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(result)
+	resp, err := client.CompressFiles(ctx, &agentpb.CompressFilesRequest{
+		ServerId:    serverID,
+		Paths:       req.Paths,
+		Destination: req.Destination,
+		Format:      format,
+	})
+	if err != nil {
+		return fileRPCError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"path":       resp.Path,
+		"file_count": resp.FileCount,
+		"size":       resp.Size,
+	})
 }
 
-// Ensure imports are used
-var _ = context.Background
+// DecompressFile extracts an archive
+// POST /servers/:id/files/decompress
+func (h *FileHandler) DecompressFile(c *fiber.Ctx) error {
+	var req struct {
+		Path        string `json:"path"`
+		Destination string `json:"destination"` // Defaults to the archive's directory
+		Format      string `json:"format"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if req.Path == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "path is required")
+	}
+	format, ok := archiveFormats[req.Format]
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "format must be zip or tar.gz")
+	}
+
+	client, ctx, serverID, err := h.agentForServer(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.DecompressFile(ctx, &agentpb.DecompressFileRequest{
+		ServerId:    serverID,
+		Path:        req.Path,
+		Destination: req.Destination,
+		Format:      format,
+	})
+	if err != nil {
+		return fileRPCError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"path":       resp.Path,
+		"file_count": resp.FileCount,
+		"size":       resp.Size,
+	})
+}
+
+// fileChunkSize is the size of each chunk streamed to the Agent on upload
+const fileChunkSize = 256 * 1024
+
+// crc32cTable is the Castagnoli table used for per-chunk transfer checksums
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// The server's 30s read and write timeouts cover a whole request, which is
+// too short for large transfers. Uploads get a read deadline scaled to their
+// size; downloads refresh the write deadline for every chunk.
+const (
+	// uploadMinRate is the slowest upload, in bytes per second, that still
+	// completes before its read deadline
+	uploadMinRate = 64 * 1024
+	// transferIdleTimeout is how long one chunk of a transfer may take
+	transferIdleTimeout = 30 * time.Second
+)
+
+// UploadFile streams a multipart file upload to the Agent in chunks.
+// Form fields: file, path (target directory), and optionally offset (resume
+// an interrupted upload; the file part then holds only the remaining bytes)
+// and sha256 (checksum of the complete file, verified by the Agent).
+// POST /servers/:id/files/upload
+func (h *FileHandler) UploadFile(c *fiber.Ctx) error {
+	// The multipart body is read by FormFile, after the handler starts
+	if size := c.Request().Header.ContentLength(); size > 0 {
+		deadline := transferIdleTimeout + time.Duration(size/uploadMinRate)*time.Second
+		c.Context().Conn().SetReadDeadline(time.Now().Add(deadline))
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "file is required")
+	}
+
+	name := path.Base(fileHeader.Filename)
+	if name == "." || name == "/" || name == ".." {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid file name")
+	}
+	target := path.Join(c.FormValue("path"), name)
+
+	var offset int64
+	if raw := c.FormValue("offset"); raw != "" {
+		offset, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || offset < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid offset")
+		}
+	}
+
+	client, ctx, serverID, err := h.agentForServer(c)
+	if err != nil {
+		return err
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to read upload")
+	}
+	defer src.Close()
+
+	stream, err := client.UploadFile(ctx)
+	if err != nil {
+		return fileRPCError(err)
+	}
+
+	msg := &agentpb.UploadFileRequest{
+		ServerId: serverID,
+		Path:     target,
+		Offset:   offset,
+		Sha256:   c.FormValue("sha256"),
+	}
+	buf := make([]byte, fileChunkSize)
+	for {
+		n, readErr := io.ReadFull(src, buf)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			stream.CloseSend()
+			return fiber.NewError(fiber.StatusBadRequest, "failed to read upload")
+		}
+
+		msg.Data = buf[:n]
+		msg.Crc32C = crc32.Checksum(msg.Data, crc32cTable)
+		if err := stream.Send(msg); err != nil {
+			// io.EOF means the Agent rejected the upload; the real error comes from CloseAndRecv
+			break
+		}
+		msg = &agentpb.UploadFileRequest{}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return fileRPCError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"path":    target,
+		"size":    resp.Size,
+		"sha256":  resp.Sha256,
+	})
+}
+
+// GetUploadStatus returns how many bytes of an interrupted upload the Agent
+// already holds, i.e. the offset to resume from
+// GET /servers/:id/files/upload?path=
+func (h *FileHandler) GetUploadStatus(c *fiber.Ctx) error {
+	filePath := c.Query("path", "")
+	if filePath == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "path is required")
+	}
+
+	client, ctx, serverID, err := h.agentForServer(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.GetUploadStatus(ctx, &agentpb.UploadStatusRequest{ServerId: serverID, Path: filePath})
+	if err != nil {
+		return fileRPCError(err)
+	}
+
+	return c.JSON(fiber.Map{"success": true, "path": filePath, "offset": resp.Offset})
+}
+
+// DownloadFile streams a file from the Agent to the client. A "Range:
+// bytes=N-" header resumes an interrupted download from byte N.
+// GET /servers/:id/files/download?path=
+func (h *FileHandler) DownloadFile(c *fiber.Ctx) error {
+	filePath := c.Query("path", "")
+	if filePath == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "path is required")
+	}
+
+	offset, err := parseRangeStart(c.Get(fiber.HeaderRange))
+	if err != nil {
+		return fiber.NewError(fiber.StatusRequestedRangeNotSatisfiable, err.Error())
+	}
+
+	client, ctx, serverID, err := h.agentForServer(c)
+	if err != nil {
+		return err
+	}
+
+	// The body is written after this handler returns, so the stream must not
+	// depend on the request context
+	streamCtx, cancel := context.WithCancel(context.Background())
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		streamCtx = metadata.NewOutgoingContext(streamCtx, md)
+	}
+
+	stream, err := client.DownloadFile(streamCtx, &agentpb.DownloadFileRequest{
+		ServerId: serverID,
+		Path:     filePath,
+		Offset:   offset,
+	})
+	if err != nil {
+		cancel()
+		return fileRPCError(err)
+	}
+
+	// Wait for the first chunk so errors still map to a proper HTTP status
+	first, err := stream.Recv()
+	if err != nil {
+		cancel()
+		if status.Code(err) == codes.OutOfRange {
+			return fiber.NewError(fiber.StatusRequestedRangeNotSatisfiable, status.Convert(err).Message())
+		}
+		return fileRPCError(err)
+	}
+
+	if offset > 0 && offset >= first.Size {
+		cancel()
+		return fiber.NewError(fiber.StatusRequestedRangeNotSatisfiable, "range start is beyond the end of the file")
+	}
+
+	c.Attachment(first.Name)
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	if offset > 0 {
+		c.Status(fiber.StatusPartialContent)
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", offset, first.Size-1, first.Size))
+	}
+
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		chunk := first
+		for {
+			conn.SetWriteDeadline(time.Now().Add(transferIdleTimeout))
+			// A corrupt chunk aborts the response; the short body tells the
+			// client to resume with a Range request
+			if crc32.Checksum(chunk.Data, crc32cTable) != chunk.Crc32C {
+				log.Printf("Download %s: checksum mismatch at offset %d", filePath, chunk.Offset)
+				return
+			}
+			if _, err := w.Write(chunk.Data); err != nil {
+				return
+			}
+			if err := w.Flush(); err != nil {
+				return // Client went away
+			}
+
+			chunk, err = stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				log.Printf("Download %s: stream error: %v", filePath, err)
+				return
+			}
+		}
+	})
+	c.Context().Response.Header.SetContentLength(int(first.Size - offset))
+
+	return nil
+}
+
+// parseRangeStart returns the start of a "bytes=N-" Range header, or 0 if
+// the header is empty. Other range forms are not supported.
+func parseRangeStart(header string) (int64, error) {
+	if header == "" {
+		return 0, nil
+	}
+
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || !strings.HasSuffix(spec, "-") || strings.Contains(spec, ",") {
+		return 0, fmt.Errorf("only \"bytes=N-\" ranges are supported")
+	}
+
+	start, err := strconv.ParseInt(strings.TrimSuffix(spec, "-"), 10, 64)
+	if err != nil || start < 0 {
+		return 0, fmt.Errorf("invalid range start")
+	}
+	return start, nil
+}
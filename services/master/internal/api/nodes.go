@@ -2,6 +2,9 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -38,27 +41,113 @@ func (h *NodeHandler) List(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"nodes": nodes})
 }
 
-// Get returns a specific node
+// nodeStatsTimeout bounds the live stats fetched for node details
+const nodeStatsTimeout = 5 * time.Second
+
+// Get returns a specific node with its servers, allocations and live stats.
+// Stats are null, with stats_error set, if the agent cannot be reached.
 func (h *NodeHandler) Get(c *fiber.Ctx) error {
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid node ID")
 	}
-	_ = id
-	return c.JSON(fiber.Map{"node": nil})
+
+	node, err := h.db.GetNodeByID(c.Context(), id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "node not found")
+	}
+	servers, err := h.db.ListServersByNode(c.Context(), node.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list servers")
+	}
+	allocations, err := h.db.ListAllocations(c.Context(), database.AllocationFilter{NodeID: &node.ID})
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list allocations")
+	}
+
+	resp := fiber.Map{
+		"node":        node,
+		"servers":     servers,
+		"allocations": allocations,
+		"stats":       nil,
+	}
+	if stats, err := fetchNodeStats(h.grpcPool, node); err != nil {
+		resp["stats_error"] = err.Error()
+	} else {
+		resp["stats"] = nodeStatsMap(stats)
+	}
+	return c.JSON(resp)
+}
+
+// fetchNodeStats asks a node's agent for its resource stats
+func fetchNodeStats(grpcPool *mastergrpc.ClientPool, node *database.Node) (*agentpb.NodeStats, error) {
+	conn, err := grpcPool.GetClient(node.GetAddress(), node.Scheme == "http")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to agent: %w", err)
+	}
+	client := agentpb.NewAgentServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), nodeStatsTimeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+node.DaemonTokenHash)
+
+	return client.GetNodeStats(ctx, &emptypb.Empty{})
+}
+
+// nodeStatsMap converts an agent's stats for API responses
+func nodeStatsMap(stats *agentpb.NodeStats) fiber.Map {
+	return fiber.Map{
+		"total_memory_bytes":     stats.TotalMemoryBytes,
+		"available_memory_bytes": stats.AvailableMemoryBytes,
+		"total_disk_bytes":       stats.TotalDiskBytes,
+		"available_disk_bytes":   stats.AvailableDiskBytes,
+		"cpu_usage_percent":      stats.CpuUsagePercent,
+		"running_containers":     stats.RunningContainers,
+		"uptime_seconds":         stats.UptimeSeconds,
+	}
+}
+
+// newDaemonToken returns a random token for a node's agent
+func newDaemonToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// checkNodeLabels validates the labels placement matches nodes on
+func checkNodeLabels(labels map[string]string) error {
+	if len(labels) > 32 {
+		return fiber.NewError(fiber.StatusBadRequest, "a node can have at most 32 labels")
+	}
+	for key, value := range labels {
+		if key == "" || len(key) > 63 || len(value) > 255 {
+			return fiber.NewError(fiber.StatusBadRequest, "label keys must be 1-63 characters and values at most 255")
+		}
+		if value == "*" {
+			return fiber.NewError(fiber.StatusBadRequest, `"*" cannot be a label value; placement uses it to match any value`)
+		}
+	}
+	return nil
 }
 
 // Create registers a new node
 func (h *NodeHandler) Create(c *fiber.Ctx) error {
 	var req struct {
-		Name        string `json:"name"`
-		FQDN        string `json:"fqdn"`
-		Scheme      string `json:"scheme"`
-		GRPCPort    int    `json:"grpc_port"`
-		Location    string `json:"location"`
-		MemoryTotal int64  `json:"memory_total"`
-		DiskTotal   int64  `json:"disk_total"`
-		DaemonToken string `json:"daemon_token"`
+		Name             string            `json:"name"`
+		FQDN             string            `json:"fqdn"`
+		Scheme           string            `json:"scheme"`
+		GRPCPort         int               `json:"grpc_port"`
+		Location         string            `json:"location"`
+		Labels           map[string]string `json:"labels"`
+		MemoryTotal      int64             `json:"memory_total"` // 0 = unlimited
+		CPUTotal         int64             `json:"cpu_total"`
+		DiskTotal        int64             `json:"disk_total"`
+		MemoryOvercommit float64           `json:"memory_overcommit"` // Defaults to 1
+		CPUOvercommit    float64           `json:"cpu_overcommit"`
+		DiskOvercommit   float64           `json:"disk_overcommit"`
+		DaemonToken      string            `json:"daemon_token"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -71,13 +160,39 @@ func (h *NodeHandler) Create(c *fiber.Ctx) error {
 	if req.GRPCPort == 0 {
 		req.GRPCPort = 8443
 	}
+	if err := checkNodeLabels(req.Labels); err != nil {
+		return err
+	}
+	if req.MemoryTotal < 0 || req.CPUTotal < 0 || req.DiskTotal < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "totals cannot be negative (0 = unlimited)")
+	}
+	if req.MemoryOvercommit < 0 || req.CPUOvercommit < 0 || req.DiskOvercommit < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "overcommit ratios must be positive")
+	}
 
 	// Store the token plaintext for simplicity (in production, hash it)
 	// For now we just store plaintext since Agent also gets plaintext
 	daemonTokenHash := req.DaemonToken
 
-	node, err := h.db.CreateNode(c.Context(), req.Name, req.FQDN, req.Scheme, req.GRPCPort, req.MemoryTotal, req.DiskTotal, daemonTokenHash, req.Location)
-	if err != nil {
+	node := &database.Node{
+		Name:             req.Name,
+		FQDN:             req.FQDN,
+		Scheme:           req.Scheme,
+		GRPCPort:         req.GRPCPort,
+		Location:         req.Location,
+		Labels:           req.Labels,
+		MemoryTotal:      req.MemoryTotal,
+		CPUTotal:         req.CPUTotal,
+		DiskTotal:        req.DiskTotal,
+		MemoryOvercommit: req.MemoryOvercommit,
+		CPUOvercommit:    req.CPUOvercommit,
+		DiskOvercommit:   req.DiskOvercommit,
+		DaemonTokenHash:  daemonTokenHash,
+	}
+	switch err := h.db.CreateNode(c.Context(), node); {
+	case errors.Is(err, database.ErrNodeNameTaken):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case err != nil:
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to create node: "+err.Error())
 	}
 
@@ -88,28 +203,212 @@ func (h *NodeHandler) Create(c *fiber.Ctx) error {
 	})
 }
 
-// Update updates node configuration
+// Update updates node configuration. Only the fields given change. A new
+// token is set with daemon_token, or generated with rotate_token; it is
+// returned once, and the agent must be restarted with it.
 func (h *NodeHandler) Update(c *fiber.Ctx) error {
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid node ID")
 	}
-	_ = id
-	return c.JSON(fiber.Map{"message": "node updated"})
+
+	var req struct {
+		Name             *string            `json:"name"`
+		FQDN             *string            `json:"fqdn"`
+		Scheme           *string            `json:"scheme"`
+		GRPCPort         *int               `json:"grpc_port"`
+		Location         *string            `json:"location"`
+		Labels           *map[string]string `json:"labels"` // Replaces all labels
+		MemoryTotal      *int64             `json:"memory_total"`
+		CPUTotal         *int64             `json:"cpu_total"`
+		DiskTotal        *int64             `json:"disk_total"`
+		MemoryOvercommit *float64           `json:"memory_overcommit"`
+		CPUOvercommit    *float64           `json:"cpu_overcommit"`
+		DiskOvercommit   *float64           `json:"disk_overcommit"`
+		DaemonToken      *string            `json:"daemon_token"`
+		RotateToken      bool               `json:"rotate_token"`
+		MaintenanceMode  *bool              `json:"maintenance_mode"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	node, err := h.db.GetNodeByID(c.Context(), id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "node not found")
+	}
+	oldAddress, oldScheme := node.GetAddress(), node.Scheme
+
+	if req.Name != nil {
+		if *req.Name == "" || len(*req.Name) > 100 {
+			return fiber.NewError(fiber.StatusBadRequest, "name must be between 1 and 100 characters")
+		}
+		node.Name = *req.Name
+	}
+	if req.FQDN != nil {
+		if *req.FQDN == "" || len(*req.FQDN) > 255 {
+			return fiber.NewError(fiber.StatusBadRequest, "fqdn must be between 1 and 255 characters")
+		}
+		node.FQDN = *req.FQDN
+	}
+	if req.Scheme != nil {
+		if *req.Scheme != "http" && *req.Scheme != "https" {
+			return fiber.NewError(fiber.StatusBadRequest, "scheme must be http or https")
+		}
+		node.Scheme = *req.Scheme
+	}
+	if req.GRPCPort != nil {
+		if *req.GRPCPort < 1 || *req.GRPCPort > 65535 {
+			return fiber.NewError(fiber.StatusBadRequest, "grpc_port must be between 1 and 65535")
+		}
+		node.GRPCPort = *req.GRPCPort
+	}
+	if req.Location != nil {
+		if len(*req.Location) > 100 {
+			return fiber.NewError(fiber.StatusBadRequest, "location must be at most 100 characters")
+		}
+		node.Location = *req.Location
+	}
+	if req.Labels != nil {
+		if err := checkNodeLabels(*req.Labels); err != nil {
+			return err
+		}
+		node.Labels = *req.Labels
+	}
+	// A total lowered below what is allocated leaves the node full until
+	// enough servers are removed or shrunk
+	for _, total := range []struct {
+		name  string
+		value *int64
+		field *int64
+	}{
+		{"memory_total", req.MemoryTotal, &node.MemoryTotal},
+		{"cpu_total", req.CPUTotal, &node.CPUTotal},
+		{"disk_total", req.DiskTotal, &node.DiskTotal},
+	} {
+		if total.value == nil {
+			continue
+		}
+		if *total.value < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, total.name+" cannot be negative (0 = unlimited)")
+		}
+		*total.field = *total.value
+	}
+	for _, ratio := range []struct {
+		name  string
+		value *float64
+		field *float64
+	}{
+		{"memory_overcommit", req.MemoryOvercommit, &node.MemoryOvercommit},
+		{"cpu_overcommit", req.CPUOvercommit, &node.CPUOvercommit},
+		{"disk_overcommit", req.DiskOvercommit, &node.DiskOvercommit},
+	} {
+		if ratio.value == nil {
+			continue
+		}
+		if *ratio.value <= 0 {
+			return fiber.NewError(fiber.StatusBadRequest, ratio.name+" must be positive")
+		}
+		*ratio.field = *ratio.value
+	}
+	if req.MaintenanceMode != nil {
+		node.MaintenanceMode = *req.MaintenanceMode
+	}
+
+	var newToken string
+	switch {
+	case req.RotateToken && req.DaemonToken != nil:
+		return fiber.NewError(fiber.StatusBadRequest, "give either daemon_token or rotate_token")
+	case req.RotateToken:
+		if newToken, err = newDaemonToken(); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		}
+	case req.DaemonToken != nil:
+		if len(*req.DaemonToken) < 16 || len(*req.DaemonToken) > 255 {
+			return fiber.NewError(fiber.StatusBadRequest, "daemon_token must be between 16 and 255 characters")
+		}
+		newToken = *req.DaemonToken
+	}
+	if newToken != "" {
+		node.DaemonTokenHash = newToken
+	}
+
+	switch err := h.db.UpdateNode(c.Context(), node); {
+	case errors.Is(err, database.ErrNodeNameTaken):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, database.ErrNodeNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "node not found")
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to update node")
+	}
+
+	// The pooled connection still dials the old address, or with the old credentials
+	if node.GetAddress() != oldAddress || node.Scheme != oldScheme {
+		h.grpcPool.RemoveClient(oldAddress)
+	}
+
+	resp := fiber.Map{"message": "node updated", "node": node}
+	if newToken != "" {
+		resp["daemon_token"] = newToken // Return token for Agent configuration
+	}
+	return c.JSON(resp)
 }
 
-// Delete removes a node
+// Delete removes a node. A node with servers or assigned allocations is
+// refused unless ?force=true is given: then its servers are deleted from the
+// database, and from the agent if it can be reached.
 func (h *NodeHandler) Delete(c *fiber.Ctx) error {
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid node ID")
 	}
+	force := c.QueryBool("force")
 
-	if err := h.db.DeleteNode(c.Context(), id); err != nil {
+	node, err := h.db.GetNodeByID(c.Context(), id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "node not found")
+	}
+
+	serverIDs, err := h.db.DeleteNode(c.Context(), id, force)
+	switch {
+	case errors.Is(err, database.ErrNodeInUse):
+		return fiber.NewError(fiber.StatusConflict, err.Error()+"; delete them first or pass ?force=true")
+	case errors.Is(err, database.ErrNodeNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "node not found")
+	case err != nil:
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete node")
 	}
 
-	return c.JSON(fiber.Map{"message": "node deleted"})
+	if len(serverIDs) > 0 {
+		h.evacuate(c.Context(), node, serverIDs)
+	}
+	h.grpcPool.RemoveClient(node.GetAddress())
+	return c.JSON(fiber.Map{"message": "node deleted", "servers_deleted": len(serverIDs)})
+}
+
+// evacuate deletes the containers of a force-deleted node's servers, so they
+// do not keep running unmanaged. An unreachable node is skipped; its
+// containers show up as orphans if it returns.
+func (h *NodeHandler) evacuate(ctx context.Context, node *database.Node, serverIDs []uuid.UUID) {
+	conn, err := h.grpcPool.GetClient(node.GetAddress(), node.Scheme == "http")
+	if err != nil {
+		log.Printf("Node %s delete: failed to connect to agent, leaving its containers: %v", node.Name, err)
+		return
+	}
+	client := agentpb.NewAgentServiceClient(conn)
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+node.DaemonTokenHash)
+
+	for _, serverID := range serverIDs {
+		rpcCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		resp, err := client.DeleteServer(rpcCtx, &agentpb.ServerIdentifier{ServerId: serverID.String()})
+		cancel()
+		switch {
+		case err != nil:
+			log.Printf("Node %s delete: DeleteServer RPC for %s failed: %v", node.Name, serverID, err)
+		case !resp.Success:
+			log.Printf("Node %s delete: agent failed to delete %s: %s", node.Name, serverID, resp.ErrorMessage)
+		}
+	}
 }
 
 // GetStats returns real-time resource stats from a registered node
@@ -146,18 +445,66 @@ func (h *NodeHandler) GetStats(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusServiceUnavailable, "failed to get node stats: "+err.Error())
 	}
 
+	resp := nodeStatsMap(stats)
+	resp["node_id"] = node.ID
+	resp["node_name"] = node.Name
+	return c.JSON(fiber.Map{"stats": resp})
+}
+
+// GetOrphans lists containers and data directories on a node that belong to
+// no server, so an admin can clean them up
+func (h *NodeHandler) GetOrphans(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid node ID")
+	}
+
+	node, err := h.db.GetNodeByID(c.Context(), id)
+	if err != nil || node == nil {
+		return fiber.NewError(fiber.StatusNotFound, "node not found")
+	}
+
+	serverIDs, err := h.db.ListServerIDsByNode(c.Context(), node.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list servers")
+	}
+	known := make([]string, len(serverIDs))
+	for i, serverID := range serverIDs {
+		known[i] = serverID.String()
+	}
+
+	conn, err := h.grpcPool.GetClient(node.GetAddress(), node.Scheme == "http")
+	if err != nil {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "failed to connect to agent: "+err.Error())
+	}
+
+	client := agentpb.NewAgentServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(c.Context(), "authorization", "Bearer "+node.DaemonTokenHash)
+
+	resp, err := client.GetOrphans(ctx, &agentpb.GetOrphansRequest{KnownServerIds: known})
+	if err != nil {
+		log.Printf("GetOrphans RPC failed for node %s: %v", node.Name, err)
+		return fiber.NewError(fiber.StatusServiceUnavailable, "failed to get orphans: "+err.Error())
+	}
+
+	orphans := make([]fiber.Map, 0, len(resp.Orphans))
+	for _, o := range resp.Orphans {
+		kind := "container"
+		if o.Kind == agentpb.OrphanKind_ORPHAN_KIND_DATA_DIRECTORY {
+			kind = "data_directory"
+		}
+		orphans = append(orphans, fiber.Map{
+			"kind":         kind,
+			"server_id":    o.ServerId,
+			"container_id": o.ContainerId,
+			"path":         o.Path,
+			"reason":       o.Reason,
+		})
+	}
+
 	return c.JSON(fiber.Map{
-		"stats": fiber.Map{
-			"node_id":                node.ID,
-			"node_name":              node.Name,
-			"total_memory_bytes":     stats.TotalMemoryBytes,
-			"available_memory_bytes": stats.AvailableMemoryBytes,
-			"total_disk_bytes":       stats.TotalDiskBytes,
-			"available_disk_bytes":   stats.AvailableDiskBytes,
-			"cpu_usage_percent":      stats.CpuUsagePercent,
-			"running_containers":     stats.RunningContainers,
-			"uptime_seconds":         stats.UptimeSeconds,
-		},
+		"orphans":       orphans,
+		"reconciled_at": time.Unix(resp.ReconciledAt, 0),
 	})
 }
 
@@ -226,61 +573,6 @@ func (h *NodeHandler) Probe(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"success":   true,
 		"reachable": true,
-		"stats": fiber.Map{
-			"total_memory_bytes":     stats.TotalMemoryBytes,
-			"available_memory_bytes": stats.AvailableMemoryBytes,
-			"total_disk_bytes":       stats.TotalDiskBytes,
-			"available_disk_bytes":   stats.AvailableDiskBytes,
-			"cpu_usage_percent":      stats.CpuUsagePercent,
-			"running_containers":     stats.RunningContainers,
-			"uptime_seconds":         stats.UptimeSeconds,
-		},
-	})
-}
-
-// AllocationHandler handles allocation-related API requests
-type AllocationHandler struct {
-	db *database.DB
-}
-
-// NewAllocationHandler creates a new allocation handler
-func NewAllocationHandler(db *database.DB) *AllocationHandler {
-	return &AllocationHandler{db: db}
-}
-
-// List returns allocations (optionally filtered by node)
-func (h *AllocationHandler) List(c *fiber.Ctx) error {
-	nodeID := c.Query("node_id")
-	_ = nodeID
-	return c.JSON(fiber.Map{"allocations": []interface{}{}})
-}
-
-// Create creates new port allocations
-func (h *AllocationHandler) Create(c *fiber.Ctx) error {
-	var req struct {
-		NodeID    uuid.UUID `json:"node_id"`
-		IPAddress string    `json:"ip_address"`
-		PortStart int       `json:"port_start"`
-		PortEnd   int       `json:"port_end"`
-	}
-
-	if err := c.BodyParser(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
-	}
-
-	// TODO: Create allocations in database
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "allocations created",
-		"count":   req.PortEnd - req.PortStart + 1,
+		"stats":     nodeStatsMap(stats),
 	})
 }
-
-// Delete removes an allocation
-func (h *AllocationHandler) Delete(c *fiber.Ctx) error {
-	id, err := uuid.Parse(c.Params("id"))
-	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid allocation ID")
-	}
-	_ = id
-	return c.JSON(fiber.Map{"message": "allocation deleted"})
-}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/ironhost/master/internal/placement"
+)
+
+// placeServer picks a node for a server created without a node_id. Live
+// stats are fetched from every node that could take it, in parallel.
+func (h *ServerHandler) placeServer(ctx context.Context, req placement.Request, protocol string) (*placement.Decision, error) {
+	if err := placement.CheckRequest(&req.Placement); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	nodes, err := h.db.ListNodes(ctx)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to list nodes")
+	}
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	free, err := h.db.CountFreeAllocations(ctx, protocol)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to count free allocations")
+	}
+
+	candidates := make([]placement.Candidate, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		candidates[i] = placement.Candidate{Node: node, FreeAllocations: free[node.ID]}
+		// Nodes that are rejected anyway are not asked
+		if node.MaintenanceMode || node.UnreachableSince != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(c *placement.Candidate) {
+			defer wg.Done()
+			c.Stats, c.StatsError = fetchNodeStats(h.grpcPool, c.Node)
+		}(&candidates[i])
+	}
+	wg.Wait()
+
+	decision, err := placement.Choose(req, candidates)
+	if errors.Is(err, placement.ErrNoNode) {
+		return decision, fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	return decision, err
+}
@@ -63,6 +63,17 @@ func RegisterRoutes(app *fiber.App, db *database.DB, grpcPool *mastergrpc.Client
 	nodes.Put("/:id", nodeHandler.Update)
 	nodes.Delete("/:id", nodeHandler.Delete)
 	nodes.Get("/:id/stats", nodeHandler.GetStats)
+	nodes.Get("/:id/orphans", nodeHandler.GetOrphans)
+
+	// Server templates (listing for everyone, changes admin only)
+	templates := protected.Group("/templates")
+	templateHandler := NewTemplateHandler(db)
+	templates.Get("/", templateHandler.List)
+	templates.Get("/:id", templateHandler.Get)
+	templates.Post("/", AdminMiddleware(db), templateHandler.Create)
+	templates.Post("/import", AdminMiddleware(db), templateHandler.Import)
+	templates.Put("/:id", AdminMiddleware(db), templateHandler.Update)
+	templates.Delete("/:id", AdminMiddleware(db), templateHandler.Delete)
 
 	// Server management
 	servers := protected.Group("/servers")
@@ -78,8 +89,12 @@ func RegisterRoutes(app *fiber.App, db *database.DB, grpcPool *mastergrpc.Client
 	servers.Post("/:id/stop", serverHandler.Stop)
 	servers.Post("/:id/restart", serverHandler.Restart)
 	servers.Post("/:id/reset", serverHandler.ResetServer)
+	servers.Post("/:id/reinstall", serverHandler.Reinstall)
 	servers.Post("/:id/command", serverHandler.SendCommand)
 	servers.Get("/:id/logs", serverHandler.GetLogs)
+	servers.Get("/:id/stats", serverHandler.GetStats)
+	servers.Post("/:id/allocations", serverHandler.AttachAllocation)
+	servers.Delete("/:id/allocations/:allocId", serverHandler.ReleaseAllocation)
 
 	// WebSocket console streaming – upgrade middleware + handler
 	servers.Use("/:id/console", func(c *fiber.Ctx) error {
@@ -97,6 +112,11 @@ func RegisterRoutes(app *fiber.App, db *database.DB, grpcPool *mastergrpc.Client
 	servers.Put("/:id/files/content", fileHandler.WriteFile)
 	servers.Delete("/:id/files", fileHandler.DeleteFile)
 	servers.Post("/:id/files/rename", fileHandler.RenameFile)
+	servers.Post("/:id/files/upload", fileHandler.UploadFile)
+	servers.Get("/:id/files/upload", fileHandler.GetUploadStatus)
+	servers.Get("/:id/files/download", fileHandler.DownloadFile)
+	servers.Post("/:id/files/compress", fileHandler.CompressFiles)
+	servers.Post("/:id/files/decompress", fileHandler.DecompressFile)
 
 	// Allocations (admin only)
 	allocations := protected.Group("/allocations")
@@ -104,6 +124,7 @@ func RegisterRoutes(app *fiber.App, db *database.DB, grpcPool *mastergrpc.Client
 	allocationHandler := NewAllocationHandler(db)
 	allocations.Get("/", allocationHandler.List)
 	allocations.Post("/", allocationHandler.Create)
+	allocations.Put("/:id", allocationHandler.Update)
 	allocations.Delete("/:id", allocationHandler.Delete)
 }
 
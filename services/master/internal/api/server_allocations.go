@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ironhost/master/internal/database"
+	agentpb "github.com/ironhost/master/internal/grpc/ironhost/v1"
+	"github.com/ironhost/master/internal/models"
+)
+
+// maxServerAllocations caps the allocations one server holds, the primary included
+const maxServerAllocations = 5
+
+// allocationsToProto converts a server's allocations for the agent. Each
+// carries its protocol and container port, so the agent needs no defaults.
+func allocationsToProto(server *models.Server, allocations []models.Allocation) []*agentpb.Allocation {
+	out := make([]*agentpb.Allocation, 0, len(allocations))
+	for i := range allocations {
+		a := &allocations[i]
+		out = append(out, &agentpb.Allocation{
+			Id:            a.ID.String(),
+			IpAddress:     a.IPAddress,
+			Port:          int32(a.Port),
+			IsPrimary:     server.PrimaryAllocationID != nil && *server.PrimaryAllocationID == a.ID,
+			Protocol:      a.Protocol,
+			ContainerPort: int32(a.Target()),
+		})
+	}
+	return out
+}
+
+// checkAllocations rejects allocation sets the agent could not publish
+func checkAllocations(allocations []models.Allocation) error {
+	if len(allocations) > maxServerAllocations {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("a server can hold at most %d allocations", maxServerAllocations))
+	}
+	mapped := make(map[string]bool, len(allocations))
+	for _, a := range allocations {
+		key := fmt.Sprintf("%d/%s", a.Target(), a.Protocol)
+		if mapped[key] {
+			return fiber.NewError(fiber.StatusBadRequest, "container port "+key+" is already mapped")
+		}
+		mapped[key] = true
+	}
+	return nil
+}
+
+// updateAllocationsOnAgent republishes a server's ports on its node. A server
+// without a container is left alone; it gets its ports when it is created.
+func (h *ServerHandler) updateAllocationsOnAgent(ctx context.Context, server *models.Server, node *database.Node, allocations []models.Allocation) error {
+	conn, err := h.grpcPool.GetClient(node.GetAddress(), node.Scheme == "http")
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to connect to agent")
+	}
+
+	client := agentpb.NewAgentServiceClient(conn)
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+node.DaemonTokenHash)
+
+	resp, err := client.UpdateServerAllocations(ctx, &agentpb.UpdateServerAllocationsRequest{
+		ServerId:    server.ID.String(),
+		Allocations: allocationsToProto(server, allocations),
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "UpdateServerAllocations RPC failed: "+err.Error())
+	}
+	if !resp.Success {
+		return fiber.NewError(fiber.StatusConflict, "agent refused allocation change: "+resp.ErrorMessage)
+	}
+	return nil
+}
+
+// allocationError converts a failed allocation change to an API error
+func allocationError(err error) error {
+	var fe *fiber.Error
+	switch {
+	case errors.As(err, &fe):
+		return fe
+	case errors.Is(err, database.ErrAllocationNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, database.ErrAllocationUnavailable):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, database.ErrPrimaryAllocation):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, "failed to update allocations: "+err.Error())
+}
+
+// AttachAllocation gives a server an extra port (only if the user owns it):
+// a free allocation of its node by ID, or the next free one. The server must
+// be stopped, as its container is recreated to publish the port.
+func (h *ServerHandler) AttachAllocation(c *fiber.Ctx) error {
+	server, err := h.getServerForUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		AllocationID  *uuid.UUID `json:"allocation_id"`  // Optional, else the next free allocation
+		Protocol      string     `json:"protocol"`       // tcp (default) or udp, when no ID is given
+		ContainerPort int        `json:"container_port"` // Port inside the container (0 = same as the host port)
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if req.Protocol == "" {
+		req.Protocol = "tcp"
+	}
+	if req.Protocol != "tcp" && req.Protocol != "udp" {
+		return fiber.NewError(fiber.StatusBadRequest, "protocol must be tcp or udp")
+	}
+	if req.ContainerPort < 0 || req.ContainerPort > 65535 {
+		return fiber.NewError(fiber.StatusBadRequest, "container_port must be between 1 and 65535")
+	}
+	if server.Status == models.StatusInstalling {
+		return fiber.NewError(fiber.StatusConflict, "server is being installed")
+	}
+
+	node, err := h.db.GetNodeByID(c.Context(), server.NodeID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "node not found")
+	}
+
+	allocation, err := h.db.AttachAllocation(c.Context(), server.ID, server.NodeID, req.AllocationID, req.Protocol, req.ContainerPort,
+		func(allocations []models.Allocation) error {
+			if err := checkAllocations(allocations); err != nil {
+				return err
+			}
+			return h.updateAllocationsOnAgent(c.Context(), server, node, allocations)
+		})
+	if err != nil {
+		return allocationError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"allocation": allocation})
+}
+
+// ReleaseAllocation takes an extra port away from a server (only if the user
+// owns it). The primary allocation cannot be released.
+func (h *ServerHandler) ReleaseAllocation(c *fiber.Ctx) error {
+	server, err := h.getServerForUser(c)
+	if err != nil {
+		return err
+	}
+	allocationID, err := uuid.Parse(c.Params("allocId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid allocation ID")
+	}
+	if server.Status == models.StatusInstalling {
+		return fiber.NewError(fiber.StatusConflict, "server is being installed")
+	}
+
+	node, err := h.db.GetNodeByID(c.Context(), server.NodeID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "node not found")
+	}
+
+	err = h.db.ReleaseAllocation(c.Context(), server.ID, allocationID, func(allocations []models.Allocation) error {
+		return h.updateAllocationsOnAgent(c.Context(), server, node, allocations)
+	})
+	if err != nil {
+		return allocationError(err)
+	}
+
+	return c.JSON(fiber.Map{"message": "allocation released"})
+}
@@ -2,20 +2,27 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/ironhost/master/internal/database"
 	mastergrpc "github.com/ironhost/master/internal/grpc"
 	agentpb "github.com/ironhost/master/internal/grpc/ironhost/v1"
 	"github.com/ironhost/master/internal/models"
+	"github.com/ironhost/master/internal/placement"
 )
 
 // blockedCommands are commands that must NOT be sent via the console.
@@ -38,6 +45,19 @@ func isCommandBlocked(raw string) bool {
 	return blockedCommands[base]
 }
 
+// consoleResubscribeDelay is how long the console WebSocket waits before
+// resubscribing to the agent after the log or stats stream ends
+const consoleResubscribeDelay = 2 * time.Second
+
+// consoleStreamName maps the Agent's console stream marker to the name sent
+// to WebSocket clients. Output from TTY containers is always "stdout".
+func consoleStreamName(stream agentpb.ConsoleStream) string {
+	if stream == agentpb.ConsoleStream_CONSOLE_STREAM_STDERR {
+		return "stderr"
+	}
+	return "stdout"
+}
+
 // ServerHandler handles server-related API requests
 type ServerHandler struct {
 	db       *database.DB
@@ -99,20 +119,63 @@ func (h *ServerHandler) Create(c *fiber.Ctx) error {
 	// Get authenticated user ID from JWT claims (set by JWTMiddleware)
 	userID := c.Locals("userID").(uuid.UUID)
 
-	// Look up the target node
-	node, err := h.db.GetNodeByID(c.Context(), req.NodeID)
+	// Servers are created from a template, the Minecraft one unless another is given
+	templateID := models.DefaultTemplateID
+	if req.TemplateID != nil {
+		templateID = *req.TemplateID
+	}
+	template, err := h.db.GetTemplate(c.Context(), templateID)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "node not found: "+err.Error())
+		return fiber.NewError(fiber.StatusBadRequest, "template not found")
 	}
 
-	// Create server model with defaults
-	server := models.NewServerFromRequest(req, userID)
+	// Create server model with the template's defaults
+	server, err := models.NewServerFromRequest(req, template, userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 
-	// Validate user has enough resources in their pool
 	user, err := h.db.GetUserByID(c.Context(), userID)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to get user")
 	}
+
+	// Look up the target node, or let the placement engine pick one
+	var node *database.Node
+	var decision *placement.Decision
+	if req.NodeID != uuid.Nil {
+		node, err = h.db.GetNodeByID(c.Context(), req.NodeID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "node not found: "+err.Error())
+		}
+	} else {
+		preq := placement.Request{MemoryMB: server.MemoryLimit, CPUPercent: server.CPULimit, DiskMB: server.DiskLimit}
+		if req.Placement != nil {
+			preq.Placement = *req.Placement
+		}
+		decision, err = h.placeServer(c.Context(), preq, template.Protocol)
+		if err != nil {
+			// Only admins see why every node was rejected: the reasons name
+			// nodes, their labels and agent addresses
+			if decision != nil && user.IsAdmin {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": true, "message": err.Error(), "placement": decision})
+			}
+			return err
+		}
+		node = decision.Node
+		server.NodeID = node.ID
+	}
+
+	// Check the node can take the server before charging for it. CreateServer
+	// checks again with the node locked, in case another server got there first.
+	if node.MaintenanceMode {
+		return capacityError(database.ErrNodeMaintenance)
+	}
+	if err := node.CheckCapacity(server.MemoryLimit, server.CPULimit, server.DiskLimit); err != nil {
+		return capacityError(err)
+	}
+
+	// Validate user has enough resources in their pool
 	usage, _ := h.db.GetResourceUsage(c.Context(), userID)
 
 	freeRAM := int64(user.ResourceRAM - usage.RAMUsed)
@@ -138,11 +201,14 @@ func (h *ServerHandler) Create(c *fiber.Ctx) error {
 	if err := h.db.CreateServer(c.Context(), server); err != nil {
 		// Refund coins if DB save fails
 		_ = h.db.AddCoins(c.Context(), userID, 50, "refund", "earned", "Server creation failed - refund")
+		if errors.Is(err, database.ErrNodeMaintenance) || errors.Is(err, database.ErrNodeFull) {
+			return capacityError(err)
+		}
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to save server: "+err.Error())
 	}
 
-	// 2. Assign a port allocation
-	allocation, err := h.db.AssignAllocation(c.Context(), server.ID, server.NodeID)
+	// 2. Assign a port allocation for the template's game port
+	_, err = h.db.AssignAllocation(c.Context(), server.ID, server.NodeID, template.Protocol, template.ContainerPort)
 	if err != nil {
 		log.Printf("Failed to assign allocation for server %s: %v", server.ID, err)
 		// Server is saved but has no allocation — still return success
@@ -152,28 +218,46 @@ func (h *ServerHandler) Create(c *fiber.Ctx) error {
 	// 3. Send CreateServer RPC to agent (async — don't block the HTTP response)
 	// 3. Send CreateServer RPC to agent (async — don't block the HTTP response)
 	go func() {
+		// Runs as long as the install script takes
+		err := h.createServerOnAgent(server, node)
+
 		// Use a detached context with timeout for background work
 		ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 		defer cancel()
 
-		if err := h.createServerOnAgent(server, node, allocation); err != nil {
+		if err != nil {
 			log.Printf("Failed to create server %s on agent: %v", server.ID, err)
 			// Update status to reflect failure
-			_ = h.db.UpdateServerStatus(ctx, server.ID, models.StatusOffline)
+			_ = h.db.UpdateServerStatus(ctx, server.ID, failureStatus(err))
 		} else {
-			log.Printf("Server %s created successfully, updating status to running", server.ID)
-			_ = h.db.UpdateServerStatus(ctx, server.ID, models.StatusRunning)
+			// Running is reported by the agent once the server is actually up
+			log.Printf("Server %s created successfully, updating status to starting", server.ID)
+			_ = h.db.UpdateServerStatus(ctx, server.ID, models.StatusStarting)
 		}
 	}()
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+	resp := fiber.Map{
 		"server":  server,
 		"message": "Server creation initiated",
-	})
+	}
+	if decision != nil {
+		if user.IsAdmin {
+			resp["placement"] = decision
+		} else {
+			resp["placement"] = fiber.Map{"node_id": decision.NodeID, "node_name": decision.NodeName}
+		}
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// capacityError converts a node refusing a server or a resize to an API error
+func capacityError(err error) error {
+	return fiber.NewError(fiber.StatusConflict, err.Error())
 }
 
-// createServerOnAgent sends the CreateServer RPC to the agent node
-func (h *ServerHandler) createServerOnAgent(server *models.Server, node *database.Node, allocation *models.Allocation) error {
+// createServerOnAgent sends the CreateServer RPC to the agent node, with
+// every allocation the server holds
+func (h *ServerHandler) createServerOnAgent(server *models.Server, node *database.Node) error {
 	log.Printf("DEBUG: Connecting to agent at %s for server %s", node.GetAddress(), server.ID)
 	// Connect to agent
 	conn, err := h.grpcPool.GetClient(node.GetAddress(), node.Scheme == "http")
@@ -189,19 +273,20 @@ func (h *ServerHandler) createServerOnAgent(server *models.Server, node *databas
 		"authorization", "Bearer "+node.DaemonTokenHash,
 	)
 
+	template, err := h.db.GetTemplate(ctx, server.TemplateID)
+	if err != nil {
+		return fmt.Errorf("failed to load template: %w", err)
+	}
+
 	// Build environment variables
 	var envVars []*agentpb.EnvVar
 	for k, v := range server.Environment {
 		envVars = append(envVars, &agentpb.EnvVar{Key: k, Value: v})
 	}
 
-	// Build allocations
-	var allocations []*agentpb.Allocation
-	if allocation != nil {
-		allocations = append(allocations, &agentpb.Allocation{
-			Port:      int32(allocation.Port),
-			IsPrimary: true,
-		})
+	allocations, err := h.db.ListServerAllocations(ctx, server.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load allocations: %w", err)
 	}
 
 	log.Printf("DEBUG: Sending CreateServer RPC to agent %s...", node.Name)
@@ -214,10 +299,19 @@ func (h *ServerHandler) createServerOnAgent(server *models.Server, node *databas
 			MemoryMb:   server.MemoryLimit,
 			DiskMb:     server.DiskLimit,
 			CpuPercent: int32(server.CPULimit),
+			IoWeight:   int32(server.IOWeight),
 		},
-		Allocations:   allocations,
-		Environment:   envVars,
-		DataDirectory: fmt.Sprintf("/var/lib/ironhost/servers/%s", server.ID.String()),
+		Allocations:      allocationsToProto(server, allocations),
+		Environment:      envVars,
+		DataDirectory:    fmt.Sprintf("/var/lib/ironhost/servers/%s", server.ID.String()),
+		ReadinessProbes:  readinessProbesToProto(template.ReadinessProbes),
+		ContainerPort:    int32(template.ContainerPort),
+		Protocol:         template.Protocol,
+		StartupCommand:   template.RenderStartup(server.Environment, server.MemoryLimit),
+		StopCommand:      template.StopCommand,
+		DataMount:        template.DataMount,
+		Install:          installScriptToProto(template),
+		CommandTransport: string(template.CommandTransport),
 	})
 
 	if err != nil {
@@ -225,8 +319,17 @@ func (h *ServerHandler) createServerOnAgent(server *models.Server, node *databas
 		return fmt.Errorf("CreateServer RPC failed: %w", err)
 	}
 
+	if resp.Installed {
+		if err := h.db.RecordInstall(context.Background(), server.ID, int(resp.InstallExitCode)); err != nil {
+			log.Printf("Failed to record install of server %s: %v", server.ID, err)
+		}
+	}
+
 	if !resp.Success {
 		log.Printf("DEBUG: Agent returned success=false: %s", resp.ErrorMessage)
+		if resp.Installed && resp.InstallExitCode != 0 {
+			return fmt.Errorf("%w: %s", errInstallFailed, resp.ErrorMessage)
+		}
 		return fmt.Errorf("agent reported failure: %s", resp.ErrorMessage)
 	}
 
@@ -234,6 +337,88 @@ func (h *ServerHandler) createServerOnAgent(server *models.Server, node *databas
 	return nil
 }
 
+// errInstallFailed marks errors caused by a template's install script
+var errInstallFailed = errors.New("install script failed")
+
+// failureStatus is the status a server is left in when creating it failed
+func failureStatus(err error) models.ServerStatus {
+	if errors.Is(err, errInstallFailed) {
+		return models.StatusInstallFailed
+	}
+	return models.StatusOffline
+}
+
+// installScriptToProto returns a template's install script for the agent, or
+// nil if it has none
+func installScriptToProto(template *models.ServerTemplate) *agentpb.InstallScript {
+	if template.InstallScript == "" {
+		return nil
+	}
+	return &agentpb.InstallScript{
+		Image:      template.InstallImage,
+		Entrypoint: template.InstallEntrypoint,
+		Script:     template.InstallScript,
+	}
+}
+
+// readinessProbesToProto converts a template's readiness probes for the agent
+func readinessProbesToProto(probes []models.ReadinessProbe) []*agentpb.ReadinessProbe {
+	out := make([]*agentpb.ReadinessProbe, 0, len(probes))
+	for _, p := range probes {
+		kind := agentpb.ProbeKind_PROBE_KIND_UNSPECIFIED
+		switch p.Kind {
+		case "log":
+			kind = agentpb.ProbeKind_PROBE_KIND_LOG
+		case "tcp":
+			kind = agentpb.ProbeKind_PROBE_KIND_TCP
+		case "minecraft_ping":
+			kind = agentpb.ProbeKind_PROBE_KIND_MINECRAFT_PING
+		}
+		out = append(out, &agentpb.ReadinessProbe{Kind: kind, Pattern: p.Pattern, Port: int32(p.Port)})
+	}
+	return out
+}
+
+// applyConfigFiles edits the template's config files in the server's data
+// directory before it starts. Files the game has not created yet are skipped.
+func (h *ServerHandler) applyConfigFiles(ctx context.Context, client agentpb.AgentServiceClient, server *models.Server) error {
+	template, err := h.db.GetTemplate(ctx, server.TemplateID)
+	if err != nil {
+		return fmt.Errorf("failed to load template: %w", err)
+	}
+	if len(template.ConfigFiles) == 0 {
+		return nil
+	}
+
+	placeholders := template.Placeholders(server.Environment, server.MemoryLimit)
+	for i := range template.ConfigFiles {
+		file := &template.ConfigFiles[i]
+		resp, err := client.ReadFile(ctx, &agentpb.ReadFileRequest{ServerId: server.ID.String(), Path: file.Path})
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Path, err)
+		}
+
+		content, err := file.Apply(resp.Content, placeholders)
+		if err != nil {
+			return fmt.Errorf("failed to edit %s: %w", file.Path, err)
+		}
+		if content == resp.Content {
+			continue
+		}
+		if _, err := client.WriteFile(ctx, &agentpb.WriteFileRequest{
+			ServerId: server.ID.String(),
+			Path:     file.Path,
+			Content:  content,
+		}); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
+
 // Update updates server settings (name + resources)
 func (h *ServerHandler) Update(c *fiber.Ctx) error {
 	server, err := h.getServerForUser(c)
@@ -248,6 +433,7 @@ func (h *ServerHandler) Update(c *fiber.Ctx) error {
 		MemoryLimit *int64  `json:"memory_limit"`
 		CPULimit    *int    `json:"cpu_limit"`
 		DiskLimit   *int64  `json:"disk_limit"`
+		IOWeight    *int    `json:"io_weight"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
@@ -267,6 +453,7 @@ func (h *ServerHandler) Update(c *fiber.Ctx) error {
 	newMemory := server.MemoryLimit
 	newCPU := server.CPULimit
 	newDisk := server.DiskLimit
+	newIOWeight := server.IOWeight
 
 	if req.MemoryLimit != nil {
 		newMemory = *req.MemoryLimit
@@ -277,9 +464,15 @@ func (h *ServerHandler) Update(c *fiber.Ctx) error {
 	if req.DiskLimit != nil {
 		newDisk = *req.DiskLimit
 	}
+	if req.IOWeight != nil {
+		newIOWeight = *req.IOWeight
+		if newIOWeight != 0 && (newIOWeight < 10 || newIOWeight > 1000) {
+			return fiber.NewError(fiber.StatusBadRequest, "io_weight must be between 10 and 1000, or 0 for the default")
+		}
+	}
 
 	// Validate resource changes against user pool
-	if newMemory != server.MemoryLimit || newCPU != server.CPULimit || newDisk != server.DiskLimit {
+	if newMemory != server.MemoryLimit || newCPU != server.CPULimit || newDisk != server.DiskLimit || newIOWeight != server.IOWeight {
 		user, err := h.db.GetUserByID(c.Context(), userID)
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "failed to get user")
@@ -312,7 +505,24 @@ func (h *ServerHandler) Update(c *fiber.Ctx) error {
 			return fiber.NewError(fiber.StatusBadRequest, "minimum 512 MB storage required")
 		}
 
-		if err := h.db.UpdateServerResources(c.Context(), server.ID, newMemory, newCPU, newDisk); err != nil {
+		node, err := h.db.GetNodeByID(c.Context(), server.NodeID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "node not found")
+		}
+
+		// The agent applies the limits while the row is locked; if it refuses, the DB change is rolled back
+		err = h.db.UpdateServerResources(c.Context(), server.ID, newMemory, newCPU, newDisk, newIOWeight,
+			func(memory int64, cpu int, disk int64, ioWeight int) error {
+				return h.updateResourcesOnAgent(c.Context(), server, node, memory, cpu, disk, ioWeight)
+			})
+		if err != nil {
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				return fe
+			}
+			if errors.Is(err, database.ErrNodeFull) {
+				return capacityError(err)
+			}
 			return fiber.NewError(fiber.StatusInternalServerError, "failed to update server resources")
 		}
 	}
@@ -322,6 +532,34 @@ func (h *ServerHandler) Update(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"server": updated})
 }
 
+// updateResourcesOnAgent applies new limits to a server's container on its node
+func (h *ServerHandler) updateResourcesOnAgent(ctx context.Context, server *models.Server, node *database.Node, memory int64, cpu int, disk int64, ioWeight int) error {
+	conn, err := h.grpcPool.GetClient(node.GetAddress(), node.Scheme == "http")
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to connect to agent")
+	}
+
+	client := agentpb.NewAgentServiceClient(conn)
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+node.DaemonTokenHash)
+
+	resp, err := client.UpdateServerResources(ctx, &agentpb.UpdateServerResourcesRequest{
+		ServerId: server.ID.String(),
+		Limits: &agentpb.ResourceLimits{
+			MemoryMb:   memory,
+			DiskMb:     disk,
+			CpuPercent: int32(cpu),
+			IoWeight:   int32(ioWeight),
+		},
+	})
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "UpdateServerResources RPC failed: "+err.Error())
+	}
+	if !resp.Success {
+		return fiber.NewError(fiber.StatusBadGateway, "agent refused resource change: "+resp.ErrorMessage)
+	}
+	return nil
+}
+
 // ResetServer wipes and recreates the server container (data loss)
 func (h *ServerHandler) ResetServer(c *fiber.Ctx) error {
 	server, err := h.getServerForUser(c)
@@ -366,16 +604,15 @@ func (h *ServerHandler) ResetServer(c *fiber.Ctx) error {
 
 	// Recreate the server on the agent in background
 	go func() {
-		allocation := server.PrimaryAllocation
-		if allocation == nil {
+		if server.PrimaryAllocation == nil {
 			_ = h.db.UpdateServerStatus(context.Background(), server.ID, models.StatusOffline)
 			return
 		}
 
-		err := h.createServerOnAgent(server, node, allocation)
+		err := h.createServerOnAgent(server, node)
 		if err != nil {
 			log.Printf("Failed to recreate server %s after reset: %v", server.ID, err)
-			_ = h.db.UpdateServerStatus(context.Background(), server.ID, models.StatusOffline)
+			_ = h.db.UpdateServerStatus(context.Background(), server.ID, failureStatus(err))
 		}
 	}()
 
@@ -384,6 +621,94 @@ func (h *ServerHandler) ResetServer(c *fiber.Ctx) error {
 	})
 }
 
+// Reinstall runs the template's install script again on the server's
+// existing files (only if the user owns it). Unlike a reset nothing is
+// deleted; the server is stopped for the install and left offline.
+func (h *ServerHandler) Reinstall(c *fiber.Ctx) error {
+	server, err := h.getServerForUser(c)
+	if err != nil {
+		return err
+	}
+	if server.Status == models.StatusInstalling {
+		return fiber.NewError(fiber.StatusConflict, "server is already being installed")
+	}
+
+	template, err := h.db.GetTemplate(c.Context(), server.TemplateID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load template")
+	}
+	install := installScriptToProto(template)
+	if install == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "the server's template has no install script")
+	}
+
+	node, err := h.db.GetNodeByID(c.Context(), server.NodeID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "node not found")
+	}
+
+	conn, err := h.grpcPool.GetClient(node.GetAddress(), node.Scheme == "http")
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to connect to agent")
+	}
+	client := agentpb.NewAgentServiceClient(conn)
+
+	var envVars []*agentpb.EnvVar
+	for k, v := range server.Environment {
+		envVars = append(envVars, &agentpb.EnvVar{Key: k, Value: v})
+	}
+
+	_ = h.db.UpdateServerStatus(c.Context(), server.ID, models.StatusInstalling)
+
+	// The install runs as long as the script takes; the agent streams its
+	// output to the console meanwhile
+	go func() {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+node.DaemonTokenHash)
+		resp, err := client.ReinstallServer(ctx, &agentpb.ReinstallServerRequest{
+			ServerId:    server.ID.String(),
+			Install:     install,
+			Environment: envVars,
+			Limits: &agentpb.ResourceLimits{
+				MemoryMb:   server.MemoryLimit,
+				DiskMb:     server.DiskLimit,
+				CpuPercent: int32(server.CPULimit),
+				IoWeight:   int32(server.IOWeight),
+			},
+			DataMount: template.DataMount,
+		})
+
+		if status.Code(err) == codes.NotFound {
+			// The first install failed, so the server never got a container:
+			// create it now, which runs the install script on the kept files
+			err = h.createServerOnAgent(server, node)
+			newStatus := models.StatusStarting
+			if err != nil {
+				log.Printf("Failed to create server %s on reinstall: %v", server.ID, err)
+				newStatus = failureStatus(err)
+			}
+			_ = h.db.UpdateServerStatus(context.Background(), server.ID, newStatus)
+			return
+		}
+
+		newStatus := models.StatusOffline
+		switch {
+		case err != nil:
+			log.Printf("ReinstallServer RPC for server %s failed: %v", server.ID, err)
+		case resp.ExitCode != 0:
+			log.Printf("Reinstall of server %s failed: %s", server.ID, resp.ErrorMessage)
+			_ = h.db.RecordInstall(context.Background(), server.ID, int(resp.ExitCode))
+			newStatus = models.StatusInstallFailed
+		case !resp.Success:
+			log.Printf("Reinstall of server %s failed: %s", server.ID, resp.ErrorMessage)
+		default:
+			_ = h.db.RecordInstall(context.Background(), server.ID, 0)
+		}
+		_ = h.db.UpdateServerStatus(context.Background(), server.ID, newStatus)
+	}()
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "server reinstalling"})
+}
+
 // Delete removes a server (only if the user owns it)
 func (h *ServerHandler) Delete(c *fiber.Ctx) error {
 	server, err := h.getServerForUser(c)
@@ -447,6 +772,10 @@ func (h *ServerHandler) Start(c *fiber.Ctx) error {
 	client := agentpb.NewAgentServiceClient(conn)
 	ctx := metadata.AppendToOutgoingContext(c.Context(), "authorization", "Bearer "+node.DaemonTokenHash)
 
+	if err := h.applyConfigFiles(ctx, client, server); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
 	resp, err := client.StartServer(ctx, &agentpb.ServerIdentifier{ServerId: server.ID.String()})
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "StartServer RPC failed: "+err.Error())
@@ -455,7 +784,8 @@ func (h *ServerHandler) Start(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, resp.ErrorMessage)
 	}
 
-	_ = h.db.UpdateServerStatus(c.Context(), server.ID, models.StatusRunning)
+	// The agent's lifecycle events move the server to running once it is up
+	_ = h.db.UpdateServerStatus(c.Context(), server.ID, models.StatusStarting)
 	return c.JSON(fiber.Map{"message": "server starting"})
 }
 
@@ -514,6 +844,10 @@ func (h *ServerHandler) Restart(c *fiber.Ctx) error {
 	client := agentpb.NewAgentServiceClient(conn)
 	ctx := metadata.AppendToOutgoingContext(c.Context(), "authorization", "Bearer "+node.DaemonTokenHash)
 
+	if err := h.applyConfigFiles(ctx, client, server); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
 	resp, err := client.RestartServer(ctx, &agentpb.ServerIdentifier{ServerId: server.ID.String()})
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "RestartServer RPC failed: "+err.Error())
@@ -522,6 +856,7 @@ func (h *ServerHandler) Restart(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, resp.ErrorMessage)
 	}
 
+	_ = h.db.UpdateServerStatus(c.Context(), server.ID, models.StatusStarting)
 	return c.JSON(fiber.Map{"message": "server restarting"})
 }
 
@@ -612,9 +947,55 @@ func (h *ServerHandler) GetLogs(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"logs": lines})
 }
 
+// GetStats returns live resource usage for a server via Agent's GetServerStatus RPC (only if the user owns it)
+func (h *ServerHandler) GetStats(c *fiber.Ctx) error {
+	server, err := h.getServerForUser(c)
+	if err != nil {
+		return err
+	}
+
+	node, err := h.db.GetNodeByID(c.Context(), server.NodeID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "node not found")
+	}
+
+	conn, err := h.grpcPool.GetClient(node.GetAddress(), node.Scheme == "http")
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to connect to agent")
+	}
+
+	client := agentpb.NewAgentServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(c.Context(), "authorization", "Bearer "+node.DaemonTokenHash)
+
+	state, err := client.GetServerStatus(ctx, &agentpb.ServerIdentifier{ServerId: server.ID.String()})
+	if err != nil {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "GetServerStatus RPC failed: "+err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"stats": fiber.Map{
+			"server_id":          server.ID,
+			"cpu_usage_percent":  state.CpuUsagePercent,
+			"memory_usage_bytes": state.MemoryUsageBytes,
+			"memory_limit_bytes": state.MemoryLimitBytes,
+			"disk_usage_bytes":   state.DiskUsageBytes,
+			"network_rx_bytes":   state.NetworkRxBytes,
+			"network_tx_bytes":   state.NetworkTxBytes,
+			"block_read_bytes":   state.BlockReadBytes,
+			"block_write_bytes":  state.BlockWriteBytes,
+			"players_online":     state.PlayersOnline,
+			"players_max":        state.PlayersMax,
+			"players":            state.Players,
+			"version":            state.Version,
+			"motd":               state.Motd,
+		},
+	})
+}
+
 // StreamConsole is the WebSocket handler for real-time console streaming.
 // It bridges the Agent's gRPC StreamConsole to the frontend via WebSocket.
 // It also accepts incoming "command" messages and forwards them via SendCommand RPC.
+// Servers whose commands go to stdin use the Agent's AttachConsole for both.
 func (h *ServerHandler) StreamConsoleWS(c *websocket.Conn) {
 	serverIDStr := c.Params("id")
 	userIDStr := c.Locals("userID")
@@ -660,40 +1041,178 @@ func (h *ServerHandler) StreamConsoleWS(c *websocket.Conn) {
 	grpcCtx = metadata.AppendToOutgoingContext(grpcCtx, "authorization", "Bearer "+node.DaemonTokenHash)
 	defer grpcCancel()
 
+	// The WebSocket connection supports only one concurrent writer, and
+	// several goroutines below push frames to it.
+	var writeMu sync.Mutex
+	writeJSON := func(v interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return c.WriteJSON(v)
+	}
+
 	// Send initial status
-	c.WriteJSON(fiber.Map{"type": "status", "status": server.Status})
+	writeJSON(fiber.Map{"type": "status", "status": server.Status})
 
-	// --- goroutine 1: stream gRPC console logs → WebSocket ---
-	go func() {
-		defer grpcCancel()
+	// With the stdin transport, commands are sent on the current AttachConsole
+	// stream, which is replaced whenever the console resubscribes
+	transport := models.TransportRCON
+	if template, err := h.db.GetTemplate(context.Background(), server.TemplateID); err == nil {
+		transport = template.CommandTransport
+	}
+	var (
+		stdinMu sync.Mutex
+		stdin   agentpb.AgentService_AttachConsoleClient
+	)
+	openConsole := func(epoch string, afterOffset int64) (func() (*agentpb.ConsoleOutput, error), error) {
+		if transport != models.TransportStdin {
+			stream, err := client.StreamConsole(grpcCtx, &agentpb.StreamConsoleRequest{
+				ServerId:    server.ID.String(),
+				AfterOffset: afterOffset,
+				Epoch:       epoch,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return stream.Recv, nil
+		}
 
-		stream, err := client.StreamConsole(grpcCtx, &agentpb.ServerIdentifier{ServerId: server.ID.String()})
+		stream, err := client.AttachConsole(grpcCtx)
 		if err != nil {
-			log.Printf("WebSocket: StreamConsole RPC failed: %v", err)
-			c.WriteJSON(fiber.Map{"type": "error", "message": "failed to start console stream: " + err.Error()})
-			return
+			return nil, err
 		}
+		if err := stream.Send(&agentpb.AttachConsoleRequest{
+			ServerId:    server.ID.String(),
+			AfterOffset: afterOffset,
+			Epoch:       epoch,
+		}); err != nil {
+			return nil, err
+		}
+		stdinMu.Lock()
+		stdin = stream
+		stdinMu.Unlock()
+		return stream.Recv, nil
+	}
+	writeStdin := func(command string) error {
+		stdinMu.Lock()
+		defer stdinMu.Unlock()
+		if stdin == nil {
+			return errors.New("console is not attached")
+		}
+		return stdin.Send(&agentpb.AttachConsoleRequest{Input: []byte(command + "\n")})
+	}
+
+	// --- goroutine 1: stream gRPC console logs → WebSocket ---
+	// The agent keeps a per-server line buffer, so whenever the stream ends
+	// (container restarted, agent reconnect) we resubscribe after the last
+	// offset sent and the browser sees neither gaps nor duplicates. Offsets
+	// start over when the agent restarts; the epoch tells the agent (and the
+	// browser) which run of offsets the last one belongs to.
+	go func() {
+		afterOffset, _ := strconv.ParseInt(c.Query("offset"), 10, 64)
+		epoch := c.Query("epoch")
+		reportedError := false
 
 		for {
-			msg, err := stream.Recv()
-			if err != nil {
-				if grpcCtx.Err() != nil {
-					return // context cancelled, clean shutdown
+			recv, err := openConsole(epoch, afterOffset)
+			if err == nil {
+				for {
+					msg, recvErr := recv()
+					if recvErr != nil {
+						err = recvErr
+						break
+					}
+					epoch, afterOffset = msg.Epoch, msg.Offset
+					reportedError = false
+					if err := writeJSON(fiber.Map{
+						"type":      "log",
+						"line":      msg.Line,
+						"stream":    consoleStreamName(msg.Stream),
+						"offset":    msg.Offset,
+						"epoch":     msg.Epoch,
+						"timestamp": msg.Timestamp,
+					}); err != nil {
+						grpcCancel()
+						return // WebSocket closed
+					}
 				}
-				log.Printf("WebSocket: StreamConsole recv error: %v", err)
+			}
+
+			if grpcCtx.Err() != nil {
+				return // context cancelled, clean shutdown
+			}
+			if err != io.EOF {
+				log.Printf("WebSocket: StreamConsole error: %v", err)
+				if !reportedError {
+					writeJSON(fiber.Map{"type": "error", "message": "console stream interrupted: " + err.Error()})
+					reportedError = true
+				}
+			}
+
+			select {
+			case <-grpcCtx.Done():
 				return
+			case <-time.After(consoleResubscribeDelay):
 			}
-			if err := c.WriteJSON(fiber.Map{
-				"type":      "log",
-				"line":      msg.Line,
-				"timestamp": msg.Timestamp,
-			}); err != nil {
-				return // WebSocket closed
+		}
+	}()
+
+	// --- goroutine 2: stream gRPC resource stats → WebSocket ---
+	// The stream ends whenever the container stops or is re-created (limits,
+	// ports, reinstall), so like the console it is resubscribed until the
+	// WebSocket closes. A server without a container answers NotFound until
+	// it has one again.
+	go func() {
+		for {
+			err := func() error {
+				stream, err := client.StreamServerStats(grpcCtx, &agentpb.StreamServerStatsRequest{
+					ServerId:        server.ID.String(),
+					IntervalSeconds: 2,
+				})
+				if err != nil {
+					return err
+				}
+				for {
+					state, err := stream.Recv()
+					if err != nil {
+						return err
+					}
+					if err := writeJSON(fiber.Map{
+						"type":               "stats",
+						"cpu_usage_percent":  state.CpuUsagePercent,
+						"memory_usage_bytes": state.MemoryUsageBytes,
+						"memory_limit_bytes": state.MemoryLimitBytes,
+						"disk_usage_bytes":   state.DiskUsageBytes,
+						"network_rx_bytes":   state.NetworkRxBytes,
+						"network_tx_bytes":   state.NetworkTxBytes,
+						"block_read_bytes":   state.BlockReadBytes,
+						"block_write_bytes":  state.BlockWriteBytes,
+						"timestamp":          state.LastUpdated.AsTime().Unix(),
+					}); err != nil {
+						grpcCancel()
+						return err // WebSocket closed
+					}
+				}
+			}()
+
+			if grpcCtx.Err() != nil {
+				return // context cancelled, clean shutdown
+			}
+			if err != io.EOF && status.Code(err) != codes.NotFound {
+				log.Printf("WebSocket: StreamServerStats error: %v", err)
+			}
+
+			select {
+			case <-grpcCtx.Done():
+				return
+			case <-time.After(consoleResubscribeDelay):
 			}
 		}
 	}()
 
-	// --- goroutine 2: poll server status every 3s → WebSocket ---
+	// --- goroutine 3: poll server status every 3s → WebSocket ---
+	// Status is read from the servers table rather than pushed: it is
+	// written both by the event watcher and by the master's own handlers
+	// (installing, install failures), and nothing publishes those writes.
 	go func() {
 		ticker := time.NewTicker(3 * time.Second)
 		defer ticker.Stop()
@@ -710,7 +1229,7 @@ func (h *ServerHandler) StreamConsoleWS(c *websocket.Conn) {
 				}
 				if srv.Status != lastStatus {
 					lastStatus = srv.Status
-					if err := c.WriteJSON(fiber.Map{"type": "status", "status": lastStatus}); err != nil {
+					if err := writeJSON(fiber.Map{"type": "status", "status": lastStatus}); err != nil {
 						return
 					}
 				}
@@ -732,7 +1251,7 @@ func (h *ServerHandler) StreamConsoleWS(c *websocket.Conn) {
 		if msg.Type == "command" && msg.Command != "" {
 			// Block dangerous commands
 			if isCommandBlocked(msg.Command) {
-				c.WriteJSON(fiber.Map{
+				writeJSON(fiber.Map{
 					"type":    "command_result",
 					"command": msg.Command,
 					"output":  "Error: this command is restricted — use the dashboard buttons to control the server",
@@ -740,18 +1259,25 @@ func (h *ServerHandler) StreamConsoleWS(c *websocket.Conn) {
 				continue
 			}
 
-			// Send command via gRPC
-			resp, err := client.SendCommand(grpcCtx, &agentpb.SendCommandRequest{
-				ServerId: server.ID.String(),
-				Command:  msg.Command,
-			})
 			output := ""
-			if err != nil {
-				output = "Error: " + err.Error()
-			} else if resp != nil {
-				output = resp.ErrorMessage
+			if transport == models.TransportStdin {
+				// The server answers in its console output
+				if err := writeStdin(msg.Command); err != nil {
+					output = "Error: " + err.Error()
+				}
+			} else {
+				// Send command via gRPC
+				resp, err := client.SendCommand(grpcCtx, &agentpb.SendCommandRequest{
+					ServerId: server.ID.String(),
+					Command:  msg.Command,
+				})
+				if err != nil {
+					output = "Error: " + err.Error()
+				} else if resp != nil {
+					output = resp.ErrorMessage
+				}
 			}
-			c.WriteJSON(fiber.Map{
+			writeJSON(fiber.Map{
 				"type":    "command_result",
 				"command": msg.Command,
 				"output":  output,
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/ironhost/master/internal/database"
+	"github.com/ironhost/master/internal/eggs"
+	"github.com/ironhost/master/internal/models"
+)
+
+// TemplateHandler handles server template requests. Any user can list
+// templates to pick one for a new server; changing them is admin only.
+type TemplateHandler struct {
+	db *database.DB
+}
+
+// NewTemplateHandler creates a new template handler
+func NewTemplateHandler(db *database.DB) *TemplateHandler {
+	return &TemplateHandler{db: db}
+}
+
+// List returns all templates
+func (h *TemplateHandler) List(c *fiber.Ctx) error {
+	templates, err := h.db.ListTemplates(c.Context())
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list templates")
+	}
+	return c.JSON(fiber.Map{"templates": templates})
+}
+
+// Get returns a specific template
+func (h *TemplateHandler) Get(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid template ID")
+	}
+
+	template, err := h.db.GetTemplate(c.Context(), id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "template not found")
+	}
+	return c.JSON(fiber.Map{"template": template})
+}
+
+// Create adds a template
+func (h *TemplateHandler) Create(c *fiber.Ctx) error {
+	template, err := parseTemplate(c)
+	if err != nil {
+		return err
+	}
+
+	switch err := h.db.CreateTemplate(c.Context(), template); {
+	case errors.Is(err, database.ErrTemplateNameTaken):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to create template: "+err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"template": template})
+}
+
+// Import converts a Pterodactyl egg in the request body into a template and
+// stores it, unless dry_run is set. The response lists the egg features that
+// could not be carried over.
+// POST /templates/import?port=&dry_run=
+func (h *TemplateHandler) Import(c *fiber.Ctx) error {
+	port := c.QueryInt("port", 0)
+	if port < 0 || port > 65535 {
+		return fiber.NewError(fiber.StatusBadRequest, "port must be between 1 and 65535")
+	}
+
+	result, err := eggs.Import(c.Body(), eggs.Options{ContainerPort: port})
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if c.QueryBool("dry_run") {
+		return c.JSON(result)
+	}
+
+	switch err := h.db.CreateTemplate(c.Context(), result.Template); {
+	case errors.Is(err, database.ErrTemplateNameTaken):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to create template: "+err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(result)
+}
+
+// Update replaces a template. Servers already created from it are not changed.
+func (h *TemplateHandler) Update(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid template ID")
+	}
+
+	template, err := parseTemplate(c)
+	if err != nil {
+		return err
+	}
+	template.ID = id
+
+	switch err := h.db.UpdateTemplate(c.Context(), template); {
+	case errors.Is(err, database.ErrTemplateNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, database.ErrTemplateNameTaken):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to update template: "+err.Error())
+	}
+	return c.JSON(fiber.Map{"template": template})
+}
+
+// Delete removes a template no server uses, other than the default one
+func (h *TemplateHandler) Delete(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid template ID")
+	}
+
+	switch err := h.db.DeleteTemplate(c.Context(), id); {
+	case errors.Is(err, database.ErrTemplateNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, database.ErrTemplateInUse), errors.Is(err, database.ErrTemplateDefault):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete template: "+err.Error())
+	}
+	return c.JSON(fiber.Map{"message": "template deleted"})
+}
+
+// parseTemplate reads and validates a template from the request body,
+// filling in the defaults for omitted settings
+func parseTemplate(c *fiber.Ctx) (*models.ServerTemplate, error) {
+	template := models.ServerTemplate{
+		Protocol:         "tcp",
+		CommandTransport: models.TransportRCON,
+		DataMount:        "/data",
+	}
+	if err := c.BodyParser(&template); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	// Store empty lists rather than JSON nulls
+	if template.Variables == nil {
+		template.Variables = []models.TemplateVariable{}
+	}
+	if template.ReadinessProbes == nil {
+		template.ReadinessProbes = []models.ReadinessProbe{}
+	}
+	if template.ConfigFiles == nil {
+		template.ConfigFiles = []models.ConfigFile{}
+	}
+	if err := template.Validate(); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return &template, nil
+}
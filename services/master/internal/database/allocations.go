@@ -0,0 +1,567 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/ironhost/master/internal/models"
+)
+
+var (
+	// ErrAllocationNotFound is returned for allocations that do not exist or the server does not hold
+	ErrAllocationNotFound = errors.New("allocation not found")
+	// ErrAllocationUnavailable is returned for allocations that are taken or on another node
+	ErrAllocationUnavailable = errors.New("allocation is not available")
+	// ErrPrimaryAllocation is returned when releasing a server's primary allocation
+	ErrPrimaryAllocation = errors.New("the primary allocation cannot be released")
+	// ErrAllocationAssigned is returned when deleting an allocation a server holds
+	ErrAllocationAssigned = errors.New("allocation is assigned to a server")
+	// ErrAllocationOverlap is returned when new allocations collide with existing ones
+	ErrAllocationOverlap = errors.New("allocations overlap existing ones")
+)
+
+// MaxAllocationBatch caps the ports one CreateAllocations call adds
+const MaxAllocationBatch = 1000
+
+// Ports tried when a server needs an allocation and the node has no pool
+const (
+	autoPortStart = 25565
+	autoPortEnd   = 25600
+)
+
+const allocationColumns = `
+	id, node_id, server_id, ip_address, port, protocol, COALESCE(container_port, 0), notes, assigned, created_at`
+
+// scanAllocation reads a row selected with allocationColumns
+func scanAllocation(row pgx.Row) (*models.Allocation, error) {
+	var a models.Allocation
+	var notes *string
+	var assigned *bool
+	err := row.Scan(&a.ID, &a.NodeID, &a.ServerID, &a.IPAddress, &a.Port, &a.Protocol, &a.ContainerPort, &notes, &assigned, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if notes != nil {
+		a.Notes = *notes
+	}
+	a.Assigned = assigned != nil && *assigned
+	return &a, nil
+}
+
+// nullPort stores a container port of 0 as NULL
+func nullPort(port int) *int {
+	if port == 0 {
+		return nil
+	}
+	return &port
+}
+
+// AllocationFilter selects allocations by node, server, assignment and
+// protocol; zero fields match everything
+type AllocationFilter struct {
+	NodeID   *uuid.UUID
+	ServerID *uuid.UUID
+	Assigned *bool
+	Protocol string
+}
+
+// ListAllocations returns the allocations matching a filter, ordered by node,
+// address and port
+func (db *DB) ListAllocations(ctx context.Context, f AllocationFilter) ([]models.Allocation, error) {
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.NodeID != nil {
+		add("node_id = $%d", *f.NodeID)
+	}
+	if f.ServerID != nil {
+		add("server_id = $%d", *f.ServerID)
+	}
+	if f.Assigned != nil {
+		if *f.Assigned {
+			where = append(where, "server_id IS NOT NULL")
+		} else {
+			where = append(where, "server_id IS NULL")
+		}
+	}
+	if f.Protocol != "" {
+		add("protocol = $%d", f.Protocol)
+	}
+
+	query := `SELECT ` + allocationColumns + ` FROM allocations`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY node_id, ip_address, port, protocol`
+
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allocations := []models.Allocation{}
+	for rows.Next() {
+		a, err := scanAllocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, *a)
+	}
+	return allocations, rows.Err()
+}
+
+// CreateAllocations adds the ports portStart to portEnd on an address of a
+// node as free allocations. Nothing is created if any of them overlaps an
+// allocation of the node: the same port and protocol on the same address, or
+// on any address if either side binds them all.
+func (db *DB) CreateAllocations(ctx context.Context, nodeID uuid.UUID, ip, protocol string, portStart, portEnd int, notes string) ([]models.Allocation, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialises allocation changes on the node, so overlaps are checked race-free
+	if err := lockNode(ctx, tx, nodeID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT ip_address, port FROM allocations
+		WHERE node_id = $1 AND protocol = $2 AND port BETWEEN $3 AND $4
+	`, nodeID, protocol, portStart, portEnd)
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[int]bool)
+	for rows.Next() {
+		var existingIP string
+		var port int
+		if err := rows.Scan(&existingIP, &port); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if addressesOverlap(ip, existingIP) {
+			taken[port] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(taken) > 0 {
+		return nil, fmt.Errorf("%w: %s/%s on %s", ErrAllocationOverlap, portList(taken), protocol, ip)
+	}
+
+	now := time.Now()
+	allocations := make([]models.Allocation, 0, portEnd-portStart+1)
+	batch := &pgx.Batch{}
+	for port := portStart; port <= portEnd; port++ {
+		a := models.Allocation{
+			ID:        uuid.New(),
+			NodeID:    nodeID,
+			IPAddress: ip,
+			Port:      port,
+			Protocol:  protocol,
+			Notes:     notes,
+			CreatedAt: now,
+		}
+		batch.Queue(`
+			INSERT INTO allocations (id, node_id, ip_address, port, protocol, notes, assigned, created_at)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), FALSE, $7)
+		`, a.ID, a.NodeID, a.IPAddress, a.Port, a.Protocol, a.Notes, a.CreatedAt)
+		allocations = append(allocations, a)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return allocations, nil
+}
+
+// UpdateAllocationNotes replaces an allocation's notes
+func (db *DB) UpdateAllocationNotes(ctx context.Context, id uuid.UUID, notes string) (*models.Allocation, error) {
+	allocation, err := scanAllocation(db.Pool.QueryRow(ctx, `
+		UPDATE allocations SET notes = NULLIF($2, '') WHERE id = $1
+		RETURNING `+allocationColumns, id, notes))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAllocationNotFound
+	}
+	return allocation, err
+}
+
+// DeleteAllocation removes a free allocation
+func (db *DB) DeleteAllocation(ctx context.Context, id uuid.UUID) error {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM allocations WHERE id = $1 AND server_id IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := db.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM allocations WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrAllocationAssigned
+	}
+	return ErrAllocationNotFound
+}
+
+// wildcardAddress reports whether an allocation binds every address of its
+// node, as hostnames and unspecified addresses do
+func wildcardAddress(ip string) bool {
+	addr := net.ParseIP(ip)
+	return addr == nil || addr.IsUnspecified()
+}
+
+// addressesOverlap reports whether allocations on two addresses of a node
+// would bind the same socket
+func addressesOverlap(a, b string) bool {
+	return a == b || wildcardAddress(a) || wildcardAddress(b)
+}
+
+// portList formats a set of ports as sorted ranges, e.g. "25565-25567, 25570"
+func portList(ports map[int]bool) string {
+	sorted := make([]int, 0, len(ports))
+	for p := range ports {
+		sorted = append(sorted, p)
+	}
+	sort.Ints(sorted)
+
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		part := strconv.Itoa(sorted[i])
+		if j > i {
+			part += "-" + strconv.Itoa(sorted[j])
+		}
+		parts = append(parts, part)
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CountFreeAllocations returns how many allocations of a protocol each node
+// could still hand a new server: its free allocations, plus, for nodes
+// without a pool, the ports of the automatic range not allocated yet (see
+// claimAllocation)
+func (db *DB) CountFreeAllocations(ctx context.Context, protocol string) (map[uuid.UUID]int, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT n.id,
+			(SELECT COUNT(*) FROM allocations a WHERE a.node_id = n.id AND a.protocol = $1 AND a.server_id IS NULL),
+			EXISTS (SELECT 1 FROM allocations a WHERE a.node_id = n.id AND a.protocol = $1 AND NOT a.automatic),
+			(SELECT COUNT(*) FROM allocations a WHERE a.node_id = n.id AND a.protocol = $1 AND a.port BETWEEN $2 AND $3)
+		FROM nodes n
+	`, protocol, autoPortStart, autoPortEnd)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var nodeID uuid.UUID
+		var free, inRange int
+		var hasPool bool
+		if err := rows.Scan(&nodeID, &free, &hasPool, &inRange); err != nil {
+			return nil, err
+		}
+		if !hasPool {
+			free += (autoPortEnd - autoPortStart + 1) - inRange
+		}
+		counts[nodeID] = free
+	}
+	return counts, rows.Err()
+}
+
+// lockNode locks a node's row until the end of tx
+func lockNode(ctx context.Context, tx pgx.Tx, nodeID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.QueryRow(ctx, `SELECT id FROM nodes WHERE id = $1 FOR UPDATE`, nodeID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNodeNotFound
+	}
+	return err
+}
+
+// GetAllocation retrieves an allocation by ID
+func (db *DB) GetAllocation(ctx context.Context, id uuid.UUID) (*models.Allocation, error) {
+	return scanAllocation(db.Pool.QueryRow(ctx, `SELECT `+allocationColumns+` FROM allocations WHERE id = $1`, id))
+}
+
+// ListServerAllocations returns a server's allocations, the primary first
+func (db *DB) ListServerAllocations(ctx context.Context, serverID uuid.UUID) ([]models.Allocation, error) {
+	return listServerAllocations(ctx, db.Pool.Query, serverID)
+}
+
+// listServerAllocations lists a server's allocations through the pool or a transaction
+func listServerAllocations(ctx context.Context, query func(context.Context, string, ...any) (pgx.Rows, error), serverID uuid.UUID) ([]models.Allocation, error) {
+	rows, err := query(ctx, `
+		SELECT `+allocationColumns+` FROM allocations a
+		WHERE server_id = $1
+		ORDER BY EXISTS(SELECT 1 FROM servers s WHERE s.primary_allocation_id = a.id) DESC, port, protocol
+	`, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allocations := []models.Allocation{}
+	for rows.Next() {
+		a, err := scanAllocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, *a)
+	}
+	return allocations, rows.Err()
+}
+
+// AssignAllocation gives a server its primary allocation: a free allocation
+// of the node if it has one, else, if the node has no pool, a new one on the
+// first free port of the automatic range
+func (db *DB) AssignAllocation(ctx context.Context, serverID, nodeID uuid.UUID, protocol string, containerPort int) (*models.Allocation, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// The server row is locked before claimAllocation locks the node
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM servers WHERE id = $1 FOR UPDATE`, serverID); err != nil {
+		return nil, err
+	}
+	allocation, err := claimAllocation(ctx, tx, serverID, nodeID, nil, protocol, containerPort)
+	if err != nil {
+		return nil, err
+	}
+
+	// Link server to primary allocation
+	_, err = tx.Exec(ctx, `
+		UPDATE servers SET primary_allocation_id = $1 WHERE id = $2
+	`, allocation.ID, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return allocation, nil
+}
+
+// AttachAllocation gives a server an extra allocation: the one with the given
+// ID, which must be free and on the server's node, or else one picked like
+// AssignAllocation does. apply receives the server's allocations including
+// the new one and runs while the server row is locked; if it fails, nothing
+// is changed. If the commit fails afterwards, apply is called again with the
+// allocations the server had before.
+func (db *DB) AttachAllocation(ctx context.Context, serverID, nodeID uuid.UUID, allocationID *uuid.UUID, protocol string, containerPort int, apply func([]models.Allocation) error) (*models.Allocation, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM servers WHERE id = $1 FOR UPDATE`, serverID); err != nil {
+		return nil, err
+	}
+	previous, err := listServerAllocations(ctx, tx.Query, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	allocation, err := claimAllocation(ctx, tx, serverID, nodeID, allocationID, protocol, containerPort)
+	if err != nil {
+		return nil, err
+	}
+
+	allocations, err := listServerAllocations(ctx, tx.Query, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if apply == nil {
+		return allocation, tx.Commit(ctx)
+	}
+	if err := apply(allocations); err != nil {
+		return nil, err
+	}
+
+	if err := commitApplied(ctx, tx, func() error { return apply(previous) }); err != nil {
+		return nil, err
+	}
+	return allocation, nil
+}
+
+// ReleaseAllocation returns one of a server's extra allocations to the node's
+// free pool. apply receives the server's remaining allocations and runs while
+// the server row is locked; if it fails, nothing is changed. If the commit
+// fails afterwards, apply is called again with the allocations the server had
+// before.
+func (db *DB) ReleaseAllocation(ctx context.Context, serverID, allocationID uuid.UUID, apply func([]models.Allocation) error) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var primaryID *uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT primary_allocation_id FROM servers WHERE id = $1 FOR UPDATE`, serverID).Scan(&primaryID)
+	if err != nil {
+		return err
+	}
+	if primaryID != nil && *primaryID == allocationID {
+		return ErrPrimaryAllocation
+	}
+	previous, err := listServerAllocations(ctx, tx.Query, serverID)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE allocations SET server_id = NULL, assigned = FALSE, container_port = NULL
+		WHERE id = $1 AND server_id = $2
+	`, allocationID, serverID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAllocationNotFound
+	}
+
+	allocations, err := listServerAllocations(ctx, tx.Query, serverID)
+	if err != nil {
+		return err
+	}
+	if apply == nil {
+		return tx.Commit(ctx)
+	}
+	if err := apply(allocations); err != nil {
+		return err
+	}
+
+	return commitApplied(ctx, tx, func() error { return apply(previous) })
+}
+
+// claimAllocation assigns an allocation to a server within tx. With an ID,
+// that allocation is claimed if it is free and on the node. Otherwise the
+// node's free allocation with the lowest port is used; rows locked by a
+// concurrent claim are skipped, so no two servers get the same one. Nodes
+// whose pool is used up return ErrAllocationUnavailable; nodes without a pool
+// for the protocol get a new allocation on the node's hostname, on the first
+// port of the automatic range not yet allocated.
+func claimAllocation(ctx context.Context, tx pgx.Tx, serverID, nodeID uuid.UUID, allocationID *uuid.UUID, protocol string, containerPort int) (*models.Allocation, error) {
+	if protocol == "" {
+		protocol = "tcp"
+	}
+
+	if allocationID != nil {
+		allocation, err := scanAllocation(tx.QueryRow(ctx, `
+			UPDATE allocations SET server_id = $1, assigned = TRUE, container_port = $4
+			WHERE id = $2 AND node_id = $3 AND server_id IS NULL
+			RETURNING `+allocationColumns,
+			serverID, *allocationID, nodeID, nullPort(containerPort)))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAllocationUnavailable
+		}
+		return allocation, err
+	}
+
+	allocation, err := scanAllocation(tx.QueryRow(ctx, `
+		UPDATE allocations SET server_id = $1, assigned = TRUE, container_port = $4
+		WHERE id = (
+			SELECT id FROM allocations
+			WHERE node_id = $2 AND protocol = $3 AND server_id IS NULL
+			ORDER BY port LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+allocationColumns,
+		serverID, nodeID, protocol, nullPort(containerPort)))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return allocation, err
+	}
+
+	// Serialises the port search with concurrent claims and bulk creates
+	if err := lockNode(ctx, tx, nodeID); err != nil {
+		return nil, err
+	}
+
+	// Admins who manage a pool decide which ports servers get
+	var hasPool bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM allocations WHERE node_id = $1 AND protocol = $2 AND NOT automatic)
+	`, nodeID, protocol).Scan(&hasPool)
+	if err != nil {
+		return nil, err
+	}
+	if hasPool {
+		return nil, fmt.Errorf("%w: the node has no free %s allocation", ErrAllocationUnavailable, protocol)
+	}
+
+	var assignedPort int
+	for port := autoPortStart; port <= autoPortEnd; port++ {
+		var exists bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM allocations WHERE node_id = $1 AND port = $2 AND protocol = $3)
+		`, nodeID, port, protocol).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			assignedPort = port
+			break
+		}
+	}
+	if assignedPort == 0 {
+		return nil, fmt.Errorf("no free %s ports available in range %d-%d", protocol, autoPortStart, autoPortEnd)
+	}
+
+	// Get Node IP (FQDN) to use in allocation
+	var nodeFQDN string
+	if err := tx.QueryRow(ctx, `SELECT fqdn FROM nodes WHERE id = $1`, nodeID).Scan(&nodeFQDN); err != nil {
+		return nil, err
+	}
+
+	allocation = &models.Allocation{
+		ID:            uuid.New(),
+		NodeID:        nodeID,
+		ServerID:      &serverID,
+		IPAddress:     nodeFQDN, // Use Node FQDN/IP as allocation IP
+		Port:          assignedPort,
+		Protocol:      protocol,
+		ContainerPort: containerPort,
+		Assigned:      true,
+		CreatedAt:     time.Now(),
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO allocations (id, node_id, server_id, ip_address, port, protocol, container_port, assigned, automatic, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, TRUE, $9)
+	`, allocation.ID, allocation.NodeID, allocation.ServerID, allocation.IPAddress, allocation.Port,
+		allocation.Protocol, nullPort(allocation.ContainerPort), allocation.Assigned, allocation.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return allocation, nil
+}
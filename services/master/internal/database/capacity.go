@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrNodeMaintenance is returned when placing a server on a node in maintenance mode
+	ErrNodeMaintenance = errors.New("node is in maintenance mode")
+	// ErrNodeFull is returned when a node lacks the capacity a server needs
+	ErrNodeFull = errors.New("node does not have enough free capacity")
+)
+
+// capacity returns how much of a resource a node can give out, or -1 if the
+// resource is unlimited (a total of 0)
+func capacity(total int64, overcommit float64) int64 {
+	if total <= 0 {
+		return -1
+	}
+	return int64(float64(total) * overcommit)
+}
+
+// Free returns the memory, CPU and disk a node can still give out. Unlimited
+// resources are -1; an oversubscribed node, e.g. after its total was lowered,
+// has 0 free.
+func (n *Node) Free() (memoryMB, cpuPercent, diskMB int64) {
+	free := func(total int64, overcommit float64, allocated int64) int64 {
+		c := capacity(total, overcommit)
+		if c < 0 {
+			return -1
+		}
+		return max(c-allocated, 0)
+	}
+	return free(n.MemoryTotal, n.MemoryOvercommit, n.MemoryAllocated),
+		free(n.CPUTotal, n.CPUOvercommit, n.CPUAllocated),
+		free(n.DiskTotal, n.DiskOvercommit, n.DiskAllocated)
+}
+
+// CheckCapacity returns an error wrapping ErrNodeFull if the node cannot give
+// out the extra memory, CPU and disk on top of what it has allocated.
+// Amounts of 0 or less are not checked, so a server can always shrink.
+func (n *Node) CheckCapacity(memoryMB int64, cpuPercent int, diskMB int64) error {
+	freeMemory, freeCPU, freeDisk := n.Free()
+	switch {
+	case memoryMB > 0 && freeMemory >= 0 && memoryMB > freeMemory:
+		return fmt.Errorf("%w: %d MB of memory requested, %d MB free", ErrNodeFull, memoryMB, freeMemory)
+	case cpuPercent > 0 && freeCPU >= 0 && int64(cpuPercent) > freeCPU:
+		return fmt.Errorf("%w: %d%% CPU requested, %d%% free", ErrNodeFull, cpuPercent, freeCPU)
+	case diskMB > 0 && freeDisk >= 0 && diskMB > freeDisk:
+		return fmt.Errorf("%w: %d MB of disk requested, %d MB free", ErrNodeFull, diskMB, freeDisk)
+	}
+	return nil
+}
+
+// lockNodeForUpdate locks a node's row until the end of tx and returns it.
+// Its allocated totals cannot change until then. A transaction that also
+// locks one of the node's servers must lock the server first, as allocation
+// changes do, or the two can deadlock.
+func lockNodeForUpdate(ctx context.Context, tx pgx.Tx, nodeID uuid.UUID) (*Node, error) {
+	node, err := scanNode(tx.QueryRow(ctx, `SELECT `+nodeColumns+` FROM nodes WHERE id = $1 FOR UPDATE`, nodeID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNodeNotFound
+	}
+	return node, err
+}
+
+// syncNodeAllocated recomputes a node's allocated totals from its servers.
+// Callers hold the node's lock, so the totals match the servers at commit.
+func syncNodeAllocated(ctx context.Context, tx pgx.Tx, nodeID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE nodes n SET
+			memory_allocated = COALESCE(s.memory, 0),
+			cpu_allocated = COALESCE(s.cpu, 0),
+			disk_allocated = COALESCE(s.disk, 0)
+		FROM (
+			SELECT SUM(memory_limit) AS memory, SUM(cpu_limit) AS cpu, SUM(disk_limit) AS disk
+			FROM servers WHERE node_id = $1
+		) s
+		WHERE n.id = $1
+	`, nodeID)
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -78,3 +79,17 @@ func (db *DB) Close() {
 func (db *DB) Health(ctx context.Context) error {
 	return db.Pool.Ping(ctx)
 }
+
+// commitApplied commits a transaction whose change an agent has already
+// applied. If the commit fails, revert pushes the previous state to the agent
+// again, so it does not keep a change the database never stored.
+func commitApplied(ctx context.Context, tx pgx.Tx, revert func() error) error {
+	err := tx.Commit(ctx)
+	if err == nil || revert == nil {
+		return err
+	}
+	if revertErr := revert(); revertErr != nil {
+		return fmt.Errorf("%w (restoring the agent failed: %v)", err, revertErr)
+	}
+	return err
+}
@@ -2,79 +2,110 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/ironhost/master/internal/models"
+)
+
+var (
+	// ErrNodeNotFound is returned for nodes that do not exist
+	ErrNodeNotFound = errors.New("node not found")
+	// ErrNodeNameTaken is returned when another node already has the name
+	ErrNodeNameTaken = errors.New("a node with this name already exists")
+	// ErrNodeInUse is returned when deleting a node that servers or allocations still use
+	ErrNodeInUse = errors.New("node is in use")
 )
 
 // Node represents a remote server running the IronHost Agent
 type Node struct {
-	ID              uuid.UUID `json:"id"`
-	Name            string    `json:"name"`
-	FQDN            string    `json:"fqdn"`
-	Scheme          string    `json:"scheme"`
-	GRPCPort        int       `json:"grpc_port"`
-	Location        string    `json:"location"`
-	MemoryTotal     int64     `json:"memory_total"`
-	MemoryAllocated int64     `json:"memory_allocated"`
-	DiskTotal       int64     `json:"disk_total"`
-	DiskAllocated   int64     `json:"disk_allocated"`
-	DaemonTokenHash string    `json:"-"`
-	MaintenanceMode bool      `json:"maintenance_mode"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID               uuid.UUID         `json:"id"`
+	Name             string            `json:"name"`
+	FQDN             string            `json:"fqdn"`
+	Scheme           string            `json:"scheme"`
+	GRPCPort         int               `json:"grpc_port"`
+	Location         string            `json:"location"`
+	Labels           map[string]string `json:"labels"`       // Matched by placement constraints and preferences
+	MemoryTotal      int64             `json:"memory_total"` // In MB, 0 = unlimited
+	MemoryAllocated  int64             `json:"memory_allocated"`
+	CPUTotal         int64             `json:"cpu_total"` // In percent (100 = 1 core), 0 = unlimited
+	CPUAllocated     int64             `json:"cpu_allocated"`
+	DiskTotal        int64             `json:"disk_total"` // In MB, 0 = unlimited
+	DiskAllocated    int64             `json:"disk_allocated"`
+	MemoryOvercommit float64           `json:"memory_overcommit"` // Capacity is the total times the ratio
+	CPUOvercommit    float64           `json:"cpu_overcommit"`
+	DiskOvercommit   float64           `json:"disk_overcommit"`
+	DaemonTokenHash  string            `json:"-"`
+	MaintenanceMode  bool              `json:"maintenance_mode"`
+	LastSeenAt       *time.Time        `json:"last_seen_at"`      // Last successful probe by the status reconciler
+	UnreachableSince *time.Time        `json:"unreachable_since"` // Set after repeated failed probes, nil while reachable
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
 }
 
-// CreateNode creates a new node in the database
-func (db *DB) CreateNode(ctx context.Context, name, fqdn, scheme string, grpcPort int, memoryTotal, diskTotal int64, daemonTokenHash, location string) (*Node, error) {
-	node := &Node{
-		ID:              uuid.New(),
-		Name:            name,
-		FQDN:            fqdn,
-		Scheme:          scheme,
-		GRPCPort:        grpcPort,
-		Location:        location,
-		MemoryTotal:     memoryTotal,
-		DiskTotal:       diskTotal,
-		DaemonTokenHash: daemonTokenHash,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
-	}
-
-	_, err := db.Pool.Exec(ctx, `
-		INSERT INTO nodes (id, name, fqdn, scheme, grpc_port, location, memory_total, disk_total, daemon_token_hash, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	`, node.ID, node.Name, node.FQDN, node.Scheme, node.GRPCPort, node.Location, node.MemoryTotal, node.DiskTotal, node.DaemonTokenHash, node.CreatedAt, node.UpdatedAt)
+const nodeColumns = `
+	id, name, fqdn, scheme, grpc_port, location, labels, memory_total, memory_allocated, cpu_total, cpu_allocated,
+	disk_total, disk_allocated, memory_overcommit, cpu_overcommit, disk_overcommit, daemon_token_hash,
+	maintenance_mode, last_seen_at, unreachable_since, created_at, updated_at`
 
+// scanNode reads a row selected with nodeColumns
+func scanNode(row pgx.Row) (*Node, error) {
+	var node Node
+	err := row.Scan(&node.ID, &node.Name, &node.FQDN, &node.Scheme, &node.GRPCPort, &node.Location, &node.Labels,
+		&node.MemoryTotal, &node.MemoryAllocated, &node.CPUTotal, &node.CPUAllocated, &node.DiskTotal, &node.DiskAllocated,
+		&node.MemoryOvercommit, &node.CPUOvercommit, &node.DiskOvercommit, &node.DaemonTokenHash,
+		&node.MaintenanceMode, &node.LastSeenAt, &node.UnreachableSince, &node.CreatedAt, &node.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	return &node, nil
+}
+
+// CreateNode stores a new node. Its ID and timestamps are set here, and
+// overcommit ratios left at 0 default to 1.
+func (db *DB) CreateNode(ctx context.Context, node *Node) error {
+	node.ID = uuid.New()
+	node.CreatedAt = time.Now()
+	node.UpdatedAt = node.CreatedAt
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	for _, ratio := range []*float64{&node.MemoryOvercommit, &node.CPUOvercommit, &node.DiskOvercommit} {
+		if *ratio == 0 {
+			*ratio = 1
+		}
+	}
 
-	return node, nil
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO nodes (id, name, fqdn, scheme, grpc_port, location, labels, memory_total, cpu_total, disk_total,
+			memory_overcommit, cpu_overcommit, disk_overcommit, daemon_token_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $16, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`, node.ID, node.Name, node.FQDN, node.Scheme, node.GRPCPort, node.Location, node.MemoryTotal, node.CPUTotal, node.DiskTotal,
+		node.MemoryOvercommit, node.CPUOvercommit, node.DiskOvercommit, node.DaemonTokenHash, node.CreatedAt, node.UpdatedAt, node.Labels)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrNodeNameTaken
+	}
+	return err
 }
 
 // GetNodeByID finds a node by ID
 func (db *DB) GetNodeByID(ctx context.Context, id uuid.UUID) (*Node, error) {
-	var node Node
-	err := db.Pool.QueryRow(ctx, `
-		SELECT id, name, fqdn, scheme, grpc_port, location, memory_total, memory_allocated, disk_total, disk_allocated, daemon_token_hash, maintenance_mode, created_at, updated_at
-		FROM nodes WHERE id = $1
-	`, id).Scan(&node.ID, &node.Name, &node.FQDN, &node.Scheme, &node.GRPCPort, &node.Location, &node.MemoryTotal, &node.MemoryAllocated, &node.DiskTotal, &node.DiskAllocated, &node.DaemonTokenHash, &node.MaintenanceMode, &node.CreatedAt, &node.UpdatedAt)
-
-	if err != nil {
-		return nil, err
+	node, err := scanNode(db.Pool.QueryRow(ctx, `SELECT `+nodeColumns+` FROM nodes WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNodeNotFound
 	}
-
-	return &node, nil
+	return node, err
 }
 
 // ListNodes returns all nodes
 func (db *DB) ListNodes(ctx context.Context) ([]*Node, error) {
-	rows, err := db.Pool.Query(ctx, `
-		SELECT id, name, fqdn, scheme, grpc_port, location, memory_total, memory_allocated, disk_total, disk_allocated, daemon_token_hash, maintenance_mode, created_at, updated_at
-		FROM nodes ORDER BY name
-	`)
+	rows, err := db.Pool.Query(ctx, `SELECT `+nodeColumns+` FROM nodes ORDER BY name`)
 	if err != nil {
 		return nil, err
 	}
@@ -82,30 +113,138 @@ func (db *DB) ListNodes(ctx context.Context) ([]*Node, error) {
 
 	var nodes []*Node
 	for rows.Next() {
-		var node Node
-		if err := rows.Scan(&node.ID, &node.Name, &node.FQDN, &node.Scheme, &node.GRPCPort, &node.Location, &node.MemoryTotal, &node.MemoryAllocated, &node.DiskTotal, &node.DiskAllocated, &node.DaemonTokenHash, &node.MaintenanceMode, &node.CreatedAt, &node.UpdatedAt); err != nil {
+		node, err := scanNode(rows)
+		if err != nil {
 			return nil, err
 		}
-		nodes = append(nodes, &node)
+		nodes = append(nodes, node)
 	}
 
 	return nodes, nil
 }
 
-// UpdateNodeResources updates the allocated resources for a node
-func (db *DB) UpdateNodeResources(ctx context.Context, id uuid.UUID, memoryAllocated, diskAllocated int64) error {
+// MarkNodeSeen records a successful probe of a node, clearing any unreachable flag
+func (db *DB) MarkNodeSeen(ctx context.Context, id uuid.UUID) error {
 	_, err := db.Pool.Exec(ctx, `
-		UPDATE nodes SET memory_allocated = $2, disk_allocated = $3, updated_at = $4 WHERE id = $1
-	`, id, memoryAllocated, diskAllocated, time.Now())
+		UPDATE nodes SET last_seen_at = $2, unreachable_since = NULL WHERE id = $1
+	`, id, time.Now())
 	return err
 }
 
-// DeleteNode deletes a node by ID
-func (db *DB) DeleteNode(ctx context.Context, id uuid.UUID) error {
-	_, err := db.Pool.Exec(ctx, `DELETE FROM nodes WHERE id = $1`, id)
+// MarkNodeUnreachable flags a node that has failed repeated probes. The
+// original time is kept if it is already flagged.
+func (db *DB) MarkNodeUnreachable(ctx context.Context, id uuid.UUID) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE nodes SET unreachable_since = COALESCE(unreachable_since, $2) WHERE id = $1
+	`, id, time.Now())
 	return err
 }
 
+// UpdateNode saves a node's settings: name, address, location, capacity,
+// overcommit ratios, labels, token and maintenance mode
+func (db *DB) UpdateNode(ctx context.Context, node *Node) error {
+	node.UpdatedAt = time.Now()
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE nodes SET name = $2, fqdn = $3, scheme = $4, grpc_port = $5, location = $6,
+			memory_total = $7, cpu_total = $8, disk_total = $9, memory_overcommit = $10, cpu_overcommit = $11,
+			disk_overcommit = $12, daemon_token_hash = $13, maintenance_mode = $14, updated_at = $15, labels = $16
+		WHERE id = $1
+	`, node.ID, node.Name, node.FQDN, node.Scheme, node.GRPCPort, node.Location,
+		node.MemoryTotal, node.CPUTotal, node.DiskTotal, node.MemoryOvercommit, node.CPUOvercommit,
+		node.DiskOvercommit, node.DaemonTokenHash, node.MaintenanceMode, node.UpdatedAt, node.Labels)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrNodeNameTaken
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNodeNotFound
+	}
+	return nil
+}
+
+// ListServersByNode returns a summary of every server placed on a node
+func (db *DB) ListServersByNode(ctx context.Context, nodeID uuid.UUID) ([]*models.Server, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, user_id, name, status, docker_image, memory_limit, disk_limit, cpu_limit, created_at
+		FROM servers WHERE node_id = $1
+		ORDER BY created_at
+	`, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	servers := []*models.Server{}
+	for rows.Next() {
+		s := models.Server{NodeID: nodeID}
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Name, &s.Status, &s.DockerImage, &s.MemoryLimit, &s.DiskLimit, &s.CPULimit, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		servers = append(servers, &s)
+	}
+	return servers, rows.Err()
+}
+
+// DeleteNode deletes a node by ID, with its allocations. A node that still
+// has servers or assigned allocations is refused with ErrNodeInUse, unless
+// force is set: then the servers' records are deleted too, and their IDs
+// returned so their containers can be removed.
+func (db *DB) DeleteNode(ctx context.Context, id uuid.UUID, force bool) ([]uuid.UUID, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockNode(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	var servers, assigned int
+	err = tx.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM servers WHERE node_id = $1),
+		       (SELECT COUNT(*) FROM allocations WHERE node_id = $1 AND server_id IS NOT NULL)
+	`, id).Scan(&servers, &assigned)
+	if err != nil {
+		return nil, err
+	}
+	if !force && (servers > 0 || assigned > 0) {
+		return nil, fmt.Errorf("%w: %d servers and %d assigned allocations", ErrNodeInUse, servers, assigned)
+	}
+
+	// Servers go first: their primary allocations would block the cascade.
+	// With the node locked no server can be placed on it meanwhile, so these
+	// are exactly the servers it had.
+	rows, err := tx.Query(ctx, `DELETE FROM servers WHERE node_id = $1 RETURNING id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var serverIDs []uuid.UUID
+	for rows.Next() {
+		var serverID uuid.UUID
+		if err := rows.Scan(&serverID); err != nil {
+			return nil, err
+		}
+		serverIDs = append(serverIDs, serverID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM nodes WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return serverIDs, nil
+}
+
 // GetAddress returns the gRPC address for connecting to the node
 func (node *Node) GetAddress() string {
 	return fmt.Sprintf("%s:%d", node.FQDN, node.GRPCPort)
@@ -2,40 +2,69 @@ package database
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 
 	"github.com/ironhost/master/internal/models"
 )
 
-// CreateServer creates a new server record
+// CreateServer creates a new server record. Its node is locked while the
+// server is added to its allocated totals, and it is refused if the node is in
+// maintenance mode or lacks the capacity.
 func (db *DB) CreateServer(ctx context.Context, server *models.Server) error {
-	_, err := db.Pool.Exec(ctx, `
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	node, err := lockNodeForUpdate(ctx, tx, server.NodeID)
+	if err != nil {
+		return err
+	}
+	if node.MaintenanceMode {
+		return ErrNodeMaintenance
+	}
+	if err := node.CheckCapacity(server.MemoryLimit, server.CPULimit, server.DiskLimit); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
 		INSERT INTO servers (
 			id, user_id, node_id, name, description, memory_limit, disk_limit, cpu_limit, 
-			docker_image, status, environment, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			docker_image, status, environment, template_id, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`,
 		server.ID, server.UserID, server.NodeID, server.Name, server.Description,
 		server.MemoryLimit, server.DiskLimit, server.CPULimit, server.DockerImage,
-		server.Status, server.Environment, server.CreatedAt, server.UpdatedAt,
+		server.Status, server.Environment, server.TemplateID, server.CreatedAt, server.UpdatedAt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if err := syncNodeAllocated(ctx, tx, server.NodeID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 // GetServer retrieves a server by ID
 func (db *DB) GetServer(ctx context.Context, id uuid.UUID) (*models.Server, error) {
 	var server models.Server
 	err := db.Pool.QueryRow(ctx, `
-		SELECT id, user_id, node_id, name, description, memory_limit, disk_limit, cpu_limit,
-		       docker_image, status, primary_allocation_id, environment, created_at, updated_at
+		SELECT id, user_id, node_id, name, description, memory_limit, disk_limit, cpu_limit, io_weight,
+		       docker_image, status, primary_allocation_id, environment, missing_since, template_id,
+		       install_exit_code, installed_at, created_at, updated_at
 		FROM servers WHERE id = $1
 	`, id).Scan(
 		&server.ID, &server.UserID, &server.NodeID, &server.Name, &server.Description,
-		&server.MemoryLimit, &server.DiskLimit, &server.CPULimit, &server.DockerImage,
-		&server.Status, &server.PrimaryAllocationID, &server.Environment, &server.CreatedAt, &server.UpdatedAt,
+		&server.MemoryLimit, &server.DiskLimit, &server.CPULimit, &server.IOWeight, &server.DockerImage,
+		&server.Status, &server.PrimaryAllocationID, &server.Environment, &server.MissingSince, &server.TemplateID,
+		&server.InstallExitCode, &server.InstalledAt, &server.CreatedAt, &server.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -52,6 +81,15 @@ func (db *DB) GetServer(ctx context.Context, id uuid.UUID) (*models.Server, erro
 		}
 	}
 
+	if allocations, err := db.ListServerAllocations(ctx, server.ID); err == nil {
+		server.Allocations = allocations
+		for i := range allocations {
+			if server.PrimaryAllocationID != nil && allocations[i].ID == *server.PrimaryAllocationID {
+				server.PrimaryAllocation = &allocations[i]
+			}
+		}
+	}
+
 	return &server, nil
 }
 
@@ -63,90 +101,100 @@ func (db *DB) UpdateServerStatus(ctx context.Context, id uuid.UUID, status model
 	return err
 }
 
-// UpdateServerContainerID sets the container ID
-func (db *DB) UpdateServerContainerID(ctx context.Context, id uuid.UUID, containerID string) error {
+// RecordInstall stores the exit code of an install script run
+func (db *DB) RecordInstall(ctx context.Context, id uuid.UUID, exitCode int) error {
 	_, err := db.Pool.Exec(ctx, `
-		UPDATE servers SET container_id = $2, updated_at = $3 WHERE id = $1
-	`, id, containerID, time.Now())
+		UPDATE servers SET install_exit_code = $2, installed_at = $3 WHERE id = $1
+	`, id, exitCode, time.Now())
 	return err
 }
 
-// DeleteServer removes a server
-func (db *DB) DeleteServer(ctx context.Context, id uuid.UUID) error {
-	_, err := db.Pool.Exec(ctx, `DELETE FROM servers WHERE id = $1`, id)
-	return err
+// SyncServerStatus records a status reported by the agent on nodeID. Reports
+// for servers placed on another node are ignored, and suspended servers keep
+// their status. It returns whether the stored status changed.
+func (db *DB) SyncServerStatus(ctx context.Context, id, nodeID uuid.UUID, status models.ServerStatus) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE servers SET status = $3, updated_at = $4
+		WHERE id = $1 AND node_id = $2 AND status <> $3 AND status <> $5
+	`, id, nodeID, status, time.Now(), models.StatusSuspended)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
 }
 
-// AssignAllocation finds a free port on the node and assigns it to the server
-// This is a simplified "Just-in-Time" allocation strategy
-func (db *DB) AssignAllocation(ctx context.Context, serverID uuid.UUID, nodeID uuid.UUID) (*models.Allocation, error) {
-	// Start transaction
-	tx, err := db.Pool.Begin(ctx)
+// ListServerStatusesByNode returns the ID, status and missing flag of every
+// server placed on a node
+func (db *DB) ListServerStatusesByNode(ctx context.Context, nodeID uuid.UUID) ([]*models.Server, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, status, missing_since FROM servers WHERE node_id = $1
+	`, nodeID)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback(ctx)
-
-	// Simple strategy: Try ports 25565 to 25600 until one is not in allocations table for this node
-	startPort := 25565
-	endPort := 25600
-	var assignedPort int
+	defer rows.Close()
 
-	for port := startPort; port <= endPort; port++ {
-		var exists bool
-		err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM allocations WHERE node_id = $1 AND port = $2)`, nodeID, port).Scan(&exists)
-		if err != nil {
+	var servers []*models.Server
+	for rows.Next() {
+		s := models.Server{NodeID: nodeID}
+		if err := rows.Scan(&s.ID, &s.Status, &s.MissingSince); err != nil {
 			return nil, err
 		}
-		if !exists {
-			assignedPort = port
-			break
-		}
+		servers = append(servers, &s)
 	}
+	return servers, rows.Err()
+}
 
-	if assignedPort == 0 {
-		return nil, fmt.Errorf("no free ports available in range %d-%d", startPort, endPort)
+// SetServerMissing flags a server whose node has no container for it, or
+// clears the flag. The original time is kept while it stays missing.
+func (db *DB) SetServerMissing(ctx context.Context, id uuid.UUID, missing bool) error {
+	var err error
+	if missing {
+		_, err = db.Pool.Exec(ctx, `
+			UPDATE servers SET missing_since = COALESCE(missing_since, $2) WHERE id = $1
+		`, id, time.Now())
+	} else {
+		_, err = db.Pool.Exec(ctx, `UPDATE servers SET missing_since = NULL WHERE id = $1`, id)
 	}
+	return err
+}
 
-	// Get Node IP (FQDN) to use in allocation
-	var nodeFQDN string
-	err = tx.QueryRow(ctx, `SELECT fqdn FROM nodes WHERE id = $1`, nodeID).Scan(&nodeFQDN)
+// UpdateServerContainerID sets the container ID
+func (db *DB) UpdateServerContainerID(ctx context.Context, id uuid.UUID, containerID string) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE servers SET container_id = $2, updated_at = $3 WHERE id = $1
+	`, id, containerID, time.Now())
+	return err
+}
+
+// DeleteServer removes a server and gives its resources back to its node
+func (db *DB) DeleteServer(ctx context.Context, id uuid.UUID) error {
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer tx.Rollback(ctx)
 
-	// Create allocation
-	allocation := &models.Allocation{
-		ID:        uuid.New(),
-		NodeID:    nodeID,
-		ServerID:  &serverID,
-		IPAddress: nodeFQDN, // Use Node FQDN/IP as allocation IP
-		Port:      assignedPort,
-		Assigned:  true,
-		CreatedAt: time.Now(),
+	var nodeID uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT node_id FROM servers WHERE id = $1 FOR UPDATE`, id).Scan(&nodeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
 	}
-
-	_, err = tx.Exec(ctx, `
-		INSERT INTO allocations (id, node_id, server_id, ip_address, port, assigned, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`, allocation.ID, allocation.NodeID, allocation.ServerID, allocation.IPAddress, allocation.Port, allocation.Assigned, allocation.CreatedAt)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	// Link server to primary allocation
-	_, err = tx.Exec(ctx, `
-		UPDATE servers SET primary_allocation_id = $1 WHERE id = $2
-	`, allocation.ID, serverID)
-	if err != nil {
-		return nil, err
+	if _, err := lockNodeForUpdate(ctx, tx, nodeID); err != nil {
+		return err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return nil, err
+	if _, err := tx.Exec(ctx, `DELETE FROM servers WHERE id = $1`, id); err != nil {
+		return err
+	}
+	if err := syncNodeAllocated(ctx, tx, nodeID); err != nil {
+		return err
 	}
 
-	return allocation, nil
+	return tx.Commit(ctx)
 }
 
 // ListServers returns all servers (admin only)
@@ -175,6 +223,25 @@ func (db *DB) ListServers(ctx context.Context) ([]*models.Server, error) {
 	return servers, nil
 }
 
+// ListServerIDsByNode returns the IDs of all servers placed on a node
+func (db *DB) ListServerIDsByNode(ctx context.Context, nodeID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT id FROM servers WHERE node_id = $1`, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // ListServersByUserID returns only servers owned by the given user
 func (db *DB) ListServersByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Server, error) {
 	rows, err := db.Pool.Query(ctx, `
@@ -202,12 +269,57 @@ func (db *DB) ListServersByUserID(ctx context.Context, userID uuid.UUID) ([]*mod
 	return servers, nil
 }
 
-// UpdateServerResources updates the resource limits for a server
-func (db *DB) UpdateServerResources(ctx context.Context, id uuid.UUID, memoryLimit int64, cpuLimit int, diskLimit int64) error {
-	_, err := db.Pool.Exec(ctx, `
-		UPDATE servers SET memory_limit = $2, cpu_limit = $3, disk_limit = $4, updated_at = $5 WHERE id = $1
-	`, id, memoryLimit, cpuLimit, diskLimit, time.Now())
-	return err
+// UpdateServerResources updates the resource limits and IO weight of a server.
+// apply is called with the new limits while the row is updated and locked; if
+// it fails the change is rolled back, and if the commit fails afterwards apply
+// is called again with the old limits, so the stored limits never disagree
+// with what the agent enforces.
+// Raised limits must fit in the node's free capacity (see Node.CheckCapacity).
+func (db *DB) UpdateServerResources(ctx context.Context, id uuid.UUID, memoryLimit int64, cpuLimit int, diskLimit int64, ioWeight int,
+	apply func(memoryLimit int64, cpuLimit int, diskLimit int64, ioWeight int) error) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var nodeID uuid.UUID
+	var oldMemory, oldDisk int64
+	var oldCPU, oldIOWeight int
+	err = tx.QueryRow(ctx, `
+		SELECT node_id, memory_limit, cpu_limit, disk_limit, io_weight FROM servers WHERE id = $1 FOR UPDATE
+	`, id).Scan(&nodeID, &oldMemory, &oldCPU, &oldDisk, &oldIOWeight)
+	if err != nil {
+		return err
+	}
+	node, err := lockNodeForUpdate(ctx, tx, nodeID)
+	if err != nil {
+		return err
+	}
+	if err := node.CheckCapacity(memoryLimit-oldMemory, cpuLimit-oldCPU, diskLimit-oldDisk); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE servers SET memory_limit = $2, cpu_limit = $3, disk_limit = $4, io_weight = $5, updated_at = $6 WHERE id = $1
+	`, id, memoryLimit, cpuLimit, diskLimit, ioWeight, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := syncNodeAllocated(ctx, tx, nodeID); err != nil {
+		return err
+	}
+
+	if apply == nil {
+		return tx.Commit(ctx)
+	}
+	if err := apply(memoryLimit, cpuLimit, diskLimit, ioWeight); err != nil {
+		return err
+	}
+
+	return commitApplied(ctx, tx, func() error {
+		return apply(oldMemory, oldCPU, oldDisk, oldIOWeight)
+	})
 }
 
 // UpdateServerName updates the name of a server
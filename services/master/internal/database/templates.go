@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/ironhost/master/internal/models"
+)
+
+var (
+	// ErrTemplateNotFound is returned for templates that do not exist
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrTemplateNameTaken is returned when another template already has the name
+	ErrTemplateNameTaken = errors.New("a template with this name already exists")
+	// ErrTemplateInUse is returned when deleting a template that servers were created from
+	ErrTemplateInUse = errors.New("template is used by servers")
+	// ErrTemplateDefault is returned when deleting the template servers get by default
+	ErrTemplateDefault = errors.New("the default template cannot be deleted")
+)
+
+// templateError maps a unique violation on the name to ErrTemplateNameTaken
+func templateError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrTemplateNameTaken
+	}
+	return err
+}
+
+const templateColumns = `
+	id, name, description, docker_images, container_port, protocol, startup_command, stop_command,
+	command_transport, data_mount, variables, readiness_probes, config_files, install_image,
+	install_entrypoint, install_script, created_at, updated_at`
+
+// scanTemplate reads a row selected with templateColumns
+func scanTemplate(row pgx.Row) (*models.ServerTemplate, error) {
+	var t models.ServerTemplate
+	var description *string
+	err := row.Scan(
+		&t.ID, &t.Name, &description, &t.DockerImages, &t.ContainerPort, &t.Protocol, &t.StartupCommand, &t.StopCommand,
+		&t.CommandTransport, &t.DataMount, &t.Variables, &t.ReadinessProbes, &t.ConfigFiles, &t.InstallImage,
+		&t.InstallEntrypoint, &t.InstallScript, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if description != nil {
+		t.Description = *description
+	}
+	return &t, nil
+}
+
+// CreateTemplate stores a new server template
+func (db *DB) CreateTemplate(ctx context.Context, t *models.ServerTemplate) error {
+	t.ID = uuid.New()
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = t.CreatedAt
+
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO server_templates (`+templateColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	`,
+		t.ID, t.Name, t.Description, t.DockerImages, t.ContainerPort, t.Protocol, t.StartupCommand, t.StopCommand,
+		t.CommandTransport, t.DataMount, t.Variables, t.ReadinessProbes, t.ConfigFiles, t.InstallImage,
+		t.InstallEntrypoint, t.InstallScript, t.CreatedAt, t.UpdatedAt,
+	)
+	return templateError(err)
+}
+
+// GetTemplate retrieves a server template by ID
+func (db *DB) GetTemplate(ctx context.Context, id uuid.UUID) (*models.ServerTemplate, error) {
+	return scanTemplate(db.Pool.QueryRow(ctx, `SELECT `+templateColumns+` FROM server_templates WHERE id = $1`, id))
+}
+
+// ListTemplates returns all server templates by name
+func (db *DB) ListTemplates(ctx context.Context) ([]*models.ServerTemplate, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+templateColumns+` FROM server_templates ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []*models.ServerTemplate{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// UpdateTemplate replaces a server template. Existing servers keep the
+// settings they were created with; changes apply to servers created afterwards.
+func (db *DB) UpdateTemplate(ctx context.Context, t *models.ServerTemplate) error {
+	t.UpdatedAt = time.Now()
+
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE server_templates SET
+			name = $2, description = $3, docker_images = $4, container_port = $5, protocol = $6,
+			startup_command = $7, stop_command = $8, command_transport = $9, data_mount = $10,
+			variables = $11, readiness_probes = $12, config_files = $13, install_image = $14,
+			install_entrypoint = $15, install_script = $16, updated_at = $17
+		WHERE id = $1
+		RETURNING created_at
+	`,
+		t.ID, t.Name, t.Description, t.DockerImages, t.ContainerPort, t.Protocol,
+		t.StartupCommand, t.StopCommand, t.CommandTransport, t.DataMount,
+		t.Variables, t.ReadinessProbes, t.ConfigFiles, t.InstallImage,
+		t.InstallEntrypoint, t.InstallScript, t.UpdatedAt,
+	).Scan(&t.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTemplateNotFound
+	}
+	return templateError(err)
+}
+
+// DeleteTemplate removes a server template, unless servers were created from
+// it or it is the default template
+func (db *DB) DeleteTemplate(ctx context.Context, id uuid.UUID) error {
+	if id == models.DefaultTemplateID {
+		return ErrTemplateDefault
+	}
+
+	var inUse bool
+	if err := db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM servers WHERE template_id = $1)`, id).Scan(&inUse); err != nil {
+		return err
+	}
+	if inUse {
+		return ErrTemplateInUse
+	}
+
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM server_templates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
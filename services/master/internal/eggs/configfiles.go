@@ -0,0 +1,144 @@
+package eggs
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ironhost/master/internal/models"
+)
+
+// wingsPlaceholder matches the {{...}} placeholders of startup commands and
+// config files
+var wingsPlaceholder = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// envName matches a plain {{VAR}} placeholder
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// builtinPlaceholders are the placeholders IronHost fills in itself
+var builtinPlaceholders = map[string]bool{
+	"SERVER_MEMORY": true,
+	"SERVER_PORT":   true,
+	"SERVER_IP":     true,
+}
+
+// placeholders rewrites Wings placeholders such as {{server.build.default.port}}
+// to their IronHost names. Ones without an equivalent are reported and kept.
+func (im *importer) placeholders(where, s string) string {
+	return wingsPlaceholder.ReplaceAllStringFunc(s, func(match string) string {
+		key := wingsPlaceholder.FindStringSubmatch(match)[1]
+		switch {
+		case key == "server.build.default.port":
+			return "{{SERVER_PORT}}"
+		case key == "server.build.default.ip":
+			return "{{SERVER_IP}}"
+		case key == "server.build.memory":
+			return "{{SERVER_MEMORY}}"
+		case strings.HasPrefix(key, "server.build.env."):
+			return "{{" + strings.TrimPrefix(key, "server.build.env.") + "}}"
+		case strings.HasPrefix(key, "env."):
+			return "{{" + strings.TrimPrefix(key, "env.") + "}}"
+		case envName.MatchString(key):
+			return "{{" + key + "}}"
+		}
+		im.warnf("%s: placeholder %s is not supported and is left as is", where, match)
+		return match
+	})
+}
+
+// startupVariables reports {{VAR}} placeholders that no variable of the
+// template defines, which would reach the server unsubstituted
+func (im *importer) startupVariables(t *models.ServerTemplate) {
+	check := func(where, s string) {
+		for _, m := range wingsPlaceholder.FindAllStringSubmatch(s, -1) {
+			if envName.MatchString(m[1]) && !builtinPlaceholders[m[1]] && !t.HasVariable(m[1]) {
+				im.warnf("%s: {{%s}} is not a variable of the egg", where, m[1])
+			}
+		}
+	}
+
+	check("startup command", t.StartupCommand)
+	for _, f := range t.ConfigFiles {
+		for _, value := range f.Set {
+			check("config file "+f.Path, value)
+		}
+	}
+}
+
+// eggConfigFile is one entry of config.files, keyed by path
+type eggConfigFile struct {
+	Parser string                     `json:"parser"`
+	Find   map[string]json.RawMessage `json:"find"`
+}
+
+// configFiles converts config.files. The properties, file and json parsers
+// carry over; yaml, ini and xml files are reported and skipped.
+func (im *importer) configFiles(raw json.RawMessage) []models.ConfigFile {
+	files := []models.ConfigFile{}
+	var eggFiles map[string]eggConfigFile
+	if !im.decodeConfig("config.files", raw, &eggFiles) {
+		return files
+	}
+
+	paths := make([]string, 0, len(eggFiles))
+	for p := range eggFiles {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		ef := eggFiles[p]
+		parser := models.ConfigParser(ef.Parser)
+		switch parser {
+		case models.ParserProperties, models.ParserFile, models.ParserJSON:
+		default:
+			im.warnf("config file %s uses the %s parser, which is not supported; skipped", p, ef.Parser)
+			continue
+		}
+
+		file := models.ConfigFile{
+			Path:   strings.TrimPrefix(p, "/"),
+			Parser: parser,
+			Set:    make(map[string]string, len(ef.Find)),
+		}
+		for key, rawValue := range ef.Find {
+			if parser == models.ParserJSON && strings.ContainsAny(key, "*[") {
+				im.warnf("config file %s: wildcard key %q is not supported; skipped", p, key)
+				continue
+			}
+			value, ok := scalar(rawValue)
+			if !ok {
+				im.warnf("config file %s: %q has a conditional or structured value, which is not supported; skipped", p, key)
+				continue
+			}
+			file.Set[key] = im.placeholders("config file "+p, value)
+		}
+
+		if err := file.Validate(); err != nil {
+			im.warnf("config file %s skipped: %v", p, err)
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// scalar returns a JSON string, number or boolean as text
+func scalar(raw json.RawMessage) (string, bool) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s, true
+	}
+	raw = bytes.TrimSpace(raw)
+	var v any
+	if json.Unmarshal(raw, &v) != nil {
+		return "", false
+	}
+	switch v.(type) {
+	case float64, bool:
+		return string(raw), true
+	}
+	return "", false
+}
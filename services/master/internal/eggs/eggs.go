@@ -0,0 +1,284 @@
+// Package eggs imports Pterodactyl eggs (PTDL_v1 and PTDL_v2 JSON exports)
+// as IronHost server templates. Egg features without an IronHost equivalent
+// are left out and reported as warnings, so an admin can review the result.
+package eggs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ironhost/master/internal/models"
+)
+
+// DefaultContainerPort is used when the caller does not choose a port. Eggs
+// do not declare one: Pterodactyl binds the game to the allocation's port.
+const DefaultContainerPort = 25565
+
+// eggDataMount is where Pterodactyl images expect the server's files
+const eggDataMount = "/home/container"
+
+// Options adjusts how an egg is imported
+type Options struct {
+	ContainerPort int // Game port inside the container (0 = DefaultContainerPort)
+}
+
+// Result is an imported template and what could not be carried over
+type Result struct {
+	Template *models.ServerTemplate `json:"template"`
+	Warnings []string               `json:"warnings"`
+}
+
+// egg is the subset of the export format the importer reads
+type egg struct {
+	Meta struct {
+		Version string `json:"version"`
+	} `json:"meta"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	Features     []string        `json:"features"`
+	DockerImages json.RawMessage `json:"docker_images"` // Display name to image, in order of preference
+	Image        string          `json:"image"`         // Single image of older PTDL_v1 exports
+	FileDenylist []string        `json:"file_denylist"`
+	Startup      string          `json:"startup"`
+	Config       struct {
+		Files   json.RawMessage `json:"files"`   // JSON-encoded string, or an object
+		Startup json.RawMessage `json:"startup"` // JSON-encoded string, or an object
+		Logs    json.RawMessage `json:"logs"`
+		Stop    string          `json:"stop"`
+		Extends json.RawMessage `json:"extends"`
+	} `json:"config"`
+	Scripts struct {
+		Installation struct {
+			Script     string `json:"script"`
+			Container  string `json:"container"`
+			Entrypoint string `json:"entrypoint"`
+		} `json:"installation"`
+	} `json:"scripts"`
+	Variables []eggVariable `json:"variables"`
+}
+
+type eggVariable struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	EnvVariable  string          `json:"env_variable"`
+	DefaultValue json.RawMessage `json:"default_value"`
+	UserViewable bool            `json:"user_viewable"`
+	UserEditable bool            `json:"user_editable"`
+	Rules        json.RawMessage `json:"rules"` // "required|string|max:20" or a list of rules
+}
+
+// importer collects the warnings of one import
+type importer struct {
+	warnings []string
+}
+
+func (im *importer) warnf(format string, args ...any) {
+	im.warnings = append(im.warnings, fmt.Sprintf(format, args...))
+}
+
+// Import converts an egg export into a server template. The template is
+// validated but not stored.
+func Import(data []byte, opts Options) (*Result, error) {
+	var e egg
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("invalid egg JSON: %w", err)
+	}
+	switch e.Meta.Version {
+	case "PTDL_v1", "PTDL_v2":
+	default:
+		return nil, fmt.Errorf("unsupported egg format %q (expected PTDL_v1 or PTDL_v2)", e.Meta.Version)
+	}
+
+	port := opts.ContainerPort
+	if port == 0 {
+		port = DefaultContainerPort
+	}
+
+	im := &importer{}
+	t := &models.ServerTemplate{
+		Name:             strings.TrimSpace(e.Name),
+		Description:      strings.TrimSpace(e.Description),
+		ContainerPort:    port,
+		Protocol:         "tcp",
+		StartupCommand:   im.placeholders("startup command", e.Startup),
+		CommandTransport: models.TransportStdin,
+		DataMount:        eggDataMount,
+	}
+	if opts.ContainerPort == 0 {
+		im.warnf("eggs do not declare a game port; assuming %d/tcp inside the container", port)
+	} else {
+		im.warnf("eggs do not declare a protocol; assuming tcp")
+	}
+
+	images, err := im.images(&e)
+	if err != nil {
+		return nil, err
+	}
+	t.DockerImages = images
+
+	t.Variables = im.variables(e.Variables)
+	t.StopCommand = im.stopCommand(e.Config.Stop)
+	t.ReadinessProbes = im.readinessProbes(e.Config.Startup)
+	t.ConfigFiles = im.configFiles(e.Config.Files)
+
+	install := e.Scripts.Installation
+	if strings.TrimSpace(install.Script) != "" {
+		t.InstallImage = install.Container
+		t.InstallEntrypoint = install.Entrypoint
+		t.InstallScript = strings.ReplaceAll(install.Script, "\r\n", "\n")
+	}
+
+	im.startupVariables(t)
+	im.unsupported(&e)
+
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("imported template is invalid: %w", err)
+	}
+	if im.warnings == nil {
+		im.warnings = []string{}
+	}
+	return &Result{Template: t, Warnings: im.warnings}, nil
+}
+
+// images reads docker_images in the order the egg lists them, so the first
+// stays the default
+func (im *importer) images(e *egg) ([]models.TemplateImage, error) {
+	var images []models.TemplateImage
+	raw := bytes.TrimSpace(e.DockerImages)
+	if len(raw) > 0 && !bytes.Equal(raw, []byte("null")) {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil, fmt.Errorf("docker_images must be an object")
+		}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("invalid docker_images: %w", err)
+			}
+			var image string
+			if err := dec.Decode(&image); err != nil {
+				return nil, fmt.Errorf("invalid docker_images: %w", err)
+			}
+			images = append(images, models.TemplateImage{Name: tok.(string), Image: image})
+		}
+	}
+	if len(images) == 0 && e.Image != "" {
+		images = append(images, models.TemplateImage{Name: e.Image, Image: e.Image})
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("egg has no docker images")
+	}
+	return images, nil
+}
+
+// stopCommand maps config.stop. "^C" and similar ask Wings for a signal
+// instead of a console command.
+func (im *importer) stopCommand(stop string) string {
+	stop = strings.TrimSpace(stop)
+	if strings.HasPrefix(stop, "^") {
+		im.warnf("stop signal %q is not supported; servers are stopped with SIGTERM", stop)
+		return ""
+	}
+	return stop
+}
+
+// readinessProbes turns config.startup.done into log probes
+func (im *importer) readinessProbes(raw json.RawMessage) []models.ReadinessProbe {
+	var startup struct {
+		Done            json.RawMessage `json:"done"`
+		UserInteraction []string        `json:"userInteraction"`
+	}
+	if !im.decodeConfig("config.startup", raw, &startup) {
+		return []models.ReadinessProbe{}
+	}
+	if len(startup.UserInteraction) > 0 {
+		im.warnf("config.startup.userInteraction is not supported")
+	}
+
+	var done []string
+	if len(startup.Done) > 0 && json.Unmarshal(startup.Done, &done) != nil {
+		var single string
+		if err := json.Unmarshal(startup.Done, &single); err != nil {
+			im.warnf("config.startup.done is not a string or list; no readiness probe imported")
+			return []models.ReadinessProbe{}
+		}
+		done = []string{single}
+	}
+
+	probes := []models.ReadinessProbe{}
+	for _, d := range done {
+		if d == "" {
+			continue
+		}
+		pattern := regexp.QuoteMeta(d)
+		if expr, ok := strings.CutPrefix(d, "regex:"); ok {
+			if _, err := regexp.Compile(expr); err != nil {
+				im.warnf("startup pattern %q is not a valid Go regular expression; skipped", expr)
+				continue
+			}
+			pattern = expr
+		}
+		probes = append(probes, models.ReadinessProbe{Kind: "log", Pattern: pattern})
+	}
+	return probes
+}
+
+// unsupported reports egg settings IronHost has no equivalent for
+func (im *importer) unsupported(e *egg) {
+	for _, f := range e.Features {
+		im.warnf("egg feature %q is not supported", f)
+	}
+	if len(e.FileDenylist) > 0 {
+		im.warnf("file_denylist is not supported; %d entries ignored", len(e.FileDenylist))
+	}
+
+	var logs any
+	if im.decodeConfig("config.logs", e.Config.Logs, &logs) && !isEmpty(logs) {
+		im.warnf("config.logs is not supported")
+	}
+
+	extends := bytes.TrimSpace(e.Config.Extends)
+	if len(extends) > 0 && !bytes.Equal(extends, []byte("null")) {
+		im.warnf("config.extends is not supported; settings inherited from the parent egg are missing")
+	}
+}
+
+// decodeConfig decodes a config section, which exports store as a
+// JSON-encoded string and older ones as a plain object. It reports whether
+// the section was present.
+func (im *importer) decodeConfig(name string, raw json.RawMessage, v any) bool {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return false
+	}
+
+	var encoded string
+	if json.Unmarshal(raw, &encoded) == nil {
+		encoded = strings.TrimSpace(encoded)
+		if encoded == "" {
+			return false
+		}
+		raw = json.RawMessage(encoded)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		im.warnf("%s could not be parsed and was skipped: %v", name, err)
+		return false
+	}
+	return true
+}
+
+// isEmpty reports whether a decoded JSON value is an empty object or list
+func isEmpty(v any) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	case nil:
+		return true
+	}
+	return false
+}
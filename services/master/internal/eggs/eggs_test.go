@@ -0,0 +1,306 @@
+package eggs
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ironhost/master/internal/models"
+)
+
+func int64p(n int64) *int64 { return &n }
+
+// checkWarnings fails unless warnings is one warning containing want, or
+// empty if want is ""
+func checkWarnings(t *testing.T, warnings []string, want string) {
+	t.Helper()
+	if want == "" {
+		if len(warnings) > 0 {
+			t.Errorf("unexpected warnings: %q", warnings)
+		}
+		return
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], want) {
+		t.Errorf("warnings = %q, want one containing %q", warnings, want)
+	}
+}
+
+func TestImportPaper(t *testing.T) {
+	data, err := os.ReadFile("testdata/egg-paper.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Import(data, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := result.Template
+
+	if tmpl.Name != "Paper" || tmpl.ContainerPort != DefaultContainerPort || tmpl.Protocol != "tcp" {
+		t.Errorf("name, port, protocol = %s, %d, %s", tmpl.Name, tmpl.ContainerPort, tmpl.Protocol)
+	}
+	if tmpl.CommandTransport != models.TransportStdin || tmpl.DataMount != "/home/container" || tmpl.StopCommand != "stop" {
+		t.Errorf("transport, data mount, stop = %s, %s, %q", tmpl.CommandTransport, tmpl.DataMount, tmpl.StopCommand)
+	}
+	if len(tmpl.DockerImages) != 5 || tmpl.DockerImages[0] != (models.TemplateImage{Name: "Java 21", Image: "ghcr.io/pterodactyl/yolks:java_21"}) {
+		t.Errorf("docker images = %+v, want 5 starting with Java 21", tmpl.DockerImages)
+	}
+	if want := []models.ReadinessProbe{{Kind: "log", Pattern: `\)! For help, type `}}; !reflect.DeepEqual(tmpl.ReadinessProbes, want) {
+		t.Errorf("readiness probes = %+v, want %+v", tmpl.ReadinessProbes, want)
+	}
+	if tmpl.InstallImage != "ghcr.io/pterodactyl/installers:alpine" || tmpl.InstallEntrypoint != "ash" ||
+		!strings.HasPrefix(tmpl.InstallScript, "#!/bin/ash\n") || strings.Contains(tmpl.InstallScript, "\r") {
+		t.Errorf("install = %s %s, script starting %q", tmpl.InstallImage, tmpl.InstallEntrypoint, tmpl.InstallScript[:20])
+	}
+
+	wantVars := []models.TemplateVariable{
+		{Name: "Minecraft Version", EnvVariable: "MINECRAFT_VERSION", Type: models.VariableString, Default: "latest", UserEditable: true, MaxLength: 20},
+		{Name: "Server Jar File", EnvVariable: "SERVER_JARFILE", Type: models.VariableString, Default: "server.jar", Required: true, UserEditable: true, Pattern: `^([\w\d._-]+)(\.jar)$`},
+		{Name: "Download Path", EnvVariable: "DL_PATH", Type: models.VariableString},
+		{Name: "Build Number", EnvVariable: "BUILD_NUMBER", Type: models.VariableString, Default: "latest", Required: true, UserEditable: true, MaxLength: 20},
+	}
+	for i := range tmpl.Variables {
+		tmpl.Variables[i].Description = "" // Long, and copied verbatim
+	}
+	if !reflect.DeepEqual(tmpl.Variables, wantVars) {
+		t.Errorf("variables =\n%+v\nwant\n%+v", tmpl.Variables, wantVars)
+	}
+
+	wantWarnings := []string{
+		"eggs do not declare a game port; assuming 25565/tcp inside the container",
+		"variable DL_PATH is hidden from users in the egg; IronHost shows every variable",
+		`egg feature "eula" is not supported`,
+		`egg feature "java_version" is not supported`,
+		`egg feature "pid_limit" is not supported`,
+	}
+	if !reflect.DeepEqual(result.Warnings, wantWarnings) {
+		t.Errorf("warnings =\n%q\nwant\n%q", result.Warnings, wantWarnings)
+	}
+
+	// The startup command renders with the server's variables
+	env, err := tmpl.Environment(map[string]string{"SERVER_JARFILE": "paper-1.20.4.jar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "java -Xms128M -XX:MaxRAMPercentage=95.0 -Dterminal.jline=false -Dterminal.ansi=true -jar paper-1.20.4.jar"
+	if got := tmpl.RenderStartup(env, 2048); got != want {
+		t.Errorf("startup = %q, want %q", got, want)
+	}
+	if _, err := tmpl.Environment(map[string]string{"SERVER_JARFILE": "paper.zip"}); err == nil {
+		t.Error("SERVER_JARFILE=paper.zip passed the egg's regex rule")
+	}
+
+	// server.properties gets the game port, whatever it held before
+	if len(tmpl.ConfigFiles) != 1 {
+		t.Fatalf("config files = %+v, want server.properties", tmpl.ConfigFiles)
+	}
+	properties := tmpl.ConfigFiles[0]
+	out, err := properties.Apply("motd=A Minecraft Server\nserver-port=25566\n", tmpl.Placeholders(env, 2048))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "motd=A Minecraft Server\nserver-port=25565\nquery.port=25565\nserver-ip=0.0.0.0\n"; out != want {
+		t.Errorf("server.properties =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestImportRejects(t *testing.T) {
+	for name, data := range map[string]string{
+		"invalid JSON":   `{"meta":`,
+		"unknown format": `{"meta": {"version": "PTDL_v3"}, "docker_images": {"Java": "java"}}`,
+		"no images":      `{"meta": {"version": "PTDL_v2"}, "name": "Empty"}`,
+		"images list":    `{"meta": {"version": "PTDL_v2"}, "name": "List", "docker_images": ["java"]}`,
+	} {
+		if _, err := Import([]byte(data), Options{}); err == nil {
+			t.Errorf("%s: Import succeeded", name)
+		}
+	}
+}
+
+func TestImportUnknownStartupVariable(t *testing.T) {
+	data := `{
+		"meta": {"version": "PTDL_v1"},
+		"name": "Bare",
+		"image": "ghcr.io/pterodactyl/yolks:java_17",
+		"startup": "java -jar {{SERVER_JARFILE}} --port {{server.build.default.port}}"
+	}`
+	result, err := Import([]byte(data), Options{ContainerPort: 25566})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Template.StartupCommand; got != "java -jar {{SERVER_JARFILE}} --port {{SERVER_PORT}}" {
+		t.Errorf("startup = %q", got)
+	}
+	if img := result.Template.DockerImages; len(img) != 1 || img[0].Image != "ghcr.io/pterodactyl/yolks:java_17" {
+		t.Errorf("docker images = %+v, want the PTDL_v1 image", img)
+	}
+	want := []string{
+		"eggs do not declare a protocol; assuming tcp",
+		"startup command: {{SERVER_JARFILE}} is not a variable of the egg",
+	}
+	if !reflect.DeepEqual(result.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", result.Warnings, want)
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	for _, tc := range []struct {
+		in, want, warning string
+	}{
+		{"--port {{server.build.default.port}}", "--port {{SERVER_PORT}}", ""},
+		{"--ip {{ server.build.default.ip }}", "--ip {{SERVER_IP}}", ""},
+		{"-Xmx{{server.build.memory}}M", "-Xmx{{SERVER_MEMORY}}M", ""},
+		{"-jar {{server.build.env.SERVER_JARFILE}}", "-jar {{SERVER_JARFILE}}", ""},
+		{"-jar {{env.SERVER_JARFILE}}", "-jar {{SERVER_JARFILE}}", ""},
+		{"./run {{ JARFILE }}", "./run {{JARFILE}}", ""},
+		{"--cpus {{server.build.cpu}}", "--cpus {{server.build.cpu}}", "placeholder {{server.build.cpu}} is not supported"},
+	} {
+		im := &importer{}
+		if got := im.placeholders("startup command", tc.in); got != tc.want {
+			t.Errorf("placeholders(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+		checkWarnings(t, im.warnings, tc.warning)
+	}
+}
+
+func TestVariableRules(t *testing.T) {
+	for _, tc := range []struct {
+		rules   string // JSON
+		def     string
+		want    models.TemplateVariable // EnvVariable V, Name and UserEditable left out
+		warning string
+	}{
+		{`"required|string|max:20"`, "latest", models.TemplateVariable{Type: models.VariableString, Default: "latest", Required: true, MaxLength: 20}, ""},
+		{`"required|integer|between:1,100"`, "20", models.TemplateVariable{Type: models.VariableInteger, Default: "20", Required: true, Min: int64p(1), Max: int64p(100)}, ""},
+		{`["required", "integer", "min:0"]`, "", models.TemplateVariable{Type: models.VariableInteger, Required: true, Min: int64p(0)}, ""},
+		{`"nullable|numeric"`, "0.5", models.TemplateVariable{Type: models.VariableString, Default: "0.5", Pattern: `-?[0-9]*\.?[0-9]+`}, ""},
+		{`"required|in:vanilla,paper,purpur"`, "paper", models.TemplateVariable{Type: models.VariableEnum, Default: "paper", Required: true, Options: []string{"vanilla", "paper", "purpur"}}, ""},
+		{`"boolean"`, "1", models.TemplateVariable{Type: models.VariableBoolean, Default: "1"}, ""},
+		{`"required|regex:/^(true|false)$/|max:5"`, "true", models.TemplateVariable{Type: models.VariableString, Default: "true", Required: true, Pattern: `^(true|false)$`, MaxLength: 5}, ""},
+		{`"regex:/^[a-z]+$/i"`, "World", models.TemplateVariable{Type: models.VariableString, Default: "World", Pattern: `(?i)^[a-z]+$`}, ""},
+		{`"nullable|alpha_dash"`, "my-world_1", models.TemplateVariable{Type: models.VariableString, Default: "my-world_1", Pattern: `[A-Za-z0-9_-]*`}, ""},
+		{`"nullable|url"`, "", models.TemplateVariable{Type: models.VariableString}, `rule "url" is not supported`},
+		{`"string|min:3|max:16"`, "Steve", models.TemplateVariable{Type: models.VariableString, Default: "Steve", MaxLength: 16}, "minimum length 3 is not supported"},
+		{`"integer|max:lots"`, "", models.TemplateVariable{Type: models.VariableInteger}, `rule "max:lots" has an invalid argument`},
+		{`"numeric|between:0,1"`, "", models.TemplateVariable{Type: models.VariableString, Pattern: `-?[0-9]*\.?[0-9]+`}, "bounds of a decimal number are not supported"},
+		{`"regex:/^(?!-)[a-z-]+$/"`, "world", models.TemplateVariable{Type: models.VariableString, Default: "world"}, "pattern"},
+		{`"required|integer"`, "latest", models.TemplateVariable{Type: models.VariableString, Default: "latest", Required: true}, "imported as a plain string"},
+	} {
+		im := &importer{}
+		vars := im.variables([]eggVariable{{
+			EnvVariable:  "V",
+			DefaultValue: json.RawMessage(`"` + tc.def + `"`),
+			UserViewable: true,
+			Rules:        json.RawMessage(tc.rules),
+		}})
+		tc.want.EnvVariable = "V"
+		if len(vars) != 1 || !reflect.DeepEqual(vars[0], tc.want) {
+			t.Errorf("rules %s: variables = %+v, want %+v", tc.rules, vars, tc.want)
+		}
+		checkWarnings(t, im.warnings, tc.warning)
+	}
+}
+
+func TestSplitRules(t *testing.T) {
+	for rules, want := range map[string][]string{
+		`"required|string"`:                     {"required", "string"},
+		`"regex:/^(a|b)$/"`:                     {"regex:/^(a|b)$/"},
+		`"regex:/^(a|b)$/i|max:3"`:              {"regex:/^(a|b)$/i", "max:3"},
+		`"required|regex:#^[a-z|]+$#|nullable"`: {"required", "regex:#^[a-z|]+$#", "nullable"},
+		`"regex:/^\\/(a|b)$/|string"`:           {`regex:/^\/(a|b)$/`, "string"},
+		`["required", "regex:/^(a|b)$/"]`:       {"required", "regex:/^(a|b)$/"},
+		`null`:                                  nil,
+	} {
+		if got := splitRules(json.RawMessage(rules)); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitRules(%s) = %q, want %q", rules, got, want)
+		}
+	}
+}
+
+func TestConfigFiles(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		files   string // config.files, as an object
+		want    []models.ConfigFile
+		warning string
+	}{
+		{
+			name:  "properties",
+			files: `{"server.properties": {"parser": "properties", "find": {"server-port": "{{server.build.default.port}}", "enable-query": true}}}`,
+			want: []models.ConfigFile{{Path: "server.properties", Parser: models.ParserProperties,
+				Set: map[string]string{"server-port": "{{SERVER_PORT}}", "enable-query": "true"}}},
+		},
+		{
+			name:  "file",
+			files: `{"/settings.txt": {"parser": "file", "find": {"port=": "port={{server.build.default.port}}"}}}`,
+			want: []models.ConfigFile{{Path: "settings.txt", Parser: models.ParserFile,
+				Set: map[string]string{"port=": "port={{SERVER_PORT}}"}}},
+		},
+		{
+			name:  "json",
+			files: `{"config/server.json": {"parser": "json", "find": {"listener.port": "{{server.build.default.port}}", "max_players": 20}}}`,
+			want: []models.ConfigFile{{Path: "config/server.json", Parser: models.ParserJSON,
+				Set: map[string]string{"listener.port": "{{SERVER_PORT}}", "max_players": "20"}}},
+		},
+		{
+			name:    "yaml skipped",
+			files:   `{"config.yml": {"parser": "yaml", "find": {"listeners[0].host": "0.0.0.0:{{server.build.default.port}}"}}}`,
+			want:    []models.ConfigFile{},
+			warning: "uses the yaml parser, which is not supported",
+		},
+		{
+			name:  "json wildcard key",
+			files: `{"config.json": {"parser": "json", "find": {"servers.*.port": "1", "motd": "hi"}}}`,
+			want: []models.ConfigFile{{Path: "config.json", Parser: models.ParserJSON,
+				Set: map[string]string{"motd": "hi"}}},
+			warning: `wildcard key "servers.*.port" is not supported`,
+		},
+		{
+			name:  "conditional value",
+			files: `{"server.properties": {"parser": "properties", "find": {"server-ip": {"127.0.0.1": "0.0.0.0"}, "server-port": "25565"}}}`,
+			want: []models.ConfigFile{{Path: "server.properties", Parser: models.ParserProperties,
+				Set: map[string]string{"server-port": "25565"}}},
+			warning: `"server-ip" has a conditional or structured value`,
+		},
+		{
+			name:    "path outside the data directory",
+			files:   `{"../server.properties": {"parser": "properties", "find": {"server-port": "25565"}}}`,
+			want:    []models.ConfigFile{},
+			warning: "path must be a clean path inside the data directory",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			im := &importer{}
+			got := im.configFiles(json.RawMessage(tc.files))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("config files = %+v, want %+v", got, tc.want)
+			}
+			checkWarnings(t, im.warnings, tc.warning)
+		})
+	}
+}
+
+func TestReadinessProbes(t *testing.T) {
+	for _, tc := range []struct {
+		startup string // config.startup, as an object
+		want    []models.ReadinessProbe
+		warning string
+	}{
+		{`{"done": "Done ("}`, []models.ReadinessProbe{{Kind: "log", Pattern: `Done \(`}}, ""},
+		{`{"done": ["Listening on", "regex:^Server started on port \\d+"]}`, []models.ReadinessProbe{
+			{Kind: "log", Pattern: "Listening on"},
+			{Kind: "log", Pattern: `^Server started on port \d+`},
+		}, ""},
+		{`{"done": "regex:^(?=Ready)"}`, []models.ReadinessProbe{}, "is not a valid Go regular expression"},
+		{`{"done": 1}`, []models.ReadinessProbe{}, "is not a string or list"},
+		{`{}`, []models.ReadinessProbe{}, ""},
+	} {
+		im := &importer{}
+		if got := im.readinessProbes(json.RawMessage(tc.startup)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("readinessProbes(%s) = %+v, want %+v", tc.startup, got, tc.want)
+		}
+		checkWarnings(t, im.warnings, tc.warning)
+	}
+}
@@ -0,0 +1,253 @@
+package eggs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/ironhost/master/internal/models"
+)
+
+// variables converts the egg's variables. Laravel validation rules become the
+// variable's type and constraints; rules without an equivalent are reported.
+func (im *importer) variables(eggVars []eggVariable) []models.TemplateVariable {
+	vars := []models.TemplateVariable{}
+	seen := make(map[string]bool, len(eggVars))
+	for _, ev := range eggVars {
+		v := models.TemplateVariable{
+			Name:         strings.TrimSpace(ev.Name),
+			Description:  strings.TrimSpace(ev.Description),
+			EnvVariable:  strings.TrimSpace(ev.EnvVariable),
+			Type:         models.VariableString,
+			Default:      defaultValue(ev.DefaultValue),
+			UserEditable: ev.UserEditable,
+		}
+		if !ev.UserViewable {
+			im.warnf("variable %s is hidden from users in the egg; IronHost shows every variable", v.EnvVariable)
+		}
+		if seen[v.EnvVariable] {
+			im.warnf("variable %s is defined twice; the second definition was skipped", v.EnvVariable)
+			continue
+		}
+
+		im.applyRules(&v, splitRules(ev.Rules))
+
+		if !im.checkVariable(&v) {
+			continue
+		}
+		seen[v.EnvVariable] = true
+		vars = append(vars, v)
+	}
+	return vars
+}
+
+// checkVariable validates an imported variable. Constraints IronHost cannot
+// apply, such as a PCRE-only pattern, are dropped with a warning; a variable
+// that is still invalid is skipped.
+func (im *importer) checkVariable(v *models.TemplateVariable) bool {
+	err := v.Check()
+	if err != nil && v.Pattern != "" {
+		im.warnf("variable %s: pattern %q dropped: %v", v.EnvVariable, v.Pattern, err)
+		v.Pattern = ""
+		err = v.Check()
+	}
+	if err != nil && (v.Min != nil || v.Max != nil || v.MaxLength > 0) {
+		im.warnf("variable %s: bounds dropped: %v", v.EnvVariable, err)
+		v.Min, v.Max, v.MaxLength = nil, nil, 0
+		err = v.Check()
+	}
+	if err != nil && v.Type != models.VariableString {
+		im.warnf("variable %s: imported as a plain string: %v", v.EnvVariable, err)
+		v.Type, v.Options = models.VariableString, nil
+		err = v.Check()
+	}
+	if err != nil {
+		im.warnf("variable %s skipped: %v", v.EnvVariable, err)
+		return false
+	}
+	return true
+}
+
+// applyRules maps Laravel validation rules onto a variable
+func (im *importer) applyRules(v *models.TemplateVariable, rules []string) {
+	var numeric bool
+	var minimum, maximum *int64
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, ":")
+		switch name {
+		case "", "nullable", "sometimes", "string":
+		case "required":
+			v.Required = true
+		case "integer", "int":
+			if v.Type != models.VariableEnum {
+				v.Type = models.VariableInteger
+			}
+		case "numeric":
+			numeric = true
+		case "boolean", "bool":
+			if v.Type != models.VariableEnum {
+				v.Type = models.VariableBoolean
+			}
+		case "in":
+			v.Type = models.VariableEnum
+			v.Options = strings.Split(arg, ",")
+		case "min", "max", "between":
+			lo, hi, ok := ruleBounds(name, arg)
+			if !ok {
+				im.warnf("variable %s: rule %q has an invalid argument; skipped", v.EnvVariable, rule)
+				continue
+			}
+			if lo != nil {
+				minimum = lo
+			}
+			if hi != nil {
+				maximum = hi
+			}
+		case "regex":
+			v.Pattern = phpPattern(arg)
+		case "alpha_dash":
+			if v.Pattern == "" {
+				v.Pattern = `[A-Za-z0-9_-]*`
+			}
+		case "alpha_num":
+			if v.Pattern == "" {
+				v.Pattern = `[A-Za-z0-9]*`
+			}
+		default:
+			im.warnf("variable %s: rule %q is not supported", v.EnvVariable, rule)
+		}
+	}
+
+	// Laravel applies sizes to the value of numbers and the length of strings
+	switch {
+	case v.Type == models.VariableInteger:
+		v.Min, v.Max = minimum, maximum
+	case numeric:
+		if v.Type == models.VariableString && v.Pattern == "" {
+			v.Pattern = `-?[0-9]*\.?[0-9]+`
+		}
+		if minimum != nil || maximum != nil {
+			im.warnf("variable %s: bounds of a decimal number are not supported", v.EnvVariable)
+		}
+	case v.Type == models.VariableString:
+		if maximum != nil {
+			v.MaxLength = int(*maximum)
+		}
+		if minimum != nil && *minimum > 0 {
+			im.warnf("variable %s: minimum length %d is not supported", v.EnvVariable, *minimum)
+		}
+	}
+}
+
+// ruleBounds parses the argument of a min, max or between rule
+func ruleBounds(name, arg string) (lo, hi *int64, ok bool) {
+	parse := func(s string) *int64 {
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return nil
+		}
+		return &n
+	}
+
+	switch name {
+	case "min":
+		lo = parse(arg)
+		return lo, nil, lo != nil
+	case "max":
+		hi = parse(arg)
+		return nil, hi, hi != nil
+	default:
+		a, b, found := strings.Cut(arg, ",")
+		lo, hi = parse(a), parse(b)
+		return lo, hi, found && lo != nil && hi != nil
+	}
+}
+
+// splitRules splits a rule string on "|", keeping a regex rule whole even if
+// its pattern contains "|". Rules may also be given as a list.
+func splitRules(raw json.RawMessage) []string {
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return list
+	}
+	var s string
+	if json.Unmarshal(raw, &s) != nil {
+		return nil
+	}
+
+	var rules []string
+	for s != "" {
+		end := strings.IndexByte(s, '|')
+		if strings.HasPrefix(s, "regex:") && len(s) > len("regex:") {
+			end = regexRuleEnd(s)
+		}
+		if end < 0 {
+			rules = append(rules, strings.TrimSpace(s))
+			break
+		}
+		rules = append(rules, strings.TrimSpace(s[:end]))
+		s = s[end+1:]
+	}
+	return rules
+}
+
+// regexRuleEnd returns the index of the "|" after a regex:/.../flags rule at
+// the start of s, or -1 if the rule runs to the end
+func regexRuleEnd(s string) int {
+	start := len("regex:")
+	delim := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case delim:
+			j := i + 1
+			for j < len(s) && isLetter(s[j]) {
+				j++
+			}
+			if j == len(s) {
+				return -1
+			}
+			if s[j] == '|' {
+				return j
+			}
+		}
+	}
+	return strings.IndexByte(s, '|')
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// phpPattern strips the delimiters and flags of a PHP regular expression,
+// keeping a case-insensitive flag as (?i)
+func phpPattern(expr string) string {
+	if len(expr) < 2 {
+		return expr
+	}
+	delim := expr[0]
+	end := strings.LastIndexByte(expr, delim)
+	if end <= 0 {
+		return expr
+	}
+	pattern, flags := expr[1:end], expr[end+1:]
+	if strings.Contains(flags, "i") {
+		pattern = "(?i)" + pattern
+	}
+	return pattern
+}
+
+// defaultValue returns an egg variable's default as text. Exports store it as
+// a string, but hand-written eggs use numbers and booleans too.
+func defaultValue(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return ""
+	}
+	if value, ok := scalar(raw); ok {
+		return value
+	}
+	return string(raw)
+}
@@ -20,23 +20,35 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	AgentService_CreateServer_FullMethodName    = "/ironhost.v1.AgentService/CreateServer"
-	AgentService_StartServer_FullMethodName     = "/ironhost.v1.AgentService/StartServer"
-	AgentService_StopServer_FullMethodName      = "/ironhost.v1.AgentService/StopServer"
-	AgentService_RestartServer_FullMethodName   = "/ironhost.v1.AgentService/RestartServer"
-	AgentService_DeleteServer_FullMethodName    = "/ironhost.v1.AgentService/DeleteServer"
-	AgentService_GetServerStatus_FullMethodName = "/ironhost.v1.AgentService/GetServerStatus"
-	AgentService_ListServers_FullMethodName     = "/ironhost.v1.AgentService/ListServers"
-	AgentService_StreamConsole_FullMethodName   = "/ironhost.v1.AgentService/StreamConsole"
-	AgentService_SendCommand_FullMethodName     = "/ironhost.v1.AgentService/SendCommand"
-	AgentService_GetLogs_FullMethodName         = "/ironhost.v1.AgentService/GetLogs"
-	AgentService_ListFiles_FullMethodName       = "/ironhost.v1.AgentService/ListFiles"
-	AgentService_ReadFile_FullMethodName        = "/ironhost.v1.AgentService/ReadFile"
-	AgentService_WriteFile_FullMethodName       = "/ironhost.v1.AgentService/WriteFile"
-	AgentService_DeleteFile_FullMethodName      = "/ironhost.v1.AgentService/DeleteFile"
-	AgentService_RenameFile_FullMethodName      = "/ironhost.v1.AgentService/RenameFile"
-	AgentService_GetNodeStats_FullMethodName    = "/ironhost.v1.AgentService/GetNodeStats"
-	AgentService_Ping_FullMethodName            = "/ironhost.v1.AgentService/Ping"
+	AgentService_CreateServer_FullMethodName            = "/ironhost.v1.AgentService/CreateServer"
+	AgentService_StartServer_FullMethodName             = "/ironhost.v1.AgentService/StartServer"
+	AgentService_StopServer_FullMethodName              = "/ironhost.v1.AgentService/StopServer"
+	AgentService_RestartServer_FullMethodName           = "/ironhost.v1.AgentService/RestartServer"
+	AgentService_DeleteServer_FullMethodName            = "/ironhost.v1.AgentService/DeleteServer"
+	AgentService_UpdateServerResources_FullMethodName   = "/ironhost.v1.AgentService/UpdateServerResources"
+	AgentService_UpdateServerAllocations_FullMethodName = "/ironhost.v1.AgentService/UpdateServerAllocations"
+	AgentService_ReinstallServer_FullMethodName         = "/ironhost.v1.AgentService/ReinstallServer"
+	AgentService_GetServerStatus_FullMethodName         = "/ironhost.v1.AgentService/GetServerStatus"
+	AgentService_ListServers_FullMethodName             = "/ironhost.v1.AgentService/ListServers"
+	AgentService_StreamServerStats_FullMethodName       = "/ironhost.v1.AgentService/StreamServerStats"
+	AgentService_WatchEvents_FullMethodName             = "/ironhost.v1.AgentService/WatchEvents"
+	AgentService_StreamConsole_FullMethodName           = "/ironhost.v1.AgentService/StreamConsole"
+	AgentService_AttachConsole_FullMethodName           = "/ironhost.v1.AgentService/AttachConsole"
+	AgentService_SendCommand_FullMethodName             = "/ironhost.v1.AgentService/SendCommand"
+	AgentService_GetLogs_FullMethodName                 = "/ironhost.v1.AgentService/GetLogs"
+	AgentService_ListFiles_FullMethodName               = "/ironhost.v1.AgentService/ListFiles"
+	AgentService_ReadFile_FullMethodName                = "/ironhost.v1.AgentService/ReadFile"
+	AgentService_WriteFile_FullMethodName               = "/ironhost.v1.AgentService/WriteFile"
+	AgentService_DeleteFile_FullMethodName              = "/ironhost.v1.AgentService/DeleteFile"
+	AgentService_RenameFile_FullMethodName              = "/ironhost.v1.AgentService/RenameFile"
+	AgentService_UploadFile_FullMethodName              = "/ironhost.v1.AgentService/UploadFile"
+	AgentService_GetUploadStatus_FullMethodName         = "/ironhost.v1.AgentService/GetUploadStatus"
+	AgentService_DownloadFile_FullMethodName            = "/ironhost.v1.AgentService/DownloadFile"
+	AgentService_CompressFiles_FullMethodName           = "/ironhost.v1.AgentService/CompressFiles"
+	AgentService_DecompressFile_FullMethodName          = "/ironhost.v1.AgentService/DecompressFile"
+	AgentService_GetNodeStats_FullMethodName            = "/ironhost.v1.AgentService/GetNodeStats"
+	AgentService_Ping_FullMethodName                    = "/ironhost.v1.AgentService/Ping"
+	AgentService_GetOrphans_FullMethodName              = "/ironhost.v1.AgentService/GetOrphans"
 )
 
 // AgentServiceClient is the client API for AgentService service.
@@ -52,11 +64,24 @@ type AgentServiceClient interface {
 	StopServer(ctx context.Context, in *StopServerRequest, opts ...grpc.CallOption) (*ServerActionResponse, error)
 	RestartServer(ctx context.Context, in *ServerIdentifier, opts ...grpc.CallOption) (*ServerActionResponse, error)
 	DeleteServer(ctx context.Context, in *ServerIdentifier, opts ...grpc.CallOption) (*ServerActionResponse, error)
+	// Succeeds without a container; the limits then come with CreateServer.
+	UpdateServerResources(ctx context.Context, in *UpdateServerResourcesRequest, opts ...grpc.CallOption) (*ServerActionResponse, error)
+	// Republishes the container's ports; the server must be stopped.
+	// NOT_FOUND if the server has no container (its first install failed).
+	UpdateServerAllocations(ctx context.Context, in *UpdateServerAllocationsRequest, opts ...grpc.CallOption) (*ServerActionResponse, error)
+	// Re-runs the install script on the existing data; the server is left stopped.
+	// NOT_FOUND if the server has no container (its first install failed).
+	ReinstallServer(ctx context.Context, in *ReinstallServerRequest, opts ...grpc.CallOption) (*InstallResponse, error)
 	// Server information
 	GetServerStatus(ctx context.Context, in *ServerIdentifier, opts ...grpc.CallOption) (*ServerState, error)
 	ListServers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListServersResponse, error)
+	StreamServerStats(ctx context.Context, in *StreamServerStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ServerState], error)
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ServerEvent], error)
 	// Console interaction
-	StreamConsole(ctx context.Context, in *ServerIdentifier, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ConsoleOutput], error)
+	StreamConsole(ctx context.Context, in *StreamConsoleRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ConsoleOutput], error)
+	// Writes client input to the container's stdin while streaming its output,
+	// for images that take console commands on stdin rather than over RCON
+	AttachConsole(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AttachConsoleRequest, ConsoleOutput], error)
 	SendCommand(ctx context.Context, in *SendCommandRequest, opts ...grpc.CallOption) (*ServerActionResponse, error)
 	GetLogs(ctx context.Context, in *ServerIdentifier, opts ...grpc.CallOption) (*ServerActionResponse, error)
 	// File management
@@ -65,9 +90,15 @@ type AgentServiceClient interface {
 	WriteFile(ctx context.Context, in *WriteFileRequest, opts ...grpc.CallOption) (*ServerActionResponse, error)
 	DeleteFile(ctx context.Context, in *DeleteFileRequest, opts ...grpc.CallOption) (*ServerActionResponse, error)
 	RenameFile(ctx context.Context, in *RenameFileRequest, opts ...grpc.CallOption) (*ServerActionResponse, error)
+	UploadFile(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadFileRequest, UploadFileResponse], error)
+	GetUploadStatus(ctx context.Context, in *UploadStatusRequest, opts ...grpc.CallOption) (*UploadStatusResponse, error)
+	DownloadFile(ctx context.Context, in *DownloadFileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error)
+	CompressFiles(ctx context.Context, in *CompressFilesRequest, opts ...grpc.CallOption) (*ArchiveResponse, error)
+	DecompressFile(ctx context.Context, in *DecompressFileRequest, opts ...grpc.CallOption) (*ArchiveResponse, error)
 	// Node health
 	GetNodeStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*NodeStats, error)
 	Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*PingResponse, error)
+	GetOrphans(ctx context.Context, in *GetOrphansRequest, opts ...grpc.CallOption) (*GetOrphansResponse, error)
 }
 
 type agentServiceClient struct {
@@ -128,6 +159,36 @@ func (c *agentServiceClient) DeleteServer(ctx context.Context, in *ServerIdentif
 	return out, nil
 }
 
+func (c *agentServiceClient) UpdateServerResources(ctx context.Context, in *UpdateServerResourcesRequest, opts ...grpc.CallOption) (*ServerActionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServerActionResponse)
+	err := c.cc.Invoke(ctx, AgentService_UpdateServerResources_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) UpdateServerAllocations(ctx context.Context, in *UpdateServerAllocationsRequest, opts ...grpc.CallOption) (*ServerActionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServerActionResponse)
+	err := c.cc.Invoke(ctx, AgentService_UpdateServerAllocations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ReinstallServer(ctx context.Context, in *ReinstallServerRequest, opts ...grpc.CallOption) (*InstallResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InstallResponse)
+	err := c.cc.Invoke(ctx, AgentService_ReinstallServer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *agentServiceClient) GetServerStatus(ctx context.Context, in *ServerIdentifier, opts ...grpc.CallOption) (*ServerState, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ServerState)
@@ -148,13 +209,51 @@ func (c *agentServiceClient) ListServers(ctx context.Context, in *emptypb.Empty,
 	return out, nil
 }
 
-func (c *agentServiceClient) StreamConsole(ctx context.Context, in *ServerIdentifier, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ConsoleOutput], error) {
+func (c *agentServiceClient) StreamServerStats(ctx context.Context, in *StreamServerStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ServerState], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[0], AgentService_StreamConsole_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[0], AgentService_StreamServerStats_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &grpc.GenericClientStream[ServerIdentifier, ConsoleOutput]{ClientStream: stream}
+	x := &grpc.GenericClientStream[StreamServerStatsRequest, ServerState]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_StreamServerStatsClient = grpc.ServerStreamingClient[ServerState]
+
+func (c *agentServiceClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ServerEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[1], AgentService_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchEventsRequest, ServerEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_WatchEventsClient = grpc.ServerStreamingClient[ServerEvent]
+
+func (c *agentServiceClient) StreamConsole(ctx context.Context, in *StreamConsoleRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ConsoleOutput], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[2], AgentService_StreamConsole_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamConsoleRequest, ConsoleOutput]{ClientStream: stream}
 	if err := x.ClientStream.SendMsg(in); err != nil {
 		return nil, err
 	}
@@ -167,6 +266,19 @@ func (c *agentServiceClient) StreamConsole(ctx context.Context, in *ServerIdenti
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type AgentService_StreamConsoleClient = grpc.ServerStreamingClient[ConsoleOutput]
 
+func (c *agentServiceClient) AttachConsole(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AttachConsoleRequest, ConsoleOutput], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[3], AgentService_AttachConsole_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AttachConsoleRequest, ConsoleOutput]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_AttachConsoleClient = grpc.BidiStreamingClient[AttachConsoleRequest, ConsoleOutput]
+
 func (c *agentServiceClient) SendCommand(ctx context.Context, in *SendCommandRequest, opts ...grpc.CallOption) (*ServerActionResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ServerActionResponse)
@@ -237,6 +349,68 @@ func (c *agentServiceClient) RenameFile(ctx context.Context, in *RenameFileReque
 	return out, nil
 }
 
+func (c *agentServiceClient) UploadFile(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadFileRequest, UploadFileResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[4], AgentService_UploadFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadFileRequest, UploadFileResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_UploadFileClient = grpc.ClientStreamingClient[UploadFileRequest, UploadFileResponse]
+
+func (c *agentServiceClient) GetUploadStatus(ctx context.Context, in *UploadStatusRequest, opts ...grpc.CallOption) (*UploadStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UploadStatusResponse)
+	err := c.cc.Invoke(ctx, AgentService_GetUploadStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) DownloadFile(ctx context.Context, in *DownloadFileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[5], AgentService_DownloadFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DownloadFileRequest, FileChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_DownloadFileClient = grpc.ServerStreamingClient[FileChunk]
+
+func (c *agentServiceClient) CompressFiles(ctx context.Context, in *CompressFilesRequest, opts ...grpc.CallOption) (*ArchiveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ArchiveResponse)
+	err := c.cc.Invoke(ctx, AgentService_CompressFiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) DecompressFile(ctx context.Context, in *DecompressFileRequest, opts ...grpc.CallOption) (*ArchiveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ArchiveResponse)
+	err := c.cc.Invoke(ctx, AgentService_DecompressFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *agentServiceClient) GetNodeStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*NodeStats, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(NodeStats)
@@ -257,6 +431,16 @@ func (c *agentServiceClient) Ping(ctx context.Context, in *emptypb.Empty, opts .
 	return out, nil
 }
 
+func (c *agentServiceClient) GetOrphans(ctx context.Context, in *GetOrphansRequest, opts ...grpc.CallOption) (*GetOrphansResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOrphansResponse)
+	err := c.cc.Invoke(ctx, AgentService_GetOrphans_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServiceServer is the server API for AgentService service.
 // All implementations must embed UnimplementedAgentServiceServer
 // for forward compatibility.
@@ -270,11 +454,24 @@ type AgentServiceServer interface {
 	StopServer(context.Context, *StopServerRequest) (*ServerActionResponse, error)
 	RestartServer(context.Context, *ServerIdentifier) (*ServerActionResponse, error)
 	DeleteServer(context.Context, *ServerIdentifier) (*ServerActionResponse, error)
+	// Succeeds without a container; the limits then come with CreateServer.
+	UpdateServerResources(context.Context, *UpdateServerResourcesRequest) (*ServerActionResponse, error)
+	// Republishes the container's ports; the server must be stopped.
+	// NOT_FOUND if the server has no container (its first install failed).
+	UpdateServerAllocations(context.Context, *UpdateServerAllocationsRequest) (*ServerActionResponse, error)
+	// Re-runs the install script on the existing data; the server is left stopped.
+	// NOT_FOUND if the server has no container (its first install failed).
+	ReinstallServer(context.Context, *ReinstallServerRequest) (*InstallResponse, error)
 	// Server information
 	GetServerStatus(context.Context, *ServerIdentifier) (*ServerState, error)
 	ListServers(context.Context, *emptypb.Empty) (*ListServersResponse, error)
+	StreamServerStats(*StreamServerStatsRequest, grpc.ServerStreamingServer[ServerState]) error
+	WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[ServerEvent]) error
 	// Console interaction
-	StreamConsole(*ServerIdentifier, grpc.ServerStreamingServer[ConsoleOutput]) error
+	StreamConsole(*StreamConsoleRequest, grpc.ServerStreamingServer[ConsoleOutput]) error
+	// Writes client input to the container's stdin while streaming its output,
+	// for images that take console commands on stdin rather than over RCON
+	AttachConsole(grpc.BidiStreamingServer[AttachConsoleRequest, ConsoleOutput]) error
 	SendCommand(context.Context, *SendCommandRequest) (*ServerActionResponse, error)
 	GetLogs(context.Context, *ServerIdentifier) (*ServerActionResponse, error)
 	// File management
@@ -283,9 +480,15 @@ type AgentServiceServer interface {
 	WriteFile(context.Context, *WriteFileRequest) (*ServerActionResponse, error)
 	DeleteFile(context.Context, *DeleteFileRequest) (*ServerActionResponse, error)
 	RenameFile(context.Context, *RenameFileRequest) (*ServerActionResponse, error)
+	UploadFile(grpc.ClientStreamingServer[UploadFileRequest, UploadFileResponse]) error
+	GetUploadStatus(context.Context, *UploadStatusRequest) (*UploadStatusResponse, error)
+	DownloadFile(*DownloadFileRequest, grpc.ServerStreamingServer[FileChunk]) error
+	CompressFiles(context.Context, *CompressFilesRequest) (*ArchiveResponse, error)
+	DecompressFile(context.Context, *DecompressFileRequest) (*ArchiveResponse, error)
 	// Node health
 	GetNodeStats(context.Context, *emptypb.Empty) (*NodeStats, error)
 	Ping(context.Context, *emptypb.Empty) (*PingResponse, error)
+	GetOrphans(context.Context, *GetOrphansRequest) (*GetOrphansResponse, error)
 	mustEmbedUnimplementedAgentServiceServer()
 }
 
@@ -311,15 +514,33 @@ func (UnimplementedAgentServiceServer) RestartServer(context.Context, *ServerIde
 func (UnimplementedAgentServiceServer) DeleteServer(context.Context, *ServerIdentifier) (*ServerActionResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method DeleteServer not implemented")
 }
+func (UnimplementedAgentServiceServer) UpdateServerResources(context.Context, *UpdateServerResourcesRequest) (*ServerActionResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateServerResources not implemented")
+}
+func (UnimplementedAgentServiceServer) UpdateServerAllocations(context.Context, *UpdateServerAllocationsRequest) (*ServerActionResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateServerAllocations not implemented")
+}
+func (UnimplementedAgentServiceServer) ReinstallServer(context.Context, *ReinstallServerRequest) (*InstallResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReinstallServer not implemented")
+}
 func (UnimplementedAgentServiceServer) GetServerStatus(context.Context, *ServerIdentifier) (*ServerState, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetServerStatus not implemented")
 }
 func (UnimplementedAgentServiceServer) ListServers(context.Context, *emptypb.Empty) (*ListServersResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ListServers not implemented")
 }
-func (UnimplementedAgentServiceServer) StreamConsole(*ServerIdentifier, grpc.ServerStreamingServer[ConsoleOutput]) error {
+func (UnimplementedAgentServiceServer) StreamServerStats(*StreamServerStatsRequest, grpc.ServerStreamingServer[ServerState]) error {
+	return status.Error(codes.Unimplemented, "method StreamServerStats not implemented")
+}
+func (UnimplementedAgentServiceServer) WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[ServerEvent]) error {
+	return status.Error(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedAgentServiceServer) StreamConsole(*StreamConsoleRequest, grpc.ServerStreamingServer[ConsoleOutput]) error {
 	return status.Error(codes.Unimplemented, "method StreamConsole not implemented")
 }
+func (UnimplementedAgentServiceServer) AttachConsole(grpc.BidiStreamingServer[AttachConsoleRequest, ConsoleOutput]) error {
+	return status.Error(codes.Unimplemented, "method AttachConsole not implemented")
+}
 func (UnimplementedAgentServiceServer) SendCommand(context.Context, *SendCommandRequest) (*ServerActionResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method SendCommand not implemented")
 }
@@ -341,12 +562,30 @@ func (UnimplementedAgentServiceServer) DeleteFile(context.Context, *DeleteFileRe
 func (UnimplementedAgentServiceServer) RenameFile(context.Context, *RenameFileRequest) (*ServerActionResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method RenameFile not implemented")
 }
+func (UnimplementedAgentServiceServer) UploadFile(grpc.ClientStreamingServer[UploadFileRequest, UploadFileResponse]) error {
+	return status.Error(codes.Unimplemented, "method UploadFile not implemented")
+}
+func (UnimplementedAgentServiceServer) GetUploadStatus(context.Context, *UploadStatusRequest) (*UploadStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetUploadStatus not implemented")
+}
+func (UnimplementedAgentServiceServer) DownloadFile(*DownloadFileRequest, grpc.ServerStreamingServer[FileChunk]) error {
+	return status.Error(codes.Unimplemented, "method DownloadFile not implemented")
+}
+func (UnimplementedAgentServiceServer) CompressFiles(context.Context, *CompressFilesRequest) (*ArchiveResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CompressFiles not implemented")
+}
+func (UnimplementedAgentServiceServer) DecompressFile(context.Context, *DecompressFileRequest) (*ArchiveResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DecompressFile not implemented")
+}
 func (UnimplementedAgentServiceServer) GetNodeStats(context.Context, *emptypb.Empty) (*NodeStats, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetNodeStats not implemented")
 }
 func (UnimplementedAgentServiceServer) Ping(context.Context, *emptypb.Empty) (*PingResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Ping not implemented")
 }
+func (UnimplementedAgentServiceServer) GetOrphans(context.Context, *GetOrphansRequest) (*GetOrphansResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetOrphans not implemented")
+}
 func (UnimplementedAgentServiceServer) mustEmbedUnimplementedAgentServiceServer() {}
 func (UnimplementedAgentServiceServer) testEmbeddedByValue()                      {}
 
@@ -458,6 +697,60 @@ func _AgentService_DeleteServer_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AgentService_UpdateServerResources_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateServerResourcesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).UpdateServerResources(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_UpdateServerResources_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).UpdateServerResources(ctx, req.(*UpdateServerResourcesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_UpdateServerAllocations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateServerAllocationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).UpdateServerAllocations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_UpdateServerAllocations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).UpdateServerAllocations(ctx, req.(*UpdateServerAllocationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ReinstallServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReinstallServerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReinstallServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_ReinstallServer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReinstallServer(ctx, req.(*ReinstallServerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AgentService_GetServerStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ServerIdentifier)
 	if err := dec(in); err != nil {
@@ -494,17 +787,46 @@ func _AgentService_ListServers_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AgentService_StreamServerStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamServerStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServiceServer).StreamServerStats(m, &grpc.GenericServerStream[StreamServerStatsRequest, ServerState]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_StreamServerStatsServer = grpc.ServerStreamingServer[ServerState]
+
+func _AgentService_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServiceServer).WatchEvents(m, &grpc.GenericServerStream[WatchEventsRequest, ServerEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_WatchEventsServer = grpc.ServerStreamingServer[ServerEvent]
+
 func _AgentService_StreamConsole_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(ServerIdentifier)
+	m := new(StreamConsoleRequest)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
-	return srv.(AgentServiceServer).StreamConsole(m, &grpc.GenericServerStream[ServerIdentifier, ConsoleOutput]{ServerStream: stream})
+	return srv.(AgentServiceServer).StreamConsole(m, &grpc.GenericServerStream[StreamConsoleRequest, ConsoleOutput]{ServerStream: stream})
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type AgentService_StreamConsoleServer = grpc.ServerStreamingServer[ConsoleOutput]
 
+func _AgentService_AttachConsole_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AgentServiceServer).AttachConsole(&grpc.GenericServerStream[AttachConsoleRequest, ConsoleOutput]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_AttachConsoleServer = grpc.BidiStreamingServer[AttachConsoleRequest, ConsoleOutput]
+
 func _AgentService_SendCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SendCommandRequest)
 	if err := dec(in); err != nil {
@@ -631,6 +953,78 @@ func _AgentService_RenameFile_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AgentService_UploadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AgentServiceServer).UploadFile(&grpc.GenericServerStream[UploadFileRequest, UploadFileResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_UploadFileServer = grpc.ClientStreamingServer[UploadFileRequest, UploadFileResponse]
+
+func _AgentService_GetUploadStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetUploadStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_GetUploadStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetUploadStatus(ctx, req.(*UploadStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_DownloadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadFileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServiceServer).DownloadFile(m, &grpc.GenericServerStream[DownloadFileRequest, FileChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_DownloadFileServer = grpc.ServerStreamingServer[FileChunk]
+
+func _AgentService_CompressFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompressFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).CompressFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_CompressFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).CompressFiles(ctx, req.(*CompressFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_DecompressFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecompressFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).DecompressFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_DecompressFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).DecompressFile(ctx, req.(*DecompressFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AgentService_GetNodeStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -667,6 +1061,24 @@ func _AgentService_Ping_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AgentService_GetOrphans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrphansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetOrphans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_GetOrphans_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetOrphans(ctx, req.(*GetOrphansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AgentService_ServiceDesc is the grpc.ServiceDesc for AgentService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -694,6 +1106,18 @@ var AgentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteServer",
 			Handler:    _AgentService_DeleteServer_Handler,
 		},
+		{
+			MethodName: "UpdateServerResources",
+			Handler:    _AgentService_UpdateServerResources_Handler,
+		},
+		{
+			MethodName: "UpdateServerAllocations",
+			Handler:    _AgentService_UpdateServerAllocations_Handler,
+		},
+		{
+			MethodName: "ReinstallServer",
+			Handler:    _AgentService_ReinstallServer_Handler,
+		},
 		{
 			MethodName: "GetServerStatus",
 			Handler:    _AgentService_GetServerStatus_Handler,
@@ -730,6 +1154,18 @@ var AgentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RenameFile",
 			Handler:    _AgentService_RenameFile_Handler,
 		},
+		{
+			MethodName: "GetUploadStatus",
+			Handler:    _AgentService_GetUploadStatus_Handler,
+		},
+		{
+			MethodName: "CompressFiles",
+			Handler:    _AgentService_CompressFiles_Handler,
+		},
+		{
+			MethodName: "DecompressFile",
+			Handler:    _AgentService_DecompressFile_Handler,
+		},
 		{
 			MethodName: "GetNodeStats",
 			Handler:    _AgentService_GetNodeStats_Handler,
@@ -738,13 +1174,43 @@ var AgentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Ping",
 			Handler:    _AgentService_Ping_Handler,
 		},
+		{
+			MethodName: "GetOrphans",
+			Handler:    _AgentService_GetOrphans_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamServerStats",
+			Handler:       _AgentService_StreamServerStats_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _AgentService_WatchEvents_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "StreamConsole",
 			Handler:       _AgentService_StreamConsole_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "AttachConsole",
+			Handler:       _AgentService_AttachConsole_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "UploadFile",
+			Handler:       _AgentService_UploadFile_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadFile",
+			Handler:       _AgentService_DownloadFile_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "ironhost/v1/agent.proto",
 }
@@ -22,6 +22,59 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// What a readiness probe checks
+type ProbeKind int32
+
+const (
+	ProbeKind_PROBE_KIND_UNSPECIFIED    ProbeKind = 0
+	ProbeKind_PROBE_KIND_LOG            ProbeKind = 1 // A line of console output matches pattern
+	ProbeKind_PROBE_KIND_TCP            ProbeKind = 2 // The port accepts TCP connections
+	ProbeKind_PROBE_KIND_MINECRAFT_PING ProbeKind = 3 // The port answers a Minecraft Server List Ping
+)
+
+// Enum value maps for ProbeKind.
+var (
+	ProbeKind_name = map[int32]string{
+		0: "PROBE_KIND_UNSPECIFIED",
+		1: "PROBE_KIND_LOG",
+		2: "PROBE_KIND_TCP",
+		3: "PROBE_KIND_MINECRAFT_PING",
+	}
+	ProbeKind_value = map[string]int32{
+		"PROBE_KIND_UNSPECIFIED":    0,
+		"PROBE_KIND_LOG":            1,
+		"PROBE_KIND_TCP":            2,
+		"PROBE_KIND_MINECRAFT_PING": 3,
+	}
+)
+
+func (x ProbeKind) Enum() *ProbeKind {
+	p := new(ProbeKind)
+	*p = x
+	return p
+}
+
+func (x ProbeKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_ironhost_v1_common_proto_enumTypes[0].Descriptor()
+}
+
+func (ProbeKind) Type() protoreflect.EnumType {
+	return &file_ironhost_v1_common_proto_enumTypes[0]
+}
+
+func (x ProbeKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ProbeKind.Descriptor instead.
+func (ProbeKind) EnumDescriptor() ([]byte, []int) {
+	return file_ironhost_v1_common_proto_rawDescGZIP(), []int{0}
+}
+
 // Server status enum
 type ServerStatus int32
 
@@ -68,11 +121,11 @@ func (x ServerStatus) String() string {
 }
 
 func (ServerStatus) Descriptor() protoreflect.EnumDescriptor {
-	return file_ironhost_v1_common_proto_enumTypes[0].Descriptor()
+	return file_ironhost_v1_common_proto_enumTypes[1].Descriptor()
 }
 
 func (ServerStatus) Type() protoreflect.EnumType {
-	return &file_ironhost_v1_common_proto_enumTypes[0]
+	return &file_ironhost_v1_common_proto_enumTypes[1]
 }
 
 func (x ServerStatus) Number() protoreflect.EnumNumber {
@@ -81,7 +134,7 @@ func (x ServerStatus) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use ServerStatus.Descriptor instead.
 func (ServerStatus) EnumDescriptor() ([]byte, []int) {
-	return file_ironhost_v1_common_proto_rawDescGZIP(), []int{0}
+	return file_ironhost_v1_common_proto_rawDescGZIP(), []int{1}
 }
 
 type ServerIdentifier struct {
@@ -241,13 +294,15 @@ func (x *ResourceLimits) GetIoWeight() int32 {
 	return 0
 }
 
-// Port allocation for a server
+// Port allocation for a server, published on ip_address:port of the node
 type Allocation struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	IpAddress     string                 `protobuf:"bytes,2,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
-	Port          int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
-	IsPrimary     bool                   `protobuf:"varint,4,opt,name=is_primary,json=isPrimary,proto3" json:"is_primary,omitempty"`
+	IpAddress     string                 `protobuf:"bytes,2,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`              // Host address to bind; a hostname or empty binds all addresses
+	Port          int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`                                        // Host port
+	IsPrimary     bool                   `protobuf:"varint,4,opt,name=is_primary,json=isPrimary,proto3" json:"is_primary,omitempty"`             // The game port; the first allocation if none is marked
+	Protocol      string                 `protobuf:"bytes,5,opt,name=protocol,proto3" json:"protocol,omitempty"`                                 // "tcp" or "udp" (empty = tcp; CreateServer uses the template's protocol for the primary)
+	ContainerPort int32                  `protobuf:"varint,6,opt,name=container_port,json=containerPort,proto3" json:"container_port,omitempty"` // Port inside the container (0 = port; CreateServer uses the template's port for the primary)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -310,6 +365,81 @@ func (x *Allocation) GetIsPrimary() bool {
 	return false
 }
 
+func (x *Allocation) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *Allocation) GetContainerPort() int32 {
+	if x != nil {
+		return x.ContainerPort
+	}
+	return 0
+}
+
+// A check that a started server is ready for players
+type ReadinessProbe struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          ProbeKind              `protobuf:"varint,1,opt,name=kind,proto3,enum=ironhost.v1.ProbeKind" json:"kind,omitempty"`
+	Pattern       string                 `protobuf:"bytes,2,opt,name=pattern,proto3" json:"pattern,omitempty"` // Regular expression, for LOG probes
+	Port          int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`      // Container port for network probes (0 = primary port)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadinessProbe) Reset() {
+	*x = ReadinessProbe{}
+	mi := &file_ironhost_v1_common_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadinessProbe) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadinessProbe) ProtoMessage() {}
+
+func (x *ReadinessProbe) ProtoReflect() protoreflect.Message {
+	mi := &file_ironhost_v1_common_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadinessProbe.ProtoReflect.Descriptor instead.
+func (*ReadinessProbe) Descriptor() ([]byte, []int) {
+	return file_ironhost_v1_common_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ReadinessProbe) GetKind() ProbeKind {
+	if x != nil {
+		return x.Kind
+	}
+	return ProbeKind_PROBE_KIND_UNSPECIFIED
+}
+
+func (x *ReadinessProbe) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *ReadinessProbe) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
 // Environment variable for container
 type EnvVar struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -321,7 +451,7 @@ type EnvVar struct {
 
 func (x *EnvVar) Reset() {
 	*x = EnvVar{}
-	mi := &file_ironhost_v1_common_proto_msgTypes[4]
+	mi := &file_ironhost_v1_common_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -333,7 +463,7 @@ func (x *EnvVar) String() string {
 func (*EnvVar) ProtoMessage() {}
 
 func (x *EnvVar) ProtoReflect() protoreflect.Message {
-	mi := &file_ironhost_v1_common_proto_msgTypes[4]
+	mi := &file_ironhost_v1_common_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -346,7 +476,7 @@ func (x *EnvVar) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EnvVar.ProtoReflect.Descriptor instead.
 func (*EnvVar) Descriptor() ([]byte, []int) {
-	return file_ironhost_v1_common_proto_rawDescGZIP(), []int{4}
+	return file_ironhost_v1_common_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *EnvVar) GetKey() string {
@@ -373,13 +503,25 @@ type ServerState struct {
 	CpuUsagePercent  float64                `protobuf:"fixed64,5,opt,name=cpu_usage_percent,json=cpuUsagePercent,proto3" json:"cpu_usage_percent,omitempty"`
 	UptimeSeconds    int64                  `protobuf:"varint,6,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
 	LastUpdated      *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=last_updated,json=lastUpdated,proto3" json:"last_updated,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	MemoryLimitBytes int64                  `protobuf:"varint,8,opt,name=memory_limit_bytes,json=memoryLimitBytes,proto3" json:"memory_limit_bytes,omitempty"`
+	NetworkRxBytes   int64                  `protobuf:"varint,9,opt,name=network_rx_bytes,json=networkRxBytes,proto3" json:"network_rx_bytes,omitempty"`     // Cumulative bytes received across all interfaces
+	NetworkTxBytes   int64                  `protobuf:"varint,10,opt,name=network_tx_bytes,json=networkTxBytes,proto3" json:"network_tx_bytes,omitempty"`    // Cumulative bytes sent across all interfaces
+	BlockReadBytes   int64                  `protobuf:"varint,11,opt,name=block_read_bytes,json=blockReadBytes,proto3" json:"block_read_bytes,omitempty"`    // Cumulative bytes read from block devices
+	BlockWriteBytes  int64                  `protobuf:"varint,12,opt,name=block_write_bytes,json=blockWriteBytes,proto3" json:"block_write_bytes,omitempty"` // Cumulative bytes written to block devices
+	// Game status from the Server List Ping and Query protocols. Only set for
+	// running Minecraft servers that answered.
+	PlayersOnline int32    `protobuf:"varint,13,opt,name=players_online,json=playersOnline,proto3" json:"players_online,omitempty"`
+	PlayersMax    int32    `protobuf:"varint,14,opt,name=players_max,json=playersMax,proto3" json:"players_max,omitempty"`
+	Version       string   `protobuf:"bytes,15,opt,name=version,proto3" json:"version,omitempty"`
+	Motd          string   `protobuf:"bytes,16,opt,name=motd,proto3" json:"motd,omitempty"`
+	Players       []string `protobuf:"bytes,17,rep,name=players,proto3" json:"players,omitempty"` // Names of online players (Query, or the ping's sample)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ServerState) Reset() {
 	*x = ServerState{}
-	mi := &file_ironhost_v1_common_proto_msgTypes[5]
+	mi := &file_ironhost_v1_common_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -391,7 +533,7 @@ func (x *ServerState) String() string {
 func (*ServerState) ProtoMessage() {}
 
 func (x *ServerState) ProtoReflect() protoreflect.Message {
-	mi := &file_ironhost_v1_common_proto_msgTypes[5]
+	mi := &file_ironhost_v1_common_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -404,7 +546,7 @@ func (x *ServerState) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ServerState.ProtoReflect.Descriptor instead.
 func (*ServerState) Descriptor() ([]byte, []int) {
-	return file_ironhost_v1_common_proto_rawDescGZIP(), []int{5}
+	return file_ironhost_v1_common_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ServerState) GetServerId() string {
@@ -456,6 +598,76 @@ func (x *ServerState) GetLastUpdated() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *ServerState) GetMemoryLimitBytes() int64 {
+	if x != nil {
+		return x.MemoryLimitBytes
+	}
+	return 0
+}
+
+func (x *ServerState) GetNetworkRxBytes() int64 {
+	if x != nil {
+		return x.NetworkRxBytes
+	}
+	return 0
+}
+
+func (x *ServerState) GetNetworkTxBytes() int64 {
+	if x != nil {
+		return x.NetworkTxBytes
+	}
+	return 0
+}
+
+func (x *ServerState) GetBlockReadBytes() int64 {
+	if x != nil {
+		return x.BlockReadBytes
+	}
+	return 0
+}
+
+func (x *ServerState) GetBlockWriteBytes() int64 {
+	if x != nil {
+		return x.BlockWriteBytes
+	}
+	return 0
+}
+
+func (x *ServerState) GetPlayersOnline() int32 {
+	if x != nil {
+		return x.PlayersOnline
+	}
+	return 0
+}
+
+func (x *ServerState) GetPlayersMax() int32 {
+	if x != nil {
+		return x.PlayersMax
+	}
+	return 0
+}
+
+func (x *ServerState) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ServerState) GetMotd() string {
+	if x != nil {
+		return x.Motd
+	}
+	return ""
+}
+
+func (x *ServerState) GetPlayers() []string {
+	if x != nil {
+		return x.Players
+	}
+	return nil
+}
+
 var File_ironhost_v1_common_proto protoreflect.FileDescriptor
 
 const file_ironhost_v1_common_proto_rawDesc = "" +
@@ -470,7 +682,7 @@ const file_ironhost_v1_common_proto_rawDesc = "" +
 	"\adisk_mb\x18\x02 \x01(\x03R\x06diskMb\x12\x1f\n" +
 	"\vcpu_percent\x18\x03 \x01(\x05R\n" +
 	"cpuPercent\x12\x1b\n" +
-	"\tio_weight\x18\x04 \x01(\x05R\bioWeight\"n\n" +
+	"\tio_weight\x18\x04 \x01(\x05R\bioWeight\"\xb1\x01\n" +
 	"\n" +
 	"Allocation\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
@@ -478,10 +690,16 @@ const file_ironhost_v1_common_proto_rawDesc = "" +
 	"ip_address\x18\x02 \x01(\tR\tipAddress\x12\x12\n" +
 	"\x04port\x18\x03 \x01(\x05R\x04port\x12\x1d\n" +
 	"\n" +
-	"is_primary\x18\x04 \x01(\bR\tisPrimary\"0\n" +
+	"is_primary\x18\x04 \x01(\bR\tisPrimary\x12\x1a\n" +
+	"\bprotocol\x18\x05 \x01(\tR\bprotocol\x12%\n" +
+	"\x0econtainer_port\x18\x06 \x01(\x05R\rcontainerPort\"j\n" +
+	"\x0eReadinessProbe\x12*\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x16.ironhost.v1.ProbeKindR\x04kind\x12\x18\n" +
+	"\apattern\x18\x02 \x01(\tR\apattern\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\"0\n" +
 	"\x06EnvVar\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value\"\xc7\x02\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"\xaf\x05\n" +
 	"\vServerState\x12\x1b\n" +
 	"\tserver_id\x18\x01 \x01(\tR\bserverId\x121\n" +
 	"\x06status\x18\x02 \x01(\x0e2\x19.ironhost.v1.ServerStatusR\x06status\x12,\n" +
@@ -489,7 +707,24 @@ const file_ironhost_v1_common_proto_rawDesc = "" +
 	"\x10disk_usage_bytes\x18\x04 \x01(\x03R\x0ediskUsageBytes\x12*\n" +
 	"\x11cpu_usage_percent\x18\x05 \x01(\x01R\x0fcpuUsagePercent\x12%\n" +
 	"\x0euptime_seconds\x18\x06 \x01(\x03R\ruptimeSeconds\x12=\n" +
-	"\flast_updated\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\vlastUpdated*\xd6\x01\n" +
+	"\flast_updated\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\vlastUpdated\x12,\n" +
+	"\x12memory_limit_bytes\x18\b \x01(\x03R\x10memoryLimitBytes\x12(\n" +
+	"\x10network_rx_bytes\x18\t \x01(\x03R\x0enetworkRxBytes\x12(\n" +
+	"\x10network_tx_bytes\x18\n" +
+	" \x01(\x03R\x0enetworkTxBytes\x12(\n" +
+	"\x10block_read_bytes\x18\v \x01(\x03R\x0eblockReadBytes\x12*\n" +
+	"\x11block_write_bytes\x18\f \x01(\x03R\x0fblockWriteBytes\x12%\n" +
+	"\x0eplayers_online\x18\r \x01(\x05R\rplayersOnline\x12\x1f\n" +
+	"\vplayers_max\x18\x0e \x01(\x05R\n" +
+	"playersMax\x12\x18\n" +
+	"\aversion\x18\x0f \x01(\tR\aversion\x12\x12\n" +
+	"\x04motd\x18\x10 \x01(\tR\x04motd\x12\x18\n" +
+	"\aplayers\x18\x11 \x03(\tR\aplayers*n\n" +
+	"\tProbeKind\x12\x1a\n" +
+	"\x16PROBE_KIND_UNSPECIFIED\x10\x00\x12\x12\n" +
+	"\x0ePROBE_KIND_LOG\x10\x01\x12\x12\n" +
+	"\x0ePROBE_KIND_TCP\x10\x02\x12\x1d\n" +
+	"\x19PROBE_KIND_MINECRAFT_PING\x10\x03*\xd6\x01\n" +
 	"\fServerStatus\x12\x1d\n" +
 	"\x19SERVER_STATUS_UNSPECIFIED\x10\x00\x12\x1c\n" +
 	"\x18SERVER_STATUS_INSTALLING\x10\x01\x12\x19\n" +
@@ -511,26 +746,29 @@ func file_ironhost_v1_common_proto_rawDescGZIP() []byte {
 	return file_ironhost_v1_common_proto_rawDescData
 }
 
-var file_ironhost_v1_common_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_ironhost_v1_common_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_ironhost_v1_common_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_ironhost_v1_common_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_ironhost_v1_common_proto_goTypes = []any{
-	(ServerStatus)(0),             // 0: ironhost.v1.ServerStatus
-	(*ServerIdentifier)(nil),      // 1: ironhost.v1.ServerIdentifier
-	(*NodeIdentifier)(nil),        // 2: ironhost.v1.NodeIdentifier
-	(*ResourceLimits)(nil),        // 3: ironhost.v1.ResourceLimits
-	(*Allocation)(nil),            // 4: ironhost.v1.Allocation
-	(*EnvVar)(nil),                // 5: ironhost.v1.EnvVar
-	(*ServerState)(nil),           // 6: ironhost.v1.ServerState
-	(*timestamppb.Timestamp)(nil), // 7: google.protobuf.Timestamp
+	(ProbeKind)(0),                // 0: ironhost.v1.ProbeKind
+	(ServerStatus)(0),             // 1: ironhost.v1.ServerStatus
+	(*ServerIdentifier)(nil),      // 2: ironhost.v1.ServerIdentifier
+	(*NodeIdentifier)(nil),        // 3: ironhost.v1.NodeIdentifier
+	(*ResourceLimits)(nil),        // 4: ironhost.v1.ResourceLimits
+	(*Allocation)(nil),            // 5: ironhost.v1.Allocation
+	(*ReadinessProbe)(nil),        // 6: ironhost.v1.ReadinessProbe
+	(*EnvVar)(nil),                // 7: ironhost.v1.EnvVar
+	(*ServerState)(nil),           // 8: ironhost.v1.ServerState
+	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
 }
 var file_ironhost_v1_common_proto_depIdxs = []int32{
-	0, // 0: ironhost.v1.ServerState.status:type_name -> ironhost.v1.ServerStatus
-	7, // 1: ironhost.v1.ServerState.last_updated:type_name -> google.protobuf.Timestamp
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	0, // 0: ironhost.v1.ReadinessProbe.kind:type_name -> ironhost.v1.ProbeKind
+	1, // 1: ironhost.v1.ServerState.status:type_name -> ironhost.v1.ServerStatus
+	9, // 2: ironhost.v1.ServerState.last_updated:type_name -> google.protobuf.Timestamp
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_ironhost_v1_common_proto_init() }
@@ -543,8 +781,8 @@ func file_ironhost_v1_common_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ironhost_v1_common_proto_rawDesc), len(file_ironhost_v1_common_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   6,
+			NumEnums:      2,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ConfigParser selects how a config file is edited
+type ConfigParser string
+
+const (
+	ParserProperties ConfigParser = "properties" // key=value lines; missing keys are appended
+	ParserFile       ConfigParser = "file"       // Lines starting with a key are replaced by its value
+	ParserJSON       ConfigParser = "json"       // Keys are dotted paths into the document
+)
+
+// ConfigFile is a file in the server's data directory that is edited before
+// each start, so settings such as the game port always match the template
+type ConfigFile struct {
+	Path   string            `json:"path"` // Relative to the data directory
+	Parser ConfigParser      `json:"parser"`
+	Set    map[string]string `json:"set"` // Key to value; {{VAR}} placeholders are substituted
+}
+
+// Validate checks a config file definition
+func (f *ConfigFile) Validate() error {
+	if f.Path == "" || path.IsAbs(f.Path) || path.Clean(f.Path) != f.Path || strings.HasPrefix(f.Path, "..") {
+		return fmt.Errorf("path must be a clean path inside the data directory")
+	}
+	switch f.Parser {
+	case ParserProperties, ParserFile, ParserJSON:
+	default:
+		return fmt.Errorf("parser must be properties, file or json")
+	}
+	if len(f.Set) == 0 {
+		return fmt.Errorf("nothing to set")
+	}
+	return nil
+}
+
+// Apply returns content with the file's settings applied, placeholders
+// substituted by r
+func (f *ConfigFile) Apply(content string, r *strings.Replacer) (string, error) {
+	keys := make([]string, 0, len(f.Set))
+	for key := range f.Set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	switch f.Parser {
+	case ParserProperties:
+		return applyProperties(content, keys, f.Set, r), nil
+	case ParserFile:
+		return applyLines(content, keys, f.Set, r), nil
+	case ParserJSON:
+		return applyJSON(content, keys, f.Set, r)
+	default:
+		return "", fmt.Errorf("unsupported parser %q", f.Parser)
+	}
+}
+
+func applyProperties(content string, keys []string, set map[string]string, r *strings.Replacer) string {
+	lines := splitLines(content)
+	done := make(map[string]bool, len(keys))
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+			continue
+		}
+		key, _, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if value, ok := set[key]; ok {
+			lines[i] = key + "=" + r.Replace(value)
+			done[key] = true
+		}
+	}
+	for _, key := range keys {
+		if !done[key] {
+			lines = append(lines, key+"="+r.Replace(set[key]))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func applyLines(content string, keys []string, set map[string]string, r *strings.Replacer) string {
+	lines := splitLines(content)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		for _, key := range keys {
+			if strings.HasPrefix(trimmed, key) {
+				lines[i] = r.Replace(set[key])
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func applyJSON(content string, keys []string, set map[string]string, r *strings.Replacer) (string, error) {
+	doc := map[string]any{}
+	if strings.TrimSpace(content) != "" {
+		if err := json.Unmarshal([]byte(content), &doc); err != nil {
+			return "", fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+
+	for _, key := range keys {
+		parts := strings.Split(key, ".")
+		node := doc
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = jsonValue(r.Replace(set[key]))
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
+
+// jsonValue keeps numbers and booleans typed when writing them into JSON
+func jsonValue(s string) any {
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err == nil {
+		switch v.(type) {
+		case float64, bool:
+			return v
+		}
+	}
+	return s
+}
+
+func splitLines(content string) []string {
+	content = strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,12 +11,13 @@ import (
 type ServerStatus string
 
 const (
-	StatusInstalling ServerStatus = "installing"
-	StatusOffline    ServerStatus = "offline"
-	StatusStarting   ServerStatus = "starting"
-	StatusRunning    ServerStatus = "running"
-	StatusStopping   ServerStatus = "stopping"
-	StatusSuspended  ServerStatus = "suspended"
+	StatusInstalling    ServerStatus = "installing"
+	StatusInstallFailed ServerStatus = "install_failed" // The install script exited non-zero; reinstall to retry
+	StatusOffline       ServerStatus = "offline"
+	StatusStarting      ServerStatus = "starting"
+	StatusRunning       ServerStatus = "running"
+	StatusStopping      ServerStatus = "stopping"
+	StatusSuspended     ServerStatus = "suspended"
 )
 
 // Node represents a remote server running the IronHost Agent daemon
@@ -45,9 +47,21 @@ type Allocation struct {
 	ServerID  *uuid.UUID `json:"server_id,omitempty" db:"server_id"` // Nullable - unassigned if nil
 	IPAddress string     `json:"ip_address" db:"ip_address"`
 	Port      int        `json:"port" db:"port"`
+	Protocol  string     `json:"protocol" db:"protocol"` // tcp or udp
 	Notes     string     `json:"notes,omitempty" db:"notes"`
 	Assigned  bool       `json:"assigned" db:"assigned"`
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+
+	// Port inside the server's container (0 = same as Port)
+	ContainerPort int `json:"container_port,omitempty" db:"container_port"`
+}
+
+// Target returns the container port an allocation maps to
+func (a *Allocation) Target() int {
+	if a.ContainerPort != 0 {
+		return a.ContainerPort
+	}
+	return a.Port
 }
 
 // Server represents a game server instance (e.g., Minecraft server)
@@ -60,10 +74,15 @@ type Server struct {
 	MemoryLimit         int64             `json:"memory_limit" db:"memory_limit"` // RAM limit in MB
 	DiskLimit           int64             `json:"disk_limit" db:"disk_limit"`     // Disk limit in MB
 	CPULimit            int               `json:"cpu_limit" db:"cpu_limit"`       // CPU percentage (100 = 1 core)
-	DockerImage         string            `json:"docker_image" db:"docker_image"` // e.g., itzg/minecraft-server
+	IOWeight            int               `json:"io_weight" db:"io_weight"`       // Block IO weight, 10-1000 (0 = Docker default)
+	TemplateID          uuid.UUID         `json:"template_id" db:"template_id"`
+	DockerImage         string            `json:"docker_image" db:"docker_image"` // One of the template's images
 	Status              ServerStatus      `json:"status" db:"status"`
 	PrimaryAllocationID *uuid.UUID        `json:"primary_allocation_id" db:"primary_allocation_id"`
-	Environment         map[string]string `json:"environment" db:"environment"` // JSONB - includes TYPE for server type
+	Environment         map[string]string `json:"environment" db:"environment"`                       // JSONB - includes TYPE for server type
+	MissingSince        *time.Time        `json:"missing_since,omitempty" db:"missing_since"`         // Set while the node has no container for the server
+	InstallExitCode     *int              `json:"install_exit_code,omitempty" db:"install_exit_code"` // Of the last install script run
+	InstalledAt         *time.Time        `json:"installed_at,omitempty" db:"installed_at"`
 	CreatedAt           time.Time         `json:"created_at" db:"created_at"`
 	UpdatedAt           time.Time         `json:"updated_at" db:"updated_at"`
 
@@ -76,6 +95,14 @@ type Server struct {
 	PrimaryAllocation *Allocation  `json:"primary_allocation,omitempty" db:"-"`
 }
 
+// CommandTransport is how console commands reach a server
+type CommandTransport string
+
+const (
+	TransportRCON  CommandTransport = "rcon"  // SendCommand RPC; the agent uses RCON where the server has it
+	TransportStdin CommandTransport = "stdin" // Written to the container's stdin through AttachConsole
+)
+
 // User represents a platform user who owns servers
 type User struct {
 	ID           uuid.UUID `json:"id" db:"id"`
@@ -92,37 +119,49 @@ type User struct {
 // ServerCreateRequest is the API request body for creating a new server
 type ServerCreateRequest struct {
 	Name        string            `json:"name" validate:"required,min=3,max=100"`
-	NodeID      uuid.UUID         `json:"node_id" validate:"required"`
+	NodeID      uuid.UUID         `json:"node_id"`                                  // Optional, else the placement engine picks a node
+	Placement   *Placement        `json:"placement"`                                // Steers the pick when node_id is omitted
+	TemplateID  *uuid.UUID        `json:"template_id"`                              // Optional, defaults to the Minecraft template
 	MemoryLimit int64             `json:"memory_limit" validate:"required,min=512"` // Minimum 512MB
 	DiskLimit   int64             `json:"disk_limit" validate:"required,min=1024"`  // Minimum 1GB
 	CPULimit    int               `json:"cpu_limit" validate:"min=25,max=400"`      // 25% to 4 cores
-	DockerImage string            `json:"docker_image"`                             // Optional, defaults to itzg/minecraft-server
-	ServerType  string            `json:"server_type"`                              // e.g., LEAF, PAPER, VANILLA
-	Environment map[string]string `json:"environment"`                              // Additional env vars
+	DockerImage string            `json:"docker_image"`                             // One of the template's images, defaults to its first
+	ServerType  string            `json:"server_type"`                              // Shorthand for the TYPE variable, e.g., LEAF, PAPER, VANILLA
+	Variables   map[string]string `json:"variables"`                                // Template variable values by env variable
 }
 
-// DefaultMinecraftImage is the default Docker image for Minecraft servers
-const DefaultMinecraftImage = "itzg/minecraft-server"
+// Placement steers which node the placement engine picks for a server created
+// without a node_id
+type Placement struct {
+	Location     string            `json:"location"`      // Preferred node location
+	Labels       map[string]string `json:"labels"`        // Node labels that must match; "*" only requires the label
+	PreferLabels map[string]string `json:"prefer_labels"` // Node labels that raise a node's score
+	Strategy     string            `json:"strategy"`      // spread (default) fills the emptiest node, binpack the fullest
+}
 
-// NewServerFromRequest creates a Server from API request with defaults
-func NewServerFromRequest(req ServerCreateRequest, userID uuid.UUID) *Server {
-	image := req.DockerImage
-	if image == "" {
-		image = DefaultMinecraftImage
+// NewServerFromRequest creates a Server from API request with the template's defaults
+func NewServerFromRequest(req ServerCreateRequest, tpl *ServerTemplate, userID uuid.UUID) (*Server, error) {
+	image, err := tpl.Image(req.DockerImage)
+	if err != nil {
+		return nil, err
 	}
 
-	env := req.Environment
-	if env == nil {
-		env = make(map[string]string)
+	values := make(map[string]string, len(req.Variables)+1)
+	for key, value := range req.Variables {
+		values[key] = value
 	}
-
 	// Set server type if provided (e.g., TYPE=LEAF, TYPE=PAPER)
 	if req.ServerType != "" {
-		env["TYPE"] = req.ServerType
+		if !tpl.HasVariable("TYPE") {
+			return nil, fmt.Errorf("template %s has no server types", tpl.Name)
+		}
+		values["TYPE"] = req.ServerType
 	}
 
-	// Required for itzg/minecraft-server
-	env["EULA"] = "TRUE"
+	env, err := tpl.Environment(values)
+	if err != nil {
+		return nil, err
+	}
 
 	cpuLimit := req.CPULimit
 	if cpuLimit == 0 {
@@ -137,10 +176,11 @@ func NewServerFromRequest(req ServerCreateRequest, userID uuid.UUID) *Server {
 		MemoryLimit: req.MemoryLimit,
 		DiskLimit:   req.DiskLimit,
 		CPULimit:    cpuLimit,
+		TemplateID:  tpl.ID,
 		DockerImage: image,
 		Status:      StatusInstalling,
 		Environment: env,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
-	}
+	}, nil
 }
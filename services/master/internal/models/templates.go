@@ -0,0 +1,312 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultTemplateID is the Minecraft: Java Edition template seeded by the
+// migrations, used when a server is created without a template
+var DefaultTemplateID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// ServerTemplate (an "egg") describes how to run one kind of game server:
+// the images it can use, how the game is started, stopped and reached, and
+// which settings users may change
+type ServerTemplate struct {
+	ID               uuid.UUID          `json:"id"`
+	Name             string             `json:"name"`
+	Description      string             `json:"description,omitempty"`
+	DockerImages     []TemplateImage    `json:"docker_images"`     // Image choices; the first is the default
+	ContainerPort    int                `json:"container_port"`    // Game port inside the container
+	Protocol         string             `json:"protocol"`          // tcp or udp
+	StartupCommand   string             `json:"startup_command"`   // Empty = the image's own command; {{VAR}} is substituted
+	StopCommand      string             `json:"stop_command"`      // Console command to stop the game (empty = SIGTERM)
+	CommandTransport CommandTransport   `json:"command_transport"` // How console commands reach the server
+	DataMount        string             `json:"data_mount"`        // Container path of the data directory
+	Variables        []TemplateVariable `json:"variables"`
+	ReadinessProbes  []ReadinessProbe   `json:"readiness_probes"` // Empty = the agent's defaults for the image
+	ConfigFiles      []ConfigFile       `json:"config_files"`     // Edited before each start
+
+	// Install script, run once in a throwaway container before the server is created
+	InstallImage      string `json:"install_image,omitempty"`
+	InstallEntrypoint string `json:"install_entrypoint,omitempty"` // e.g. bash (empty = sh)
+	InstallScript     string `json:"install_script,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TemplateImage is one Docker image a template's servers can run
+type TemplateImage struct {
+	Name  string `json:"name"` // Shown to users, e.g. "Java 21"
+	Image string `json:"image"`
+}
+
+// VariableType selects how a template variable's value is validated
+type VariableType string
+
+const (
+	VariableString  VariableType = "string"
+	VariableInteger VariableType = "integer"
+	VariableBoolean VariableType = "boolean"
+	VariableEnum    VariableType = "enum"
+)
+
+// TemplateVariable is a setting passed to the server as an environment variable
+type TemplateVariable struct {
+	Name         string       `json:"name"`
+	Description  string       `json:"description,omitempty"`
+	EnvVariable  string       `json:"env_variable"`
+	Type         VariableType `json:"type"`
+	Default      string       `json:"default"`
+	Required     bool         `json:"required"`             // An empty value is rejected
+	UserEditable bool         `json:"user_editable"`        // Otherwise the default always applies
+	Options      []string     `json:"options,omitempty"`    // Allowed values of an enum
+	Min          *int64       `json:"min,omitempty"`        // Lower bound of an integer
+	Max          *int64       `json:"max,omitempty"`        // Upper bound of an integer
+	MaxLength    int          `json:"max_length,omitempty"` // Of a string (0 = unlimited)
+	Pattern      string       `json:"pattern,omitempty"`    // Regular expression a whole string must match
+}
+
+// ReadinessProbe tells the agent when a started server is up. Kind is log
+// (Pattern matches a console line), tcp or minecraft_ping (on Port inside the
+// container, 0 = the template's port).
+type ReadinessProbe struct {
+	Kind    string `json:"kind"`
+	Pattern string `json:"pattern,omitempty"`
+	Port    int    `json:"port,omitempty"`
+}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedEnv are variables the platform sets itself
+var reservedEnv = map[string]bool{
+	"MEMORY":        true,
+	"STARTUP":       true,
+	"SERVER_MEMORY": true,
+	"SERVER_PORT":   true,
+	"SERVER_IP":     true,
+}
+
+// Validate checks that a template is complete and consistent
+func (t *ServerTemplate) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(t.DockerImages) == 0 {
+		return fmt.Errorf("at least one docker image is required")
+	}
+	for _, img := range t.DockerImages {
+		if strings.TrimSpace(img.Image) == "" {
+			return fmt.Errorf("docker image %q has no image reference", img.Name)
+		}
+	}
+	if t.ContainerPort < 1 || t.ContainerPort > 65535 {
+		return fmt.Errorf("container_port must be between 1 and 65535")
+	}
+	if t.Protocol != "tcp" && t.Protocol != "udp" {
+		return fmt.Errorf("protocol must be tcp or udp")
+	}
+	if t.CommandTransport != TransportRCON && t.CommandTransport != TransportStdin {
+		return fmt.Errorf("command_transport must be rcon or stdin")
+	}
+	if !path.IsAbs(t.DataMount) {
+		return fmt.Errorf("data_mount must be an absolute path")
+	}
+	if t.InstallScript != "" && t.InstallImage == "" {
+		return fmt.Errorf("install_image is required with an install script")
+	}
+
+	seen := make(map[string]bool, len(t.Variables))
+	for i := range t.Variables {
+		v := &t.Variables[i]
+		if err := v.Check(); err != nil {
+			return fmt.Errorf("variable %q: %w", v.Name, err)
+		}
+		if seen[v.EnvVariable] {
+			return fmt.Errorf("variable %s is defined twice", v.EnvVariable)
+		}
+		seen[v.EnvVariable] = true
+	}
+
+	for i := range t.ConfigFiles {
+		if err := t.ConfigFiles[i].Validate(); err != nil {
+			return fmt.Errorf("config file %q: %w", t.ConfigFiles[i].Path, err)
+		}
+	}
+
+	for i, p := range t.ReadinessProbes {
+		switch p.Kind {
+		case "log":
+			if _, err := regexp.Compile(p.Pattern); err != nil || p.Pattern == "" {
+				return fmt.Errorf("readiness probe %d: log probes need a valid pattern", i)
+			}
+		case "tcp", "minecraft_ping":
+			if p.Port < 0 || p.Port > 65535 {
+				return fmt.Errorf("readiness probe %d: invalid port %d", i, p.Port)
+			}
+		default:
+			return fmt.Errorf("readiness probe %d: kind must be log, tcp or minecraft_ping", i)
+		}
+	}
+	return nil
+}
+
+// Check validates a variable's definition
+func (v *TemplateVariable) Check() error {
+	if !envNamePattern.MatchString(v.EnvVariable) {
+		return fmt.Errorf("invalid env_variable %q", v.EnvVariable)
+	}
+	if reservedEnv[v.EnvVariable] {
+		return fmt.Errorf("env_variable %s is set by the platform", v.EnvVariable)
+	}
+	switch v.Type {
+	case VariableString, VariableInteger, VariableBoolean:
+	case VariableEnum:
+		if len(v.Options) == 0 {
+			return fmt.Errorf("enum variables need options")
+		}
+	default:
+		return fmt.Errorf("type must be string, integer, boolean or enum")
+	}
+	if v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if v.Min != nil && v.Max != nil && *v.Min > *v.Max {
+		return fmt.Errorf("min is greater than max")
+	}
+	if v.Default != "" {
+		if err := v.ValidateValue(v.Default); err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateValue checks a value against the variable's type and rules
+func (v *TemplateVariable) ValidateValue(value string) error {
+	if value == "" {
+		if v.Required {
+			return fmt.Errorf("is required")
+		}
+		return nil
+	}
+
+	switch v.Type {
+	case VariableInteger:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be a whole number")
+		}
+		if v.Min != nil && n < *v.Min {
+			return fmt.Errorf("must be at least %d", *v.Min)
+		}
+		if v.Max != nil && n > *v.Max {
+			return fmt.Errorf("must be at most %d", *v.Max)
+		}
+	case VariableBoolean:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+	case VariableEnum:
+		for _, opt := range v.Options {
+			if value == opt {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(v.Options, ", "))
+	case VariableString:
+		if v.MaxLength > 0 && len(value) > v.MaxLength {
+			return fmt.Errorf("must be at most %d characters", v.MaxLength)
+		}
+		if v.Pattern != "" && !regexp.MustCompile(`^(?:`+v.Pattern+`)$`).MatchString(value) {
+			return fmt.Errorf("must match %s", v.Pattern)
+		}
+	}
+	return nil
+}
+
+// Environment resolves the variables for a new server from the values a user
+// gave, keyed by env variable. Variables left out get their default.
+func (t *ServerTemplate) Environment(values map[string]string) (map[string]string, error) {
+	env := make(map[string]string, len(t.Variables))
+	known := make(map[string]bool, len(t.Variables))
+	for i := range t.Variables {
+		v := &t.Variables[i]
+		known[v.EnvVariable] = true
+
+		value, given := values[v.EnvVariable]
+		if given && !v.UserEditable && value != v.Default {
+			return nil, fmt.Errorf("%s cannot be changed", v.EnvVariable)
+		}
+		if !given {
+			value = v.Default
+		}
+		if err := v.ValidateValue(value); err != nil {
+			return nil, fmt.Errorf("%s %w", v.EnvVariable, err)
+		}
+		env[v.EnvVariable] = value
+	}
+
+	for key := range values {
+		if !known[key] {
+			return nil, fmt.Errorf("unknown variable %s", key)
+		}
+	}
+	return env, nil
+}
+
+// Image returns the image reference for a choice given by name or reference,
+// or the default image if choice is empty
+func (t *ServerTemplate) Image(choice string) (string, error) {
+	if choice == "" {
+		return t.DockerImages[0].Image, nil
+	}
+	for _, img := range t.DockerImages {
+		if choice == img.Image || choice == img.Name {
+			return img.Image, nil
+		}
+	}
+	return "", fmt.Errorf("docker image %q is not offered by template %s", choice, t.Name)
+}
+
+// HasVariable reports whether the template defines an env variable
+func (t *ServerTemplate) HasVariable(envVariable string) bool {
+	for _, v := range t.Variables {
+		if v.EnvVariable == envVariable {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderStartup substitutes {{VAR}} placeholders in the startup command with
+// the server's variables and SERVER_MEMORY, SERVER_PORT and SERVER_IP
+func (t *ServerTemplate) RenderStartup(env map[string]string, memoryMB int64) string {
+	if t.StartupCommand == "" {
+		return ""
+	}
+	return t.Placeholders(env, memoryMB).Replace(t.StartupCommand)
+}
+
+// Placeholders returns a replacer for the {{VAR}} placeholders of a server
+// created from the template
+func (t *ServerTemplate) Placeholders(env map[string]string, memoryMB int64) *strings.Replacer {
+	pairs := []string{
+		"{{SERVER_MEMORY}}", strconv.FormatInt(memoryMB, 10),
+		"{{SERVER_PORT}}", strconv.Itoa(t.ContainerPort),
+		"{{SERVER_IP}}", "0.0.0.0",
+	}
+	for key, value := range env {
+		pairs = append(pairs, "{{"+key+"}}", value)
+	}
+	return strings.NewReplacer(pairs...)
+}
@@ -0,0 +1,214 @@
+// Package monitor keeps the server and node state stored by the master in
+// line with what the agents actually report.
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ironhost/master/internal/database"
+	mastergrpc "github.com/ironhost/master/internal/grpc"
+	agentpb "github.com/ironhost/master/internal/grpc/ironhost/v1"
+	"github.com/ironhost/master/internal/models"
+)
+
+const (
+	// nodeRefreshInterval is how often the list of nodes to watch is re-read
+	nodeRefreshInterval = 30 * time.Second
+	// minRetryDelay and maxRetryDelay bound the backoff between reconnects to an agent
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = time.Minute
+)
+
+// EventWatcher holds a WatchEvents stream open to every node and writes the
+// status transitions it receives to the servers table
+type EventWatcher struct {
+	db       *database.DB
+	grpcPool *mastergrpc.ClientPool
+
+	mu       sync.Mutex
+	watching map[uuid.UUID]*nodeWatch
+}
+
+// nodeWatch is the stream to one node. It is restarted if the node's
+// address or token changes.
+type nodeWatch struct {
+	address string
+	token   string
+	cancel  context.CancelFunc
+}
+
+// NewEventWatcher creates an event watcher
+func NewEventWatcher(db *database.DB, grpcPool *mastergrpc.ClientPool) *EventWatcher {
+	return &EventWatcher{
+		db:       db,
+		grpcPool: grpcPool,
+		watching: make(map[uuid.UUID]*nodeWatch),
+	}
+}
+
+// Run watches every node until ctx is done, picking up added and removed nodes
+func (w *EventWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(nodeRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		w.syncNodes(ctx)
+
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			for id, nw := range w.watching {
+				nw.cancel()
+				delete(w.watching, id)
+			}
+			w.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncNodes starts a stream for each new node and stops those of removed ones
+func (w *EventWatcher) syncNodes(ctx context.Context) {
+	nodes, err := w.db.ListNodes(ctx)
+	if err != nil {
+		log.Printf("Event watcher: failed to list nodes: %v", err)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	current := make(map[uuid.UUID]bool, len(nodes))
+	for _, node := range nodes {
+		current[node.ID] = true
+
+		if nw, ok := w.watching[node.ID]; ok {
+			if nw.address == node.GetAddress() && nw.token == node.DaemonTokenHash {
+				continue
+			}
+			nw.cancel()
+		}
+
+		nodeCtx, cancel := context.WithCancel(ctx)
+		w.watching[node.ID] = &nodeWatch{address: node.GetAddress(), token: node.DaemonTokenHash, cancel: cancel}
+		go w.watchNode(nodeCtx, node)
+	}
+
+	for id, nw := range w.watching {
+		if !current[id] {
+			nw.cancel()
+			delete(w.watching, id)
+		}
+	}
+}
+
+// watchNode keeps a stream open to one node, reconnecting with backoff
+func (w *EventWatcher) watchNode(ctx context.Context, node *database.Node) {
+	delay := minRetryDelay
+	for {
+		started := time.Now()
+		err := w.stream(ctx, node)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A stream that stayed up for a while was healthy; start backing off afresh
+		if time.Since(started) > maxRetryDelay {
+			delay = minRetryDelay
+		}
+		log.Printf("Event watcher: stream to node %s ended: %v (retrying in %s)", node.Name, err, delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// stream consumes one WatchEvents stream, starting from a snapshot so that
+// anything missed while disconnected is corrected
+func (w *EventWatcher) stream(ctx context.Context, node *database.Node) error {
+	conn, err := w.grpcPool.GetClient(node.GetAddress(), node.Scheme == "http")
+	if err != nil {
+		return fmt.Errorf("failed to connect to agent: %w", err)
+	}
+
+	client := agentpb.NewAgentServiceClient(conn)
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+node.DaemonTokenHash)
+
+	stream, err := client.WatchEvents(ctx, &agentpb.WatchEventsRequest{Snapshot: true})
+	if err != nil {
+		return err
+	}
+
+	for {
+		ev, err := stream.Recv()
+		if err == io.EOF {
+			return fmt.Errorf("agent closed the stream")
+		}
+		if err != nil {
+			return err
+		}
+		w.apply(ctx, node, ev)
+	}
+}
+
+// apply records the status carried by an event
+func (w *EventWatcher) apply(ctx context.Context, node *database.Node, ev *agentpb.ServerEvent) {
+	if ev.Action == "oom" {
+		log.Printf("Server %s on node %s ran out of memory", ev.ServerId, node.Name)
+	}
+
+	status, ok := StatusFromProto(ev.Status)
+	if !ok {
+		return
+	}
+	serverID, err := uuid.Parse(ev.ServerId)
+	if err != nil {
+		return // Not a server the master created
+	}
+
+	changed, err := w.db.SyncServerStatus(ctx, serverID, node.ID, status)
+	if err != nil {
+		log.Printf("Event watcher: failed to update status of server %s: %v", serverID, err)
+		return
+	}
+	if changed {
+		if ev.Action == "die" && ev.ExitCode != 0 {
+			log.Printf("Server %s on node %s exited with code %d", serverID, node.Name, ev.ExitCode)
+		}
+		log.Printf("Server %s is now %s (%s)", serverID, status, ev.Action)
+	}
+}
+
+// StatusFromProto converts an agent status to the stored form. It returns
+// false for UNSPECIFIED, which means the status is unknown or unchanged.
+func StatusFromProto(status agentpb.ServerStatus) (models.ServerStatus, bool) {
+	switch status {
+	case agentpb.ServerStatus_SERVER_STATUS_INSTALLING:
+		return models.StatusInstalling, true
+	case agentpb.ServerStatus_SERVER_STATUS_OFFLINE:
+		return models.StatusOffline, true
+	case agentpb.ServerStatus_SERVER_STATUS_STARTING:
+		return models.StatusStarting, true
+	case agentpb.ServerStatus_SERVER_STATUS_RUNNING:
+		return models.StatusRunning, true
+	case agentpb.ServerStatus_SERVER_STATUS_STOPPING:
+		return models.StatusStopping, true
+	case agentpb.ServerStatus_SERVER_STATUS_SUSPENDED:
+		return models.StatusSuspended, true
+	default:
+		return "", false
+	}
+}
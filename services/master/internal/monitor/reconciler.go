@@ -0,0 +1,200 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/ironhost/master/internal/database"
+	mastergrpc "github.com/ironhost/master/internal/grpc"
+	agentpb "github.com/ironhost/master/internal/grpc/ironhost/v1"
+	"github.com/ironhost/master/internal/models"
+)
+
+// probeTimeout bounds a single ListServers call to a node
+const probeTimeout = 30 * time.Second
+
+// Reconciler periodically compares what each node reports with the servers
+// table. Event streams keep statuses current between passes; the reconciler
+// catches anything they missed, servers that vanished from their node, and
+// nodes that stopped answering.
+type Reconciler struct {
+	db               *database.DB
+	grpcPool         *mastergrpc.ClientPool
+	interval         time.Duration
+	failureThreshold int
+
+	mu       sync.Mutex
+	failures map[uuid.UUID]int // Consecutive failed probes by node ID
+}
+
+// NewReconciler creates a reconciler that runs every interval and marks a
+// node unreachable after failureThreshold consecutive failed probes
+func NewReconciler(db *database.DB, grpcPool *mastergrpc.ClientPool, interval time.Duration, failureThreshold int) *Reconciler {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return &Reconciler{
+		db:               db,
+		grpcPool:         grpcPool,
+		interval:         interval,
+		failureThreshold: failureThreshold,
+		failures:         make(map[uuid.UUID]int),
+	}
+}
+
+// Run reconciles all nodes every interval until ctx is done
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		r.reconcileAll(ctx)
+	}
+}
+
+// reconcileAll probes every node in parallel
+func (r *Reconciler) reconcileAll(ctx context.Context) {
+	nodes, err := r.db.ListNodes(ctx)
+	if err != nil {
+		log.Printf("Reconciler: failed to list nodes: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.reconcileNode(ctx, node)
+		}()
+	}
+	wg.Wait()
+}
+
+// reconcileNode brings the stored status of a node's servers in line with
+// what its agent reports
+func (r *Reconciler) reconcileNode(ctx context.Context, node *database.Node) {
+	// Read the rows before probing, so a server created meanwhile is not
+	// mistaken for one the node has lost
+	servers, err := r.db.ListServerStatusesByNode(ctx, node.ID)
+	if err != nil {
+		log.Printf("Reconciler: failed to list servers of node %s: %v", node.Name, err)
+		return
+	}
+
+	resp, err := r.probe(ctx, node)
+	if err != nil {
+		r.recordFailure(ctx, node, err)
+		return
+	}
+	r.recordSuccess(ctx, node)
+
+	reported := make(map[string]*agentpb.ServerState, len(resp.Servers))
+	for _, state := range resp.Servers {
+		reported[state.ServerId] = state
+	}
+
+	for _, server := range servers {
+		state, ok := reported[server.ID.String()]
+		if !ok {
+			r.markMissing(ctx, node, server)
+			continue
+		}
+
+		if server.MissingSince != nil {
+			log.Printf("Reconciler: server %s is back on node %s", server.ID, node.Name)
+			if err := r.db.SetServerMissing(ctx, server.ID, false); err != nil {
+				log.Printf("Reconciler: failed to clear missing flag of server %s: %v", server.ID, err)
+			}
+		}
+
+		status, ok := StatusFromProto(state.Status)
+		if !ok || status == server.Status {
+			continue
+		}
+		changed, err := r.db.SyncServerStatus(ctx, server.ID, node.ID, status)
+		if err != nil {
+			log.Printf("Reconciler: failed to update status of server %s: %v", server.ID, err)
+			continue
+		}
+		if changed {
+			log.Printf("Reconciler: server %s drifted from %s to %s", server.ID, server.Status, status)
+		}
+	}
+}
+
+// markMissing flags a server its node does not have. Servers still being
+// installed, or whose install failed, have no container and are left alone.
+func (r *Reconciler) markMissing(ctx context.Context, node *database.Node, server *models.Server) {
+	if server.Status == models.StatusInstalling || server.Status == models.StatusInstallFailed {
+		return
+	}
+	if server.MissingSince == nil {
+		log.Printf("Reconciler: server %s is missing on node %s", server.ID, node.Name)
+	}
+	if err := r.db.SetServerMissing(ctx, server.ID, true); err != nil {
+		log.Printf("Reconciler: failed to flag server %s as missing: %v", server.ID, err)
+	}
+	if _, err := r.db.SyncServerStatus(ctx, server.ID, node.ID, models.StatusOffline); err != nil {
+		log.Printf("Reconciler: failed to update status of server %s: %v", server.ID, err)
+	}
+}
+
+// probe asks a node for the state of all its servers
+func (r *Reconciler) probe(ctx context.Context, node *database.Node) (*agentpb.ListServersResponse, error) {
+	conn, err := r.grpcPool.GetClient(node.GetAddress(), node.Scheme == "http")
+	if err != nil {
+		return nil, err
+	}
+
+	client := agentpb.NewAgentServiceClient(conn)
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+node.DaemonTokenHash)
+
+	return client.ListServers(ctx, &emptypb.Empty{})
+}
+
+// recordFailure counts a failed probe and marks the node unreachable once the
+// threshold is reached
+func (r *Reconciler) recordFailure(ctx context.Context, node *database.Node, err error) {
+	r.mu.Lock()
+	r.failures[node.ID]++
+	failures := r.failures[node.ID]
+	r.mu.Unlock()
+
+	log.Printf("Reconciler: probe of node %s failed (%d/%d): %v", node.Name, failures, r.failureThreshold, err)
+	if failures < r.failureThreshold || node.UnreachableSince != nil {
+		return
+	}
+
+	log.Printf("Reconciler: marking node %s unreachable", node.Name)
+	if err := r.db.MarkNodeUnreachable(ctx, node.ID); err != nil {
+		log.Printf("Reconciler: failed to mark node %s unreachable: %v", node.Name, err)
+	}
+}
+
+// recordSuccess resets the failure count and clears the unreachable flag
+func (r *Reconciler) recordSuccess(ctx context.Context, node *database.Node) {
+	r.mu.Lock()
+	delete(r.failures, node.ID)
+	r.mu.Unlock()
+
+	if node.UnreachableSince != nil {
+		log.Printf("Reconciler: node %s is reachable again", node.Name)
+	}
+	if err := r.db.MarkNodeSeen(ctx, node.ID); err != nil {
+		log.Printf("Reconciler: failed to record probe of node %s: %v", node.Name, err)
+	}
+}
@@ -0,0 +1,236 @@
+// Package placement picks the node for a server created without a node_id.
+// Nodes that cannot take the server are filtered out; the rest are scored on
+// free capacity, location, labels and free allocations, and the decision is
+// explained so admins can see why a node won or lost.
+package placement
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/ironhost/master/internal/database"
+	agentpb "github.com/ironhost/master/internal/grpc/ironhost/v1"
+	"github.com/ironhost/master/internal/models"
+)
+
+// Strategies
+const (
+	// Spread places servers on the node with the most room left, so load
+	// spreads evenly and one node failing affects few servers
+	Spread = "spread"
+	// BinPack places servers on the fullest node that still fits, keeping
+	// other nodes empty for large servers or for draining
+	BinPack = "binpack"
+)
+
+// Score weights; a node's score is out of their sum, 100
+const (
+	capacityWeight    = 50
+	locationWeight    = 25
+	labelWeight       = 15
+	allocationsWeight = 10
+
+	// enoughAllocations free allocations earn a node the full allocations score
+	enoughAllocations = 10
+)
+
+// ErrNoNode is returned when no node can take the server
+var ErrNoNode = errors.New("no node can take the server")
+
+// Request describes the server to place
+type Request struct {
+	MemoryMB   int64
+	CPUPercent int
+	DiskMB     int64
+	models.Placement
+}
+
+// Candidate is a node with what is known about it right now
+type Candidate struct {
+	Node            *database.Node
+	Stats           *agentpb.NodeStats // Live stats, nil if the agent did not answer
+	StatsError      error
+	FreeAllocations int // Allocations of the server's protocol the node can still hand out
+}
+
+// Evaluation is how one node fared
+type Evaluation struct {
+	NodeID   uuid.UUID `json:"node_id"`
+	NodeName string    `json:"node_name"`
+	Eligible bool      `json:"eligible"`
+	Score    float64   `json:"score"`   // 0-100, for eligible nodes
+	Reasons  []string  `json:"reasons"` // Why the node was rejected, or how its score was made up
+}
+
+// Decision is the chosen node and how every node was evaluated
+type Decision struct {
+	Node        *database.Node `json:"-"`
+	NodeID      uuid.UUID      `json:"node_id"`
+	NodeName    string         `json:"node_name"`
+	Strategy    string         `json:"strategy"`
+	Evaluations []Evaluation   `json:"evaluations"` // Best first, rejected nodes last
+}
+
+// CheckRequest validates placement preferences
+func CheckRequest(p *models.Placement) error {
+	if p.Strategy != "" && p.Strategy != Spread && p.Strategy != BinPack {
+		return fmt.Errorf("strategy must be %s or %s", Spread, BinPack)
+	}
+	return nil
+}
+
+// Choose picks the best node for req. If none can take the server the
+// decision is still returned, with why each node was rejected, alongside
+// ErrNoNode.
+func Choose(req Request, candidates []Candidate) (*Decision, error) {
+	strategy := req.Strategy
+	if strategy == "" {
+		strategy = Spread
+	}
+	decision := &Decision{Strategy: strategy, Evaluations: make([]Evaluation, 0, len(candidates))}
+
+	byID := make(map[uuid.UUID]*database.Node, len(candidates))
+	for _, c := range candidates {
+		byID[c.Node.ID] = c.Node
+		decision.Evaluations = append(decision.Evaluations, evaluate(req, strategy, c))
+	}
+
+	sort.SliceStable(decision.Evaluations, func(i, j int) bool {
+		a, b := decision.Evaluations[i], decision.Evaluations[j]
+		if a.Eligible != b.Eligible {
+			return a.Eligible
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.NodeName < b.NodeName
+	})
+
+	if len(decision.Evaluations) == 0 || !decision.Evaluations[0].Eligible {
+		return decision, ErrNoNode
+	}
+	best := decision.Evaluations[0]
+	decision.Node = byID[best.NodeID]
+	decision.NodeID = best.NodeID
+	decision.NodeName = best.NodeName
+	return decision, nil
+}
+
+// evaluate filters and scores one node
+func evaluate(req Request, strategy string, c Candidate) Evaluation {
+	node := c.Node
+	e := Evaluation{NodeID: node.ID, NodeName: node.Name}
+	reject := func(format string, args ...any) Evaluation {
+		e.Reasons = append(e.Reasons, fmt.Sprintf(format, args...))
+		return e
+	}
+
+	switch {
+	case node.MaintenanceMode:
+		return reject("in maintenance mode")
+	case node.UnreachableSince != nil:
+		return reject("unreachable since %s", node.UnreachableSince.Format("2006-01-02 15:04:05"))
+	case c.Stats == nil:
+		return reject("live stats unavailable: %v", c.StatsError)
+	}
+	for key, want := range req.Labels {
+		have, ok := node.Labels[key]
+		if !ok {
+			return reject("missing required label %s", key)
+		}
+		if want != "*" && have != want {
+			return reject("label %s is %q, %q required", key, have, want)
+		}
+	}
+	if err := node.CheckCapacity(req.MemoryMB, req.CPUPercent, req.DiskMB); err != nil {
+		return reject("%v", err)
+	}
+	if c.FreeAllocations <= 0 {
+		return reject("no free allocations")
+	}
+	e.Eligible = true
+
+	// Capacity: the share of the node left free once the server is placed
+	headroom, detail := headroom(req, node, c.Stats)
+	capacityScore := headroom
+	if strategy == BinPack {
+		capacityScore = 1 - headroom
+	}
+	e.Score += capacityWeight * capacityScore
+	e.Reasons = append(e.Reasons, fmt.Sprintf("%.0f%% free after placement (%s): +%.1f", headroom*100, detail, capacityWeight*capacityScore))
+
+	if req.Location != "" {
+		if strings.EqualFold(node.Location, req.Location) {
+			e.Score += locationWeight
+			e.Reasons = append(e.Reasons, fmt.Sprintf("in preferred location %s: +%d", req.Location, locationWeight))
+		} else {
+			e.Reasons = append(e.Reasons, fmt.Sprintf("location %q is not the preferred %s: +0", node.Location, req.Location))
+		}
+	}
+
+	if len(req.PreferLabels) > 0 {
+		matched := 0
+		for key, want := range req.PreferLabels {
+			if have, ok := node.Labels[key]; ok && (want == "*" || have == want) {
+				matched++
+			}
+		}
+		score := labelWeight * float64(matched) / float64(len(req.PreferLabels))
+		e.Score += score
+		e.Reasons = append(e.Reasons, fmt.Sprintf("%d of %d preferred labels: +%.1f", matched, len(req.PreferLabels), score))
+	}
+
+	allocationsScore := allocationsWeight * float64(min(c.FreeAllocations, enoughAllocations)) / enoughAllocations
+	e.Score += allocationsScore
+	e.Reasons = append(e.Reasons, fmt.Sprintf("%d free allocations: +%.1f", c.FreeAllocations, allocationsScore))
+
+	return e
+}
+
+// headroom returns the share (0-1) of a node left free once the server is
+// placed: the average over its limited resources by the master's accounting
+// and its memory, disk and CPU as the agent reports them live
+func headroom(req Request, node *database.Node, stats *agentpb.NodeStats) (float64, string) {
+	var shares []float64
+	var parts []string
+	share := func(name string, free, total float64) {
+		s := clamp(free / total)
+		shares = append(shares, s)
+		parts = append(parts, fmt.Sprintf("%s %.0f%%", name, s*100))
+	}
+
+	freeMemory, freeCPU, freeDisk := node.Free()
+	if freeMemory >= 0 {
+		share("allocated memory", float64(freeMemory-req.MemoryMB), float64(node.MemoryTotal)*node.MemoryOvercommit)
+	}
+	if freeCPU >= 0 {
+		share("allocated cpu", float64(freeCPU-int64(req.CPUPercent)), float64(node.CPUTotal)*node.CPUOvercommit)
+	}
+	if freeDisk >= 0 {
+		share("allocated disk", float64(freeDisk-req.DiskMB), float64(node.DiskTotal)*node.DiskOvercommit)
+	}
+
+	const mb = 1024 * 1024
+	if stats.TotalMemoryBytes > 0 {
+		share("live memory", float64(stats.AvailableMemoryBytes-req.MemoryMB*mb), float64(stats.TotalMemoryBytes))
+	}
+	if stats.TotalDiskBytes > 0 {
+		share("live disk", float64(stats.AvailableDiskBytes-req.DiskMB*mb), float64(stats.TotalDiskBytes))
+	}
+	share("live cpu", 100-stats.CpuUsagePercent, 100)
+
+	var sum float64
+	for _, s := range shares {
+		sum += s
+	}
+	return sum / float64(len(shares)), strings.Join(parts, ", ")
+}
+
+// clamp limits a share to 0-1
+func clamp(v float64) float64 {
+	return max(0, min(v, 1))
+}
@@ -0,0 +1,152 @@
+package placement
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ironhost/master/internal/database"
+	agentpb "github.com/ironhost/master/internal/grpc/ironhost/v1"
+	"github.com/ironhost/master/internal/models"
+)
+
+const gb = 1024 * 1024 * 1024
+
+// node returns a healthy 16 GB node with memoryAllocated MB given out
+func node(name string, memoryAllocated int64) *database.Node {
+	return &database.Node{
+		ID:               uuid.New(),
+		Name:             name,
+		Location:         "eu-west",
+		Labels:           map[string]string{"disk": "ssd"},
+		MemoryTotal:      16384,
+		MemoryAllocated:  memoryAllocated,
+		MemoryOvercommit: 1,
+		CPUOvercommit:    1,
+		DiskOvercommit:   1,
+	}
+}
+
+// candidate pairs a node with live stats that match its accounting
+func candidate(n *database.Node) Candidate {
+	return Candidate{
+		Node: n,
+		Stats: &agentpb.NodeStats{
+			TotalMemoryBytes:     16 * gb,
+			AvailableMemoryBytes: (16384 - n.MemoryAllocated) * 1024 * 1024,
+			TotalDiskBytes:       100 * gb,
+			AvailableDiskBytes:   50 * gb,
+			CpuUsagePercent:      20,
+		},
+		FreeAllocations: 20,
+	}
+}
+
+func TestChooseRejects(t *testing.T) {
+	unreachable := time.Now()
+	for _, tc := range []struct {
+		name   string
+		req    Request
+		modify func(*Candidate)
+		reason string
+	}{
+		{"maintenance", Request{}, func(c *Candidate) { c.Node.MaintenanceMode = true }, "maintenance"},
+		{"unreachable", Request{}, func(c *Candidate) { c.Node.UnreachableSince = &unreachable }, "unreachable"},
+		{"no stats", Request{}, func(c *Candidate) { c.Stats, c.StatsError = nil, errors.New("dial tcp: refused") }, "live stats unavailable"},
+		{"missing label", Request{Placement: models.Placement{Labels: map[string]string{"gpu": "*"}}}, nil, "missing required label gpu"},
+		{"wrong label", Request{Placement: models.Placement{Labels: map[string]string{"disk": "nvme"}}}, nil, "label disk"},
+		{"full", Request{MemoryMB: 4096}, func(c *Candidate) { c.Node.MemoryAllocated = 14336 }, "not have enough free capacity"},
+		{"overcommit still full", Request{MemoryMB: 4096}, func(c *Candidate) {
+			c.Node.MemoryAllocated = 18432
+			c.Node.MemoryOvercommit = 1.25
+		}, "not have enough free capacity"},
+		{"no allocations", Request{}, func(c *Candidate) { c.FreeAllocations = 0 }, "no free allocations"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := candidate(node("node-1", 0))
+			if tc.modify != nil {
+				tc.modify(&c)
+			}
+			decision, err := Choose(tc.req, []Candidate{c})
+			if !errors.Is(err, ErrNoNode) {
+				t.Fatalf("Choose returned %v, want ErrNoNode", err)
+			}
+			e := decision.Evaluations[0]
+			if e.Eligible || len(e.Reasons) != 1 || !strings.Contains(e.Reasons[0], tc.reason) {
+				t.Fatalf("evaluation = %+v, want rejected for %q", e, tc.reason)
+			}
+		})
+	}
+}
+
+func TestChooseScores(t *testing.T) {
+	empty, full := node("empty", 0), node("full", 12288)
+	elsewhere := node("elsewhere", 0)
+	elsewhere.Location = "us-east"
+	nvme := node("nvme", 6144)
+	nvme.Labels = map[string]string{"disk": "nvme"}
+
+	for _, tc := range []struct {
+		name  string
+		req   Request
+		nodes []*database.Node
+		want  string
+	}{
+		{"spread picks the emptiest", Request{MemoryMB: 1024}, []*database.Node{full, empty}, "empty"},
+		{"binpack picks the fullest", Request{MemoryMB: 1024, Placement: models.Placement{Strategy: BinPack}}, []*database.Node{empty, full}, "full"},
+		{"location outweighs room", Request{MemoryMB: 1024, Placement: models.Placement{Location: "EU-WEST"}}, []*database.Node{elsewhere, full}, "full"},
+		{"preferred labels", Request{MemoryMB: 1024, Placement: models.Placement{PreferLabels: map[string]string{"disk": "nvme"}}}, []*database.Node{empty, nvme}, "nvme"},
+		{"wildcard label constraint", Request{MemoryMB: 1024, Placement: models.Placement{Labels: map[string]string{"disk": "*"}}}, []*database.Node{full, empty}, "empty"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			candidates := make([]Candidate, 0, len(tc.nodes))
+			for _, n := range tc.nodes {
+				candidates = append(candidates, candidate(n))
+			}
+			decision, err := Choose(tc.req, candidates)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision.NodeName != tc.want {
+				t.Fatalf("chose %s, want %s; evaluations: %+v", decision.NodeName, tc.want, decision.Evaluations)
+			}
+			if decision.Node.Name != tc.want || decision.NodeID != decision.Node.ID {
+				t.Fatalf("decision node %s (%s) does not match %s", decision.Node.Name, decision.NodeID, tc.want)
+			}
+		})
+	}
+}
+
+func TestChooseOrder(t *testing.T) {
+	// Identical nodes tie and are ordered by name; rejected ones come last
+	rejected := candidate(node("a-maintenance", 0))
+	rejected.Node.MaintenanceMode = true
+	decision, err := Choose(Request{MemoryMB: 1024}, []Candidate{
+		candidate(node("c", 0)), rejected, candidate(node("b", 0)), candidate(node("d", 8192)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, e := range decision.Evaluations {
+		got = append(got, e.NodeName)
+	}
+	if want := "b c d a-maintenance"; strings.Join(got, " ") != want {
+		t.Fatalf("evaluation order = %v, want %s", got, want)
+	}
+	if decision.Strategy != Spread {
+		t.Fatalf("default strategy = %s, want %s", decision.Strategy, Spread)
+	}
+}
+
+func TestCheckRequest(t *testing.T) {
+	for strategy, ok := range map[string]bool{"": true, Spread: true, BinPack: true, "random": false} {
+		if err := CheckRequest(&models.Placement{Strategy: strategy}); (err == nil) != ok {
+			t.Errorf("CheckRequest(strategy %q) = %v", strategy, err)
+		}
+	}
+}
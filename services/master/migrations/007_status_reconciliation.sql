@@ -0,0 +1,7 @@
+-- 007_status_reconciliation.sql
+-- Track what the status reconciler finds: servers their node no longer has,
+-- and nodes that stop answering probes
+
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS missing_since TIMESTAMPTZ;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS unreachable_since TIMESTAMPTZ;
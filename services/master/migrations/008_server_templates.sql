@@ -0,0 +1,49 @@
+-- 008_server_templates.sql
+-- Server templates ("eggs"): how to run one kind of game server. Servers are
+-- created from a template; existing servers get the Minecraft template, which
+-- matches what the platform used to hardcode.
+
+CREATE TABLE IF NOT EXISTS server_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    docker_images JSONB NOT NULL DEFAULT '[]',      -- [{"name": "...", "image": "..."}], first is the default
+    container_port INTEGER NOT NULL,
+    protocol VARCHAR(3) NOT NULL DEFAULT 'tcp',     -- tcp or udp
+    startup_command TEXT NOT NULL DEFAULT '',       -- Empty = the image's own command
+    stop_command VARCHAR(255) NOT NULL DEFAULT '',  -- Empty = SIGTERM
+    command_transport VARCHAR(10) NOT NULL DEFAULT 'rcon',
+    data_mount VARCHAR(255) NOT NULL DEFAULT '/data',
+    variables JSONB NOT NULL DEFAULT '[]',
+    readiness_probes JSONB NOT NULL DEFAULT '[]',
+    install_image VARCHAR(255) NOT NULL DEFAULT '',
+    install_entrypoint VARCHAR(100) NOT NULL DEFAULT '',
+    install_script TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO server_templates (
+    id, name, description, docker_images, container_port, protocol, stop_command,
+    command_transport, data_mount, variables, readiness_probes
+) VALUES (
+    '00000000-0000-0000-0000-000000000001',
+    'Minecraft: Java Edition',
+    'Vanilla, Paper, Spigot, Forge, Fabric, Purpur and Leaf servers on itzg/minecraft-server',
+    '[{"name": "Latest Java", "image": "itzg/minecraft-server"}]',
+    25565, 'tcp', 'stop', 'rcon', '/data',
+    '[
+        {"name": "EULA", "description": "Accept the Minecraft EULA", "env_variable": "EULA", "type": "boolean", "default": "TRUE", "required": true, "user_editable": false},
+        {"name": "Server type", "env_variable": "TYPE", "type": "enum", "default": "VANILLA", "required": true, "user_editable": true,
+         "options": ["VANILLA", "PAPER", "SPIGOT", "FORGE", "FABRIC", "PURPUR", "LEAF"]},
+        {"name": "Minecraft version", "description": "A version such as 1.20.4, or LATEST", "env_variable": "VERSION", "type": "string", "default": "LATEST", "required": true, "user_editable": true,
+         "max_length": 32, "pattern": "LATEST|SNAPSHOT|[0-9][0-9A-Za-z.\\-]*"}
+    ]',
+    '[{"kind": "log", "pattern": "Done \\([0-9.,]+m?s\\)! For help"}, {"kind": "minecraft_ping"}]'
+) ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS template_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES server_templates(id) ON DELETE RESTRICT;
+ALTER TABLE servers ALTER COLUMN template_id DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_servers_template_id ON servers(template_id);
@@ -0,0 +1,5 @@
+-- 009_template_config_files.sql
+-- Config files a template edits before each start, e.g. to force the game
+-- port in server.properties. Imported Pterodactyl eggs use these.
+
+ALTER TABLE server_templates ADD COLUMN IF NOT EXISTS config_files JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,6 @@
+-- 010_install_results.sql
+-- Outcome of the last install script run. Servers whose template has no
+-- install script keep NULLs.
+
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS install_exit_code INTEGER;
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS installed_at TIMESTAMPTZ;
@@ -0,0 +1,19 @@
+-- 011_allocation_ports.sql
+-- Allocations carry their protocol and the port they map to inside the
+-- server's container, so a server can publish several TCP and UDP ports.
+-- A port number may be allocated once per protocol.
+
+ALTER TABLE allocations ADD COLUMN IF NOT EXISTS protocol VARCHAR(3) NOT NULL DEFAULT 'tcp'
+    CHECK (protocol IN ('tcp', 'udp'));
+ALTER TABLE allocations ADD COLUMN IF NOT EXISTS container_port INTEGER;  -- NULL = same as port
+
+ALTER TABLE allocations DROP CONSTRAINT IF EXISTS allocations_node_id_port_key;
+ALTER TABLE allocations DROP CONSTRAINT IF EXISTS allocations_node_id_port_protocol_key;
+ALTER TABLE allocations ADD CONSTRAINT allocations_node_id_port_protocol_key UNIQUE (node_id, port, protocol);
+
+-- Primary allocations map to their template's game port, as they always did
+UPDATE allocations a
+SET protocol = t.protocol, container_port = t.container_port
+FROM servers s
+JOIN server_templates t ON t.id = s.template_id
+WHERE s.primary_allocation_id = a.id AND a.container_port IS NULL;
@@ -0,0 +1,13 @@
+-- 012_allocation_pools.sql
+-- Admins create allocations in bulk per node and address. A port may be
+-- allocated once per address and protocol; allocations on a hostname or
+-- 0.0.0.0 bind every address of the node, which the API checks for overlaps.
+
+ALTER TABLE allocations DROP CONSTRAINT IF EXISTS allocations_node_id_port_protocol_key;
+ALTER TABLE allocations DROP CONSTRAINT IF EXISTS allocations_node_id_ip_address_port_protocol_key;
+ALTER TABLE allocations ADD CONSTRAINT allocations_node_id_ip_address_port_protocol_key
+    UNIQUE (node_id, ip_address, port, protocol);
+
+-- Free allocations are picked per node and protocol, lowest port first
+CREATE INDEX IF NOT EXISTS idx_allocations_free
+    ON allocations(node_id, protocol, port) WHERE server_id IS NULL;
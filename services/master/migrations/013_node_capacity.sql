@@ -0,0 +1,16 @@
+-- 013_node_capacity.sql
+-- Nodes track the CPU, memory and disk their servers are given, kept in step
+-- with the servers table. Capacity is the total times the overcommit ratio;
+-- a total of 0 leaves that resource unlimited.
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS cpu_total INTEGER NOT NULL DEFAULT 0;  -- in percent, 100 = 1 core
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS cpu_allocated INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS memory_overcommit REAL NOT NULL DEFAULT 1.0 CHECK (memory_overcommit > 0);
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS cpu_overcommit REAL NOT NULL DEFAULT 1.0 CHECK (cpu_overcommit > 0);
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS disk_overcommit REAL NOT NULL DEFAULT 1.0 CHECK (disk_overcommit > 0);
+
+-- The allocated totals were never maintained; derive them from the servers
+UPDATE nodes n SET
+    memory_allocated = COALESCE((SELECT SUM(memory_limit) FROM servers s WHERE s.node_id = n.id), 0),
+    cpu_allocated = COALESCE((SELECT SUM(cpu_limit) FROM servers s WHERE s.node_id = n.id), 0),
+    disk_allocated = COALESCE((SELECT SUM(disk_limit) FROM servers s WHERE s.node_id = n.id), 0);
@@ -0,0 +1,5 @@
+-- 014_node_labels.sql
+-- Free-form labels on nodes (e.g. {"disk": "nvme", "tier": "premium"}), which
+-- the placement engine matches when a server is created without a node_id.
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,12 @@
+-- 015_automatic_allocations.sql
+-- Allocations the master creates itself, on the automatic port range, for
+-- nodes without an admin-managed pool. Nodes with a pool never get them.
+
+ALTER TABLE allocations ADD COLUMN IF NOT EXISTS automatic BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Before pools every allocation was created this way: on the node's FQDN,
+-- in the automatic range and without notes
+UPDATE allocations a SET automatic = TRUE
+FROM nodes n
+WHERE a.node_id = n.id AND a.ip_address = n.fqdn AND a.notes IS NULL
+    AND a.port BETWEEN 25565 AND 25600;
@@ -0,0 +1,5 @@
+-- 016_server_io_weight.sql
+-- Block IO weight of a server's container, 10-1000; 0 leaves Docker's default.
+
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS io_weight INTEGER NOT NULL DEFAULT 0
+    CHECK (io_weight = 0 OR io_weight BETWEEN 10 AND 1000);